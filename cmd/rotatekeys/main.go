@@ -0,0 +1,72 @@
+// rotatekeys 使用当前主密钥重新加密数据库中的供应商上游 Key。
+//
+// 启用加密后首次运行会加密存量明文 Key；更换主密钥时，把新主密钥配置为 encryption.masterKey、
+// 旧主密钥加入 encryption.previousKeys 后运行，完成后即可移除旧主密钥。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/ioc"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/repository"
+	"ai-gateway/internal/repository/cache"
+	"ai-gateway/internal/repository/dao"
+)
+
+func main() {
+	configPath := flag.String("config", "", "配置文件路径")
+	flag.Parse()
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: rotatekeys -config <path>")
+		os.Exit(2)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fatal("failed to load config", err)
+	}
+
+	l := ioc.InitLogger(cfg)
+	if cfg.Encryption.MasterKey == "" {
+		fatal("encryption master key is not configured", nil)
+	}
+	keyring, err := ioc.InitKeyring(cfg, l)
+	if err != nil {
+		fatal("failed to init keyring", err)
+	}
+	db, err := ioc.InitDB(cfg, l)
+	if err != nil {
+		fatal("failed to init database", err)
+	}
+	// 重新加密后清除 Redis 中的供应商缓存
+	rdb, err := ioc.InitRedis(cfg, l)
+	if err != nil {
+		fatal("failed to init redis", err)
+	}
+	var providerCache cache.ProviderCache
+	if rdb != nil {
+		providerCache = cache.NewRedisProviderCache(rdb)
+	}
+
+	repo := repository.NewProviderRepository(dao.NewGormProviderDAO(db), providerCache, keyring, l)
+	n, err := repo.RotateSecrets(context.Background())
+	if err != nil {
+		l.Error("failed to rotate provider api keys", logger.Int("rotated", n), logger.Error(err))
+		os.Exit(1)
+	}
+	l.Info("provider api keys rotated", logger.Int("rotated", n))
+	fmt.Printf("rotated api keys of %d provider(s)\n", n)
+}
+
+func fatal(msg string, err error) {
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
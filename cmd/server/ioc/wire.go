@@ -12,6 +12,7 @@ import (
 	"ai-gateway/config"
 	httpapi "ai-gateway/internal/api/http"
 	"ai-gateway/internal/api/http/handler"
+	"ai-gateway/internal/pkg/envelope"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/pkg/ratelimit"
 	"ai-gateway/internal/repository"
@@ -19,12 +20,15 @@ import (
 	"ai-gateway/internal/repository/dao"
 	"ai-gateway/internal/service/apikey"
 	"ai-gateway/internal/service/auth"
+	"ai-gateway/internal/service/billing"
 	"ai-gateway/internal/service/chat"
 	"ai-gateway/internal/service/gateway"
 	"ai-gateway/internal/service/loadbalance"
+	"ai-gateway/internal/service/modelcapability"
 	"ai-gateway/internal/service/modelrate"
 	"ai-gateway/internal/service/provider"
 	"ai-gateway/internal/service/routingrule"
+	"ai-gateway/internal/service/shadow"
 	"ai-gateway/internal/service/usage"
 	"ai-gateway/internal/service/user"
 	"ai-gateway/internal/service/wallet"
@@ -47,9 +51,11 @@ func InitApp(cfg *config.Config) (*App, error) {
 		provideLogger,
 		provideDB,
 		provideRedis,
+		provideKeyring,
 		provideLimiter,
 		provideAuthService,
 		provideAuthConfig,
+		provideBillingConfig,
 
 		// 缓存
 		provideAPIKeyCache,
@@ -66,6 +72,8 @@ func InitApp(cfg *config.Config) (*App, error) {
 		dao.NewGormUsageLogDAO,
 		dao.NewGormWalletDAO,
 		dao.NewGormModelRateDAO,
+		dao.NewGormModelCapabilityDAO,
+		dao.NewGormShadowResultDAO,
 
 		// Repository
 		repository.NewProviderRepository,
@@ -76,13 +84,19 @@ func InitApp(cfg *config.Config) (*App, error) {
 		repository.NewUsageLogRepository,
 		repository.NewWalletRepository,
 		repository.NewModelRateRepository,
+		repository.NewModelCapabilityRepository,
+		repository.NewShadowResultRepository,
 
 		// Service
 		apikey.NewService,
 		modelrate.NewService,
+		modelcapability.NewService,
+		shadow.NewService,
 		wallet.NewService,
 		user.NewService,
 		usage.NewService,
+		billing.NewService,
+		billing.NewRecorder,
 		provider.NewService,
 		routingrule.NewService,
 		loadbalance.NewService,
@@ -118,6 +132,10 @@ func provideRedis(cfg *config.Config, l logger.Logger) (redis.Cmdable, error) {
 	return baseioc.InitRedis(cfg, l)
 }
 
+func provideKeyring(cfg *config.Config, l logger.Logger) (*envelope.Keyring, error) {
+	return baseioc.InitKeyring(cfg, l)
+}
+
 func provideLimiter(cfg *config.Config, rdb redis.Cmdable) ratelimit.Limiter {
 	if !cfg.RateLimit.Enabled || rdb == nil {
 		return nil
@@ -137,6 +155,10 @@ func provideAuthConfig(cfg *config.Config) config.AuthConfig {
 	return cfg.Auth
 }
 
+func provideBillingConfig(cfg *config.Config) config.BillingConfig {
+	return cfg.Billing
+}
+
 func provideAuthService(cfg *config.Config) *auth.AuthService {
 	secret := cfg.Auth.JWTSecret
 	if secret == "" {
@@ -11,6 +11,7 @@ import (
 	"ai-gateway/internal/api/http"
 	"ai-gateway/internal/api/http/handler"
 	"ai-gateway/internal/ioc"
+	"ai-gateway/internal/pkg/envelope"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/pkg/ratelimit"
 	"ai-gateway/internal/repository"
@@ -18,12 +19,15 @@ import (
 	"ai-gateway/internal/repository/dao"
 	"ai-gateway/internal/service/apikey"
 	"ai-gateway/internal/service/auth"
+	"ai-gateway/internal/service/billing"
 	"ai-gateway/internal/service/chat"
 	"ai-gateway/internal/service/gateway"
 	"ai-gateway/internal/service/loadbalance"
+	"ai-gateway/internal/service/modelcapability"
 	"ai-gateway/internal/service/modelrate"
 	"ai-gateway/internal/service/provider"
 	"ai-gateway/internal/service/routingrule"
+	"ai-gateway/internal/service/shadow"
 	"ai-gateway/internal/service/usage"
 	"ai-gateway/internal/service/user"
 	"ai-gateway/internal/service/wallet"
@@ -47,44 +51,60 @@ func InitApp(cfg *config.Config) (*App, error) {
 		return nil, err
 	}
 	providerCache := provideProviderCache(cmdable)
-	providerRepository := repository.NewProviderRepository(providerDAO, providerCache)
+	keyring, err := provideKeyring(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	providerRepository := repository.NewProviderRepository(providerDAO, providerCache, keyring, logger)
 	routingRuleDAO := dao.NewGormRoutingRuleDAO(db)
 	routingRuleCache := provideRoutingRuleCache(cmdable)
 	routingRuleRepository := repository.NewRoutingRuleRepository(routingRuleDAO, routingRuleCache)
 	loadBalanceDAO := dao.NewGormLoadBalanceDAO(db)
 	loadBalanceRepository := repository.NewLoadBalanceRepository(loadBalanceDAO)
-	gatewayService := gateway.NewGatewayService(providerRepository, routingRuleRepository, loadBalanceRepository, logger)
+	modelCapabilityDAO := dao.NewGormModelCapabilityDAO(db)
+	modelCapabilityRepository := repository.NewModelCapabilityRepository(modelCapabilityDAO)
+	shadowResultDAO := dao.NewGormShadowResultDAO(db)
+	shadowResultRepository := repository.NewShadowResultRepository(shadowResultDAO)
+	gatewayService := gateway.NewGatewayService(providerRepository, routingRuleRepository, loadBalanceRepository, modelCapabilityRepository, shadowResultRepository, logger)
 	walletDAO := dao.NewGormWalletDAO(db)
 	walletRepository := repository.NewWalletRepository(walletDAO)
 	modelRateDAO := dao.NewGormModelRateDAO(db)
 	modelRateCache := provideModelRateCache(cmdable)
 	modelRateRepository := repository.NewModelRateRepository(modelRateDAO, modelRateCache)
 	service := modelrate.NewService(modelRateRepository, logger)
-	walletService := wallet.NewService(walletRepository, service, logger)
-	usageLogDAO := dao.NewGormUsageLogDAO(db)
-	usageLogRepository := repository.NewUsageLogRepository(usageLogDAO)
-	usageService := usage.NewService(usageLogRepository, walletService, logger)
 	apiKeyDAO := dao.NewGormAPIKeyDAO(db)
 	apiKeyCache := provideAPIKeyCache(cmdable)
 	apiKeyRepository := repository.NewAPIKeyRepository(apiKeyDAO, apiKeyCache)
+	billingConfig := provideBillingConfig(cfg)
+	walletService := wallet.NewService(walletRepository, apiKeyRepository, billingConfig, logger)
+	usageLogDAO := dao.NewGormUsageLogDAO(db)
+	usageLogRepository := repository.NewUsageLogRepository(usageLogDAO)
+	billingService := billing.NewService(walletRepository, apiKeyRepository, usageLogRepository, service, billingConfig, logger)
+	recorder, err := billing.NewRecorder(billingService, billingConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	chatService := chat.NewService(gatewayService, walletService, billingService, recorder, logger)
 	apikeyService := apikey.NewService(apiKeyRepository, logger)
-	chatService := chat.NewService(gatewayService, walletService, usageService, apikeyService, service, logger)
 	openAIHandler := handler.NewOpenAIHandler(gatewayService, chatService, logger)
 	anthropicHandler := handler.NewAnthropicHandler(chatService, logger)
 	providerService := provider.NewService(providerRepository, logger)
 	routingruleService := routingrule.NewService(routingRuleRepository, logger)
 	loadbalanceService := loadbalance.NewService(loadBalanceRepository, logger)
+	modelcapabilityService := modelcapability.NewService(modelCapabilityRepository, logger)
+	shadowService := shadow.NewService(shadowResultRepository, logger)
 	userDAO := dao.NewGormUserDAO(db)
 	userRepository := repository.NewUserRepository(userDAO)
 	userService := user.NewService(userRepository, usageLogRepository, logger)
-	adminHandler := handler.NewAdminHandler(providerService, routingruleService, loadbalanceService, apikeyService, userService, usageService, gatewayService, service, walletService, logger)
+	usageService := usage.NewService(usageLogRepository, logger)
+	adminHandler := handler.NewAdminHandler(providerService, routingruleService, loadbalanceService, apikeyService, userService, usageService, gatewayService, service, modelcapabilityService, shadowService, walletService, logger)
 	authService := provideAuthService(cfg)
 	authHandler := handler.NewAuthHandler(userService, authService, logger)
 	userHandler := handler.NewUserHandler(userService, apikeyService, walletService, gatewayService, service, logger)
-	healthHandler := handler.NewHealthHandler(db, cmdable, logger)
+	healthHandler := handler.NewHealthHandler(db, cmdable, gatewayService, recorder, logger)
 	limiter := provideLimiter(cfg, cmdable)
 	authConfig := provideAuthConfig(cfg)
-	server := http.NewServer(openAIHandler, anthropicHandler, adminHandler, authHandler, userHandler, healthHandler, authService, apikeyService, limiter, authConfig, logger)
+	server := http.NewServer(openAIHandler, anthropicHandler, adminHandler, authHandler, userHandler, healthHandler, authService, apikeyService, limiter, recorder, authConfig, billingConfig, logger)
 	app := &App{
 		Logger:     logger,
 		HTTPServer: server,
@@ -112,6 +132,10 @@ func provideRedis(cfg *config.Config, l logger.Logger) (redis.Cmdable, error) {
 	return ioc.InitRedis(cfg, l)
 }
 
+func provideKeyring(cfg *config.Config, l logger.Logger) (*envelope.Keyring, error) {
+	return ioc.InitKeyring(cfg, l)
+}
+
 func provideLimiter(cfg *config.Config, rdb redis.Cmdable) ratelimit.Limiter {
 	if !cfg.RateLimit.Enabled || rdb == nil {
 		return nil
@@ -131,6 +155,10 @@ func provideAuthConfig(cfg *config.Config) config.AuthConfig {
 	return cfg.Auth
 }
 
+func provideBillingConfig(cfg *config.Config) config.BillingConfig {
+	return cfg.Billing
+}
+
 func provideAuthService(cfg *config.Config) *auth.AuthService {
 	secret := cfg.Auth.JWTSecret
 	if secret == "" {
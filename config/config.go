@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -11,15 +12,17 @@ import (
 
 // Config 代表应用程序配置。
 type Config struct {
-	App       AppConfig        `yaml:"app"`
-	Log       LogConfig        `yaml:"log"`
-	HTTP      HTTPConfig       `yaml:"http"`
-	MySQL     MySQLConfig      `yaml:"mysql"`
-	Redis     RedisConfig      `yaml:"redis"`
-	Auth      AuthConfig       `yaml:"auth"`
-	RateLimit RateLimitConfig  `yaml:"rateLimit"`
-	Providers []ProviderConfig `yaml:"providers"`
-	Models    ModelsConfig     `yaml:"models"`
+	App        AppConfig        `yaml:"app"`
+	Log        LogConfig        `yaml:"log"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	MySQL      MySQLConfig      `yaml:"mysql"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Billing    BillingConfig    `yaml:"billing"`
+	RateLimit  RateLimitConfig  `yaml:"rateLimit"`
+	Providers  []ProviderConfig `yaml:"providers"`
+	Models     ModelsConfig     `yaml:"models"`
 }
 
 // AppConfig 包含应用程序级别的设置。
@@ -60,6 +63,41 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// EncryptionConfig 包含上游供应商 Key 的信封加密设置。
+// 主密钥为 base64 编码的 32 字节密钥，未配置时 Key 以明文保存且不缓存到 Redis。
+type EncryptionConfig struct {
+	MasterKey    string   `yaml:"masterKey"`    // 当前主密钥，新写入的 Key 使用它加密
+	PreviousKeys []string `yaml:"previousKeys"` // 轮换前的主密钥，仅用于解密尚未轮换的 Key
+}
+
+// BillingConfig 包含钱包计费设置。
+type BillingConfig struct {
+	// NoOverdraft 为 true 时余额最多扣至 0，超出余额的部分不再收取（用量日志的 charged 小于 cost）；
+	// 默认允许透支，请求结束后照常按全额扣费
+	NoOverdraft bool `yaml:"noOverdraft"`
+	// HoldTTL 预授权有效期，请求进行期间每隔半个有效期顺延；超时未结算（如进程崩溃）的预授权不再占用余额，默认 10 分钟
+	HoldTTL time.Duration `yaml:"holdTTL"`
+	// DefaultMaxTokens 请求未指定 max_tokens 时用于预估费用的输出 token 数，默认 4096
+	DefaultMaxTokens int `yaml:"defaultMaxTokens"`
+	// Queue 异步记账队列
+	Queue BillingQueueConfig `yaml:"queue"`
+}
+
+// BillingQueueConfig 包含异步记账队列设置。
+type BillingQueueConfig struct {
+	Size          int           `yaml:"size"`          // 队列容量，默认 10000
+	Workers       int           `yaml:"workers"`       // 批量写入的 worker 数，默认 4
+	BatchSize     int           `yaml:"batchSize"`     // 每批最多记录数，默认 100
+	FlushInterval time.Duration `yaml:"flushInterval"` // 不足一批时的最长等待时间，默认 1s
+	MaxAttempts   int           `yaml:"maxAttempts"`   // 单条记录非连接类错误的最多记账次数，超过后转入死信，默认 5
+	// SpoolDir 本地预写日志目录，为空时不启用；启用后数据库不可用期间的记录保存在磁盘上，恢复后重放。
+	// 未启用时队列写满或停机超时的用量会被丢弃，不允许丢失用量的部署必须配置
+	SpoolDir          string `yaml:"spoolDir"`
+	SpoolSegmentBytes int64  `yaml:"spoolSegmentBytes"` // 预写日志分段大小，默认 16MB
+	// DrainTimeout 关闭服务时排空队列的最长时间，在 HTTP 请求全部结束后单独计时，默认 30s
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+}
+
 // RateLimitConfig 包含限流设置。
 type RateLimitConfig struct {
 	Enabled bool          `yaml:"enabled"`
@@ -70,7 +108,7 @@ type RateLimitConfig struct {
 // ProviderConfig 包含单个供应商实例的设置。
 type ProviderConfig struct {
 	Name    string        `yaml:"name"` // 唯一标识符
-	Type    string        `yaml:"type"` // "openai" | "anthropic"
+	Type    string        `yaml:"type"` // "openai" | "anthropic" | "gemini" | "azure-openai"
 	APIKey  string        `yaml:"apiKey"`
 	BaseURL string        `yaml:"baseURL"`
 	Timeout time.Duration `yaml:"timeout"`
@@ -89,8 +127,26 @@ type ModelsConfig struct {
 
 // ModelRoute 定义模型请求应路由到的位置（精确匹配）。
 type ModelRoute struct {
-	Provider    string `yaml:"provider"`    // 供应商名称
-	ActualModel string `yaml:"actualModel"` // 可选：要使用的实际模型名称
+	Provider    string         `yaml:"provider"`    // 供应商名称
+	ActualModel string         `yaml:"actualModel"` // 可选：要使用的实际模型名称
+	Fallbacks   []ModelRoute   `yaml:"fallbacks"`   // 可选：主供应商失败时依次尝试的回退链
+	Variants    []ModelVariant `yaml:"variants"`    // 可选：A/B 分流的实验组
+	Shadow      *ShadowRoute   `yaml:"shadow"`      // 可选：影子流量
+}
+
+// ModelVariant 定义 A/B 分流中的一个实验组，按百分比从对照组分出流量。
+type ModelVariant struct {
+	Name        string `yaml:"name"`
+	Provider    string `yaml:"provider"`
+	ActualModel string `yaml:"actualModel"`
+	Percent     int    `yaml:"percent"`
+}
+
+// ShadowRoute 定义影子流量：按百分比采样请求，异步复制一份发往影子供应商用于对比。
+type ShadowRoute struct {
+	Provider    string `yaml:"provider"`
+	ActualModel string `yaml:"actualModel"`
+	Percent     int    `yaml:"percent"`
 }
 
 // PrefixRoute 定义基于前缀的路由（例如，"deepseek-" -> siliconflow）。
@@ -109,7 +165,7 @@ type LoadBalanceConfig struct {
 type LoadBalanceMember struct {
 	Name     string `yaml:"name"`     // 供应商名称
 	Weight   int    `yaml:"weight"`   // 用于权重策略
-	Priority int    `yaml:"priority"` // 用于故障转移策略（数值越小优先级越高）
+	Priority int    `yaml:"priority"` // 优先级层级（数值越大优先级越高）
 }
 
 // AuthConfig 包含身份验证设置。
@@ -184,6 +240,8 @@ func Load(path string) (*Config, error) {
 //   - DB_PASSWORD: 数据库密码
 //   - DB_NAME: 数据库名称
 //   - JWT_SECRET: JWT 密钥
+//   - ENCRYPTION_MASTER_KEY: 供应商 Key 加密主密钥
+//   - ENCRYPTION_PREVIOUS_KEYS: 轮换前的主密钥，逗号分隔
 func overrideFromEnv(cfg *Config) {
 	// 数据库配置
 	if v := os.Getenv("DB_HOST"); v != "" {
@@ -211,6 +269,14 @@ func overrideFromEnv(cfg *Config) {
 		cfg.Auth.JWTSecret = v
 	}
 
+	// 加密主密钥
+	if v := os.Getenv("ENCRYPTION_MASTER_KEY"); v != "" {
+		cfg.Encryption.MasterKey = v
+	}
+	if v := os.Getenv("ENCRYPTION_PREVIOUS_KEYS"); v != "" {
+		cfg.Encryption.PreviousKeys = strings.Split(v, ",")
+	}
+
 	// Redis 覆盖
 	if v := os.Getenv("REDIS_ADDR"); v != "" {
 		cfg.Redis.Addr = v
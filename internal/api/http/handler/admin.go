@@ -13,13 +13,17 @@ import (
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/ginx"
+	"ai-gateway/internal/pkg/keypool"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/service/apikey"
 	"ai-gateway/internal/service/gateway"
 	"ai-gateway/internal/service/loadbalance"
+	"ai-gateway/internal/service/modelcapability"
 	"ai-gateway/internal/service/modelrate"
 	"ai-gateway/internal/service/provider"
 	"ai-gateway/internal/service/routingrule"
+	"ai-gateway/internal/service/shadow"
 	"ai-gateway/internal/service/usage"
 	"ai-gateway/internal/service/user"
 	"ai-gateway/internal/service/wallet"
@@ -35,6 +39,8 @@ type AdminHandler struct {
 	usageSvc       usage.Service
 	gatewaySvc     gateway.GatewayService
 	modelRateSvc   modelrate.Service
+	capabilitySvc  modelcapability.Service
+	shadowSvc      shadow.Service
 	walletSvc      wallet.Service
 	logger         logger.Logger
 }
@@ -49,6 +55,8 @@ func NewAdminHandler(
 	usageSvc usage.Service,
 	gatewaySvc gateway.GatewayService,
 	modelRateSvc modelrate.Service,
+	capabilitySvc modelcapability.Service,
+	shadowSvc shadow.Service,
 	walletSvc wallet.Service,
 	l logger.Logger,
 ) *AdminHandler {
@@ -61,6 +69,8 @@ func NewAdminHandler(
 		usageSvc:       usageSvc,
 		gatewaySvc:     gatewaySvc,
 		modelRateSvc:   modelRateSvc,
+		capabilitySvc:  capabilitySvc,
+		shadowSvc:      shadowSvc,
 		walletSvc:      walletSvc,
 		logger:         l.With(logger.String("handler", "admin")),
 	}
@@ -76,7 +86,11 @@ func (h *AdminHandler) ListProviders(c *gin.Context) {
 		ginx.FromErr(c, err)
 		return
 	}
-	ginx.OK(c, providers)
+	masked := make([]domain.Provider, len(providers))
+	for i := range providers {
+		masked[i] = *maskProvider(&providers[i])
+	}
+	ginx.OK(c, masked)
 }
 
 // GetProvider 获取单个提供商详情。
@@ -97,19 +111,33 @@ func (h *AdminHandler) GetProvider(c *gin.Context) {
 		ginx.Fail(c, errs.CodeNotFound, "provider not found")
 		return
 	}
-	ginx.OK(c, provider)
+	ginx.OK(c, maskProvider(provider))
 }
 
 // CreateProviderRequest 创建提供商的请求体。
 type CreateProviderRequest struct {
-	Name      string   `json:"name" binding:"required"`
-	Type      string   `json:"type" binding:"required"` // openai, anthropic
-	APIKey    string   `json:"apiKey" binding:"required"`
-	BaseURL   string   `json:"baseURL" binding:"required"`
-	Models    []string `json:"models"` // Optional list of supported models
-	TimeoutMs int      `json:"timeoutMs"`
-	IsDefault bool     `json:"isDefault"`
-	Enabled   bool     `json:"enabled"`
+	Name                    string               `json:"name" binding:"required"`
+	Type                    string               `json:"type" binding:"required"` // openai, anthropic, gemini, azure-openai
+	APIKey                  string               `json:"apiKey"`
+	ClearAPIKey             bool                 `json:"clearApiKey"` // Update only: remove the stored apiKey
+	APIKeys                 []domain.ProviderKey `json:"apiKeys"`     // Optional key pool, rotated together with apiKey
+	KeyStrategy             string               `json:"keyStrategy"` // round_robin (default), least_used
+	BaseURL                 string               `json:"baseURL" binding:"required"`
+	Models                  []string             `json:"models"`      // Optional list of supported models
+	APIVersion              string               `json:"apiVersion"`  // Azure OpenAI only
+	Deployments             map[string]string    `json:"deployments"` // Azure OpenAI only: model -> deployment
+	TimeoutMs               int                  `json:"timeoutMs"`
+	ConnectTimeoutMs        int                  `json:"connectTimeoutMs"`
+	ResponseHeaderTimeoutMs int                  `json:"responseHeaderTimeoutMs"`
+	MaxIdleConns            int                  `json:"maxIdleConns"`
+	MaxIdleConnsPerHost     int                  `json:"maxIdleConnsPerHost"`
+	ProxyURL                string               `json:"proxyURL"`         // Optional outbound HTTP proxy
+	RetryMaxAttempts        int                  `json:"retryMaxAttempts"` // 0 = default, 1 = no retry
+	RetryInitialDelayMs     int                  `json:"retryInitialDelayMs"`
+	RetryMaxDelayMs         int                  `json:"retryMaxDelayMs"`
+	FirstTokenTimeoutMs     int                  `json:"firstTokenTimeoutMs"` // 0 = unlimited
+	IsDefault               bool                 `json:"isDefault"`
+	Enabled                 bool                 `json:"enabled"`
 }
 
 // CreateProvider 创建新的提供商。
@@ -121,14 +149,27 @@ func (h *AdminHandler) CreateProvider(c *gin.Context) {
 	}
 
 	provider := &domain.Provider{
-		Name:      req.Name,
-		Type:      req.Type,
-		APIKey:    req.APIKey,
-		BaseURL:   req.BaseURL,
-		Models:    req.Models,
-		TimeoutMs: req.TimeoutMs,
-		IsDefault: req.IsDefault,
-		Enabled:   req.Enabled,
+		Name:                    req.Name,
+		Type:                    req.Type,
+		APIKey:                  req.APIKey,
+		APIKeys:                 req.APIKeys,
+		KeyStrategy:             req.KeyStrategy,
+		BaseURL:                 req.BaseURL,
+		Models:                  req.Models,
+		APIVersion:              req.APIVersion,
+		Deployments:             req.Deployments,
+		TimeoutMs:               req.TimeoutMs,
+		ConnectTimeoutMs:        req.ConnectTimeoutMs,
+		ResponseHeaderTimeoutMs: req.ResponseHeaderTimeoutMs,
+		MaxIdleConns:            req.MaxIdleConns,
+		MaxIdleConnsPerHost:     req.MaxIdleConnsPerHost,
+		ProxyURL:                req.ProxyURL,
+		RetryMaxAttempts:        req.RetryMaxAttempts,
+		RetryInitialDelayMs:     req.RetryInitialDelayMs,
+		RetryMaxDelayMs:         req.RetryMaxDelayMs,
+		FirstTokenTimeoutMs:     req.FirstTokenTimeoutMs,
+		IsDefault:               req.IsDefault,
+		Enabled:                 req.Enabled,
 	}
 
 	if err := h.providerSvc.Create(c.Request.Context(), provider); err != nil {
@@ -141,7 +182,7 @@ func (h *AdminHandler) CreateProvider(c *gin.Context) {
 		h.logger.Warn("failed to reload gateway configuration", logger.Error(err))
 	}
 	c.Status(http.StatusCreated)
-	ginx.OK(c, provider)
+	ginx.OK(c, maskProvider(provider))
 }
 
 // UpdateProvider 更新提供商。
@@ -171,10 +212,28 @@ func (h *AdminHandler) UpdateProvider(c *gin.Context) {
 
 	provider.Name = req.Name
 	provider.Type = req.Type
-	provider.APIKey = req.APIKey
+	// 管理后台只能拿到脱敏后的 Key，原样提交（或留空）时保留已保存的值，需显式指定 clearApiKey 才清除
+	if req.ClearAPIKey {
+		provider.APIKey = ""
+	} else {
+		provider.APIKey = keepSecret(req.APIKey, provider.APIKey)
+	}
+	provider.APIKeys = mergeProviderKeys(req.APIKeys, provider.APIKeys)
+	provider.KeyStrategy = req.KeyStrategy
 	provider.BaseURL = req.BaseURL
 	provider.Models = req.Models
+	provider.APIVersion = req.APIVersion
+	provider.Deployments = req.Deployments
 	provider.TimeoutMs = req.TimeoutMs
+	provider.ConnectTimeoutMs = req.ConnectTimeoutMs
+	provider.ResponseHeaderTimeoutMs = req.ResponseHeaderTimeoutMs
+	provider.MaxIdleConns = req.MaxIdleConns
+	provider.MaxIdleConnsPerHost = req.MaxIdleConnsPerHost
+	provider.ProxyURL = req.ProxyURL
+	provider.RetryMaxAttempts = req.RetryMaxAttempts
+	provider.RetryInitialDelayMs = req.RetryInitialDelayMs
+	provider.RetryMaxDelayMs = req.RetryMaxDelayMs
+	provider.FirstTokenTimeoutMs = req.FirstTokenTimeoutMs
 	provider.IsDefault = req.IsDefault
 	provider.Enabled = req.Enabled
 
@@ -187,7 +246,7 @@ func (h *AdminHandler) UpdateProvider(c *gin.Context) {
 	if err := h.gatewaySvc.Reload(c.Request.Context()); err != nil {
 		h.logger.Warn("failed to reload gateway configuration", logger.Error(err))
 	}
-	ginx.OK(c, provider)
+	ginx.OK(c, maskProvider(provider))
 }
 
 // DeleteProvider 删除提供商。
@@ -210,6 +269,91 @@ func (h *AdminHandler) DeleteProvider(c *gin.Context) {
 	ginx.OK(c, gin.H{"message": "deleted"})
 }
 
+// ListProviderKeys 获取提供商 Key 池中各 Key 的状态与用量，未配置 Key 池时返回空列表。
+func (h *AdminHandler) ListProviderKeys(c *gin.Context) {
+	provider, ok := h.providerByParam(c)
+	if !ok {
+		return
+	}
+	keys := h.gatewaySvc.ProviderKeys(provider.Name)
+	if keys == nil {
+		keys = []keypool.Snapshot{}
+	}
+	ginx.OK(c, keys)
+}
+
+// EnableProviderKey 重新启用被自动禁用（401）的 Key 并清除其冷却。
+func (h *AdminHandler) EnableProviderKey(c *gin.Context) {
+	provider, ok := h.providerByParam(c)
+	if !ok {
+		return
+	}
+	if err := h.gatewaySvc.EnableProviderKey(provider.Name, c.Param("name")); err != nil {
+		ginx.FromErr(c, err)
+		return
+	}
+	ginx.OK(c, gin.H{"message": "enabled"})
+}
+
+// providerByParam 按路径参数 id 查询提供商，失败时写入错误响应并返回 false。
+func (h *AdminHandler) providerByParam(c *gin.Context) (*domain.Provider, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ginx.Fail(c, errs.CodeInvalidParameter, "invalid id")
+		return nil, false
+	}
+	provider, err := h.providerSvc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to get provider", logger.Error(err))
+		ginx.FromErr(c, err)
+		return nil, false
+	}
+	if provider == nil {
+		ginx.Fail(c, errs.CodeNotFound, "provider not found")
+		return nil, false
+	}
+	return provider, true
+}
+
+// maskProvider 返回上游 Key 已脱敏的副本，管理接口不返回完整 Key。
+func maskProvider(p *domain.Provider) *domain.Provider {
+	out := *p
+	if out.APIKey != "" {
+		out.APIKey = keypool.Mask(p.APIKey)
+	}
+	out.APIKeys = make([]domain.ProviderKey, len(p.APIKeys))
+	for i, k := range p.APIKeys {
+		out.APIKeys[i] = domain.ProviderKey{Name: k.Name, Key: keypool.Mask(k.Key)}
+	}
+	return &out
+}
+
+// keepSecret 提交的 Key 为空或与已保存 Key 的脱敏值相同时返回已保存的 Key。
+func keepSecret(submitted, stored string) string {
+	if submitted == "" || (stored != "" && submitted == keypool.Mask(stored)) {
+		return stored
+	}
+	return submitted
+}
+
+// mergeProviderKeys 按名称合并提交的 Key 池，未修改的 Key 沿用已保存的值。
+func mergeProviderKeys(submitted, stored []domain.ProviderKey) []domain.ProviderKey {
+	saved := make(map[string]string, len(stored))
+	for _, k := range stored {
+		saved[k.Name] = k.Key
+	}
+	out := make([]domain.ProviderKey, len(submitted))
+	for i, k := range submitted {
+		out[i] = domain.ProviderKey{Name: k.Name, Key: keepSecret(k.Key, saved[k.Name])}
+	}
+	return out
+}
+
+// ListCircuitBreakers 获取各提供商的熔断器状态。
+func (h *AdminHandler) ListCircuitBreakers(c *gin.Context) {
+	ginx.OK(c, h.gatewaySvc.CircuitBreakers())
+}
+
 // --- 路由规则管理 API ---
 
 // ListRoutingRules 获取所有路由规则。
@@ -225,12 +369,15 @@ func (h *AdminHandler) ListRoutingRules(c *gin.Context) {
 
 // CreateRoutingRuleRequest 创建路由规则的请求体。
 type CreateRoutingRuleRequest struct {
-	RuleType     string `json:"ruleType" binding:"required"` // exact, prefix, wildcard
-	Pattern      string `json:"pattern" binding:"required"`
-	ProviderName string `json:"providerName" binding:"required"`
-	ActualModel  string `json:"actualModel"`
-	Priority     int    `json:"priority"`
-	Enabled      bool   `json:"enabled"`
+	RuleType     string                `json:"ruleType" binding:"required"` // exact, prefix, wildcard, regex
+	Pattern      string                `json:"pattern" binding:"required"`
+	ProviderName string                `json:"providerName" binding:"required"`
+	ActualModel  string                `json:"actualModel"`
+	Priority     int                   `json:"priority"`
+	Fallbacks    []domain.RouteTarget  `json:"fallbacks"` // 有序回退链
+	Variants     []domain.RouteVariant `json:"variants"`  // A/B 分流的实验组
+	Shadow       *domain.ShadowTarget  `json:"shadow"`    // 影子流量
+	Enabled      bool                  `json:"enabled"`
 }
 
 // CreateRoutingRule 创建新的路由规则。
@@ -247,6 +394,9 @@ func (h *AdminHandler) CreateRoutingRule(c *gin.Context) {
 		ProviderName: req.ProviderName,
 		ActualModel:  req.ActualModel,
 		Priority:     req.Priority,
+		Fallbacks:    req.Fallbacks,
+		Variants:     req.Variants,
+		Shadow:       req.Shadow,
 		Enabled:      req.Enabled,
 	}
 
@@ -284,6 +434,9 @@ func (h *AdminHandler) UpdateRoutingRule(c *gin.Context) {
 		ProviderName: req.ProviderName,
 		ActualModel:  req.ActualModel,
 		Priority:     req.Priority,
+		Fallbacks:    req.Fallbacks,
+		Variants:     req.Variants,
+		Shadow:       req.Shadow,
 		Enabled:      req.Enabled,
 	}
 
@@ -319,6 +472,17 @@ func (h *AdminHandler) DeleteRoutingRule(c *gin.Context) {
 	ginx.OK(c, gin.H{"message": "deleted"})
 }
 
+// ExplainRoute 返回模型的路由解析过程（精确规则、负载均衡组、通配符 / 正则、前缀、类型默认），
+// 不发起上游请求。
+func (h *AdminHandler) ExplainRoute(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		ginx.Fail(c, errs.CodeInvalidParameter, "model is required")
+		return
+	}
+	ginx.OK(c, h.gatewaySvc.ExplainRoute(model))
+}
+
 // --- 负载均衡组管理 API ---
 
 // ListLoadBalanceGroups 获取所有负载均衡组。
@@ -336,7 +500,7 @@ func (h *AdminHandler) ListLoadBalanceGroups(c *gin.Context) {
 type CreateLoadBalanceGroupRequest struct {
 	Name         string `json:"name" binding:"required"`
 	ModelPattern string `json:"modelPattern" binding:"required"`
-	Strategy     string `json:"strategy" binding:"required"` // round-robin, random, failover, weighted
+	Strategy     string `json:"strategy" binding:"required"` // round-robin, random, failover, weighted, least-outstanding, peak-ewma, consistent-hash
 	Enabled      bool   `json:"enabled"`
 }
 
@@ -599,10 +763,10 @@ func (h *AdminHandler) GetGlobalUsage(c *gin.Context) {
 
 // --- Mode Rate 管理 API ---
 type CreateModelRateRequest struct {
-	ModelPattern    string  `json:"modelPattern" binding:"required"`
-	PromptPrice     float64 `json:"promptPrice"`
-	CompletionPrice float64 `json:"completionPrice"`
-	Enabled         bool    `json:"enabled"`
+	ModelPattern    string       `json:"modelPattern" binding:"required"`
+	PromptPrice     money.Amount `json:"promptPrice"`
+	CompletionPrice money.Amount `json:"completionPrice"`
+	Enabled         bool         `json:"enabled"`
 }
 
 // ListModelRates 获取所有模型费率。
@@ -694,10 +858,138 @@ func (h *AdminHandler) DeleteModelRate(c *gin.Context) {
 	ginx.OK(c, gin.H{"message": "deleted"})
 }
 
+// --- 模型能力管理 API ---
+
+type CreateModelCapabilityRequest struct {
+	ModelPattern     string `json:"modelPattern" binding:"required"`
+	ProviderName     string `json:"providerName"`
+	Vision           bool   `json:"vision"`
+	Tools            bool   `json:"tools"`
+	Thinking         bool   `json:"thinking"`
+	JSONSchema       bool   `json:"jsonSchema"`
+	ContextWindow    int    `json:"contextWindow"`
+	MaxOutputTokens  int    `json:"maxOutputTokens"`
+	LongContextModel string `json:"longContextModel"`
+	Enabled          bool   `json:"enabled"`
+}
+
+// ListModelCapabilities 获取所有模型能力配置。
+func (h *AdminHandler) ListModelCapabilities(c *gin.Context) {
+	list, err := h.capabilitySvc.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("failed to list model capabilities", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	ginx.OK(c, list)
+}
+
+// CreateModelCapability 创建模型能力配置。
+func (h *AdminHandler) CreateModelCapability(c *gin.Context) {
+	var req CreateModelCapabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginx.Fail(c, errs.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	capability := &domain.ModelCapability{
+		ModelPattern:     req.ModelPattern,
+		ProviderName:     req.ProviderName,
+		Vision:           req.Vision,
+		Tools:            req.Tools,
+		Thinking:         req.Thinking,
+		JSONSchema:       req.JSONSchema,
+		ContextWindow:    req.ContextWindow,
+		MaxOutputTokens:  req.MaxOutputTokens,
+		LongContextModel: req.LongContextModel,
+		Enabled:          req.Enabled,
+	}
+
+	if err := h.capabilitySvc.Create(c.Request.Context(), capability); err != nil {
+		h.logger.Error("failed to create model capability", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	// Reload gateway configuration
+	if err := h.gatewaySvc.Reload(c.Request.Context()); err != nil {
+		h.logger.Warn("failed to reload gateway configuration", logger.Error(err))
+	}
+	c.Status(http.StatusCreated)
+	ginx.OK(c, capability)
+}
+
+// UpdateModelCapability 更新模型能力配置。
+func (h *AdminHandler) UpdateModelCapability(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ginx.Fail(c, errs.CodeInvalidParameter, "invalid id")
+		return
+	}
+
+	capability, err := h.capabilitySvc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to get model capability", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	if capability == nil {
+		ginx.Fail(c, errs.CodeNotFound, "model capability not found")
+		return
+	}
+
+	var req CreateModelCapabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ginx.Fail(c, errs.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	capability.ModelPattern = req.ModelPattern
+	capability.ProviderName = req.ProviderName
+	capability.Vision = req.Vision
+	capability.Tools = req.Tools
+	capability.Thinking = req.Thinking
+	capability.JSONSchema = req.JSONSchema
+	capability.ContextWindow = req.ContextWindow
+	capability.MaxOutputTokens = req.MaxOutputTokens
+	capability.LongContextModel = req.LongContextModel
+	capability.Enabled = req.Enabled
+
+	if err := h.capabilitySvc.Update(c.Request.Context(), capability); err != nil {
+		h.logger.Error("failed to update model capability", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	// Reload gateway configuration
+	if err := h.gatewaySvc.Reload(c.Request.Context()); err != nil {
+		h.logger.Warn("failed to reload gateway configuration", logger.Error(err))
+	}
+	ginx.OK(c, capability)
+}
+
+// DeleteModelCapability 删除模型能力配置。
+func (h *AdminHandler) DeleteModelCapability(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		ginx.Fail(c, errs.CodeInvalidParameter, "invalid id")
+		return
+	}
+
+	if err := h.capabilitySvc.Delete(c.Request.Context(), id); err != nil {
+		h.logger.Error("failed to delete model capability", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	// Reload gateway configuration
+	if err := h.gatewaySvc.Reload(c.Request.Context()); err != nil {
+		h.logger.Warn("failed to reload gateway configuration", logger.Error(err))
+	}
+	ginx.OK(c, gin.H{"message": "deleted"})
+}
+
 // --- 钱包管理 API ---
 
 type TopUpRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
 // TopUpUserWallet 为用户钱包充值。
@@ -814,3 +1106,46 @@ func (h *AdminHandler) GetUsageLeaderboard(c *gin.Context) {
 	}
 	ginx.OK(c, entries)
 }
+
+// --- 影子流量 API ---
+
+// ListShadowResults 获取影子流量对比记录。
+func (h *AdminHandler) ListShadowResults(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	results, total, err := h.shadowSvc.List(c.Request.Context(), page, pageSize, c.Query("model"))
+	if err != nil {
+		h.logger.Error("failed to list shadow results", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	ginx.OK(c, gin.H{
+		"data":  results,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// GetShadowStats 获取影子流量汇总对比。
+func (h *AdminHandler) GetShadowStats(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 365 {
+		days = 7
+	}
+
+	stats, err := h.shadowSvc.Stats(c.Request.Context(), days)
+	if err != nil {
+		h.logger.Error("failed to get shadow stats", logger.Error(err))
+		ginx.FromErr(c, err)
+		return
+	}
+	ginx.OK(c, stats)
+}
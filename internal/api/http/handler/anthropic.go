@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -58,9 +59,10 @@ func (h *AnthropicHandler) handleNonStream(c *gin.Context, req *domain.ChatReque
 	meta := chat.RequestMeta{
 		UserID:    ctxGetInt64(c, "user_id"),
 		APIKeyID:  ctxGetInt64Ptr(c, "api_key_id"),
-		RequestID: c.GetString("request_id"),
+		RequestID: c.GetString("billing_id"),
 		ClientIP:  c.ClientIP(),
 		UserAgent: c.Request.UserAgent(),
+		SessionID: c.GetHeader(headerSessionID),
 	}
 
 	resp, err := h.chatSvc.Chat(c.Request.Context(), req, meta)
@@ -78,6 +80,7 @@ func (h *AnthropicHandler) handleNonStream(c *gin.Context, req *domain.ChatReque
 		return
 	}
 
+	c.Header(headerFallbackHop, strconv.Itoa(resp.FallbackHop))
 	c.Data(http.StatusOK, "application/json", respBody)
 }
 
@@ -85,9 +88,10 @@ func (h *AnthropicHandler) handleStream(c *gin.Context, req *domain.ChatRequest)
 	meta := chat.RequestMeta{
 		UserID:    ctxGetInt64(c, "user_id"),
 		APIKeyID:  ctxGetInt64Ptr(c, "api_key_id"),
-		RequestID: c.GetString("request_id"),
+		RequestID: c.GetString("billing_id"),
 		ClientIP:  c.ClientIP(),
 		UserAgent: c.Request.UserAgent(),
+		SessionID: c.GetHeader(headerSessionID),
 	}
 
 	deltaCh, _, err := h.chatSvc.ChatStream(c.Request.Context(), req, meta)
@@ -9,20 +9,27 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"ai-gateway/internal/pkg/circuitbreaker"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/service/billing"
+	"ai-gateway/internal/service/gateway"
 )
 
 type HealthHandler struct {
-	db     *gorm.DB
-	redis  redis.Cmdable
-	logger logger.Logger
+	db         *gorm.DB
+	redis      redis.Cmdable
+	gatewaySvc gateway.GatewayService
+	recorder   billing.Recorder
+	logger     logger.Logger
 }
 
-func NewHealthHandler(db *gorm.DB, redis redis.Cmdable, l logger.Logger) *HealthHandler {
+func NewHealthHandler(db *gorm.DB, redis redis.Cmdable, gatewaySvc gateway.GatewayService, recorder billing.Recorder, l logger.Logger) *HealthHandler {
 	return &HealthHandler{
-		db:     db,
-		redis:  redis,
-		logger: l,
+		db:         db,
+		redis:      redis,
+		gatewaySvc: gatewaySvc,
+		recorder:   recorder,
+		logger:     l,
 	}
 }
 
@@ -41,7 +48,7 @@ func (h *HealthHandler) LivenessCheck(c *gin.Context) {
 
 // ReadinessCheck godoc
 // @Summary 服务就绪检查
-// @Description 检查服务及其依赖（DB, Redis）是否就绪
+// @Description 检查服务及其依赖（DB, Redis）是否就绪，并报告各提供商熔断器状态与丢弃的用量记录数
 // @Tags Health
 // @Success 200 {object} map[string]interface{}
 // @Router /health/ready [get]
@@ -50,12 +57,15 @@ func (h *HealthHandler) ReadinessCheck(c *gin.Context) {
 		"status": "ok",
 		"time":   time.Now().Format(time.RFC3339),
 		"components": gin.H{
-			"database": h.checkDB(c.Request.Context()),
-			"redis":    h.checkRedis(c.Request.Context()),
+			"database":  h.checkDB(c.Request.Context()),
+			"redis":     h.checkRedis(c.Request.Context()),
+			"providers": h.checkProviders(),
+			"usage":     h.checkUsage(),
 		},
 	}
 
 	// 如果任何组件不健康，返回 503
+	// 提供商熔断只标记为 degraded，不影响就绪状态：上游故障时摘除网关实例无济于事
 	components := status["components"].(gin.H)
 	if components["database"].(gin.H)["status"] != "ok" || components["redis"].(gin.H)["status"] != "ok" {
 		status["status"] = "degraded"
@@ -83,6 +93,42 @@ func (h *HealthHandler) checkDB(ctx context.Context) gin.H {
 	}
 }
 
+func (h *HealthHandler) checkProviders() gin.H {
+	if h.gatewaySvc == nil {
+		return gin.H{"status": "disabled"}
+	}
+
+	breakers := h.gatewaySvc.CircuitBreakers()
+	status := "ok"
+	for _, b := range breakers {
+		if b.State != circuitbreaker.StateClosed.String() {
+			status = "degraded"
+			break
+		}
+	}
+	return gin.H{
+		"status":          status,
+		"circuitBreakers": breakers,
+	}
+}
+
+// checkUsage 报告记账队列丢弃的用量记录数，有丢弃时标记为 degraded，同样不影响就绪状态
+func (h *HealthHandler) checkUsage() gin.H {
+	if h.recorder == nil {
+		return gin.H{"status": "disabled"}
+	}
+
+	dropped := h.recorder.Dropped()
+	status := "ok"
+	if dropped > 0 {
+		status = "degraded"
+	}
+	return gin.H{
+		"status":  status,
+		"dropped": dropped,
+	}
+}
+
 func (h *HealthHandler) checkRedis(ctx context.Context) gin.H {
 	if h.redis == nil {
 		return gin.H{"status": "disabled"}
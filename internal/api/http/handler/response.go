@@ -9,6 +9,12 @@ import (
 	"ai-gateway/internal/errs"
 )
 
+// headerFallbackHop 响应头：实际服务请求的回退链跳数，0 表示主路由。
+const headerFallbackHop = "X-Gateway-Fallback-Hop"
+
+// headerSessionID 请求头：客户端会话标识，一致性哈希负载均衡组据此把同一会话固定到同一个供应商节点。
+const headerSessionID = "X-Session-ID"
+
 func toAppError(err error, fallbackCode errs.ErrorCode, fallbackMsg string) *errs.AppError {
 	if err == nil {
 		return errs.New(errs.CodeSuccess, "")
@@ -12,6 +12,7 @@ import (
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/ginx"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/service/apikey"
 	"ai-gateway/internal/service/gateway"
 	"ai-gateway/internal/service/modelrate"
@@ -62,9 +63,9 @@ func (h *UserHandler) ListAvailableModels(c *gin.Context) {
 
 // ModelWithPricing 模型及定价信息。
 type ModelWithPricing struct {
-	ModelName       string  `json:"modelName"`       // 模型名称
-	PromptPrice     float64 `json:"promptPrice"`     // 输入价格（每 1M tokens）
-	CompletionPrice float64 `json:"completionPrice"` // 输出价格（每 1M tokens）
+	ModelName       string       `json:"modelName"`       // 模型名称
+	PromptPrice     money.Amount `json:"promptPrice"`     // 输入价格（每 1M tokens）
+	CompletionPrice money.Amount `json:"completionPrice"` // 输出价格（每 1M tokens）
 }
 
 // ListModelsWithPricing 获取带价格信息的模型列表。
@@ -170,10 +171,10 @@ func (h *UserHandler) ListMyAPIKeys(c *gin.Context) {
 
 // CreateAPIKeyRequest 创建 API Key 请求。
 type CreateAPIKeyRequest struct {
-	Name      string     `json:"name" binding:"required"`
-	Enabled   *bool      `json:"enabled"`
-	Quota     *float64   `json:"quota"`
-	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Name      string        `json:"name" binding:"required"`
+	Enabled   *bool         `json:"enabled"`
+	Quota     *money.Amount `json:"quota"`
+	ExpiresAt *time.Time    `json:"expiresAt,omitempty"`
 }
 
 // CreateMyAPIKey 创建 API Key。
@@ -35,6 +35,10 @@ func Logger(l logger.Logger) gin.HandlerFunc {
 			fields = append(fields, logger.String("request_id", requestID))
 		}
 
+		if billingID := c.GetString("billing_id"); billingID != "" {
+			fields = append(fields, logger.String("billing_id", billingID))
+		}
+
 		if len(c.Errors) > 0 {
 			fields = append(fields, logger.String("errors", c.Errors.String()))
 		}
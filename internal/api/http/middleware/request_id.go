@@ -6,15 +6,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxRequestIDLen 客户端传入的 X-Request-ID 的最大长度，超出时由网关重新生成。
+const maxRequestIDLen = 128
+
 // RequestID 为每个请求添加唯一的请求 ID。
+// 客户端传入的 X-Request-ID 会被沿用于日志和响应头，便于链路追踪；
+// 计费的幂等键另行由网关生成（billing_id），避免客户端重复使用同一 ID 时请求不再计费。
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		if requestID == "" || len(requestID) > maxRequestIDLen {
 			requestID = uuid.New().String()
 		}
 
 		c.Set("request_id", requestID)
+		c.Set("billing_id", uuid.New().String())
 		c.Header("X-Request-ID", requestID)
 
 		c.Next()
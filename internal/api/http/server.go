@@ -3,6 +3,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -15,13 +16,19 @@ import (
 	"ai-gateway/internal/pkg/ratelimit"
 	"ai-gateway/internal/service/apikey"
 	"ai-gateway/internal/service/auth"
+	"ai-gateway/internal/service/billing"
 )
 
+// defaultDrainTimeout 关闭服务时排空记账队列的默认最长时间
+const defaultDrainTimeout = 30 * time.Second
+
 // Server 是 AI 网关的 HTTP 服务器。
 type Server struct {
-	engine *gin.Engine
-	server *http.Server
-	logger logger.Logger
+	engine       *gin.Engine
+	server       *http.Server
+	recorder     billing.Recorder
+	drainTimeout time.Duration
+	logger       logger.Logger
 }
 
 // NewServer 创建一个新的 HTTP 服务器。
@@ -35,7 +42,9 @@ func NewServer(
 	authService *auth.AuthService,
 	apiKeyService apikey.Service,
 	limiter ratelimit.Limiter,
+	recorder billing.Recorder,
 	authCfg config.AuthConfig,
+	billingCfg config.BillingConfig,
 	l logger.Logger,
 ) *Server {
 	gin.SetMode(gin.ReleaseMode)
@@ -54,9 +63,15 @@ func NewServer(
 	// 注册路由
 	registerRoutes(engine, openaiHandler, anthropicHandler, adminHandler, authHandler, userHandler, healthHandler, authService, apiKeyService, authCfg, l)
 
+	drainTimeout := billingCfg.Queue.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
 	return &Server{
-		engine: engine,
-		logger: l.With(logger.String("service", "http.server")),
+		engine:       engine,
+		recorder:     recorder,
+		drainTimeout: drainTimeout,
+		logger:       l.With(logger.String("service", "http.server")),
 	}
 }
 
@@ -136,12 +151,16 @@ func registerRoutes(
 		adminGroup.GET("/providers/:id", adminHandler.GetProvider)
 		adminGroup.PUT("/providers/:id", adminHandler.UpdateProvider)
 		adminGroup.DELETE("/providers/:id", adminHandler.DeleteProvider)
+		adminGroup.GET("/providers/:id/keys", adminHandler.ListProviderKeys)
+		adminGroup.POST("/providers/:id/keys/:name/enable", adminHandler.EnableProviderKey)
+		adminGroup.GET("/circuit-breakers", adminHandler.ListCircuitBreakers)
 
 		// 路由规则管理
 		adminGroup.GET("/routing-rules", adminHandler.ListRoutingRules)
 		adminGroup.POST("/routing-rules", adminHandler.CreateRoutingRule)
 		adminGroup.PUT("/routing-rules/:id", adminHandler.UpdateRoutingRule)
 		adminGroup.DELETE("/routing-rules/:id", adminHandler.DeleteRoutingRule)
+		adminGroup.GET("/routing/explain", adminHandler.ExplainRoute)
 
 		// 负载均衡管理
 		adminGroup.GET("/load-balance-groups", adminHandler.ListLoadBalanceGroups)
@@ -155,6 +174,12 @@ func registerRoutes(
 		adminGroup.PUT("/model-rates/:id", adminHandler.UpdateModelRate)
 		adminGroup.DELETE("/model-rates/:id", adminHandler.DeleteModelRate)
 
+		// 模型能力管理
+		adminGroup.GET("/model-capabilities", adminHandler.ListModelCapabilities)
+		adminGroup.POST("/model-capabilities", adminHandler.CreateModelCapability)
+		adminGroup.PUT("/model-capabilities/:id", adminHandler.UpdateModelCapability)
+		adminGroup.DELETE("/model-capabilities/:id", adminHandler.DeleteModelCapability)
+
 		// 钱包管理 (管理员充值)
 		adminGroup.POST("/users/:id/top-up", adminHandler.TopUpUserWallet)
 		adminGroup.GET("/users/:id/wallet", adminHandler.GetUserWallet)
@@ -180,6 +205,10 @@ func registerRoutes(
 
 		// 使用量排行榜
 		adminGroup.GET("/usage/leaderboard", adminHandler.GetUsageLeaderboard)
+
+		// 影子流量对比
+		adminGroup.GET("/shadow-results", adminHandler.ListShadowResults)
+		adminGroup.GET("/shadow-results/stats", adminHandler.GetShadowStats)
 	}
 
 	// 静态文件服务（生产模式下托管前端）
@@ -211,10 +240,20 @@ func (s *Server) Start(addr string) error {
 	return s.server.ListenAndServe()
 }
 
-// Shutdown 优雅地关闭服务器。
+// Shutdown 优雅地关闭服务器：等待进行中的请求结束后排空记账队列，避免用量与计费丢失。
+// 排空队列使用单独的 billing.queue.drainTimeout，不与 ctx 中已被 HTTP 停机消耗的时间共用。
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down http server")
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+
+	s.logger.Info("draining usage queue", logger.Duration("timeout", s.drainTimeout))
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.drainTimeout)
+	defer cancel()
+	err = errors.Join(err, s.recorder.Shutdown(drainCtx))
+	if n := s.recorder.Dropped(); n > 0 {
+		s.logger.Error("usage records dropped since startup", logger.Int64("dropped", int64(n)))
+	}
+	return err
 }
 
 // Engine 返回 Gin 引擎（用于测试）。
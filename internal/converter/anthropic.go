@@ -426,6 +426,8 @@ func (c *AnthropicConverter) mapFinishReason(reason domain.FinishReason) string
 		return "max_tokens"
 	case domain.FinishReasonToolCalls:
 		return "tool_use"
+	case domain.FinishReasonContentFilter:
+		return "refusal"
 	default:
 		return "end_turn"
 	}
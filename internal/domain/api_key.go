@@ -3,21 +3,23 @@ package domain
 
 import (
 	"time"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // APIKey API 密钥领域实体。
 type APIKey struct {
-	ID         int64      `json:"id"`
-	UserID     int64      `json:"userId"`
-	Key        string     `json:"key"`
-	KeyHash    string     `json:"-"`
-	Name       string     `json:"name"`
-	Enabled    bool       `json:"enabled"`
-	Quota      *float64   `json:"quota"` // 额度限制(nil=无限)
-	UsedAmount float64    `json:"usedAmount"`
-	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
-	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
-	CreatedAt  time.Time  `json:"createdAt"`
+	ID         int64         `json:"id"`
+	UserID     int64         `json:"userId"`
+	Key        string        `json:"key"`
+	KeyHash    string        `json:"-"`
+	Name       string        `json:"name"`
+	Enabled    bool          `json:"enabled"`
+	Quota      *money.Amount `json:"quota"` // 额度限制(nil=无限)
+	UsedAmount money.Amount  `json:"usedAmount"`
+	ExpiresAt  *time.Time    `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time    `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
 }
 
 // IsValid 判断 API Key 是否有效。
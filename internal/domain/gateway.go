@@ -1,2 +1,32 @@
 // Package domain 定义领域模型和业务实体。
 package domain
+
+// RouteExplanation 描述一次模型路由解析的完整过程，用于排查模型被路由到了哪个上游。
+type RouteExplanation struct {
+	Model       string           `json:"model"`
+	MatchedBy   string           `json:"matchedBy,omitempty"` // exact, loadBalance, wildcard, regex, prefix, typeDefault
+	Rule        string           `json:"rule,omitempty"`      // 命中的规则模式、负载均衡组模式或供应商类型
+	Provider    string           `json:"provider,omitempty"`  // 选中的供应商；负载均衡按请求选择，仅在只有一个可用节点时给出
+	ActualModel string           `json:"actualModel,omitempty"`
+	Candidates  []RouteCandidate `json:"candidates,omitempty"` // 负载均衡组的候选节点
+	Fallbacks   []RouteCandidate `json:"fallbacks,omitempty"`  // 回退链
+	Steps       []RouteStep      `json:"steps"`                // 按优先级依次检查的各个阶段
+	Error       string           `json:"error,omitempty"`      // 解析失败的原因
+}
+
+// RouteStep 路由解析中的一个阶段。
+type RouteStep struct {
+	Stage   string `json:"stage"` // exact, loadBalance, pattern, prefix, typeDefault
+	Matched bool   `json:"matched"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// RouteCandidate 路由解析中的候选供应商。
+type RouteCandidate struct {
+	Provider    string `json:"provider"`
+	ActualModel string `json:"actualModel,omitempty"`
+	Hop         int    `json:"hop,omitempty"`      // 在回退链中的位置
+	Priority    int    `json:"priority,omitempty"` // 负载均衡成员优先级，值越大越优先
+	Available   bool   `json:"available"`          // 健康检查与熔断器均允许选中
+	Breaker     string `json:"breaker,omitempty"`  // 熔断器状态
+}
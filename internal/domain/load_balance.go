@@ -7,7 +7,7 @@ type LoadBalanceGroup struct {
 	ID           int64     `json:"id"`
 	Name         string    `json:"name"`
 	ModelPattern string    `json:"modelPattern"`
-	Strategy     string    `json:"strategy"` // round-robin, random, failover, weighted
+	Strategy     string    `json:"strategy"` // round-robin, random, failover, weighted, least-outstanding, peak-ewma, consistent-hash
 	Enabled      bool      `json:"enabled"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
@@ -19,6 +19,6 @@ type LoadBalanceMember struct {
 	GroupID      int64     `json:"groupId"`
 	ProviderName string    `json:"providerName"`
 	Weight       int       `json:"weight"`
-	Priority     int       `json:"priority"`
+	Priority     int       `json:"priority"` // 优先级层级，值越大越优先（与路由规则一致）；流量只在最高优先级的可用层内按权重分配
 	CreatedAt    time.Time `json:"createdAt"`
 }
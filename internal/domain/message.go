@@ -37,9 +37,10 @@ type ContentPart struct {
 	URL       string `json:"url,omitempty"`        // image URL
 
 	// 工具使用
-	ToolID    string         `json:"tool_id,omitempty"`
-	ToolName  string         `json:"tool_name,omitempty"`
-	ToolInput map[string]any `json:"tool_input,omitempty"`
+	ToolID        string         `json:"tool_id,omitempty"`
+	ToolName      string         `json:"tool_name,omitempty"`
+	ToolInput     map[string]any `json:"tool_input,omitempty"`
+	ToolSignature string         `json:"tool_signature,omitempty"` // 上游要求在后续轮次原样回传的签名（如 Gemini thoughtSignature）
 
 	// 工具结果
 	ToolUseID string `json:"tool_use_id,omitempty"`
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// 模型能力名称，用于描述请求需要但候选模型不支持的能力。
+const (
+	CapabilityStreaming     = "streaming"
+	CapabilityVision        = "vision"
+	CapabilityTools         = "tools"
+	CapabilityThinking      = "thinking"
+	CapabilityJSONSchema    = "json_schema"
+	CapabilityContextWindow = "context_window"
+	CapabilityMaxOutput     = "max_output"
+)
+
+// ModelCapability 模型目录条目：模型的能力与容量，路由时据此过滤不满足请求的候选。
+// ProviderName 为空表示适用于所有供应商，否则只约束该供应商实例上的模型（如能力受限的私有部署）。
+type ModelCapability struct {
+	ID               int64     `json:"id"`
+	ModelPattern     string    `json:"modelPattern"` // 模型匹配模式，支持末尾通配符 *
+	ProviderName     string    `json:"providerName"`
+	Vision           bool      `json:"vision"`
+	Tools            bool      `json:"tools"`
+	Thinking         bool      `json:"thinking"`
+	JSONSchema       bool      `json:"jsonSchema"`
+	ContextWindow    int       `json:"contextWindow"`    // 上下文窗口 (tokens)，0 表示不限制
+	MaxOutputTokens  int       `json:"maxOutputTokens"`  // 单次最大输出 (tokens)，0 表示不限制
+	LongContextModel string    `json:"longContextModel"` // 请求超出容量时改用的模型（如 gpt-4o-mini -> gpt-4.1），空表示直接拒绝
+	Enabled          bool      `json:"enabled"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Match 返回模式与模型名的匹配程度：精确匹配最高，末尾通配符按前缀长度计，不匹配返回 -1。
+func (c *ModelCapability) Match(model string) int {
+	if c.ModelPattern == model {
+		return len(model) + 1
+	}
+	if prefix, ok := strings.CutSuffix(c.ModelPattern, "*"); ok && strings.HasPrefix(model, prefix) {
+		return len(prefix)
+	}
+	return -1
+}
+
+// Missing 返回请求需要而该模型不支持的能力。
+func (c *ModelCapability) Missing(need Requirements) []string {
+	var missing []string
+	if need.Vision && !c.Vision {
+		missing = append(missing, CapabilityVision)
+	}
+	if need.Tools && !c.Tools {
+		missing = append(missing, CapabilityTools)
+	}
+	if need.Thinking && !c.Thinking {
+		missing = append(missing, CapabilityThinking)
+	}
+	if need.JSONSchema && !c.JSONSchema {
+		missing = append(missing, CapabilityJSONSchema)
+	}
+	if c.ContextWindow > 0 && need.PromptTokens+need.MaxTokens > c.ContextWindow {
+		missing = append(missing, CapabilityContextWindow)
+	}
+	if c.MaxOutputTokens > 0 && need.MaxTokens > c.MaxOutputTokens {
+		missing = append(missing, CapabilityMaxOutput)
+	}
+	return missing
+}
+
+// IsCapacityOnly 判断缺少的能力是否都是容量限制（上下文窗口、最大输出），
+// 这类请求可以改用长上下文模型，而不是能力缺失。
+func IsCapacityOnly(missing []string) bool {
+	for _, m := range missing {
+		if m != CapabilityContextWindow && m != CapabilityMaxOutput {
+			return false
+		}
+	}
+	return len(missing) > 0
+}
+
+// Requirements 描述一次请求对模型能力的要求。
+type Requirements struct {
+	Streaming    bool
+	Vision       bool
+	Tools        bool
+	Thinking     bool
+	JSONSchema   bool
+	PromptTokens int // 估算的提示词 token 数
+	MaxTokens    int // 请求的最大输出 token 数，0 表示未指定
+}
+
+// Requirements 从请求内容推断所需的模型能力。
+func (r *ChatRequest) Requirements() Requirements {
+	need := Requirements{
+		Streaming:    r.Stream,
+		Tools:        len(r.Tools) > 0,
+		Thinking:     r.Thinking != nil && r.Thinking.Type == "enabled",
+		JSONSchema:   r.ResponseFormat != nil && r.ResponseFormat.Type == ResponseFormatJSONSchema,
+		PromptTokens: r.EstimatePromptTokens(),
+		MaxTokens:    r.MaxTokens,
+	}
+	for _, m := range r.Messages {
+		for _, part := range m.Content {
+			switch part.Type {
+			case ContentTypeImage:
+				need.Vision = true
+			case ContentTypeToolUse, ContentTypeToolResult:
+				need.Tools = true
+			}
+		}
+	}
+	return need
+}
@@ -4,15 +4,46 @@ import "time"
 
 // Provider 提供商领域实体。
 type Provider struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Type      string    `json:"type"` // openai, anthropic
-	APIKey    string    `json:"apiKey"`
-	BaseURL   string    `json:"baseURL"`
-	Models    []string  `json:"models"` // 支持的模型列表
-	TimeoutMs int       `json:"timeoutMs"`
-	IsDefault bool      `json:"isDefault"`
-	Enabled   bool      `json:"enabled"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID                      int64             `json:"id"`
+	Name                    string            `json:"name"`
+	Type                    string            `json:"type"` // openai, anthropic, gemini, azure-openai
+	APIKey                  string            `json:"apiKey"`
+	APIKeys                 []ProviderKey     `json:"apiKeys,omitempty"`     // 额外的上游 Key，与 APIKey 组成 Key 池
+	KeyStrategy             string            `json:"keyStrategy,omitempty"` // Key 池选择策略：round_robin（默认）、least_used
+	BaseURL                 string            `json:"baseURL"`
+	Models                  []string          `json:"models"`                // 支持的模型列表
+	APIVersion              string            `json:"apiVersion,omitempty"`  // Azure OpenAI api-version
+	Deployments             map[string]string `json:"deployments,omitempty"` // Azure OpenAI 模型名 -> 部署名
+	TimeoutMs               int               `json:"timeoutMs"`             // 非流式请求的总超时，0 表示不限制；流式请求只受响应头和首 token 超时限制
+	ConnectTimeoutMs        int               `json:"connectTimeoutMs"`      // HTTP 传输配置，0 表示使用默认值
+	ResponseHeaderTimeoutMs int               `json:"responseHeaderTimeoutMs"`
+	MaxIdleConns            int               `json:"maxIdleConns"`
+	MaxIdleConnsPerHost     int               `json:"maxIdleConnsPerHost"`
+	ProxyURL                string            `json:"proxyURL,omitempty"`
+	RetryMaxAttempts        int               `json:"retryMaxAttempts"` // 重试策略，0 表示使用默认值，1 表示不重试
+	RetryInitialDelayMs     int               `json:"retryInitialDelayMs"`
+	RetryMaxDelayMs         int               `json:"retryMaxDelayMs"`
+	FirstTokenTimeoutMs     int               `json:"firstTokenTimeoutMs"` // 流式首个增量超时，0 表示不限制
+	IsDefault               bool              `json:"isDefault"`
+	Enabled                 bool              `json:"enabled"`
+	CreatedAt               time.Time         `json:"createdAt"`
+	UpdatedAt               time.Time         `json:"updatedAt"`
+}
+
+// DefaultKeyName Key 池中供应商主 Key（APIKey）的名称。
+const DefaultKeyName = "default"
+
+// ProviderKey Key 池中的一个上游 API Key。
+type ProviderKey struct {
+	Name string `json:"name"` // 在供应商内唯一，用于统计与手动启用
+	Key  string `json:"key"`
+}
+
+// Keys 返回供应商的全部上游 Key：非空的 APIKey（名为 default）在前，其后为 APIKeys。
+func (p *Provider) Keys() []ProviderKey {
+	keys := make([]ProviderKey, 0, len(p.APIKeys)+1)
+	if p.APIKey != "" {
+		keys = append(keys, ProviderKey{Name: DefaultKeyName, Key: p.APIKey})
+	}
+	return append(keys, p.APIKeys...)
 }
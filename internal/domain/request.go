@@ -111,16 +111,26 @@ type ChatRequest struct {
 
 	// 提供商特定的元数据（用于透传）
 	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// 会话亲和键，一致性哈希负载均衡据此把同一会话固定到同一个供应商节点 (内部使用)
+	SessionKey string `json:"-"`
+
+	// A/B 分流键，同一用户或 API Key 总是分到同一个实验组 (内部使用)
+	SplitKey string `json:"-"`
+
+	// 网关请求 ID，用于关联用量日志与影子流量对比记录 (内部使用)
+	RequestID string `json:"-"`
 }
 
 // FinishReason 表示模型停止生成的原因。
 type FinishReason string
 
 const (
-	FinishReasonStop      FinishReason = "stop"
-	FinishReasonLength    FinishReason = "length"
-	FinishReasonToolCalls FinishReason = "tool_calls"
-	FinishReasonError     FinishReason = "error"
+	FinishReasonStop          FinishReason = "stop"
+	FinishReasonLength        FinishReason = "length"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
+	FinishReasonContentFilter FinishReason = "content_filter"
+	FinishReasonError         FinishReason = "error"
 )
 
 // TokenUsage 表示令牌消耗统计数据。
@@ -147,8 +157,23 @@ type ChatResponse struct {
 	// 令牌使用统计数据
 	Usage *TokenUsage `json:"usage,omitempty"`
 
-	// 提供商名称 (内部使用)
-	Provider string `json:"-"`
+	// 实际处理请求的供应商实例名称与 ID (内部使用)
+	Provider   string `json:"-"`
+	ProviderID int64  `json:"-"`
+
+	// 实际服务请求的回退链跳数，0 表示主路由 (内部使用)
+	FallbackHop int `json:"-"`
+
+	// 服务请求的 A/B 分流实验组，未分流时为空 (内部使用)
+	Variant string `json:"-"`
+}
+
+// ProviderRef 标识实际处理请求的供应商实例 (内部使用)。
+type ProviderRef struct {
+	ID          int64
+	Name        string
+	FallbackHop int    // 回退链中的跳数，0 表示主路由
+	Variant     string // A/B 分流的实验组，未分流时为空
 }
 
 // StreamDelta 表示流式响应中的单个分块。
@@ -1,16 +1,89 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 路由规则类型
+const (
+	RuleTypeExact    = "exact"    // 精确匹配
+	RuleTypePrefix   = "prefix"   // 前缀匹配（不区分大小写）
+	RuleTypeWildcard = "wildcard" // 通配符：* 匹配任意字符串，? 匹配单个字符（不区分大小写）
+	RuleTypeRegex    = "regex"    // 正则表达式，自动锚定整个模型名
+)
 
 // RoutingRule 路由规则领域实体。
 type RoutingRule struct {
-	ID           int64     `json:"id"`
-	RuleType     string    `json:"ruleType"` // exact, prefix, wildcard
-	Pattern      string    `json:"pattern"`
-	ProviderName string    `json:"providerName"`
-	ActualModel  string    `json:"actualModel"`
-	Priority     int       `json:"priority"`
-	Enabled      bool      `json:"enabled"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           int64          `json:"id"`
+	RuleType     string         `json:"ruleType"` // exact, prefix, wildcard, regex
+	Pattern      string         `json:"pattern"`
+	ProviderName string         `json:"providerName"`
+	ActualModel  string         `json:"actualModel"` // wildcard / regex 规则中可用 $1、${name} 引用捕获组
+	Priority     int            `json:"priority"`
+	Fallbacks    []RouteTarget  `json:"fallbacks,omitempty"` // 主供应商失败时依次尝试的回退链
+	Variants     []RouteVariant `json:"variants,omitempty"`  // A/B 分流的实验组，余下的流量归对照组
+	Shadow       *ShadowTarget  `json:"shadow,omitempty"`    // 影子流量，采样复制请求用于对比
+	Enabled      bool           `json:"enabled"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+// RouteTarget 回退链中的一跳：供应商实例及其实际模型名，ActualModel 为空时沿用请求模型。
+type RouteTarget struct {
+	Provider    string `json:"provider"`
+	ActualModel string `json:"actualModel,omitempty"`
+}
+
+// VariantControl 是 A/B 分流中对照组的名称：未分到任何实验组的流量走规则本身的供应商与模型。
+const VariantControl = "control"
+
+// RouteVariant A/B 分流中的一个实验组：按百分比从对照组分出流量，发往另一个 (供应商, 模型)。
+// 请求按用户或 API Key 确定性地分组，同一调用方总是落在同一组。
+type RouteVariant struct {
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	ActualModel string `json:"actualModel,omitempty"`
+	Percent     int    `json:"percent"` // 分得的流量百分比，各实验组之和不超过 100
+}
+
+// ControlPercent 返回对照组分得的流量百分比。
+func (r *RoutingRule) ControlPercent() int {
+	percent := 100
+	for _, v := range r.Variants {
+		percent -= v.Percent
+	}
+	return percent
+}
+
+// CompilePattern 将 wildcard / regex 规则编译为锚定的正则表达式。
+// 通配符中的每个 * 和 ? 都是一个捕获组，按出现顺序编号。其他规则类型返回 nil。
+func (r *RoutingRule) CompilePattern() (*regexp.Regexp, error) {
+	switch r.RuleType {
+	case RuleTypeWildcard:
+		var b strings.Builder
+		b.WriteString("(?i)^")
+		for _, c := range r.Pattern {
+			switch c {
+			case '*':
+				b.WriteString("(.*)")
+			case '?':
+				b.WriteString("(.)")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		b.WriteString("$")
+		return regexp.Compile(b.String())
+	case RuleTypeRegex:
+		re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", r.Pattern, err)
+		}
+		return re, nil
+	default:
+		return nil, nil
+	}
 }
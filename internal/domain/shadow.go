@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// ShadowTarget 路由规则的影子流量配置：按百分比采样请求，异步复制一份发往影子供应商与模型。
+// 影子响应不返回给客户端、不计费，只与主请求的结果一起保存用于对比。
+type ShadowTarget struct {
+	Provider    string `json:"provider"`
+	ActualModel string `json:"actualModel,omitempty"` // 为空时沿用请求模型
+	Percent     int    `json:"percent"`               // 采样百分比，1-100
+}
+
+// ShadowResult 一次影子请求与对应主请求的对比记录。
+type ShadowResult struct {
+	ID        int64  `json:"id"`
+	RequestID string `json:"requestId,omitempty"`
+	Model     string `json:"model"` // 客户端请求的模型
+
+	PrimaryProvider     string `json:"primaryProvider"`
+	PrimaryModel        string `json:"primaryModel"`
+	PrimaryLatencyMs    int    `json:"primaryLatencyMs"`
+	PrimaryInputTokens  int    `json:"primaryInputTokens"`
+	PrimaryOutputTokens int    `json:"primaryOutputTokens"`
+	PrimaryContent      string `json:"primaryContent"`
+	PrimaryError        string `json:"primaryError,omitempty"`
+
+	ShadowProvider     string `json:"shadowProvider"`
+	ShadowModel        string `json:"shadowModel"`
+	ShadowLatencyMs    int    `json:"shadowLatencyMs"`
+	ShadowInputTokens  int    `json:"shadowInputTokens"`
+	ShadowOutputTokens int    `json:"shadowOutputTokens"`
+	ShadowContent      string `json:"shadowContent"`
+	ShadowError        string `json:"shadowError,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ShadowStats 按 (请求模型, 影子供应商, 影子模型) 汇总的影子流量对比。
+type ShadowStats struct {
+	Model                  string `json:"model"`
+	ShadowProvider         string `json:"shadowProvider"`
+	ShadowModel            string `json:"shadowModel"`
+	Requests               int64  `json:"requests"`
+	PrimaryErrors          int64  `json:"primaryErrors"`
+	ShadowErrors           int64  `json:"shadowErrors"`
+	PrimaryAvgLatencyMs    int64  `json:"primaryAvgLatencyMs"`
+	ShadowAvgLatencyMs     int64  `json:"shadowAvgLatencyMs"`
+	PrimaryAvgOutputTokens int64  `json:"primaryAvgOutputTokens"`
+	ShadowAvgOutputTokens  int64  `json:"shadowAvgOutputTokens"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// 提示词 token 估算参数。估算只用于路由前的容量判断，宁可略微高估，避免把放不下的请求发到上游。
+const (
+	tokensPerMessage = 4   // 每条消息的角色与分隔符开销
+	tokensPerImage   = 765 // 一张高分辨率图像的典型开销
+	tokensPerTool    = 8   // 每个工具定义的结构开销
+)
+
+// EstimatePromptTokens 估算请求的提示词 token 数：ASCII 文本约 4 个字符一个 token，
+// 中日韩等非 ASCII 字符约每个字符一个 token，图像、工具定义和工具调用参数按固定开销或 JSON 长度计。
+func (r *ChatRequest) EstimatePromptTokens() int {
+	tokens := estimateTextTokens(r.System)
+	for _, m := range r.Messages {
+		tokens += tokensPerMessage
+		for _, part := range m.Content {
+			switch part.Type {
+			case ContentTypeImage:
+				tokens += tokensPerImage
+			case ContentTypeToolUse:
+				tokens += estimateTextTokens(part.ToolName) + estimateJSONTokens(part.ToolInput)
+			default:
+				tokens += estimateTextTokens(part.Text) + estimateTextTokens(part.Thinking)
+			}
+		}
+	}
+	for _, t := range r.Tools {
+		tokens += tokensPerTool + estimateTextTokens(t.Name) + estimateTextTokens(t.Description) + estimateJSONTokens(t.InputSchema)
+	}
+	return tokens
+}
+
+func estimateTextTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	ascii, other := 0, 0
+	for i := 0; i < len(s); {
+		if s[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		other++
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
+
+func estimateJSONTokens(v map[string]any) int {
+	if len(v) == 0 {
+		return 0
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return estimateTextTokens(string(b))
+}
@@ -3,23 +3,30 @@ package domain
 
 import (
 	"time"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // UsageLog 使用记录领域实体。
 type UsageLog struct {
-	ID           int64     `json:"id"`
-	UserID       int64     `json:"userId"`
-	APIKeyID     *int64    `json:"apiKeyId,omitempty"`
-	Model        string    `json:"model"`
-	Provider     string    `json:"provider"`
-	InputTokens  int       `json:"inputTokens"`
-	OutputTokens int       `json:"outputTokens"`
-	LatencyMs    int       `json:"latencyMs"`
-	StatusCode   int       `json:"statusCode"`
-	ClientIP     string    `json:"clientIp,omitempty"`
-	UserAgent    string    `json:"userAgent,omitempty"`
-	RequestID    string    `json:"requestId,omitempty"`
-	CreatedAt    time.Time `json:"createdAt"`
+	ID           int64        `json:"id"`
+	UserID       int64        `json:"userId"`
+	APIKeyID     *int64       `json:"apiKeyId,omitempty"`
+	Model        string       `json:"model"`
+	Provider     string       `json:"provider"` // 供应商实例名称
+	ProviderID   int64        `json:"providerId,omitempty"`
+	FallbackHop  int          `json:"fallbackHop"`       // 回退链中实际服务的一跳，0 表示主路由
+	Variant      string       `json:"variant,omitempty"` // A/B 分流的实验组，未分流时为空
+	InputTokens  int          `json:"inputTokens"`
+	OutputTokens int          `json:"outputTokens"`
+	Cost         money.Amount `json:"cost"`    // 按模型费率计算的费用，每个请求只计算一次
+	Charged      money.Amount `json:"charged"` // 实际收取的费用，计入钱包流水与 Key 用量；开启 NoOverdraft 且余额不足时小于 Cost，差额不再收取
+	LatencyMs    int          `json:"latencyMs"`
+	StatusCode   int          `json:"statusCode"`
+	ClientIP     string       `json:"clientIp,omitempty"`
+	UserAgent    string       `json:"userAgent,omitempty"`
+	RequestID    string       `json:"requestId,omitempty"`
+	CreatedAt    time.Time    `json:"createdAt"`
 }
 
 // TotalTokens 返回总 Token 数。
@@ -1,14 +1,18 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"ai-gateway/internal/pkg/money"
+)
 
 // Wallet 用户钱包
 type Wallet struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"userId"`
-	Balance   float64   `json:"balance"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"userId"`
+	Balance   money.Amount `json:"balance"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
 }
 
 // TransactionType 交易类型
@@ -25,10 +29,34 @@ type WalletTransaction struct {
 	ID            int64           `json:"id"`
 	WalletID      int64           `json:"walletId"`
 	Type          TransactionType `json:"type"`
-	Amount        float64         `json:"amount"`
-	BalanceBefore float64         `json:"balanceBefore"`
-	BalanceAfter  float64         `json:"balanceAfter"`
+	Amount        money.Amount    `json:"amount"`
+	BalanceBefore money.Amount    `json:"balanceBefore"`
+	BalanceAfter  money.Amount    `json:"balanceAfter"`
 	ReferenceID   string          `json:"referenceId"` // 关联ID，如请求ID或管理员操作ID
 	Description   string          `json:"description"`
 	CreatedAt     time.Time       `json:"createdAt"`
 }
+
+// HoldStatus 预授权状态
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"   // 有效，占用余额与 Key 额度
+	HoldStatusSettled  HoldStatus = "settled"  // 已按实际费用结算
+	HoldStatusReleased HoldStatus = "released" // 请求未产生费用，已释放
+	HoldStatusExpired  HoldStatus = "expired"  // 超时未结算（如进程崩溃），不再占用余额
+)
+
+// WalletHold 请求发出前按预估最大费用冻结的金额，同时占用钱包余额与 API Key 额度，
+// 请求结束后按实际费用结算。以请求 ID 唯一标识。
+type WalletHold struct {
+	ID        int64        `json:"id"`
+	RequestID string       `json:"requestId"`
+	UserID    int64        `json:"userId"`
+	WalletID  int64        `json:"walletId"`
+	APIKeyID  *int64       `json:"apiKeyId,omitempty"`
+	Amount    money.Amount `json:"amount"`
+	Status    HoldStatus   `json:"status"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
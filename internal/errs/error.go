@@ -60,14 +60,16 @@ const (
 	CodeProviderOverloaded  ErrorCode = 600005
 	CodeModelNotFound       ErrorCode = 600006
 	CodeInvalidModel        ErrorCode = 600007
+	CodeContentFiltered     ErrorCode = 600008
 
 	// 限流错误
 	CodeRateLimited  ErrorCode = 600100
 	CodeStreamClosed ErrorCode = 600101
 
 	// 转换错误 (7XXYYY)
-	CodeUnsupportedFeature ErrorCode = 700001
-	CodeConversionFailed   ErrorCode = 700002
+	CodeUnsupportedFeature    ErrorCode = 700001
+	CodeConversionFailed      ErrorCode = 700002
+	CodeContextLengthExceeded ErrorCode = 700003
 )
 
 // AppError 统一的应用错误类型
@@ -160,6 +162,8 @@ func (e *AppError) HTTPStatus() int {
 		switch e.Code {
 		case CodeProviderNotFound, CodeModelNotFound:
 			return http.StatusNotFound
+		case CodeContentFiltered:
+			return http.StatusBadRequest
 		case CodeRateLimited:
 			return http.StatusTooManyRequests
 		case CodeProviderTimeout:
@@ -169,6 +173,9 @@ func (e *AppError) HTTPStatus() int {
 		default:
 			return http.StatusBadGateway
 		}
+	case e.Code == CodeUnsupportedFeature, e.Code == CodeContextLengthExceeded:
+		// 请求使用了目标模型不支持的能力，或超出了模型容量
+		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
 	}
@@ -188,10 +195,15 @@ func (e *AppError) APIErrorType() string {
 	case e.Code >= 500000 && e.Code < 600000:
 		return "invalid_request_error"
 	case e.Code >= 600000 && e.Code < 700000:
-		if e.Code == CodeRateLimited {
+		switch e.Code {
+		case CodeRateLimited:
 			return "rate_limit_error"
+		case CodeContentFiltered:
+			return "invalid_request_error"
 		}
 		return "api_error"
+	case e.Code == CodeUnsupportedFeature, e.Code == CodeContextLengthExceeded:
+		return "invalid_request_error"
 	default:
 		return "api_error"
 	}
@@ -263,14 +275,16 @@ var (
 	ErrProviderOverloaded  = New(CodeProviderOverloaded, "provider overloaded")
 	ErrModelNotFound       = New(CodeModelNotFound, "model not found")
 	ErrInvalidModel        = New(CodeInvalidModel, "invalid model")
+	ErrContentFiltered     = New(CodeContentFiltered, "content filtered by provider")
 	ErrRateLimited         = New(CodeRateLimited, "rate limited")
 	ErrStreamClosed        = New(CodeStreamClosed, "stream closed")
 )
 
 // 转换错误
 var (
-	ErrUnsupportedFeature = New(CodeUnsupportedFeature, "unsupported feature")
-	ErrConversionFailed   = New(CodeConversionFailed, "conversion failed")
+	ErrUnsupportedFeature    = New(CodeUnsupportedFeature, "unsupported feature")
+	ErrConversionFailed      = New(CodeConversionFailed, "conversion failed")
+	ErrContextLengthExceeded = New(CodeContextLengthExceeded, "context length exceeded")
 )
 
 // ============================================
@@ -47,6 +47,16 @@ func TestAppError_HTTPStatus(t *testing.T) {
 			err:  New(CodeUserNotFound, "not found"),
 			want: http.StatusNotFound,
 		},
+		{
+			name: "UnsupportedFeature",
+			err:  New(CodeUnsupportedFeature, "model does not support: vision"),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "ContextLengthExceeded",
+			err:  New(CodeContextLengthExceeded, "prompt too long"),
+			want: http.StatusBadRequest,
+		},
 		{
 			name: "Server Error",
 			err:  New(CodeInternalError, "internal error"),
@@ -63,7 +63,10 @@ func InitDB(cfg *config.Config, l logger.Logger) (*gorm.DB, error) {
 		&dao.UsageLog{},
 		&dao.Wallet{},
 		&dao.WalletTransaction{},
+		&dao.WalletHold{},
 		&dao.ModelRate{},
+		&dao.ModelCapability{},
+		&dao.ShadowResult{},
 	); err != nil {
 		return nil, fmt.Errorf("数据库迁移失败: %w", err)
 	}
@@ -0,0 +1,22 @@
+package ioc
+
+import (
+	"fmt"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/pkg/envelope"
+	"ai-gateway/internal/pkg/logger"
+)
+
+// InitKeyring 根据配置创建供应商 Key 的加密 Keyring，未配置主密钥时返回 nil（明文保存）。
+func InitKeyring(cfg *config.Config, l logger.Logger) (*envelope.Keyring, error) {
+	if cfg.Encryption.MasterKey == "" {
+		l.Warn("encryption master key not configured, provider api keys are stored in plaintext and not cached in redis")
+		return nil, nil
+	}
+	keyring, err := envelope.NewKeyring(cfg.Encryption.MasterKey, cfg.Encryption.PreviousKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密主密钥失败: %w", err)
+	}
+	return keyring, nil
+}
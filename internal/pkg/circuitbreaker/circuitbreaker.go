@@ -0,0 +1,294 @@
+// Package circuitbreaker 提供基于滑动窗口的熔断器。
+//
+// 熔断器有三种状态：
+//   - Closed：正常放行请求，并在滑动窗口内统计成功/失败；
+//   - Open：错误率或连续失败次数超过阈值后熔断，直接拒绝请求；
+//   - HalfOpen：熔断超时后进入半开状态，放行少量探测请求，
+//     全部成功则恢复 Closed，任一失败则重新 Open。
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen 熔断器处于打开状态（或半开状态下探测名额已满）时返回。
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State 熔断器状态。
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Config 熔断器配置。
+type Config struct {
+	Window              time.Duration // 滑动窗口长度
+	Buckets             int           // 窗口分桶数
+	MinRequests         int           // 窗口内请求数达到该值才按错误率判断
+	ErrorRateThreshold  float64       // 错误率阈值 (0.0 - 1.0)
+	ConsecutiveFailures int           // 连续失败次数阈值，0 表示不启用
+	OpenTimeout         time.Duration // 打开状态持续时间，之后进入半开
+	HalfOpenMaxRequests int           // 半开状态下允许的并发探测数
+}
+
+// DefaultConfig 默认配置
+var DefaultConfig = Config{
+	Window:              60 * time.Second,
+	Buckets:             10,
+	MinRequests:         20,
+	ErrorRateThreshold:  0.5,
+	ConsecutiveFailures: 5,
+	OpenTimeout:         30 * time.Second,
+	HalfOpenMaxRequests: 1,
+}
+
+// Snapshot 熔断器状态快照。
+type Snapshot struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	Requests            int        `json:"requests"` // 窗口内请求数
+	Failures            int        `json:"failures"` // 窗口内失败数
+	ErrorRate           float64    `json:"errorRate"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+}
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// Breaker 熔断器，并发安全。
+type Breaker struct {
+	name string
+	cfg  Config
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64 // 每次状态切换递增，用于丢弃旧状态下发出的请求结果
+	buckets     []bucket
+	consecutive int
+	openedAt    time.Time
+	halfOpenIn  int // 半开状态下正在进行的探测数
+	halfOpenOK  int // 半开状态下已成功的探测数
+	now         func() time.Time
+}
+
+// New 创建熔断器，零值配置项使用默认值。
+func New(name string, cfg Config) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultConfig.Window
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = DefaultConfig.Buckets
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultConfig.MinRequests
+	}
+	if cfg.ErrorRateThreshold <= 0 {
+		cfg.ErrorRateThreshold = DefaultConfig.ErrorRateThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultConfig.OpenTimeout
+	}
+	if cfg.HalfOpenMaxRequests <= 0 {
+		cfg.HalfOpenMaxRequests = DefaultConfig.HalfOpenMaxRequests
+	}
+	return &Breaker{
+		name:    name,
+		cfg:     cfg,
+		buckets: make([]bucket, cfg.Buckets),
+		now:     time.Now,
+	}
+}
+
+// Name 返回熔断器名称。
+func (b *Breaker) Name() string { return b.name }
+
+// State 返回当前状态。打开状态超时后会在此处切换为半开。
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(b.now())
+	return b.state
+}
+
+// Ready 报告熔断器当前是否可能放行请求（Closed，或半开且仍有探测名额）。
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(b.now())
+	switch b.state {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		return b.halfOpenIn < b.cfg.HalfOpenMaxRequests
+	default:
+		return false
+	}
+}
+
+// Allow 申请执行一次请求。熔断时返回 ErrOpen；
+// 否则返回 done 回调，调用方必须在请求结束后调用且仅调用一次。
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advanceLocked(now)
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenIn >= b.cfg.HalfOpenMaxRequests {
+			return nil, ErrOpen
+		}
+		b.halfOpenIn++
+	}
+
+	gen := b.generation
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.record(gen, success) })
+	}, nil
+}
+
+// Snapshot 返回当前状态快照。
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advanceLocked(now)
+	successes, failures := b.countLocked(now)
+	s := Snapshot{
+		Name:                b.name,
+		State:               b.state.String(),
+		Requests:            successes + failures,
+		Failures:            failures,
+		ConsecutiveFailures: b.consecutive,
+	}
+	if s.Requests > 0 {
+		s.ErrorRate = float64(failures) / float64(s.Requests)
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
+
+func (b *Breaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 状态已切换，旧请求的结果不再影响新状态
+	if gen != b.generation {
+		return
+	}
+
+	now := b.now()
+	switch b.state {
+	case StateHalfOpen:
+		b.halfOpenIn--
+		if !success {
+			b.toOpenLocked(now)
+			return
+		}
+		b.halfOpenOK++
+		if b.halfOpenOK >= b.cfg.HalfOpenMaxRequests {
+			b.toClosedLocked()
+		}
+	case StateClosed:
+		bk := b.bucketLocked(now)
+		if success {
+			bk.successes++
+			b.consecutive = 0
+			return
+		}
+		bk.failures++
+		b.consecutive++
+		if b.shouldTripLocked(now) {
+			b.toOpenLocked(now)
+		}
+	}
+}
+
+func (b *Breaker) shouldTripLocked(now time.Time) bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	successes, failures := b.countLocked(now)
+	total := successes + failures
+	return total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.ErrorRateThreshold
+}
+
+// advanceLocked 在打开超时后切换到半开状态。
+func (b *Breaker) advanceLocked(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = StateHalfOpen
+		b.generation++
+		b.halfOpenIn = 0
+		b.halfOpenOK = 0
+	}
+}
+
+func (b *Breaker) toOpenLocked(now time.Time) {
+	b.state = StateOpen
+	b.generation++
+	b.openedAt = now
+}
+
+func (b *Breaker) toClosedLocked() {
+	b.state = StateClosed
+	b.generation++
+	b.consecutive = 0
+	for i := range b.buckets {
+		b.buckets[i] = bucket{}
+	}
+}
+
+func (b *Breaker) bucketWidth() time.Duration {
+	return b.cfg.Window / time.Duration(b.cfg.Buckets)
+}
+
+// bucketLocked 返回当前时间对应的桶，过期的桶会被重置。
+func (b *Breaker) bucketLocked(now time.Time) *bucket {
+	width := b.bucketWidth()
+	start := now.Truncate(width)
+	idx := int(start.UnixNano()/int64(width)) % len(b.buckets)
+	bk := &b.buckets[idx]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+func (b *Breaker) countLocked(now time.Time) (successes, failures int) {
+	for _, bk := range b.buckets {
+		if !bk.start.IsZero() && now.Sub(bk.start) < b.cfg.Window {
+			successes += bk.successes
+			failures += bk.failures
+		}
+	}
+	return successes, failures
+}
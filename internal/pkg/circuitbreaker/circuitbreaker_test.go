@@ -0,0 +1,114 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestBreaker(cfg Config) (*Breaker, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	b := New("test", cfg)
+	b.now = clock.Now
+	return b, clock
+}
+
+func call(t *testing.T, b *Breaker, success bool) {
+	t.Helper()
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(success)
+}
+
+func TestBreaker_ConsecutiveFailuresTrip(t *testing.T) {
+	b, _ := newTestBreaker(Config{ConsecutiveFailures: 3, MinRequests: 100})
+
+	call(t, b, false)
+	call(t, b, false)
+	call(t, b, true) // 成功重置连续失败计数
+	call(t, b, false)
+	call(t, b, false)
+	assert.Equal(t, StateClosed, b.State())
+
+	call(t, b, false)
+	assert.Equal(t, StateOpen, b.State())
+
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+}
+
+func TestBreaker_ErrorRateTrip(t *testing.T) {
+	b, _ := newTestBreaker(Config{MinRequests: 10, ErrorRateThreshold: 0.5, ConsecutiveFailures: 0})
+
+	for i := 0; i < 9; i++ {
+		call(t, b, i%2 == 0) // 5 成功 4 失败
+	}
+	assert.Equal(t, StateClosed, b.State(), "below MinRequests")
+
+	call(t, b, false) // 10 次请求，5 次失败
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_WindowExpires(t *testing.T) {
+	b, clock := newTestBreaker(Config{Window: 10 * time.Second, Buckets: 10, MinRequests: 4, ConsecutiveFailures: 0})
+
+	call(t, b, false)
+	call(t, b, false)
+	call(t, b, true)
+	clock.Advance(11 * time.Second) // 旧的失败已滑出窗口
+
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, true)
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, 4, b.Snapshot().Requests)
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	b, clock := newTestBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: 5 * time.Second})
+
+	call(t, b, false)
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Ready())
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// 半开状态只放行一个探测
+	probe, err := b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+
+	// 探测失败，重新打开
+	probe(false)
+	assert.Equal(t, StateOpen, b.State())
+
+	clock.Advance(5 * time.Second)
+	call(t, b, true)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_StaleResultIgnored(t *testing.T) {
+	b, clock := newTestBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	slow, err := b.Allow()
+	require.NoError(t, err)
+	call(t, b, false)
+	clock.Advance(time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// 熔断前发出的请求结果不影响半开状态
+	slow(true)
+	assert.Equal(t, StateHalfOpen, b.State())
+}
@@ -0,0 +1,175 @@
+// Package envelope 提供信封加密：每个值使用随机生成的数据密钥（DEK）以 AES-256-GCM 加密，
+// DEK 再由主密钥（KEK）加密后与密文一起保存。
+//
+// 密文格式为 enc:v1:<主密钥 ID>:<加密后的 DEK>:<加密后的值>（均为 base64）。
+// 更换主密钥时只需用新主密钥重新加密 DEK；旧主密钥保留在 Keyring 中即可继续解密尚未轮换的值。
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const prefix = "enc:v1:"
+
+var (
+	// ErrNoMasterKey 未配置主密钥却需要解密时返回。
+	ErrNoMasterKey = errors.New("envelope: master key is not configured")
+	// ErrUnknownMasterKey 密文使用的主密钥不在 Keyring 中时返回。
+	ErrUnknownMasterKey = errors.New("envelope: value was encrypted with an unknown master key")
+	// ErrMalformed 密文格式错误或校验失败时返回。
+	ErrMalformed = errors.New("envelope: malformed ciphertext")
+)
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring 持有当前主密钥以及用于解密的历史主密钥。nil Keyring 表示未启用加密：
+// Encrypt 原样返回明文，Decrypt 只能处理未加密的值。
+type Keyring struct {
+	primary *masterKey
+	keys    map[string]*masterKey
+}
+
+// NewKeyring 创建 Keyring。primary 为当前主密钥，previous 为仍需用于解密的历史主密钥，
+// 均为 base64 编码的 32 字节密钥（可用 openssl rand -base64 32 生成）。
+func NewKeyring(primary string, previous ...string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*masterKey)}
+	for i, encoded := range append([]string{primary}, previous...) {
+		mk, err := parseMasterKey(encoded)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("master key: %w", err)
+			}
+			return nil, fmt.Errorf("previous master key #%d: %w", i, err)
+		}
+		if i == 0 {
+			k.primary = mk
+		}
+		if _, ok := k.keys[mk.id]; !ok {
+			k.keys[mk.id] = mk
+		}
+	}
+	return k, nil
+}
+
+func parseMasterKey(encoded string) (*masterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("want 32 bytes, got %d", len(raw))
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncrypted 报告值是否为本包生成的密文。
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 使用新的 DEK 加密明文并以当前主密钥加密 DEK。空值与 nil Keyring 原样返回。
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.primary.aead, dek, []byte(k.primary.id))
+	if err != nil {
+		return "", err
+	}
+	body, err := seal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary.id + ":" + wrapped + ":" + body, nil
+}
+
+// Decrypt 解密 Encrypt 生成的密文，未加密的值原样返回。
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoMasterKey
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	mk, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w (id %s)", ErrUnknownMasterKey, parts[0])
+	}
+	dek, err := open(mk.aead, parts[1], []byte(mk.id))
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	plaintext, err := open(aead, parts[2], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 报告值是否需要重新加密：未加密的非空值，或不是由当前主密钥加密的密文。
+func (k *Keyring) NeedsRotation(value string) bool {
+	if k == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.primary.id+":")
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, additional)), nil
+}
+
+func open(aead cipher.AEAD, encoded string, additional []byte) ([]byte, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return plaintext, nil
+}
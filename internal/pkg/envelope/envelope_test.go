@@ -0,0 +1,86 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMasterKey(t *testing.T) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestKeyring_RoundTrip(t *testing.T) {
+	k, err := NewKeyring(newMasterKey(t))
+	require.NoError(t, err)
+
+	c1, err := k.Encrypt("sk-secret")
+	require.NoError(t, err)
+	c2, err := k.Encrypt("sk-secret")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(c1))
+	assert.NotContains(t, c1, "sk-secret")
+	assert.NotEqual(t, c1, c2, "each value uses a fresh data key")
+
+	plain, err := k.Decrypt(c1)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", plain)
+
+	// 未加密的值原样返回，便于迁移存量明文
+	plain, err = k.Decrypt("sk-legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", plain)
+	assert.True(t, k.NeedsRotation("sk-legacy"))
+	assert.False(t, k.NeedsRotation(c1))
+
+	_, err = k.Decrypt(c1[:len(c1)-2] + "AA")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey, newKey := newMasterKey(t), newMasterKey(t)
+	old, err := NewKeyring(oldKey)
+	require.NoError(t, err)
+	sealed, err := old.Encrypt("sk-secret")
+	require.NoError(t, err)
+
+	// 新主密钥不认识旧密文
+	fresh, err := NewKeyring(newKey)
+	require.NoError(t, err)
+	_, err = fresh.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrUnknownMasterKey)
+
+	// 保留旧主密钥即可解密，并识别出需要轮换
+	rotating, err := NewKeyring(newKey, oldKey)
+	require.NoError(t, err)
+	assert.True(t, rotating.NeedsRotation(sealed))
+	plain, err := rotating.Decrypt(sealed)
+	require.NoError(t, err)
+	resealed, err := rotating.Encrypt(plain)
+	require.NoError(t, err)
+	assert.False(t, rotating.NeedsRotation(resealed))
+
+	plain, err = fresh.Decrypt(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", plain)
+}
+
+func TestKeyring_Nil(t *testing.T) {
+	var k *Keyring
+	v, err := k.Encrypt("sk-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", v)
+	_, err = k.Decrypt("enc:v1:00000000:a:b")
+	assert.ErrorIs(t, err, ErrNoMasterKey)
+
+	_, err = NewKeyring(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 16))))
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+// Package httpclient 根据供应商配置构建独立的 HTTP 客户端。
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config HTTP 客户端配置。零值字段使用默认值。
+// Config 是可比较的，调用方可以据此判断配置是否变化、是否需要重建客户端。
+// 客户端不设置整个请求的超时，否则长时间的流式响应会被截断；非流式请求的总超时由调用方通过 context 施加。
+type Config struct {
+	ConnectTimeout        time.Duration // 建立 TCP 连接的超时
+	ResponseHeaderTimeout time.Duration // 发送请求后等待响应头的超时，0 表示不限制
+	IdleConnTimeout       time.Duration // 空闲连接保留时长
+	MaxIdleConns          int           // 所有主机的最大空闲连接数
+	MaxIdleConnsPerHost   int           // 每个主机的最大空闲连接数
+	ProxyURL              string        // 出站代理地址，为空时使用环境变量 (HTTP_PROXY/HTTPS_PROXY)
+}
+
+// 默认值
+const (
+	DefaultConnectTimeout      = 10 * time.Second
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+)
+
+// New 创建一个使用独立 Transport 的 HTTP 客户端。
+func New(cfg Config) (*http.Client, error) {
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", cfg.ProxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
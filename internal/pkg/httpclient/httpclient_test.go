@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("ZeroValueUsesDefaults", func(t *testing.T) {
+		c, err := New(Config{})
+		require.NoError(t, err)
+		// 不设置整个请求的超时，以免截断流式响应
+		assert.Zero(t, c.Timeout)
+
+		tr := c.Transport.(*http.Transport)
+		assert.Equal(t, DefaultConnectTimeout, tr.TLSHandshakeTimeout)
+		assert.Equal(t, DefaultIdleConnTimeout, tr.IdleConnTimeout)
+		assert.Equal(t, DefaultMaxIdleConns, tr.MaxIdleConns)
+		assert.Equal(t, DefaultMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+		assert.Zero(t, tr.ResponseHeaderTimeout)
+	})
+
+	t.Run("ExplicitValues", func(t *testing.T) {
+		c, err := New(Config{
+			ConnectTimeout:        3 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			IdleConnTimeout:       time.Minute,
+			MaxIdleConns:          7,
+			MaxIdleConnsPerHost:   2,
+		})
+		require.NoError(t, err)
+
+		tr := c.Transport.(*http.Transport)
+		assert.Equal(t, 3*time.Second, tr.TLSHandshakeTimeout)
+		assert.Equal(t, 30*time.Second, tr.ResponseHeaderTimeout)
+		assert.Equal(t, time.Minute, tr.IdleConnTimeout)
+		assert.Equal(t, 7, tr.MaxIdleConns)
+		assert.Equal(t, 2, tr.MaxIdleConnsPerHost)
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", nil)
+		require.NoError(t, err)
+
+		c, err := New(Config{ProxyURL: "http://proxy.internal:3128"})
+		require.NoError(t, err)
+		u, err := c.Transport.(*http.Transport).Proxy(req)
+		require.NoError(t, err)
+		assert.Equal(t, "http://proxy.internal:3128", u.String())
+
+		// 未配置时使用环境变量
+		t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+		c, err = New(Config{})
+		require.NoError(t, err)
+		u, err = c.Transport.(*http.Transport).Proxy(req)
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.Equal(t, "env-proxy:8080", u.Host)
+	})
+
+	t.Run("InvalidProxy", func(t *testing.T) {
+		for _, proxy := range []string{"proxy.internal:3128", "://bad", "http://"} {
+			_, err := New(Config{ProxyURL: proxy})
+			assert.Error(t, err, proxy)
+		}
+	})
+}
@@ -0,0 +1,290 @@
+// Package keypool 管理同一供应商下的多个上游 API Key。
+//
+// 每次请求从池中租用一个 Key，请求结束后回报结果：
+//   - 429 / 额度不足的 Key 进入冷却，冷却期间不再被选中；
+//   - 401 的 Key 被自动禁用，直到 Key 变更或被手动重新启用；
+//   - 每个 Key 单独统计请求数、失败数与 token 用量。
+package keypool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCoolingDown 池中所有可用 Key 都处于冷却期时返回。
+	ErrCoolingDown = errors.New("all api keys are cooling down")
+	// ErrNoKey 池中所有 Key 都已被禁用时返回。
+	ErrNoKey = errors.New("no usable api key")
+)
+
+// 选择策略
+const (
+	StrategyRoundRobin = "round_robin" // 轮流使用各 Key（默认）
+	StrategyLeastUsed  = "least_used"  // 优先在途请求最少、最久未使用的 Key
+)
+
+// DefaultCooldown 上游未通过 Retry-After 给出等待时间时，限流 Key 的冷却时长。
+const DefaultCooldown = 30 * time.Second
+
+// QuotaCooldown 额度耗尽（insufficient_quota、402 等）的 Key 的冷却时长。
+const QuotaCooldown = 10 * time.Minute
+
+// minMaskedLen 部分展示所需的最短 Key 长度，更短的 Key 脱敏为 "****"。
+const minMaskedLen = 12
+
+// Key 池中的一个上游 Key。
+type Key struct {
+	Name   string
+	Secret string
+}
+
+// Outcome 一次请求在某个 Key 上的结果。
+type Outcome int
+
+const (
+	Success      Outcome = iota
+	Failure              // 与 Key 无关的失败（5xx、网络错误等），只计数
+	RateLimited          // 429 / 额度不足，Key 进入冷却
+	Unauthorized         // 401，Key 被禁用
+)
+
+// Snapshot Key 的状态与用量快照，Secret 已脱敏。
+type Snapshot struct {
+	Name           string     `json:"name"`
+	Key            string     `json:"key"`   // 脱敏后的 Key，如 sk-…a1b2
+	State          string     `json:"state"` // active, cooldown, disabled
+	CooldownUntil  *time.Time `json:"cooldownUntil,omitempty"`
+	DisabledReason string     `json:"disabledReason,omitempty"`
+	Inflight       int        `json:"inflight"`
+	Requests       int64      `json:"requests"`
+	Successes      int64      `json:"successes"`
+	Failures       int64      `json:"failures"`
+	RateLimited    int64      `json:"rateLimited"`
+	InputTokens    int64      `json:"inputTokens"`
+	OutputTokens   int64      `json:"outputTokens"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+}
+
+type entry struct {
+	Key
+	inflight       int
+	requests       int64
+	successes      int64
+	failures       int64
+	rateLimited    int64
+	inputTokens    int64
+	outputTokens   int64
+	cooldownUntil  time.Time
+	disabled       bool
+	disabledReason string
+	lastUsedAt     time.Time
+	lastError      string
+}
+
+// Pool Key 池，并发安全。
+type Pool struct {
+	strategy string
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries []*entry
+	next    int
+}
+
+// New 创建 Key 池。prev 非 nil 时沿用其中名称与 Secret 都相同的 Key 的状态和计数，
+// 使冷却、禁用与用量统计在重新加载配置后保留；Secret 变化的 Key 视为新 Key。
+// 旧池上仍在进行的请求结束时只回报给旧池，不计入新池。
+func New(strategy string, keys []Key, prev *Pool) *Pool {
+	p := &Pool{strategy: strategy, now: time.Now}
+	old := make(map[Key]entry)
+	if prev != nil {
+		prev.mu.Lock()
+		for _, e := range prev.entries {
+			old[e.Key] = *e
+		}
+		prev.mu.Unlock()
+	}
+	for _, k := range keys {
+		e, ok := old[k]
+		if !ok {
+			e = entry{Key: k}
+		}
+		e.inflight = 0
+		p.entries = append(p.entries, &e)
+	}
+	return p
+}
+
+// Len 返回池中 Key 的数量。
+func (p *Pool) Len() int { return len(p.entries) }
+
+// Lease 一次 Key 租用，请求结束后必须调用且仅调用一次 Done。
+type Lease struct {
+	pool  *Pool
+	entry *entry
+	Index int // Key 在池中的下标，与 New 传入的顺序一致
+}
+
+// Name 返回租用 Key 的名称。
+func (l *Lease) Name() string { return l.entry.Name }
+
+// Acquire 按策略选出一个未冷却、未禁用且不在 exclude 中的 Key。
+// 没有可选 Key 时返回 ErrCoolingDown（附带最早结束冷却的等待时间）或 ErrNoKey。
+func (p *Pool) Acquire(exclude ...int) (*Lease, time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	skipped := make(map[int]bool, len(exclude))
+	for _, i := range exclude {
+		skipped[i] = true
+	}
+
+	best := -1
+	var wait time.Duration
+	cooling := false
+	n := len(p.entries)
+	for k := 0; k < n; k++ {
+		i := (p.next + k) % n
+		e := p.entries[i]
+		if e.disabled || skipped[i] {
+			continue
+		}
+		if now.Before(e.cooldownUntil) {
+			if d := e.cooldownUntil.Sub(now); !cooling || d < wait {
+				wait = d
+			}
+			cooling = true
+			continue
+		}
+		if best < 0 {
+			best = i
+			if p.strategy != StrategyLeastUsed {
+				break
+			}
+			continue
+		}
+		if b := p.entries[best]; e.inflight < b.inflight ||
+			(e.inflight == b.inflight && e.lastUsedAt.Before(b.lastUsedAt)) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		if cooling {
+			return nil, wait, ErrCoolingDown
+		}
+		return nil, 0, ErrNoKey
+	}
+
+	p.next = (best + 1) % n
+	e := p.entries[best]
+	e.inflight++
+	e.requests++
+	e.lastUsedAt = now
+	return &Lease{pool: p, entry: e, Index: best}, 0, nil
+}
+
+// Done 回报本次租用的结果。cooldown 仅对 RateLimited 生效，<= 0 时使用 DefaultCooldown；
+// reason 作为最近错误（Unauthorized 时同时作为禁用原因）记录。
+func (l *Lease) Done(outcome Outcome, cooldown time.Duration, reason string) {
+	p := l.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := l.entry
+	if e.inflight > 0 {
+		e.inflight--
+	}
+	switch outcome {
+	case Success:
+		e.successes++
+		return
+	case RateLimited:
+		e.rateLimited++
+		if cooldown <= 0 {
+			cooldown = DefaultCooldown
+		}
+		if until := p.now().Add(cooldown); until.After(e.cooldownUntil) {
+			e.cooldownUntil = until
+		}
+	case Unauthorized:
+		e.disabled = true
+		e.disabledReason = reason
+	}
+	e.failures++
+	e.lastError = reason
+}
+
+// AddUsage 累加本次租用消耗的 token。
+func (l *Lease) AddUsage(input, output int) {
+	p := l.pool
+	p.mu.Lock()
+	l.entry.inputTokens += int64(input)
+	l.entry.outputTokens += int64(output)
+	p.mu.Unlock()
+}
+
+// Enable 重新启用被禁用的 Key 并清除冷却，Key 不存在时返回 false。
+func (p *Pool) Enable(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.entries {
+		if e.Name == name {
+			e.disabled, e.disabledReason = false, ""
+			e.cooldownUntil = time.Time{}
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshots 返回池中各 Key 的快照，顺序与 New 传入的顺序一致。
+func (p *Pool) Snapshots() []Snapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	out := make([]Snapshot, 0, len(p.entries))
+	for _, e := range p.entries {
+		s := Snapshot{
+			Name:           e.Name,
+			Key:            Mask(e.Secret),
+			State:          "active",
+			DisabledReason: e.disabledReason,
+			Inflight:       e.inflight,
+			Requests:       e.requests,
+			Successes:      e.successes,
+			Failures:       e.failures,
+			RateLimited:    e.rateLimited,
+			InputTokens:    e.inputTokens,
+			OutputTokens:   e.outputTokens,
+			LastError:      e.lastError,
+		}
+		switch {
+		case e.disabled:
+			s.State = "disabled"
+		case now.Before(e.cooldownUntil):
+			s.State = "cooldown"
+			until := e.cooldownUntil
+			s.CooldownUntil = &until
+		}
+		if !e.lastUsedAt.IsZero() {
+			last := e.lastUsedAt
+			s.LastUsedAt = &last
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Mask 脱敏 Key，只保留前缀与末尾 4 位；较短的 Key 露出 7 位已接近全文，整体脱敏。
+func Mask(secret string) string {
+	if len(secret) < minMaskedLen {
+		return "****"
+	}
+	return secret[:3] + "…" + secret[len(secret)-4:]
+}
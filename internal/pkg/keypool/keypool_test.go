@@ -0,0 +1,129 @@
+package keypool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeys() []Key {
+	return []Key{{"a", "sk-aaaaaaaa1111"}, {"b", "sk-bbbbbbbb2222"}, {"c", "sk-cccccccc3333"}}
+}
+
+func acquire(t *testing.T, p *Pool, exclude ...int) *Lease {
+	t.Helper()
+	l, _, err := p.Acquire(exclude...)
+	require.NoError(t, err)
+	return l
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	p := New(StrategyRoundRobin, testKeys(), nil)
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		l := acquire(t, p)
+		names = append(names, l.Name())
+		l.Done(Success, 0, "")
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, names)
+
+	l := acquire(t, p, 1, 2)
+	assert.Equal(t, "a", l.Name())
+}
+
+func TestPool_LeastUsed(t *testing.T) {
+	p := New(StrategyLeastUsed, testKeys(), nil)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	busy := acquire(t, p)
+	assert.Equal(t, "a", busy.Name())
+
+	now = now.Add(time.Second)
+	l := acquire(t, p)
+	assert.Equal(t, "b", l.Name())
+	l.Done(Success, 0, "")
+
+	// a 仍有在途请求，b 刚被使用，c 最久未使用
+	now = now.Add(time.Second)
+	assert.Equal(t, "c", acquire(t, p).Name())
+}
+
+func TestPool_CooldownAndDisable(t *testing.T) {
+	p := New(StrategyRoundRobin, testKeys(), nil)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	acquire(t, p).Done(RateLimited, time.Minute, "429")
+	acquire(t, p).Done(Unauthorized, 0, "401 invalid api key")
+
+	// a 冷却、b 禁用，只剩 c
+	for i := 0; i < 3; i++ {
+		l := acquire(t, p)
+		assert.Equal(t, "c", l.Name())
+		l.Done(RateLimited, 10*time.Second, "429")
+		now = now.Add(11 * time.Second)
+	}
+	l := acquire(t, p)
+	assert.Equal(t, "c", l.Name())
+	l.Done(RateLimited, 10*time.Second, "429")
+
+	_, wait, err := p.Acquire()
+	assert.ErrorIs(t, err, ErrCoolingDown)
+	assert.Equal(t, 10*time.Second, wait)
+
+	snaps := p.Snapshots()
+	assert.Equal(t, "cooldown", snaps[0].State)
+	assert.Equal(t, "disabled", snaps[1].State)
+	assert.Equal(t, "401 invalid api key", snaps[1].DisabledReason)
+	assert.Equal(t, int64(4), snaps[2].RateLimited)
+	assert.Equal(t, "sk-…3333", snaps[2].Key)
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, "a", acquire(t, p, 2).Name())
+	assert.True(t, p.Enable("b"))
+	assert.Equal(t, "b", acquire(t, p).Name())
+
+	p.Enable("a")
+	p.Enable("c")
+	for i := 0; i < 3; i++ {
+		acquire(t, p).Done(Unauthorized, 0, "401")
+	}
+	_, _, err = p.Acquire()
+	assert.ErrorIs(t, err, ErrNoKey)
+}
+
+func TestPool_KeepsStateAcrossReload(t *testing.T) {
+	old := New(StrategyRoundRobin, testKeys(), nil)
+	l := acquire(t, old)
+	l.AddUsage(10, 20)
+	l.Done(Success, 0, "")
+	acquire(t, old).Done(Unauthorized, 0, "401")
+	acquire(t, old) // 在途请求不带到新池
+
+	keys := testKeys()
+	keys[1].Secret = "sk-rotated-2222"
+	p := New(StrategyRoundRobin, keys, old)
+
+	snaps := p.Snapshots()
+	assert.Equal(t, int64(1), snaps[0].Requests)
+	assert.Equal(t, int64(20), snaps[0].OutputTokens)
+	assert.Equal(t, "active", snaps[1].State, "rotated key starts fresh")
+	assert.Equal(t, int64(0), snaps[1].Requests)
+	assert.Equal(t, 0, snaps[2].Inflight)
+}
+
+func TestMask(t *testing.T) {
+	for secret, want := range map[string]string{
+		"":                  "****",
+		"sk-short":          "****",
+		"sk-abcdefgh":       "****", // 11 位
+		"sk-abcdefghi":      "sk-…fghi",
+		"sk-proj-abcd12345": "sk-…2345",
+	} {
+		assert.Equal(t, want, Mask(secret), secret)
+	}
+}
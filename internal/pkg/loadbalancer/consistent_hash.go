@@ -0,0 +1,128 @@
+// Package loadbalancer 提供通用的负载均衡算法。
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultVirtualNodes 是一致性哈希环上每个节点的虚拟节点数。
+const DefaultVirtualNodes = 160
+
+// ConsistentHash 实现一致性哈希负载均衡：相同的键总是落到环上同一个节点，
+// 节点增减时只有约 1/N 的键会迁移。键对应的节点不可用时沿环顺延到下一个可用节点，
+// 恢复后键会回到原节点。没有键的请求按轮询分配。
+type ConsistentHash[T Node] struct {
+	nodes   []T
+	counter uint64
+	health  *healthTracker
+
+	mu   sync.RWMutex
+	ring []ringPoint
+}
+
+type ringPoint struct {
+	hash uint64
+	id   string
+}
+
+// NewConsistentHash 创建一个一致性哈希负载均衡器。
+func NewConsistentHash[T Node](nodes []T, opts ...Option) *ConsistentHash[T] {
+	c := &ConsistentHash[T]{
+		health: newHealthTracker(opts),
+	}
+	c.UpdateNodes(nodes)
+	return c
+}
+
+// Select 在没有键时按轮询选择节点。
+func (c *ConsistentHash[T]) Select() (T, error) { return c.SelectFilter(nil) }
+
+func (c *ConsistentHash[T]) SelectFilter(accept func(T) bool) (T, error) {
+	var zero T
+	candidates := available(c.health, c.Nodes(), accept)
+	if len(candidates) == 0 {
+		return zero, ErrNoAvailableNode
+	}
+	idx := atomic.AddUint64(&c.counter, 1) - 1
+	node := candidates[idx%uint64(len(candidates))]
+	c.health.acquire(node.ID())
+	return node, nil
+}
+
+// SelectKey 返回键在环上顺时针方向的第一个可用节点。
+func (c *ConsistentHash[T]) SelectKey(key string) (T, error) { return c.SelectKeyFilter(key, nil) }
+
+func (c *ConsistentHash[T]) SelectKeyFilter(key string, accept func(T) bool) (T, error) {
+	var zero T
+	candidates := available(c.health, c.Nodes(), accept)
+	if len(candidates) == 0 {
+		return zero, ErrNoAvailableNode
+	}
+	byID := make(map[string]T, len(candidates))
+	for _, n := range candidates {
+		byID[n.ID()] = n
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	h := hashKey(key)
+	start := sort.Search(len(c.ring), func(i int) bool { return c.ring[i].hash >= h })
+	for i := 0; i < len(c.ring); i++ {
+		p := c.ring[(start+i)%len(c.ring)]
+		if node, ok := byID[p.id]; ok {
+			c.health.acquire(p.id)
+			return node, nil
+		}
+	}
+	return zero, ErrNoAvailableNode
+}
+
+func (c *ConsistentHash[T]) ReportSuccess(node T) { c.health.success(node.ID()) }
+func (c *ConsistentHash[T]) ReportFailure(node T) { c.health.failure(node.ID()) }
+
+func (c *ConsistentHash[T]) Available() []T {
+	return available(c.health, c.Nodes(), nil)
+}
+
+func (c *ConsistentHash[T]) Nodes() []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nodes
+}
+
+// UpdateNodes 替换节点并重建哈希环。虚拟节点的位置只取决于节点 ID，
+// 因此未变化的节点在环上的位置保持不变。
+func (c *ConsistentHash[T]) UpdateNodes(nodes []T) {
+	ring := make([]ringPoint, 0, len(nodes)*DefaultVirtualNodes)
+	for _, n := range nodes {
+		for i := 0; i < DefaultVirtualNodes; i++ {
+			ring = append(ring, ringPoint{hash: hashKey(n.ID() + "#" + strconv.Itoa(i)), id: n.ID()})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	c.mu.Lock()
+	c.nodes, c.ring = nodes, ring
+	c.mu.Unlock()
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// FNV 对相近字符串的低位区分度不足，再做一次混合使虚拟节点在环上分布均匀
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+var _ LoadBalancer[Node] = (*ConsistentHash[Node])(nil)
+var _ FilterSelector[Node] = (*ConsistentHash[Node])(nil)
+var _ KeySelector[Node] = (*ConsistentHash[Node])(nil)
@@ -6,49 +6,44 @@ import (
 )
 
 // Failover 实现故障转移负载均衡。
-// 它按顺序尝试节点，跳过失败的节点，直到报告成功。
+// 它按顺序尝试节点，跳过不健康的节点；被摘除的节点冷却后经半开探测重新接纳。
 type Failover[T Node] struct {
 	nodes  []T
-	failed map[string]bool
+	health *healthTracker
 	mu     sync.RWMutex
 }
 
 // NewFailover 创建一个新的故障转移负载均衡器。
 // 节点按优先级排序（第一个为主要节点）。
-func NewFailover[T Node](nodes []T) *Failover[T] {
+func NewFailover[T Node](nodes []T, opts ...Option) *Failover[T] {
 	return &Failover[T]{
 		nodes:  nodes,
-		failed: make(map[string]bool),
+		health: newHealthTracker(opts),
 	}
 }
 
-func (f *Failover[T]) Select() (T, error) {
+func (f *Failover[T]) Select() (T, error) { return f.SelectFilter(nil) }
+
+func (f *Failover[T]) SelectFilter(accept func(T) bool) (T, error) {
 	f.mu.RLock()
-	defer f.mu.RUnlock()
+	nodes := f.nodes
+	f.mu.RUnlock()
 
 	var zero T
-	for _, node := range f.nodes {
-		if !f.failed[node.ID()] {
-			return node, nil
-		}
-	}
-	// 全部失败，仍然尝试第一个节点
-	if len(f.nodes) > 0 {
-		return f.nodes[0], nil
+	candidates := available(f.health, nodes, accept)
+	if len(candidates) == 0 {
+		return zero, ErrNoAvailableNode
 	}
-	return zero, ErrNoAvailableNode
+	node := candidates[0]
+	f.health.acquire(node.ID())
+	return node, nil
 }
 
-func (f *Failover[T]) ReportSuccess(node T) {
-	f.mu.Lock()
-	f.failed[node.ID()] = false
-	f.mu.Unlock()
-}
+func (f *Failover[T]) ReportSuccess(node T) { f.health.success(node.ID()) }
+func (f *Failover[T]) ReportFailure(node T) { f.health.failure(node.ID()) }
 
-func (f *Failover[T]) ReportFailure(node T) {
-	f.mu.Lock()
-	f.failed[node.ID()] = true
-	f.mu.Unlock()
+func (f *Failover[T]) Available() []T {
+	return available(f.health, f.nodes, nil)
 }
 
 func (f *Failover[T]) Nodes() []T {
@@ -62,3 +57,4 @@ func (f *Failover[T]) UpdateNodes(nodes []T) {
 }
 
 var _ LoadBalancer[Node] = (*Failover[Node])(nil)
+var _ FilterSelector[Node] = (*Failover[Node])(nil)
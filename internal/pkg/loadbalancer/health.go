@@ -0,0 +1,188 @@
+// Package loadbalancer 提供通用的负载均衡算法。
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+)
+
+// 健康检查默认值
+const (
+	DefaultFailureThreshold = 1
+	DefaultCooldown         = 10 * time.Second
+	DefaultMaxCooldown      = 2 * time.Minute
+)
+
+// Option 配置负载均衡器的健康检查行为。
+type Option func(*healthTracker)
+
+// WithFailureThreshold 设置节点被摘除前允许的连续失败次数。
+func WithFailureThreshold(n int) Option {
+	return func(h *healthTracker) {
+		if n > 0 {
+			h.threshold = n
+		}
+	}
+}
+
+// WithCooldown 设置被摘除节点的冷却时间。
+// 半开探测再次失败时冷却时间翻倍，最长不超过 max。
+func WithCooldown(base, max time.Duration) Option {
+	return func(h *healthTracker) {
+		if base > 0 {
+			h.baseCooldown = base
+		}
+		if max >= h.baseCooldown {
+			h.maxCooldown = max
+		}
+	}
+}
+
+// WithFilter 设置额外的节点过滤条件，返回 false 的节点在本次选择中被跳过，
+// 例如外部熔断器已打开的节点。
+func WithFilter(accept func(id string) bool) Option {
+	return func(h *healthTracker) {
+		h.accept = accept
+	}
+}
+
+// WithPriority 将节点按优先级分层，priority 返回值越大优先级越高（与路由规则的优先级方向一致）。
+// 选择时只在最高优先级且存在可用节点的层级内按策略分配流量，
+// 整层节点都不可用（被摘除、熔断或因限流冷却）时才溢出到下一层。
+// 注意：节点只要可用就会被选中，不考虑其在途请求数；高优先级层已饱和但未报错时流量不会溢出，
+// 需要按容量溢出时应依靠上游限流（429）触发的冷却。
+func WithPriority(priority func(id string) int) Option {
+	return func(h *healthTracker) {
+		h.priority = priority
+	}
+}
+
+// healthTracker 跟踪节点健康状态，所有策略共用。
+// 节点连续失败达到阈值后被摘除；冷却期结束后进入半开状态，只放行一个探测请求，
+// 探测成功则恢复，失败则以更长的冷却时间再次摘除。
+type healthTracker struct {
+	mu           sync.Mutex
+	threshold    int
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+	states       map[string]*nodeState
+	accept       func(id string) bool
+	priority     func(id string) int
+	now          func() time.Time
+}
+
+type nodeState struct {
+	failures  int       // 连续失败次数
+	trips     int       // 连续被摘除次数，用于计算冷却时间
+	downUntil time.Time // 非零表示节点已被摘除
+	probeAt   time.Time // 非零表示半开探测请求正在进行
+}
+
+func newHealthTracker(opts []Option) *healthTracker {
+	h := &healthTracker{
+		threshold:    DefaultFailureThreshold,
+		baseCooldown: DefaultCooldown,
+		maxCooldown:  DefaultMaxCooldown,
+		states:       make(map[string]*nodeState),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// available 返回可被选中的节点：健康节点，以及冷却结束且没有探测在进行中的节点。
+// accept 非 nil 时只保留其接受的节点（按次过滤，见 FilterSelector）。
+// 配置了优先级时只返回最高优先级且存在可用节点的层级，过滤在分层之前进行。
+func available[T Node](h *healthTracker, nodes []T, accept func(T) bool) []T {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.states) == 0 && h.accept == nil && h.priority == nil && accept == nil {
+		return nodes
+	}
+	now := h.now()
+	out := make([]T, 0, len(nodes))
+	for _, n := range nodes {
+		if h.availableLocked(n.ID(), now) && (h.accept == nil || h.accept(n.ID())) && (accept == nil || accept(n)) {
+			out = append(out, n)
+		}
+	}
+	if h.priority == nil || len(out) == 0 {
+		return out
+	}
+
+	best := h.priority(out[0].ID())
+	for _, n := range out[1:] {
+		if p := h.priority(n.ID()); p > best {
+			best = p
+		}
+	}
+	tier := out[:0]
+	for _, n := range out {
+		if h.priority(n.ID()) == best {
+			tier = append(tier, n)
+		}
+	}
+	return tier
+}
+
+func (h *healthTracker) availableLocked(id string, now time.Time) bool {
+	st, ok := h.states[id]
+	if !ok || st.downUntil.IsZero() {
+		return true
+	}
+	if now.Before(st.downUntil) {
+		return false
+	}
+	// 半开：同一时间只放行一个探测；探测长时间未回报时允许重新探测
+	return st.probeAt.IsZero() || now.Sub(st.probeAt) >= h.baseCooldown
+}
+
+// acquire 在节点被选中后调用，半开节点会被标记为探测中。
+func (h *healthTracker) acquire(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st, ok := h.states[id]
+	if !ok || st.downUntil.IsZero() {
+		return
+	}
+	if now := h.now(); !now.Before(st.downUntil) {
+		st.probeAt = now
+	}
+}
+
+func (h *healthTracker) success(id string) {
+	h.mu.Lock()
+	delete(h.states, id)
+	h.mu.Unlock()
+}
+
+func (h *healthTracker) failure(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st, ok := h.states[id]
+	if !ok {
+		st = &nodeState{}
+		h.states[id] = st
+	}
+	st.failures++
+	halfOpen := !st.downUntil.IsZero()
+	if !halfOpen && st.failures < h.threshold {
+		return
+	}
+
+	st.trips++
+	cooldown := h.baseCooldown
+	for i := 1; i < st.trips && cooldown < h.maxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > h.maxCooldown {
+		cooldown = h.maxCooldown
+	}
+	st.downUntil = h.now().Add(cooldown)
+	st.probeAt = time.Time{}
+}
@@ -0,0 +1,89 @@
+// Package loadbalancer 提供通用的负载均衡算法。
+package loadbalancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LeastOutstanding 选择在途请求数最少的节点，跳过不健康的节点。
+// 在途请求数相同时轮流选择，避免总是落在第一个节点上。
+type LeastOutstanding[T Node] struct {
+	nodes   []T
+	counter uint64
+	health  *healthTracker
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+// NewLeastOutstanding 创建一个最少在途请求负载均衡器。
+func NewLeastOutstanding[T Node](nodes []T, opts ...Option) *LeastOutstanding[T] {
+	return &LeastOutstanding[T]{
+		nodes:    nodes,
+		health:   newHealthTracker(opts),
+		inflight: make(map[string]int),
+	}
+}
+
+func (l *LeastOutstanding[T]) Select() (T, error) { return l.SelectFilter(nil) }
+
+func (l *LeastOutstanding[T]) SelectFilter(accept func(T) bool) (T, error) {
+	var zero T
+	candidates := available(l.health, l.nodes, accept)
+	if len(candidates) == 0 {
+		return zero, ErrNoAvailableNode
+	}
+	start := int(atomic.AddUint64(&l.counter, 1) - 1)
+
+	l.mu.Lock()
+	node := pickLeast(candidates, start, func(n T) float64 { return float64(l.inflight[n.ID()]) })
+	l.inflight[node.ID()]++
+	l.mu.Unlock()
+
+	l.health.acquire(node.ID())
+	return node, nil
+}
+
+// Release 结束一次在途请求，本策略不使用延迟样本。
+func (l *LeastOutstanding[T]) Release(node T, _ time.Duration) {
+	l.mu.Lock()
+	if l.inflight[node.ID()] > 0 {
+		l.inflight[node.ID()]--
+	}
+	l.mu.Unlock()
+}
+
+func (l *LeastOutstanding[T]) ReportSuccess(node T) { l.health.success(node.ID()) }
+func (l *LeastOutstanding[T]) ReportFailure(node T) { l.health.failure(node.ID()) }
+
+func (l *LeastOutstanding[T]) Available() []T {
+	return available(l.health, l.nodes, nil)
+}
+
+func (l *LeastOutstanding[T]) Nodes() []T {
+	return l.nodes
+}
+
+func (l *LeastOutstanding[T]) UpdateNodes(nodes []T) {
+	l.nodes = nodes
+}
+
+// pickLeast 返回代价最小的节点，从 start 开始遍历以便在代价相同时轮流选择。
+func pickLeast[T Node](candidates []T, start int, cost func(T) float64) T {
+	n := len(candidates)
+	best := candidates[start%n]
+	bestCost := cost(best)
+	for i := 1; i < n; i++ {
+		c := candidates[(start+i)%n]
+		if v := cost(c); v < bestCost {
+			best, bestCost = c, v
+		}
+	}
+	return best
+}
+
+var _ LoadBalancer[Node] = (*LeastOutstanding[Node])(nil)
+var _ FilterSelector[Node] = (*LeastOutstanding[Node])(nil)
+var _ LoadTracker[Node] = (*LeastOutstanding[Node])(nil)
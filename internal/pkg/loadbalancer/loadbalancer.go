@@ -4,6 +4,7 @@ package loadbalancer
 
 import (
 	"errors"
+	"time"
 )
 
 // ErrNoAvailableNode 当没有可用节点时返回。
@@ -26,10 +27,35 @@ type LoadBalancer[T Node] interface {
 	ReportFailure(node T)
 	// Nodes 返回负载均衡器中的所有节点。
 	Nodes() []T
+	// Available 返回当前可被选中的节点，不改变负载均衡状态。
+	Available() []T
 	// UpdateNodes 更新节点列表。
 	UpdateNodes(nodes []T)
 }
 
+// LoadTracker 由按节点负载选择的策略实现（LeastOutstanding、PeakEWMA）。
+// 这些策略的 Select 会把选中节点的在途请求数加一，调用方必须在请求结束后调用且仅调用一次 Release。
+type LoadTracker[T Node] interface {
+	// Release 结束一次在途请求。latency > 0 时作为该节点的一次延迟样本
+	// （非流式请求为响应耗时，流式请求为首 token 时间）。
+	Release(node T, latency time.Duration)
+}
+
+// FilterSelector 由支持按次过滤节点的策略实现（所有内置策略）。
+// accept 返回 false 的节点在本次选择中视为不可用，不占用其半开探测名额；
+// 与 WithFilter 不同，过滤条件只对本次调用生效，例如按请求所需能力筛选成员。accept 为 nil 时等同于 Select。
+type FilterSelector[T Node] interface {
+	SelectFilter(accept func(T) bool) (T, error)
+}
+
+// KeySelector 由按请求键选择节点的策略实现（ConsistentHash）。
+// 相同的键总是落到同一个可用节点上，该节点不可用时顺延到下一个节点。
+type KeySelector[T Node] interface {
+	SelectKey(key string) (T, error)
+	// SelectKeyFilter 同 SelectKey，只在 accept 接受的节点中选择
+	SelectKeyFilter(key string, accept func(T) bool) (T, error)
+}
+
 // WeightedNode 包装一个带有权重的节点。
 type WeightedNode[T Node] struct {
 	Node   T
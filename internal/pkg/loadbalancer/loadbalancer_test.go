@@ -1,7 +1,9 @@
 package loadbalancer
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -72,3 +74,268 @@ func TestFailover(t *testing.T) {
 	n2, _ := lb.Select()
 	assert.Equal(t, "Primary", n2.ID())
 }
+
+func TestFailover_SkipsFailedNode(t *testing.T) {
+	nodes := []Node{&mockNode{"Primary"}, &mockNode{"Backup"}}
+	lb := NewFailover[Node](nodes)
+
+	n, _ := lb.Select()
+	lb.ReportFailure(n)
+
+	n2, err := lb.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "Backup", n2.ID())
+}
+
+func TestFailover_HalfOpenReadmission(t *testing.T) {
+	nodes := []Node{&mockNode{"Primary"}, &mockNode{"Backup"}}
+	lb := NewFailover[Node](nodes, WithCooldown(time.Second, 4*time.Second))
+	now := time.Now()
+	lb.health.now = func() time.Time { return now }
+
+	primary, _ := lb.Select()
+	lb.ReportFailure(primary)
+
+	// 冷却期内使用备用节点
+	n, _ := lb.Select()
+	assert.Equal(t, "Backup", n.ID())
+
+	// 冷却结束后放行一个探测请求，探测进行中时其余请求仍走备用节点
+	now = now.Add(time.Second)
+	probe, _ := lb.Select()
+	assert.Equal(t, "Primary", probe.ID())
+	n, _ = lb.Select()
+	assert.Equal(t, "Backup", n.ID())
+
+	// 探测失败，冷却时间翻倍
+	lb.ReportFailure(probe)
+	now = now.Add(time.Second)
+	n, _ = lb.Select()
+	assert.Equal(t, "Backup", n.ID())
+	now = now.Add(time.Second)
+	probe, _ = lb.Select()
+	assert.Equal(t, "Primary", probe.ID())
+
+	// 探测成功，节点恢复
+	lb.ReportSuccess(probe)
+	n, _ = lb.Select()
+	assert.Equal(t, "Primary", n.ID())
+}
+
+func TestRoundRobin_SkipsUnhealthy(t *testing.T) {
+	nodes := []Node{&mockNode{"1"}, &mockNode{"2"}, &mockNode{"3"}}
+	lb := NewRoundRobin[Node](nodes, WithFailureThreshold(2))
+
+	lb.ReportFailure(nodes[1])
+	seen := map[string]bool{}
+	for i := 0; i < 6; i++ {
+		n, _ := lb.Select()
+		seen[n.ID()] = true
+	}
+	assert.True(t, seen["2"], "node below failure threshold should stay in rotation")
+
+	lb.ReportFailure(nodes[1])
+	for i := 0; i < 6; i++ {
+		n, err := lb.Select()
+		assert.NoError(t, err)
+		assert.NotEqual(t, "2", n.ID())
+	}
+}
+
+func TestWeighted_SkipsUnhealthy(t *testing.T) {
+	nodes := []Node{&mockNode{"A"}, &mockNode{"B"}}
+	lb := NewWeighted[Node](nodes, []int{100, 1})
+
+	lb.ReportFailure(nodes[0])
+	for i := 0; i < 10; i++ {
+		n, err := lb.Select()
+		assert.NoError(t, err)
+		assert.Equal(t, "B", n.ID())
+	}
+
+	lb.ReportFailure(nodes[1])
+	_, err := lb.Select()
+	assert.ErrorIs(t, err, ErrNoAvailableNode)
+}
+
+func TestPriorityTiers(t *testing.T) {
+	nodes := []Node{&mockNode{"reserved-1"}, &mockNode{"reserved-2"}, &mockNode{"payg"}}
+	priorities := map[string]int{"reserved-1": 1, "reserved-2": 1, "payg": 0}
+	lb := NewWeighted[Node](nodes, []int{1, 1, 100}, WithPriority(func(id string) int { return priorities[id] }))
+
+	// 流量只在最高优先级层内分配，即使低优先级节点权重更高
+	for i := 0; i < 20; i++ {
+		n, err := lb.Select()
+		assert.NoError(t, err)
+		assert.NotEqual(t, "payg", n.ID())
+	}
+
+	// 层内仍有可用节点时不溢出
+	lb.ReportFailure(nodes[0])
+	for i := 0; i < 10; i++ {
+		n, _ := lb.Select()
+		assert.Equal(t, "reserved-2", n.ID())
+	}
+
+	// 整层不可用时溢出到下一层
+	lb.ReportFailure(nodes[1])
+	n, err := lb.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "payg", n.ID())
+	assert.Equal(t, []Node{nodes[2]}, lb.Available())
+
+	// 高优先级节点恢复后流量回到该层
+	lb.ReportSuccess(nodes[1])
+	n, _ = lb.Select()
+	assert.Equal(t, "reserved-2", n.ID())
+}
+
+func TestSelectFilter(t *testing.T) {
+	nodes := []Node{&mockNode{"primary"}, &mockNode{"backup"}, &mockNode{"payg"}}
+	priorities := map[string]int{"primary": 1, "backup": 1, "payg": 0}
+	lb := NewFailover[Node](nodes, WithCooldown(time.Second, time.Second), WithPriority(func(id string) int { return priorities[id] }))
+	now := time.Now()
+	lb.health.now = func() time.Time { return now }
+	lb.ReportFailure(nodes[0])
+	now = now.Add(time.Second)
+
+	// 半开的 primary 被过滤时不占用其探测名额
+	n, err := lb.SelectFilter(func(n Node) bool { return n.ID() != "primary" })
+	assert.NoError(t, err)
+	assert.Equal(t, "backup", n.ID())
+	n, _ = lb.Select()
+	assert.Equal(t, "primary", n.ID())
+
+	// 过滤在分层之前进行：最高优先级层全部被过滤时溢出到下一层
+	n, err = lb.SelectFilter(func(n Node) bool { return n.ID() == "payg" })
+	assert.NoError(t, err)
+	assert.Equal(t, "payg", n.ID())
+	_, err = lb.SelectFilter(func(Node) bool { return false })
+	assert.ErrorIs(t, err, ErrNoAvailableNode)
+
+	ch := NewConsistentHash[Node](nodes)
+	n, err = ch.SelectKeyFilter("session", func(n Node) bool { return n.ID() == "backup" })
+	assert.NoError(t, err)
+	assert.Equal(t, "backup", n.ID())
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	n1, n2 := &mockNode{"1"}, &mockNode{"2"}
+	lb := NewLeastOutstanding[Node]([]Node{n1, n2})
+
+	// 在途数相同时轮流选择
+	a, _ := lb.Select()
+	b, _ := lb.Select()
+	assert.Equal(t, "1", a.ID())
+	assert.Equal(t, "2", b.ID())
+
+	// 之后总是选在途请求最少的节点
+	lb.Release(n2, 0)
+	for i := 0; i < 2; i++ {
+		n, _ := lb.Select()
+		assert.Equal(t, "2", n.ID())
+		lb.Release(n, 0)
+	}
+	lb.Select() // 2 的在途数回到 1，与 1 持平
+	c, _ := lb.Select()
+	d, _ := lb.Select()
+	assert.NotEqual(t, c.ID(), d.ID())
+}
+
+func TestPeakEWMA(t *testing.T) {
+	slow, fast := &mockNode{"slow"}, &mockNode{"fast"}
+	lb := NewPeakEWMA[Node]([]Node{slow, fast})
+	now := time.Unix(0, 0)
+	lb.now = func() time.Time { return now }
+
+	lb.Select()
+	lb.Select()
+	lb.Release(slow, 500*time.Millisecond)
+	lb.Release(fast, 50*time.Millisecond)
+
+	// 延迟低的节点即使有少量在途请求也优先
+	for i := 0; i < 3; i++ {
+		n, _ := lb.Select()
+		assert.Equal(t, "fast", n.ID())
+	}
+	for i := 0; i < 3; i++ {
+		lb.Release(fast, 0)
+	}
+
+	// 峰值样本立即取代均值
+	lb.Select()
+	lb.Release(fast, time.Second)
+	n, _ := lb.Select()
+	assert.Equal(t, "slow", n.ID())
+	lb.Release(slow, 0)
+
+}
+
+func TestPeakEWMA_IdleDecay(t *testing.T) {
+	slow, fast := &mockNode{"slow"}, &mockNode{"fast"}
+	lb := NewPeakEWMA[Node]([]Node{slow, fast})
+	now := time.Unix(0, 0)
+	lb.now = func() time.Time { return now }
+
+	lb.Select()
+	lb.Select()
+	lb.Release(slow, 500*time.Millisecond)
+	lb.Release(fast, 50*time.Millisecond)
+
+	// 一分钟后 fast 持续有样本，slow 没有新样本，其均值随空闲时间衰减，重新获得流量
+	now = now.Add(time.Minute)
+	n, _ := lb.Select()
+	assert.Equal(t, "fast", n.ID())
+	lb.Release(fast, 50*time.Millisecond)
+
+	n, _ = lb.Select()
+	assert.Equal(t, "slow", n.ID())
+}
+
+func TestConsistentHash(t *testing.T) {
+	nodes := []Node{&mockNode{"1"}, &mockNode{"2"}, &mockNode{"3"}}
+	lb := NewConsistentHash[Node](nodes)
+
+	keys := make([]string, 1000)
+	before := make(map[string]string, len(keys))
+	counts := make(map[string]int)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("session-%d", i)
+		n, err := lb.SelectKey(keys[i])
+		assert.NoError(t, err)
+		before[keys[i]] = n.ID()
+		counts[n.ID()]++
+
+		// 相同的键总是落到同一个节点
+		again, _ := lb.SelectKey(keys[i])
+		assert.Equal(t, n.ID(), again.ID())
+	}
+	for _, n := range nodes {
+		assert.Greater(t, counts[n.ID()], 200, "node %s", n.ID())
+	}
+
+	// 增加一个节点只迁移约 1/4 的键，且只迁移到新节点
+	lb.UpdateNodes(append(nodes, &mockNode{"4"}))
+	moved := 0
+	for _, k := range keys {
+		n, _ := lb.SelectKey(k)
+		if n.ID() != before[k] {
+			moved++
+			assert.Equal(t, "4", n.ID())
+		}
+	}
+	assert.InDelta(t, 250, moved, 100)
+
+	// 节点不可用时顺延，恢复后回到原节点
+	lb.UpdateNodes(nodes)
+	k := keys[0]
+	owner := before[k]
+	for i := 0; i < DefaultFailureThreshold; i++ {
+		lb.ReportFailure(&mockNode{owner})
+	}
+	n, _ := lb.SelectKey(k)
+	assert.NotEqual(t, owner, n.ID())
+	lb.ReportSuccess(&mockNode{owner})
+	n, _ = lb.SelectKey(k)
+	assert.Equal(t, owner, n.ID())
+}
@@ -0,0 +1,148 @@
+// Package loadbalancer 提供通用的负载均衡算法。
+package loadbalancer
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEWMADecay 是 PeakEWMA 延迟均值的衰减时间常数。
+const DefaultEWMADecay = 10 * time.Second
+
+// PeakEWMA 按 延迟EWMA × (在途请求数 + 1) 选择代价最小的节点，跳过不健康的节点。
+// 高于当前均值的样本直接取代均值（峰值敏感），低于均值的样本按时间指数衰减并入，
+// 因此变慢的节点会被立即避开，恢复后需要一段时间才会重新获得流量。
+// 长时间没有样本的节点其均值也会随时间衰减，保证慢节点之后还能被再次探测。
+type PeakEWMA[T Node] struct {
+	nodes   []T
+	counter uint64
+	health  *healthTracker
+	decay   time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	stats map[string]*ewmaStat
+}
+
+type ewmaStat struct {
+	ewma     float64 // 纳秒
+	inflight int
+	stamp    time.Time
+	observed bool
+}
+
+// NewPeakEWMA 创建一个 Peak-EWMA 负载均衡器，衰减时间常数为 DefaultEWMADecay。
+func NewPeakEWMA[T Node](nodes []T, opts ...Option) *PeakEWMA[T] {
+	return &PeakEWMA[T]{
+		nodes:  nodes,
+		health: newHealthTracker(opts),
+		decay:  DefaultEWMADecay,
+		now:    time.Now,
+		stats:  make(map[string]*ewmaStat),
+	}
+}
+
+func (p *PeakEWMA[T]) Select() (T, error) { return p.SelectFilter(nil) }
+
+func (p *PeakEWMA[T]) SelectFilter(accept func(T) bool) (T, error) {
+	var zero T
+	candidates := available(p.health, p.nodes, accept)
+	if len(candidates) == 0 {
+		return zero, ErrNoAvailableNode
+	}
+	start := int(atomic.AddUint64(&p.counter, 1) - 1)
+
+	p.mu.Lock()
+	now := p.now()
+	// 尚无样本的节点按已观测节点的平均延迟计算，避免新节点瞬间被打满或被饿死
+	var sum float64
+	var observed int
+	for _, n := range candidates {
+		if st := p.stats[n.ID()]; st != nil && st.observed {
+			sum += p.decayed(st, now)
+			observed++
+		}
+	}
+	var mean float64
+	if observed > 0 {
+		mean = sum / float64(observed)
+	}
+	node := pickLeast(candidates, start, func(n T) float64 {
+		st := p.stat(n.ID())
+		latency := mean
+		if st.observed {
+			latency = p.decayed(st, now)
+		}
+		// 延迟为 0（尚无任何样本）时退化为最少在途请求
+		return (latency + 1) * float64(st.inflight+1)
+	})
+	p.stat(node.ID()).inflight++
+	p.mu.Unlock()
+
+	p.health.acquire(node.ID())
+	return node, nil
+}
+
+// Release 结束一次在途请求，latency > 0 时更新该节点的延迟均值。
+func (p *PeakEWMA[T]) Release(node T, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.stat(node.ID())
+	if st.inflight > 0 {
+		st.inflight--
+	}
+	if latency <= 0 {
+		return
+	}
+	now := p.now()
+	sample := float64(latency)
+	switch {
+	case !st.observed || sample > st.ewma:
+		st.ewma = sample
+	default:
+		w := math.Exp(-float64(now.Sub(st.stamp)) / float64(p.decay))
+		st.ewma = st.ewma*w + sample*(1-w)
+	}
+	st.stamp = now
+	st.observed = true
+}
+
+// decayed 返回按空闲时间衰减后的延迟均值，调用方需持有锁。
+func (p *PeakEWMA[T]) decayed(st *ewmaStat, now time.Time) float64 {
+	idle := now.Sub(st.stamp)
+	if idle <= 0 {
+		return st.ewma
+	}
+	return st.ewma * math.Exp(-float64(idle)/float64(p.decay))
+}
+
+func (p *PeakEWMA[T]) stat(id string) *ewmaStat {
+	st, ok := p.stats[id]
+	if !ok {
+		st = &ewmaStat{}
+		p.stats[id] = st
+	}
+	return st
+}
+
+func (p *PeakEWMA[T]) ReportSuccess(node T) { p.health.success(node.ID()) }
+func (p *PeakEWMA[T]) ReportFailure(node T) { p.health.failure(node.ID()) }
+
+func (p *PeakEWMA[T]) Available() []T {
+	return available(p.health, p.nodes, nil)
+}
+
+func (p *PeakEWMA[T]) Nodes() []T {
+	return p.nodes
+}
+
+func (p *PeakEWMA[T]) UpdateNodes(nodes []T) {
+	p.nodes = nodes
+}
+
+var _ LoadBalancer[Node] = (*PeakEWMA[Node])(nil)
+var _ FilterSelector[Node] = (*PeakEWMA[Node])(nil)
+var _ LoadTracker[Node] = (*PeakEWMA[Node])(nil)
@@ -5,28 +5,39 @@ import (
 	"math/rand"
 )
 
-// Random 实现随机负载均衡。
+// Random 实现随机负载均衡，跳过不健康的节点。
 type Random[T Node] struct {
-	nodes []T
+	nodes  []T
+	health *healthTracker
 }
 
 // NewRandom 创建一个新的随机负载均衡器。
-func NewRandom[T Node](nodes []T) *Random[T] {
+func NewRandom[T Node](nodes []T, opts ...Option) *Random[T] {
 	return &Random[T]{
-		nodes: nodes,
+		nodes:  nodes,
+		health: newHealthTracker(opts),
 	}
 }
 
-func (r *Random[T]) Select() (T, error) {
+func (r *Random[T]) Select() (T, error) { return r.SelectFilter(nil) }
+
+func (r *Random[T]) SelectFilter(accept func(T) bool) (T, error) {
 	var zero T
-	if len(r.nodes) == 0 {
+	candidates := available(r.health, r.nodes, accept)
+	if len(candidates) == 0 {
 		return zero, ErrNoAvailableNode
 	}
-	return r.nodes[rand.Intn(len(r.nodes))], nil
+	node := candidates[rand.Intn(len(candidates))]
+	r.health.acquire(node.ID())
+	return node, nil
 }
 
-func (r *Random[T]) ReportSuccess(node T) {}
-func (r *Random[T]) ReportFailure(node T) {}
+func (r *Random[T]) ReportSuccess(node T) { r.health.success(node.ID()) }
+func (r *Random[T]) ReportFailure(node T) { r.health.failure(node.ID()) }
+
+func (r *Random[T]) Available() []T {
+	return available(r.health, r.nodes, nil)
+}
 
 func (r *Random[T]) Nodes() []T {
 	return r.nodes
@@ -37,3 +48,4 @@ func (r *Random[T]) UpdateNodes(nodes []T) {
 }
 
 var _ LoadBalancer[Node] = (*Random[Node])(nil)
+var _ FilterSelector[Node] = (*Random[Node])(nil)
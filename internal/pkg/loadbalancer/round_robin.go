@@ -5,30 +5,41 @@ import (
 	"sync/atomic"
 )
 
-// RoundRobin 实现轮询负载均衡。
+// RoundRobin 实现轮询负载均衡，跳过不健康的节点。
 type RoundRobin[T Node] struct {
 	nodes   []T
 	counter uint64
+	health  *healthTracker
 }
 
 // NewRoundRobin 创建一个新的轮询负载均衡器。
-func NewRoundRobin[T Node](nodes []T) *RoundRobin[T] {
+func NewRoundRobin[T Node](nodes []T, opts ...Option) *RoundRobin[T] {
 	return &RoundRobin[T]{
-		nodes: nodes,
+		nodes:  nodes,
+		health: newHealthTracker(opts),
 	}
 }
 
-func (r *RoundRobin[T]) Select() (T, error) {
+func (r *RoundRobin[T]) Select() (T, error) { return r.SelectFilter(nil) }
+
+func (r *RoundRobin[T]) SelectFilter(accept func(T) bool) (T, error) {
 	var zero T
-	if len(r.nodes) == 0 {
+	candidates := available(r.health, r.nodes, accept)
+	if len(candidates) == 0 {
 		return zero, ErrNoAvailableNode
 	}
 	idx := atomic.AddUint64(&r.counter, 1) - 1
-	return r.nodes[idx%uint64(len(r.nodes))], nil
+	node := candidates[idx%uint64(len(candidates))]
+	r.health.acquire(node.ID())
+	return node, nil
 }
 
-func (r *RoundRobin[T]) ReportSuccess(node T) {}
-func (r *RoundRobin[T]) ReportFailure(node T) {}
+func (r *RoundRobin[T]) ReportSuccess(node T) { r.health.success(node.ID()) }
+func (r *RoundRobin[T]) ReportFailure(node T) { r.health.failure(node.ID()) }
+
+func (r *RoundRobin[T]) Available() []T {
+	return available(r.health, r.nodes, nil)
+}
 
 func (r *RoundRobin[T]) Nodes() []T {
 	return r.nodes
@@ -39,3 +50,4 @@ func (r *RoundRobin[T]) UpdateNodes(nodes []T) {
 }
 
 var _ LoadBalancer[Node] = (*RoundRobin[Node])(nil)
+var _ FilterSelector[Node] = (*RoundRobin[Node])(nil)
@@ -5,16 +5,17 @@ import (
 	"math/rand"
 )
 
-// Weighted 实现加权随机负载均衡。
+// Weighted 实现加权随机负载均衡，跳过不健康的节点。
 type Weighted[T Node] struct {
 	nodes       []T
 	weights     []int
 	totalWeight int
+	health      *healthTracker
 }
 
 // NewWeighted 创建一个新的加权负载均衡器。
 // weights 切片的长度必须与 nodes 相同。
-func NewWeighted[T Node](nodes []T, weights []int) *Weighted[T] {
+func NewWeighted[T Node](nodes []T, weights []int, opts ...Option) *Weighted[T] {
 	total := 0
 	for _, w := range weights {
 		total += w
@@ -23,38 +24,76 @@ func NewWeighted[T Node](nodes []T, weights []int) *Weighted[T] {
 		nodes:       nodes,
 		weights:     weights,
 		totalWeight: total,
+		health:      newHealthTracker(opts),
 	}
 }
 
 // NewWeightedFromNodes 从 WeightedNode 切片创建一个加权负载均衡器。
-func NewWeightedFromNodes[T Node](weightedNodes []WeightedNode[T]) *Weighted[T] {
+func NewWeightedFromNodes[T Node](weightedNodes []WeightedNode[T], opts ...Option) *Weighted[T] {
 	nodes := make([]T, len(weightedNodes))
 	weights := make([]int, len(weightedNodes))
 	for i, wn := range weightedNodes {
 		nodes[i] = wn.Node
 		weights[i] = wn.Weight
 	}
-	return NewWeighted(nodes, weights)
+	return NewWeighted(nodes, weights, opts...)
 }
 
-func (w *Weighted[T]) Select() (T, error) {
+func (w *Weighted[T]) Select() (T, error) { return w.SelectFilter(nil) }
+
+func (w *Weighted[T]) SelectFilter(accept func(T) bool) (T, error) {
 	var zero T
 	if len(w.nodes) == 0 || w.totalWeight == 0 {
 		return zero, ErrNoAvailableNode
 	}
 
-	r := rand.Intn(w.totalWeight)
-	for i, weight := range w.weights {
+	candidates := available(w.health, w.nodes, accept)
+	if len(candidates) == len(w.nodes) {
+		node := w.pick(w.nodes, w.weights, w.totalWeight)
+		w.health.acquire(node.ID())
+		return node, nil
+	}
+
+	// 只在健康节点之间按权重分配
+	healthy := make(map[string]bool, len(candidates))
+	for _, n := range candidates {
+		healthy[n.ID()] = true
+	}
+	var nodes []T
+	var weights []int
+	total := 0
+	for i, n := range w.nodes {
+		if healthy[n.ID()] && w.weights[i] > 0 {
+			nodes = append(nodes, n)
+			weights = append(weights, w.weights[i])
+			total += w.weights[i]
+		}
+	}
+	if total == 0 {
+		return zero, ErrNoAvailableNode
+	}
+	node := w.pick(nodes, weights, total)
+	w.health.acquire(node.ID())
+	return node, nil
+}
+
+func (w *Weighted[T]) pick(nodes []T, weights []int, total int) T {
+	r := rand.Intn(total)
+	for i, weight := range weights {
 		r -= weight
 		if r < 0 {
-			return w.nodes[i], nil
+			return nodes[i]
 		}
 	}
-	return w.nodes[0], nil
+	return nodes[0]
 }
 
-func (w *Weighted[T]) ReportSuccess(node T) {}
-func (w *Weighted[T]) ReportFailure(node T) {}
+func (w *Weighted[T]) ReportSuccess(node T) { w.health.success(node.ID()) }
+func (w *Weighted[T]) ReportFailure(node T) { w.health.failure(node.ID()) }
+
+func (w *Weighted[T]) Available() []T {
+	return available(w.health, w.nodes, nil)
+}
 
 func (w *Weighted[T]) Nodes() []T {
 	return w.nodes
@@ -77,3 +116,4 @@ func (w *Weighted[T]) UpdateNodesWithWeights(nodes []T, weights []int) {
 }
 
 var _ LoadBalancer[Node] = (*Weighted[Node])(nil)
+var _ FilterSelector[Node] = (*Weighted[Node])(nil)
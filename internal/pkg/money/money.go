@@ -0,0 +1,212 @@
+// Package money 以整数微单位（百万分之一美元）表示金额，计费全程使用整数运算，避免浮点误差累积。
+//
+// 舍入规则：
+//   - 每次计费只在最后一步舍入一次（TokenCost 先以整数精确计算 tokens × 单价之和，再换算为微单位）；
+//   - 舍入一律四舍五入，.5 远离零；
+//   - 解析超过 6 位小数的输入时按同样规则舍入到微单位。
+//
+// 数据库中金额以 DECIMAL(20,6) 保存，与微单位一一对应；JSON 中以十进制数字表示（单位为美元）。
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale 每美元的微单位数。
+const Scale = 1_000_000
+
+// PriceTokens 模型单价的计价单位：每 1M tokens。
+const PriceTokens = 1_000_000
+
+// MaxDollars int64 微单位能表示的最大整数美元（约 9.22 万亿）。DECIMAL(20,6) 允许 14 位整数，
+// 超出此值的输入无法用 Amount 表示，解析时返回 ErrInvalid 而不是溢出。
+const MaxDollars = math.MaxInt64 / Scale
+
+// maxIntDigits MaxDollars 的位数，超过此位数的整数部分一定越界。
+const maxIntDigits = 13
+
+// ErrInvalid 金额格式错误或超出范围。
+var ErrInvalid = errors.New("invalid money amount")
+
+// Amount 以微单位表示的金额。
+type Amount int64
+
+// FromFloat 将美元浮点数四舍五入为微单位，仅用于兼容外部输入；NaN、无穷或超出 Amount 范围时返回 ErrInvalid。
+func FromFloat(f float64) (Amount, error) {
+	v := math.Round(f * Scale)
+	// float64(math.MaxInt64) 恰为 2^63，等于它时已越界
+	if math.IsNaN(v) || v >= math.MaxInt64 || v <= math.MinInt64 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalid, f)
+	}
+	return Amount(v), nil
+}
+
+// Dollars 返回整数美元对应的金额。
+func Dollars(d int64) Amount {
+	return Amount(d * Scale)
+}
+
+// Parse 解析十进制金额字符串（如 "12.5"、"-0.000001"），超过 6 位的小数四舍五入。
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		return FromFloat(f)
+	}
+
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if (intPart == "" && fracPart == "") || len(intPart) > maxIntDigits || !digits(intPart) || !digits(fracPart) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	// 整数部分不超过 13 位，以 uint64 计算微单位不会溢出，最后再检查是否超出 int64
+	var v uint64
+	for _, c := range intPart {
+		v = v*10 + uint64(c-'0')
+	}
+	for i := 0; i < 6; i++ {
+		v *= 10
+		if i < len(fracPart) {
+			v += uint64(fracPart[i] - '0')
+		}
+	}
+	if len(fracPart) > 6 && fracPart[6] >= '5' {
+		v++
+	}
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	if neg {
+		return Amount(-int64(v)), nil
+	}
+	return Amount(v), nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 返回以美元为单位的浮点数，仅用于展示与日志。
+func (a Amount) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// String 返回以美元为单位的十进制表示，去掉末尾多余的 0，如 12.5、-0.000001、0。
+func (a Amount) String() string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-v)
+	}
+	s := sign + strconv.FormatUint(u/Scale, 10)
+	if frac := u % Scale; frac != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%06d", frac), "0")
+	}
+	return s
+}
+
+// MarshalJSON 以十进制数字输出。
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON 接受数字或数字字符串。
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	v, err := Parse(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value 以十进制字符串写入数据库，由 DECIMAL 列精确保存。
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan 从 DECIMAL / 整数 / 浮点列读取金额。
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		if v > MaxDollars || v < -MaxDollars {
+			return fmt.Errorf("%w: %d", ErrInvalid, v)
+		}
+		*a = Dollars(v)
+	case float64:
+		f, err := FromFloat(v)
+		if err != nil {
+			return err
+		}
+		*a = f
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalid, src)
+	}
+	return nil
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// TokenCost 计算一次请求的费用。promptPrice、completionPrice 为每 1M tokens 的单价，
+// 先以整数精确计算 tokens × 单价之和，最后四舍五入到微单位，整个请求只舍入一次。
+func TokenCost(inputTokens, outputTokens int, promptPrice, completionPrice Amount) Amount {
+	total := int64(inputTokens)*int64(promptPrice) + int64(outputTokens)*int64(completionPrice)
+	return Amount(divRound(total, PriceTokens))
+}
+
+// divRound 整数除法，四舍五入，.5 远离零。
+func divRound(n, d int64) int64 {
+	q, r := n/d, n%d
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= d {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
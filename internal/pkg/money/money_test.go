@@ -0,0 +1,115 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Amount{
+		"0":            0,
+		"12.5":         12_500_000,
+		"-0.000001":    -1,
+		".25":          250_000,
+		"+3":           3_000_000,
+		"0.0000004":    0,
+		"0.0000005":    1,
+		"-0.0000005":   -1,
+		"1.23456789":   1_234_568,
+		"100.00000000": 100_000_000,
+		"1e-6":         1,
+	}
+	for in, want := range cases {
+		got, err := Parse(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", ".", "abc", "1.2.3", "-", "1,5", "123456789012345"} {
+		_, err := Parse(in)
+		assert.ErrorIs(t, err, ErrInvalid, in)
+	}
+}
+
+func TestParse_Range(t *testing.T) {
+	// int64 微单位的上限为 9223372036854.775807 美元
+	got, err := Parse("9223372036854.775807")
+	require.NoError(t, err)
+	assert.Equal(t, Amount(math.MaxInt64), got)
+	got, err = Parse("-9223372036854.775807")
+	require.NoError(t, err)
+	assert.Equal(t, Amount(-math.MaxInt64), got)
+
+	for _, in := range []string{
+		"9223372036854.775808",
+		"9223372036854.7758075",
+		"9223373000000",
+		"9999999999999",
+		"99999999999999",
+		"-9223372036855",
+		"9.3e12",
+		"-1e13",
+		"1e400",
+	} {
+		_, err := Parse(in)
+		assert.ErrorIs(t, err, ErrInvalid, in)
+	}
+
+	_, err = FromFloat(math.NaN())
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = FromFloat(float64(MaxDollars) + 1)
+	assert.ErrorIs(t, err, ErrInvalid)
+	var a Amount
+	assert.ErrorIs(t, a.Scan(int64(MaxDollars+1)), ErrInvalid)
+	assert.ErrorIs(t, a.Scan(1e13), ErrInvalid)
+}
+
+func TestAmount_StringAndJSON(t *testing.T) {
+	assert.Equal(t, "0", Amount(0).String())
+	assert.Equal(t, "12.5", Amount(12_500_000).String())
+	assert.Equal(t, "-0.000001", Amount(-1).String())
+
+	var v struct {
+		Price Amount  `json:"price"`
+		Quota *Amount `json:"quota"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"price": 0.15, "quota": "20"}`), &v))
+	assert.Equal(t, Amount(150_000), v.Price)
+	assert.Equal(t, Dollars(20), *v.Quota)
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"price": 0.15, "quota": 20}`, string(out))
+}
+
+func TestAmount_Scan(t *testing.T) {
+	var a Amount
+	require.NoError(t, a.Scan([]byte("12.34567800")))
+	assert.Equal(t, Amount(12_345_678), a)
+	require.NoError(t, a.Scan(nil))
+	assert.Equal(t, Amount(0), a)
+	require.NoError(t, a.Scan(int64(2)))
+	assert.Equal(t, Dollars(2), a)
+
+	v, err := Amount(-1_500_000).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "-1.5", v)
+}
+
+func TestTokenCost(t *testing.T) {
+	// $0.15 / $0.60 每 1M tokens
+	prompt, completion := Amount(150_000), Amount(600_000)
+
+	assert.Equal(t, Amount(0), TokenCost(0, 0, prompt, completion))
+	// 1000 × 0.15 + 500 × 0.60 = 450 微单位
+	assert.Equal(t, Amount(450), TokenCost(1000, 500, prompt, completion))
+	// 3 × 0.15 = 0.45 微单位，舍入为 0；4 × 0.15 = 0.6 微单位，舍入为 1
+	assert.Equal(t, Amount(0), TokenCost(3, 0, prompt, completion))
+	assert.Equal(t, Amount(1), TokenCost(4, 0, prompt, completion))
+	// 输入与输出合计后只舍入一次：0.45 + 0.45 = 0.9 -> 1（分别舍入会得到 0）
+	assert.Equal(t, Amount(1), TokenCost(3, 3, prompt, prompt))
+}
@@ -2,6 +2,7 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"time"
 )
@@ -13,15 +14,43 @@ type Config struct {
 	MaxDelay     time.Duration
 	Multiplier   float64
 	Jitter       float64 // 0.0 - 1.0, 随机抖动因子
+
+	// Retryable 判断错误是否可重试，nil 表示所有错误都重试。
+	Retryable func(err error) bool
+	// MaxRetryAfter 服务端建议的等待时间（见 Delayer）超过该值时放弃重试，0 表示不限制。
+	MaxRetryAfter time.Duration
 }
 
 // DefaultConfig 默认配置
 var DefaultConfig = Config{
-	MaxAttempts:  3,
-	InitialDelay: 100 * time.Millisecond,
-	MaxDelay:     2 * time.Second,
-	Multiplier:   2.0,
-	Jitter:       0.2,
+	MaxAttempts:   3,
+	InitialDelay:  100 * time.Millisecond,
+	MaxDelay:      2 * time.Second,
+	Multiplier:    2.0,
+	Jitter:        0.2,
+	MaxRetryAfter: 10 * time.Second,
+}
+
+// Delayer 由携带服务端建议重试间隔的错误实现（例如 HTTP Retry-After）。
+// Do 会等待不短于该间隔的时间再重试。
+type Delayer interface {
+	RetryDelay() time.Duration
+}
+
+// permanentError 标记不应重试的错误。
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装一个错误，使 Do 立即返回该错误而不再重试。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
 }
 
 // Do 执行重试操作
@@ -37,14 +66,35 @@ func Do(ctx context.Context, cfg Config, fn func() error) error {
 			return ctx.Err()
 		}
 
-		// 检查错误是否可重试 (这里假设所有错误都重试，实际可以过滤)
-		// 如果是不可重试错误，return err
+		// 不可重试错误直接返回原始错误
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if cfg.Retryable != nil && !cfg.Retryable(err) {
+			return err
+		}
 
 		if i == cfg.MaxAttempts-1 {
 			break
 		}
 
 		delay := calculateDelay(cfg, i)
+		var d Delayer
+		if errors.As(err, &d) {
+			if hint := d.RetryDelay(); hint > 0 {
+				if cfg.MaxRetryAfter > 0 && hint > cfg.MaxRetryAfter {
+					return err
+				}
+				if hint > delay {
+					delay = hint
+				}
+			}
+		}
+		// 等待后已超过截止时间则不再重试
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
 
 		timer := time.NewTimer(delay)
 		select {
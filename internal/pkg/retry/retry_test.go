@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errRetryable = errors.New("retryable")
+
+type delayErr struct {
+	delay time.Duration
+}
+
+func (e *delayErr) Error() string             { return "slow down" }
+func (e *delayErr) RetryDelay() time.Duration { return e.delay }
+
+func testConfig() Config {
+	return Config{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Millisecond,
+		Multiplier:   2,
+		Retryable:    func(err error) bool { return errors.Is(err, errRetryable) },
+	}
+}
+
+func TestDo_OnlyRetriesRetryableErrors(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), testConfig(), func() error {
+		calls++
+		return errors.New("bad request")
+	})
+	assert.EqualError(t, err, "bad request")
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Do(context.Background(), testConfig(), func() error {
+		calls++
+		return errRetryable
+	})
+	assert.ErrorIs(t, err, errRetryable)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDo_Permanent(t *testing.T) {
+	cfg := testConfig()
+	cfg.Retryable = nil
+	calls := 0
+	err := Do(context.Background(), cfg, func() error {
+		calls++
+		return Permanent(errRetryable)
+	})
+	assert.Equal(t, errRetryable, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDo_HonorsRetryDelay(t *testing.T) {
+	cfg := testConfig()
+	cfg.Retryable = nil
+	cfg.MaxRetryAfter = time.Second
+
+	calls := 0
+	start := time.Now()
+	err := Do(context.Background(), cfg, func() error {
+		calls++
+		if calls == 1 {
+			return &delayErr{delay: 50 * time.Millisecond}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// 建议等待时间超过上限时放弃重试
+	calls = 0
+	err = Do(context.Background(), cfg, func() error {
+		calls++
+		return &delayErr{delay: time.Minute}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,275 @@
+// Package spool 实现本地预写日志：记录按行追加到分段文件，按写入顺序读取，
+// 分段写满且其中的记录全部确认后删除该分段。
+//
+// 进程重启后尚未删除的分段会从头重新读取，记录至少投递一次，消费方需保证幂等。
+// 追加只写入操作系统缓冲，进程崩溃不丢数据；分段切换与关闭时落盘。
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed 预写日志已关闭。
+var ErrClosed = errors.New("spool: closed")
+
+// DefaultSegmentBytes 默认分段大小。
+const DefaultSegmentBytes = 16 << 20
+
+const segmentExt = ".log"
+
+// Entry 读取到的一条记录，处理完成后须调用 Ack 确认。
+type Entry struct {
+	Data []byte
+	seg  uint64
+}
+
+// Spool 分段的本地预写日志，并发安全。
+type Spool struct {
+	dir          string
+	segmentBytes int64
+
+	mu      sync.Mutex
+	closed  bool
+	w       *os.File
+	wSeq    uint64
+	wSize   int64
+	r       *bufio.Reader
+	rFile   *os.File
+	rSeq    uint64
+	rPos    int64          // 当前分段已读取的字节数
+	pending map[uint64]int // 各分段已读取、未确认的记录数
+
+	notify chan struct{}
+	done   chan struct{}
+}
+
+// Open 打开目录中的预写日志，已有分段从头重新读取，新记录写入新的分段。
+func Open(dir string, segmentBytes int64) (*Spool, error) {
+	if segmentBytes <= 0 {
+		segmentBytes = DefaultSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spool: create dir: %w", err)
+	}
+	segs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		pending:      make(map[uint64]int),
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	next := uint64(1)
+	if len(segs) > 0 {
+		next = segs[len(segs)-1] + 1
+		s.rSeq = segs[0]
+	} else {
+		s.rSeq = next
+	}
+	if err := s.openWriter(next); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Append 追加一条记录，data 中不能包含换行符。
+func (s *Spool) Append(data []byte) error {
+	if bytes.IndexByte(data, '\n') >= 0 {
+		return errors.New("spool: record contains newline")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.wSize > 0 && s.wSize+int64(len(data))+1 > s.segmentBytes {
+		if err := s.w.Sync(); err != nil {
+			return fmt.Errorf("spool: sync segment: %w", err)
+		}
+		if err := s.w.Close(); err != nil {
+			return fmt.Errorf("spool: close segment: %w", err)
+		}
+		if err := s.openWriter(s.wSeq + 1); err != nil {
+			return err
+		}
+	}
+
+	line := make([]byte, 0, len(data)+1)
+	line = append(append(line, data...), '\n')
+	n, err := s.w.Write(line)
+	s.wSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("spool: write: %w", err)
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Read 按写入顺序返回下一条记录，没有新记录时阻塞，直到有新记录、ctx 结束或预写日志关闭。
+func (s *Spool) Read(ctx context.Context) (Entry, error) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return Entry{}, ErrClosed
+		}
+		line, err := s.readLine()
+		switch {
+		case err == nil:
+			s.pending[s.rSeq]++
+			e := Entry{Data: line, seg: s.rSeq}
+			s.mu.Unlock()
+			return e, nil
+		case !errors.Is(err, io.EOF):
+			s.mu.Unlock()
+			return Entry{}, err
+		case s.rSeq < s.wSeq:
+			// 分段已写满且读完，切换到下一分段
+			err := s.finishSegment()
+			s.mu.Unlock()
+			if err != nil {
+				return Entry{}, err
+			}
+			continue
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-s.done:
+		case <-ctx.Done():
+			return Entry{}, ctx.Err()
+		}
+	}
+}
+
+// Ack 确认记录已处理，分段写满、读完且全部确认后删除。
+func (s *Spool) Ack(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[e.seg]--
+	if s.pending[e.seg] > 0 || e.seg >= s.rSeq {
+		return
+	}
+	delete(s.pending, e.seg)
+	_ = os.Remove(s.path(e.seg))
+}
+
+// Close 落盘并关闭预写日志，未确认的记录在下次 Open 后重新读取。
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	if s.rFile != nil {
+		_ = s.rFile.Close()
+	}
+	err := s.w.Sync()
+	if cerr := s.w.Close(); err == nil {
+		err = cerr
+	}
+	// 当前分段为空，或已全部读取并确认时直接删除，避免重启后重放
+	if s.wSize == 0 || (s.rSeq == s.wSeq && s.rPos == s.wSize && s.pending[s.wSeq] == 0) {
+		_ = os.Remove(s.path(s.wSeq))
+	}
+	return err
+}
+
+func (s *Spool) openWriter(seq uint64) error {
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("spool: open segment: %w", err)
+	}
+	s.w, s.wSeq, s.wSize = f, seq, 0
+	return nil
+}
+
+// readLine 读取当前分段的下一整行。写入在同一把锁下整行完成，只有崩溃前未写完的分段末尾会出现半行，直接丢弃。
+func (s *Spool) readLine() ([]byte, error) {
+	if s.rFile == nil {
+		f, err := os.Open(s.path(s.rSeq))
+		if err != nil {
+			return nil, fmt.Errorf("spool: open segment: %w", err)
+		}
+		s.rFile, s.r, s.rPos = f, bufio.NewReader(f), 0
+	}
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		s.rPos += int64(len(line))
+		if len(line) > 1 {
+			return line[:len(line)-1], nil
+		}
+	}
+}
+
+func (s *Spool) finishSegment() error {
+	if err := s.rFile.Close(); err != nil {
+		return fmt.Errorf("spool: close segment: %w", err)
+	}
+	s.rFile, s.r = nil, nil
+	if s.pending[s.rSeq] == 0 {
+		delete(s.pending, s.rSeq)
+		_ = os.Remove(s.path(s.rSeq))
+	}
+	// 确认乱序时，上次运行中较新的分段可能先于较旧的分段删除，跳过已不存在的分段
+	for s.rSeq++; s.rSeq < s.wSeq; s.rSeq++ {
+		if _, err := os.Stat(s.path(s.rSeq)); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("spool: stat segment: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("spool: read dir: %w", err)
+	}
+	var segs []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, seq)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+	return segs, nil
+}
@@ -0,0 +1,125 @@
+package spool
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readN(t *testing.T, s *Spool, n int) []Entry {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	entries := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		e, err := s.Read(ctx)
+		require.NoError(t, err)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func segments(t *testing.T, dir string) []uint64 {
+	t.Helper()
+	segs, err := listSegments(dir)
+	require.NoError(t, err)
+	return segs
+}
+
+func TestSpool_ReadAckRotate(t *testing.T) {
+	dir := t.TempDir()
+	// 每个分段只能容纳两条记录
+	s, err := Open(dir, 8)
+	require.NoError(t, err)
+
+	for _, rec := range []string{"a1", "b2", "c3", "d4", "e5"} {
+		require.NoError(t, s.Append([]byte(rec)))
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, segments(t, dir))
+	assert.Error(t, s.Append([]byte("x\ny")))
+
+	entries := readN(t, s, 5)
+	for i, want := range []string{"a1", "b2", "c3", "d4", "e5"} {
+		assert.Equal(t, want, string(entries[i].Data))
+	}
+
+	// 没有新记录时 Read 阻塞到 ctx 结束
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Read(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 分段 1 全部确认后删除；分段 2 仍有未确认记录
+	s.Ack(entries[0])
+	s.Ack(entries[1])
+	s.Ack(entries[2])
+	assert.Equal(t, []uint64{2, 3}, segments(t, dir))
+	s.Ack(entries[3])
+	assert.Equal(t, []uint64{3}, segments(t, dir))
+
+	require.NoError(t, s.Close())
+	_, err = s.Read(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, s.Append([]byte("f6")), ErrClosed)
+}
+
+func TestSpool_ReplayAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]byte("a")))
+	require.NoError(t, s.Append([]byte("b")))
+	s.Ack(readN(t, s, 1)[0])
+	require.NoError(t, s.Close())
+
+	// 模拟崩溃时写了一半的记录
+	f, err := os.OpenFile(s.path(1), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"torn`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// 未删除的分段从头重新读取（至少一次），新记录写入新分段
+	s, err = Open(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]byte("c")))
+	entries := readN(t, s, 3)
+	assert.Equal(t, "a", string(entries[0].Data))
+	assert.Equal(t, "b", string(entries[1].Data))
+	assert.Equal(t, "c", string(entries[2].Data))
+	for _, e := range entries {
+		s.Ack(e)
+	}
+	assert.Equal(t, []uint64{2}, segments(t, dir))
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_OutOfOrderAckReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 8)
+	require.NoError(t, err)
+	for _, rec := range []string{"a1", "b2", "c3", "d4", "e5", "f6", "g7"} {
+		require.NoError(t, s.Append([]byte(rec)))
+	}
+	entries := readN(t, s, 7)
+
+	// 分段 2、3 先于分段 1 全部确认并删除，分段 4 的记录未确认
+	for _, e := range entries[2:6] {
+		s.Ack(e)
+	}
+	assert.Equal(t, []uint64{1, 4}, segments(t, dir))
+	require.NoError(t, s.Close())
+
+	// 重启后跳过已删除的分段，剩余记录全部读到
+	s, err = Open(dir, 8)
+	require.NoError(t, err)
+	got := readN(t, s, 3)
+	assert.Equal(t, "a1", string(got[0].Data))
+	assert.Equal(t, "b2", string(got[1].Data))
+	assert.Equal(t, "g7", string(got[2].Data))
+	require.NoError(t, s.Close())
+}
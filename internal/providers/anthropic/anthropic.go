@@ -14,11 +14,13 @@ import (
 	"ai-gateway/internal/pkg/logger"
 
 	"ai-gateway/internal/domain"
-	"ai-gateway/internal/errs"
+	"ai-gateway/internal/providers"
 )
 
 // Provider implements the Provider interface for Anthropic API.
 type Provider struct {
+	id      int64
+	name    string
 	apiKey  string
 	baseURL string
 	client  *http.Client
@@ -26,22 +28,30 @@ type Provider struct {
 }
 
 // NewProvider creates a new Anthropic provider.
-func NewProvider(apiKey, baseURL string, client *http.Client, l logger.Logger) *Provider {
+// id and name identify the configured provider instance; name defaults to "anthropic".
+func NewProvider(id int64, name, apiKey, baseURL string, client *http.Client, l logger.Logger) *Provider {
 	if baseURL == "" {
 		baseURL = "https://api.anthropic.com"
 	}
 	if client == nil {
 		client = http.DefaultClient
 	}
+	if name == "" {
+		name = "anthropic"
+	}
 	return &Provider{
+		id:      id,
+		name:    name,
 		apiKey:  apiKey,
 		baseURL: baseURL,
 		client:  client,
-		logger:  l.With(logger.String("provider", "anthropic")),
+		logger:  l.With(logger.String("provider", name)),
 	}
 }
 
-func (p *Provider) Name() string { return "anthropic" }
+func (p *Provider) ID() int64    { return p.id }
+func (p *Provider) Name() string { return p.name }
+func (p *Provider) Type() string { return "anthropic" }
 
 func (p *Provider) SupportsStreaming() bool { return true }
 func (p *Provider) SupportsTools() bool     { return true }
@@ -167,7 +177,7 @@ func (p *Provider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.C
 			logger.Int("status", resp.StatusCode),
 			logger.String("body", string(respBody)),
 		)
-		return nil, fmt.Errorf("%w: status %d", errs.ErrProviderError, resp.StatusCode)
+		return nil, p.parseError(resp, respBody)
 	}
 
 	var claudeResp messagesResponse
@@ -206,7 +216,7 @@ func (p *Provider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-c
 			logger.Int("status", resp.StatusCode),
 			logger.String("body", string(respBody)),
 		)
-		return nil, fmt.Errorf("%w: status %d", errs.ErrProviderError, resp.StatusCode)
+		return nil, p.parseError(resp, respBody)
 	}
 
 	ch := make(chan domain.StreamDelta, 100)
@@ -569,3 +579,18 @@ func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
 		"claude-3-haiku-20240307",
 	}, nil
 }
+
+// errorResponse is the Anthropic error body: {"type":"error","error":{"type":"...","message":"..."}}.
+type errorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// parseError converts a non-200 response into a typed upstream error.
+func (p *Provider) parseError(resp *http.Response, body []byte) error {
+	var e errorResponse
+	_ = json.Unmarshal(body, &e)
+	return providers.NewUpstreamError(p.name, resp.StatusCode, resp.Header, e.Error.Type, "", e.Error.Message)
+}
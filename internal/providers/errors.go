@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ai-gateway/internal/errs"
+)
+
+// UpstreamError 表示上游提供商返回的非 2xx 响应。
+// 它通过 Unwrap 映射到对应的 errs 哨兵错误（限流、超时、过载等），
+// 因此 errors.Is / errors.As 以及 HTTP 状态码映射均可照常使用。
+type UpstreamError struct {
+	Provider   string        // 供应商实例名称
+	StatusCode int           // 上游 HTTP 状态码
+	Type       string        // 上游错误类型，如 rate_limit_error、RESOURCE_EXHAUSTED
+	Code       string        // 上游错误码（如有）
+	Message    string        // 上游错误信息
+	RetryAfter time.Duration // 上游通过 Retry-After 建议的等待时间，0 表示未提供
+	Err        error         // 对应的 errs 哨兵错误
+}
+
+// NewUpstreamError 根据上游响应创建 UpstreamError，并按状态码选择对应的哨兵错误。
+func NewUpstreamError(provider string, status int, header http.Header, errType, code, message string) *UpstreamError {
+	return &UpstreamError{
+		Provider:   provider,
+		StatusCode: status,
+		Type:       errType,
+		Code:       code,
+		Message:    message,
+		RetryAfter: ParseRetryAfter(header),
+		Err:        sentinelForStatus(status),
+	}
+}
+
+func sentinelForStatus(status int) error {
+	switch status {
+	case http.StatusTooManyRequests:
+		return errs.ErrRateLimited
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return errs.ErrProviderTimeout
+	case http.StatusServiceUnavailable, 529: // 529: Anthropic overloaded_error
+		return errs.ErrProviderOverloaded
+	default:
+		return errs.ErrProviderError
+	}
+}
+
+func (e *UpstreamError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "upstream %s: status %d", e.Provider, e.StatusCode)
+	if e.Type != "" {
+		fmt.Fprintf(&b, " (%s)", e.Type)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	return b.String()
+}
+
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// Retryable 报告该错误是否值得重试：408、429 和 5xx。
+func (e *UpstreamError) Retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// RetryDelay 返回上游建议的重试等待时间，实现 retry.Delayer。
+func (e *UpstreamError) RetryDelay() time.Duration { return e.RetryAfter }
+
+// ParseRetryAfter 解析 Retry-After（秒数或 HTTP 日期）以及 OpenAI 使用的 retry-after-ms 头。
+func ParseRetryAfter(h http.Header) time.Duration {
+	if h == nil {
+		return 0
+	}
+	if v := h.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// IsRetryable 判断一次上游调用的错误是否可以重试：
+// 408 / 429 / 5xx 响应以及瞬时网络错误可以重试，其余（如 400、401、响应解析失败、
+// 协议不支持、URL 非法、TLS 证书错误）重试也不会成功，不重试。
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		return upstream.Retryable()
+	}
+
+	return isTransientNetError(err)
+}
+
+// isTransientNetError 判断网络错误是否是瞬时的：超时、连接被拒绝或重置、连接意外关闭，
+// 以及 DNS 服务器的临时故障。
+// 单纯的 io.EOF 只在收到响应之前（HTTP 客户端返回的 *url.Error，如复用的空闲连接已被上游关闭）才重试；
+// 200 响应之后读到 EOF 说明上游已经处理了请求，重试可能重复生成与计费。
+func isTransientNetError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) && beforeResponse(err)
+}
+
+// beforeResponse 判断错误是否由 HTTP 客户端在返回响应之前产生
+func beforeResponse(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"ai-gateway/internal/errs"
+)
+
+func TestUpstreamError_Classification(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+		sentinel  *errs.AppError
+	}{
+		{http.StatusBadRequest, false, errs.ErrProviderError},
+		{http.StatusUnauthorized, false, errs.ErrProviderError},
+		{http.StatusRequestTimeout, true, errs.ErrProviderTimeout},
+		{http.StatusTooManyRequests, true, errs.ErrRateLimited},
+		{http.StatusInternalServerError, true, errs.ErrProviderError},
+		{http.StatusServiceUnavailable, true, errs.ErrProviderOverloaded},
+		{529, true, errs.ErrProviderOverloaded},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", NewUpstreamError("p", tt.status, nil, "", "", ""))
+		assert.Equal(t, tt.retryable, IsRetryable(err), "status %d", tt.status)
+		assert.ErrorIs(t, err, tt.sentinel, "status %d", tt.status)
+	}
+}
+
+func TestIsRetryable_NetworkErrors(t *testing.T) {
+	post := func(err error) error { return &url.Error{Op: "Post", URL: "https://api.example.com/v1", Err: err} }
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"connection refused", post(dial(syscall.ECONNREFUSED)), true},
+		{"connection reset", post(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection closed before response", post(io.EOF), true},
+		{"truncated body", fmt.Errorf("read response: %w", io.ErrUnexpectedEOF), true},
+		{"dial timeout", post(&net.OpError{Op: "dial", Net: "tcp", Err: &timeoutError{}}), true},
+		{"deadline exceeded", post(context.DeadlineExceeded), true},
+		{"temporary dns failure", post(&net.DNSError{Err: "server misbehaving", Name: "api.example.com", IsTemporary: true}), true},
+
+		{"unsupported scheme", post(errors.New(`unsupported protocol scheme "htp"`)), false},
+		{"invalid url", &url.Error{Op: "parse", URL: "http://[::1", Err: errors.New("missing ']' in host")}, false},
+		{"unknown host", post(&net.DNSError{Err: "no such host", Name: "api.exmaple.com", IsNotFound: true}), false},
+		{"unknown certificate authority", post(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false},
+		{"hostname mismatch", post(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "api.example.com"}), false},
+		{"client canceled", post(context.Canceled), false},
+		{"bad json", errors.New("decode response: bad json"), false},
+		{"eof after response", fmt.Errorf("decode response: %w", io.EOF), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.retryable, IsRetryable(tt.err), tt.name)
+	}
+}
+
+// timeoutError 模拟超时的网络错误。
+type timeoutError struct{}
+
+func (*timeoutError) Error() string   { return "i/o timeout" }
+func (*timeoutError) Timeout() bool   { return true }
+func (*timeoutError) Temporary() bool { return true }
+
+func TestParseRetryAfter(t *testing.T) {
+	h := http.Header{}
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(h))
+
+	h.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, ParseRetryAfter(h))
+
+	h.Set("retry-after-ms", "1500")
+	assert.Equal(t, 1500*time.Millisecond, ParseRetryAfter(h))
+
+	h = http.Header{}
+	h.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	d := ParseRetryAfter(h)
+	assert.Greater(t, d, 50*time.Second)
+	assert.LessOrEqual(t, d, time.Minute)
+}
@@ -0,0 +1,722 @@
+// Package gemini 实现 Google Gemini 原生 API 提供商适配器。
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ai-gateway/internal/pkg/logger"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/providers"
+)
+
+// Provider 为 Gemini generateContent API 实现 Provider 接口。
+type Provider struct {
+	id      int64
+	name    string
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	logger  logger.Logger
+}
+
+// NewProvider 创建一个新的 Gemini 提供商。
+// id 和 name 标识已配置的供应商实例，name 为空时使用 "gemini"。
+func NewProvider(id int64, name, apiKey, baseURL string, client *http.Client, l logger.Logger) *Provider {
+	if baseURL == "" {
+		baseURL = "https://generativelanguage.googleapis.com/v1beta"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if name == "" {
+		name = "gemini"
+	}
+	return &Provider{
+		id:      id,
+		name:    name,
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+		logger:  l.With(logger.String("provider", name)),
+	}
+}
+
+func (p *Provider) ID() int64    { return p.id }
+func (p *Provider) Name() string { return p.name }
+func (p *Provider) Type() string { return "gemini" }
+
+func (p *Provider) SupportsStreaming() bool { return true }
+func (p *Provider) SupportsTools() bool     { return true }
+func (p *Provider) SupportsVision() bool    { return true }
+
+// Gemini API 类型
+type generateRequest struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Tools             []tool            `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"` // user 或 model
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text             string            `json:"text,omitempty"`
+	Thought          bool              `json:"thought,omitempty"`
+	ThoughtSignature string            `json:"thoughtSignature,omitempty"`
+	InlineData       *blob             `json:"inlineData,omitempty"`
+	FileData         *fileData         `json:"fileData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+}
+
+type blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64 编码
+}
+
+type fileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type functionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type functionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+type functionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig *functionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO, ANY, NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"topP,omitempty"`
+	TopK             *int            `json:"topK,omitempty"`
+	MaxOutputTokens  int             `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string        `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64        `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequencyPenalty,omitempty"`
+	ResponseMimeType string          `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any  `json:"responseJsonSchema,omitempty"`
+	ThinkingConfig   *thinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type thinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+}
+
+type generateResponse struct {
+	Candidates    []candidate    `json:"candidates"`
+	UsageMetadata *usageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string         `json:"modelVersion,omitempty"`
+	ResponseID    string         `json:"responseId,omitempty"`
+}
+
+type candidate struct {
+	Content      content `json:"content"`
+	FinishReason string  `json:"finishReason,omitempty"`
+	Index        int     `json:"index"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// Chat 发送非流式聊天请求。
+func (p *Provider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+	body, err := json.Marshal(p.toGeminiRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.modelURL(req.Model, "generateContent"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		p.logger.Error("Gemini API error",
+			logger.Int("status", resp.StatusCode),
+			logger.String("body", string(respBody)),
+		)
+		return nil, p.parseError(resp, respBody)
+	}
+
+	var gResp generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	result := p.fromGeminiResponse(&gResp)
+	if result.Model == "" {
+		result.Model = req.Model
+	}
+	return result, nil
+}
+
+// ChatStream 发送流式聊天请求。
+func (p *Provider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, error) {
+	body, err := json.Marshal(p.toGeminiRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.modelURL(req.Model, "streamGenerateContent")+"?alt=sse", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	p.setHeaders(httpReq)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		p.logger.Error("Gemini API error",
+			logger.Int("status", resp.StatusCode),
+			logger.String("body", string(respBody)),
+		)
+		return nil, p.parseError(resp, respBody)
+	}
+
+	ch := make(chan domain.StreamDelta, 100)
+	go p.readStream(resp.Body, ch)
+
+	return ch, nil
+}
+
+func (p *Provider) readStream(body io.ReadCloser, ch chan<- domain.StreamDelta) {
+	defer close(ch)
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	// 函数调用参数可能整体出现在一个分块中，放大行缓冲区
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var (
+		toolIndex    int
+		finishReason string
+		usage        *usageMetadata
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+
+		var chunk generateResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			p.logger.Warn("failed to parse SSE chunk", logger.Error(err))
+			continue
+		}
+		// 用量是累计值，可能单独出现在不含候选的分块中，以最后一次为准
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata
+		}
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+
+		c := chunk.Candidates[0]
+		if c.FinishReason != "" {
+			finishReason = c.FinishReason
+		}
+		for _, pt := range c.Content.Parts {
+			switch {
+			case pt.FunctionCall != nil:
+				ch <- domain.StreamDelta{
+					Type:    "tool_use",
+					Content: p.toolUseFromCall(pt.FunctionCall, pt.ThoughtSignature, toolIndex),
+				}
+				toolIndex++
+			case pt.Thought && pt.Text != "":
+				ch <- domain.StreamDelta{
+					Type: "thinking",
+					Content: &domain.ContentPart{
+						Type:     domain.ContentTypeThinking,
+						Thinking: pt.Text,
+					},
+				}
+			case pt.Text != "":
+				ch <- domain.StreamDelta{
+					Type: "content",
+					Content: &domain.ContentPart{
+						Type: domain.ContentTypeText,
+						Text: pt.Text,
+					},
+				}
+			}
+		}
+	}
+
+	// 流读取中断且未收到 finishReason 时视为异常结束，不发送 done；
+	// 正常读完但最后一个分块没有 finishReason 时（部分模型/代理如此）按正常结束处理
+	if err := scanner.Err(); err != nil {
+		p.logger.Warn("gemini stream read error", logger.Error(err))
+		if finishReason == "" {
+			return
+		}
+	}
+	delta := domain.StreamDelta{
+		Type:         "done",
+		FinishReason: mapFinishReason(finishReason, toolIndex > 0),
+	}
+	if usage != nil {
+		delta.Usage = toTokenUsage(usage)
+	}
+	ch <- delta
+}
+
+func (p *Provider) modelURL(model, method string) string {
+	model = strings.TrimPrefix(model, "models/")
+	return fmt.Sprintf("%s/models/%s:%s", p.baseURL, model, method)
+}
+
+func (p *Provider) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+}
+
+func (p *Provider) toGeminiRequest(req *domain.ChatRequest) *generateRequest {
+	gReq := &generateRequest{}
+
+	// 系统指令：优先使用独立的 System 字段，其次合并 system 角色消息
+	var systemTexts []string
+	if req.System != "" {
+		systemTexts = append(systemTexts, req.System)
+	}
+
+	// Gemini 的 functionResponse 需要函数名，而统一格式只携带 tool_use_id，
+	// 因此先收集历史中的 tool_use 以便回填名称。
+	toolNames := make(map[string]string)
+	for _, m := range req.Messages {
+		for _, pt := range m.Content {
+			if pt.Type == domain.ContentTypeToolUse && pt.ToolID != "" {
+				toolNames[pt.ToolID] = pt.ToolName
+			}
+		}
+	}
+
+	for _, m := range req.Messages {
+		if m.Role == domain.RoleSystem {
+			if text := m.GetTextContent(); text != "" {
+				systemTexts = append(systemTexts, text)
+			}
+			continue
+		}
+
+		c := p.toGeminiContent(m, toolNames)
+		if len(c.Parts) == 0 {
+			continue
+		}
+
+		// Gemini 要求同一角色的连续消息合并（例如多个工具结果）
+		if n := len(gReq.Contents); n > 0 && gReq.Contents[n-1].Role == c.Role {
+			gReq.Contents[n-1].Parts = append(gReq.Contents[n-1].Parts, c.Parts...)
+			continue
+		}
+		gReq.Contents = append(gReq.Contents, c)
+	}
+
+	if len(systemTexts) > 0 {
+		gReq.SystemInstruction = &content{
+			Parts: []part{{Text: strings.Join(systemTexts, "\n\n")}},
+		}
+	}
+
+	// 转换工具
+	if len(req.Tools) > 0 {
+		decls := make([]functionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			decls = append(decls, functionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.InputSchema,
+			})
+		}
+		gReq.Tools = []tool{{FunctionDeclarations: decls}}
+	}
+
+	// 转换 tool_choice
+	if req.ToolChoice != nil {
+		gReq.ToolConfig = p.toGeminiToolConfig(req.ToolChoice)
+	}
+
+	gReq.GenerationConfig = p.toGenerationConfig(req)
+
+	return gReq
+}
+
+// toGeminiToolConfig 将统一格式的 ToolChoice 转换为 Gemini 格式
+func (p *Provider) toGeminiToolConfig(tc *domain.ToolChoice) *toolConfig {
+	cfg := &functionCallingConfig{}
+	switch tc.Type {
+	case domain.ToolChoiceNone:
+		cfg.Mode = "NONE"
+	case domain.ToolChoiceAny:
+		cfg.Mode = "ANY"
+	case domain.ToolChoiceTool:
+		cfg.Mode = "ANY"
+		cfg.AllowedFunctionNames = []string{tc.Name}
+	default:
+		cfg.Mode = "AUTO"
+	}
+	return &toolConfig{FunctionCallingConfig: cfg}
+}
+
+func (p *Provider) toGenerationConfig(req *domain.ChatRequest) *generationConfig {
+	cfg := &generationConfig{
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		TopK:             req.TopK,
+		MaxOutputTokens:  req.MaxTokens,
+		StopSequences:    req.StopSequences,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+	}
+
+	// 转换 response_format
+	if rf := req.ResponseFormat; rf != nil {
+		switch rf.Type {
+		case domain.ResponseFormatJSONObject:
+			cfg.ResponseMimeType = "application/json"
+		case domain.ResponseFormatJSONSchema:
+			cfg.ResponseMimeType = "application/json"
+			if rf.JSONSchema != nil {
+				cfg.ResponseSchema = rf.JSONSchema.Schema
+			}
+		}
+	}
+
+	// 转换 thinking
+	if req.Thinking != nil {
+		switch req.Thinking.Type {
+		case "enabled":
+			cfg.ThinkingConfig = &thinkingConfig{IncludeThoughts: true}
+			if req.Thinking.BudgetTokens > 0 {
+				budget := req.Thinking.BudgetTokens
+				cfg.ThinkingConfig.ThinkingBudget = &budget
+			}
+		case "disabled":
+			budget := 0
+			cfg.ThinkingConfig = &thinkingConfig{ThinkingBudget: &budget}
+		}
+	}
+
+	return cfg
+}
+
+func (p *Provider) toGeminiContent(m domain.Message, toolNames map[string]string) content {
+	c := content{Role: "user"}
+	if m.Role == domain.RoleAssistant {
+		c.Role = "model"
+	}
+
+	for _, pt := range m.Content {
+		switch pt.Type {
+		case domain.ContentTypeText:
+			if pt.Text != "" {
+				c.Parts = append(c.Parts, part{Text: pt.Text})
+			}
+		case domain.ContentTypeImage:
+			if pt.Data != "" {
+				c.Parts = append(c.Parts, part{InlineData: &blob{
+					MimeType: pt.MediaType,
+					Data:     pt.Data,
+				}})
+			} else if pt.URL != "" {
+				// data URL 转为内联数据，其余 URL 作为文件引用
+				if mimeType, data, ok := parseDataURL(pt.URL); ok {
+					c.Parts = append(c.Parts, part{InlineData: &blob{MimeType: mimeType, Data: data}})
+				} else {
+					c.Parts = append(c.Parts, part{FileData: &fileData{MimeType: pt.MediaType, FileURI: pt.URL}})
+				}
+			}
+		case domain.ContentTypeToolUse:
+			id, signature := splitToolID(pt.ToolID)
+			if pt.ToolSignature != "" {
+				signature = pt.ToolSignature
+			}
+			c.Parts = append(c.Parts, part{
+				ThoughtSignature: signature,
+				FunctionCall: &functionCall{
+					ID:   upstreamCallID(id, pt.ToolName),
+					Name: pt.ToolName,
+					Args: pt.ToolInput,
+				},
+			})
+		case domain.ContentTypeToolResult:
+			name := toolNames[pt.ToolUseID]
+			if name == "" {
+				name = m.Name
+			}
+			id, _ := splitToolID(pt.ToolUseID)
+			c.Role = "user"
+			c.Parts = append(c.Parts, part{FunctionResponse: &functionResponse{
+				ID:       upstreamCallID(id, name),
+				Name:     name,
+				Response: toFunctionResponse(pt.Text, pt.IsError),
+			}})
+		case domain.ContentTypeThinking:
+			// 思考摘要仅用于展示，Gemini 不接受回传，跳过
+		}
+	}
+
+	return c
+}
+
+// toFunctionResponse 将工具结果文本包装为 Gemini 要求的 JSON 对象。
+func toFunctionResponse(text string, isError bool) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(text), &obj); err == nil && obj != nil {
+		return obj
+	}
+	if isError {
+		return map[string]any{"error": text}
+	}
+	return map[string]any{"content": text}
+}
+
+// parseDataURL 解析 data:<mime>;base64,<data> 格式的 URL。
+func parseDataURL(url string) (mimeType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	meta, payload, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), payload, true
+}
+
+func (p *Provider) fromGeminiResponse(resp *generateResponse) *domain.ChatResponse {
+	result := &domain.ChatResponse{
+		ID:    resp.ResponseID,
+		Model: resp.ModelVersion,
+	}
+
+	if len(resp.Candidates) > 0 {
+		c := resp.Candidates[0]
+		toolIndex := 0
+		for _, pt := range c.Content.Parts {
+			switch {
+			case pt.FunctionCall != nil:
+				result.Content = append(result.Content, *p.toolUseFromCall(pt.FunctionCall, pt.ThoughtSignature, toolIndex))
+				toolIndex++
+			case pt.Thought:
+				result.Content = append(result.Content, domain.ContentPart{
+					Type:     domain.ContentTypeThinking,
+					Thinking: pt.Text,
+				})
+			case pt.Text != "":
+				result.Content = append(result.Content, domain.ContentPart{
+					Type: domain.ContentTypeText,
+					Text: pt.Text,
+				})
+			}
+		}
+		result.FinishReason = mapFinishReason(c.FinishReason, toolIndex > 0)
+	}
+
+	if resp.UsageMetadata != nil {
+		result.Usage = toTokenUsage(resp.UsageMetadata)
+	}
+
+	return result
+}
+
+// toolUseFromCall 将 Gemini 函数调用转换为统一的 tool_use 内容。
+// 旧版本接口不返回调用 ID，此时按序号生成一个稳定的 ID。
+// 思考模型要求后续轮次回传函数调用的 thoughtSignature，而客户端只会原样回传工具调用 ID，
+// 因此签名除保存在 ToolSignature 外还编码进 ID，由 toGeminiContent 还原。
+func (p *Provider) toolUseFromCall(fc *functionCall, signature string, index int) *domain.ContentPart {
+	id := fc.ID
+	if id == "" {
+		id = generatedCallID(fc.Name, index)
+	}
+	return &domain.ContentPart{
+		Type:          domain.ContentTypeToolUse,
+		ToolID:        joinToolID(id, signature),
+		ToolName:      fc.Name,
+		ToolInput:     fc.Args,
+		ToolSignature: signature,
+	}
+}
+
+// signatureSep 工具调用 ID 中调用 ID 与签名的分隔符
+const signatureSep = "__ts_"
+
+// joinToolID 把签名以 URL 安全的 base64 追加到调用 ID 后，使 ID 仍只含字母、数字、_ 与 -
+func joinToolID(id, signature string) string {
+	if signature == "" {
+		return id
+	}
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return id + signatureSep + signature
+	}
+	return id + signatureSep + base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// splitToolID 拆分 joinToolID 生成的 ID，返回原始调用 ID 与 Gemini 格式的签名
+func splitToolID(toolID string) (id, signature string) {
+	id, encoded, ok := strings.Cut(toolID, signatureSep)
+	if !ok {
+		return toolID, ""
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return id, encoded
+	}
+	return id, base64.StdEncoding.EncodeToString(raw)
+}
+
+func generatedCallID(name string, index int) string {
+	return fmt.Sprintf("call_%s_%d", name, index)
+}
+
+// upstreamCallID 返回回传给 Gemini 的调用 ID：网关为旧版本接口生成的 ID 不回传
+func upstreamCallID(id, name string) string {
+	rest, ok := strings.CutPrefix(id, "call_"+name+"_")
+	if !ok || rest == "" {
+		return id
+	}
+	if _, err := strconv.Atoi(rest); err == nil {
+		return ""
+	}
+	return id
+}
+
+func toTokenUsage(u *usageMetadata) *domain.TokenUsage {
+	// 思考 token 按输出计费
+	completion := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + completion
+	}
+	return &domain.TokenUsage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: completion,
+		TotalTokens:      total,
+	}
+}
+
+func mapFinishReason(reason string, hasToolCall bool) domain.FinishReason {
+	if hasToolCall {
+		return domain.FinishReasonToolCalls
+	}
+	switch reason {
+	case "STOP", "":
+		return domain.FinishReasonStop
+	case "MAX_TOKENS":
+		return domain.FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return domain.FinishReasonContentFilter
+	default:
+		return domain.FinishReasonStop
+	}
+}
+
+// ListModels 返回可用模型列表。
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, strings.TrimPrefix(m.Name, "models/"))
+				break
+			}
+		}
+	}
+	return models, nil
+}
+
+// errorResponse Gemini 错误响应：{"error":{"code":429,"message":"...","status":"RESOURCE_EXHAUSTED"}}
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// parseError 将非 200 响应转换为带类型的上游错误。
+func (p *Provider) parseError(resp *http.Response, body []byte) error {
+	var e errorResponse
+	_ = json.Unmarshal(body, &e)
+	return providers.NewUpstreamError(p.name, resp.StatusCode, resp.Header, e.Error.Status, "", e.Error.Message)
+}
@@ -0,0 +1,193 @@
+package gemini
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+)
+
+func newTestProvider() *Provider {
+	return NewProvider(1, "", "key", "", nil, logger.NewNopLogger())
+}
+
+func TestProvider_ToGeminiRequest(t *testing.T) {
+	p := newTestProvider()
+
+	cases := []struct {
+		name string
+		req  *domain.ChatRequest
+		want string
+	}{
+		{
+			name: "system and text",
+			req: &domain.ChatRequest{
+				System: "be brief",
+				Messages: []domain.Message{
+					domain.NewTextMessage(domain.RoleSystem, "answer in English"),
+					domain.NewTextMessage(domain.RoleUser, "hi"),
+					domain.NewTextMessage(domain.RoleAssistant, "hello"),
+				},
+				MaxTokens: 100,
+			},
+			want: `{
+				"contents": [
+					{"role": "user", "parts": [{"text": "hi"}]},
+					{"role": "model", "parts": [{"text": "hello"}]}
+				],
+				"systemInstruction": {"parts": [{"text": "be brief\n\nanswer in English"}]},
+				"generationConfig": {"maxOutputTokens": 100}
+			}`,
+		},
+		{
+			name: "image data url and thinking",
+			req: &domain.ChatRequest{
+				Messages: []domain.Message{{Role: domain.RoleUser, Content: []domain.ContentPart{
+					{Type: domain.ContentTypeImage, URL: "data:image/png;base64,AAAA"},
+					{Type: domain.ContentTypeImage, URL: "gs://bucket/a.png", MediaType: "image/png"},
+				}}},
+				Thinking: &domain.ThinkingConfig{Type: "enabled", BudgetTokens: 1024},
+			},
+			want: `{
+				"contents": [{"role": "user", "parts": [
+					{"inlineData": {"mimeType": "image/png", "data": "AAAA"}},
+					{"fileData": {"mimeType": "image/png", "fileUri": "gs://bucket/a.png"}}
+				]}],
+				"generationConfig": {"thinkingConfig": {"includeThoughts": true, "thinkingBudget": 1024}}
+			}`,
+		},
+		{
+			name: "tool call round trip",
+			req: &domain.ChatRequest{
+				Messages: []domain.Message{
+					domain.NewTextMessage(domain.RoleUser, "weather?"),
+					{Role: domain.RoleAssistant, Content: []domain.ContentPart{
+						{Type: domain.ContentTypeThinking, Thinking: "let me check"},
+						{Type: domain.ContentTypeToolUse, ToolID: "fc-1__ts_c2ln", ToolName: "weather", ToolInput: map[string]any{"city": "Paris"}},
+						{Type: domain.ContentTypeToolUse, ToolID: "call_time_1", ToolName: "time"},
+					}},
+					{Role: domain.RoleTool, Content: []domain.ContentPart{{Type: domain.ContentTypeToolResult, ToolUseID: "fc-1__ts_c2ln", Text: `{"temp": 20}`}}},
+					{Role: domain.RoleTool, Content: []domain.ContentPart{{Type: domain.ContentTypeToolResult, ToolUseID: "call_time_1", Text: "boom", IsError: true}}},
+				},
+				Tools:      []domain.ToolDefinition{{Name: "weather", Description: "get weather", InputSchema: map[string]any{"type": "object"}}},
+				ToolChoice: &domain.ToolChoice{Type: domain.ToolChoiceTool, Name: "weather"},
+			},
+			// 签名与上游调用 ID 原样回传；网关生成的 ID 不回传
+			want: `{
+				"contents": [
+					{"role": "user", "parts": [{"text": "weather?"}]},
+					{"role": "model", "parts": [
+						{"thoughtSignature": "c2ln", "functionCall": {"id": "fc-1", "name": "weather", "args": {"city": "Paris"}}},
+						{"functionCall": {"name": "time"}}
+					]},
+					{"role": "user", "parts": [
+						{"functionResponse": {"id": "fc-1", "name": "weather", "response": {"temp": 20}}},
+						{"functionResponse": {"name": "time", "response": {"error": "boom"}}}
+					]}
+				],
+				"tools": [{"functionDeclarations": [{"name": "weather", "description": "get weather", "parameters": {"type": "object"}}]}],
+				"toolConfig": {"functionCallingConfig": {"mode": "ANY", "allowedFunctionNames": ["weather"]}},
+				"generationConfig": {}
+			}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(p.toGeminiRequest(tc.req))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestProvider_ToolSignatureRoundTrip(t *testing.T) {
+	p := newTestProvider()
+	resp := p.fromGeminiResponse(&generateResponse{Candidates: []candidate{{
+		Content: content{Role: "model", Parts: []part{{
+			ThoughtSignature: "+/+/aGk=",
+			FunctionCall:     &functionCall{Name: "weather", Args: map[string]any{"city": "Paris"}},
+		}}},
+		FinishReason: "STOP",
+	}}})
+	require.Len(t, resp.Content, 1)
+	call := resp.Content[0]
+	assert.Equal(t, domain.FinishReasonToolCalls, resp.FinishReason)
+	assert.Equal(t, "+/+/aGk=", call.ToolSignature)
+	// 编码进 ID 的签名只含 URL 安全字符
+	assert.NotContains(t, call.ToolID, "+")
+	assert.NotContains(t, call.ToolID, "/")
+
+	// 客户端只回传 ID 时也能还原签名
+	call.ToolSignature = ""
+	gReq := p.toGeminiRequest(&domain.ChatRequest{Messages: []domain.Message{
+		{Role: domain.RoleAssistant, Content: []domain.ContentPart{call}},
+		{Role: domain.RoleTool, Content: []domain.ContentPart{{Type: domain.ContentTypeToolResult, ToolUseID: call.ToolID, Text: "sunny"}}},
+	}})
+	require.Len(t, gReq.Contents, 2)
+	assert.Equal(t, "+/+/aGk=", gReq.Contents[0].Parts[0].ThoughtSignature)
+	assert.Equal(t, "", gReq.Contents[0].Parts[0].FunctionCall.ID)
+	assert.Equal(t, "weather", gReq.Contents[1].Parts[0].FunctionResponse.Name)
+}
+
+func TestProvider_ReadStream(t *testing.T) {
+	cases := []struct {
+		name   string
+		stream string
+		want   []domain.StreamDelta
+	}{
+		{
+			name: "text with finish reason",
+			stream: `data: {"candidates":[{"content":{"parts":[{"text":"thinking","thought":true}]}}]}
+
+data: {"candidates":[{"content":{"parts":[{"text":"Hel"}]}}]}
+
+data: {"candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":2,"thoughtsTokenCount":3,"totalTokenCount":10}}
+`,
+			want: []domain.StreamDelta{
+				{Type: "thinking", Content: &domain.ContentPart{Type: domain.ContentTypeThinking, Thinking: "thinking"}},
+				{Type: "content", Content: &domain.ContentPart{Type: domain.ContentTypeText, Text: "Hel"}},
+				{Type: "content", Content: &domain.ContentPart{Type: domain.ContentTypeText, Text: "lo"}},
+				{Type: "done", FinishReason: domain.FinishReasonLength, Usage: &domain.TokenUsage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10}}},
+		},
+		{
+			name: "tool call with signature",
+			stream: `data: {"candidates":[{"content":{"parts":[{"functionCall":{"id":"fc-1","name":"weather","args":{"city":"Paris"}},"thoughtSignature":"c2ln"}]},"finishReason":"STOP"}]}
+`,
+			want: []domain.StreamDelta{
+				{Type: "tool_use", Content: &domain.ContentPart{Type: domain.ContentTypeToolUse, ToolID: "fc-1__ts_c2ln", ToolName: "weather", ToolInput: map[string]any{"city": "Paris"}, ToolSignature: "c2ln"}},
+				{Type: "done", FinishReason: domain.FinishReasonToolCalls},
+			},
+		},
+		{
+			// 最后一个分块没有 finishReason，用量单独出现在不含候选的分块中
+			name: "ends without finish reason",
+			stream: `data: {"candidates":[{"content":{"parts":[{"text":"hi"}]}}]}
+
+data: not json
+
+data: {"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1}}
+`,
+			want: []domain.StreamDelta{
+				{Type: "content", Content: &domain.ContentPart{Type: domain.ContentTypeText, Text: "hi"}},
+				{Type: "done", FinishReason: domain.FinishReasonStop, Usage: &domain.TokenUsage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan domain.StreamDelta, 100)
+			newTestProvider().readStream(io.NopCloser(strings.NewReader(tc.stream)), ch)
+			var got []domain.StreamDelta
+			for d := range ch {
+				got = append(got, d)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
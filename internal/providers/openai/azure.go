@@ -0,0 +1,107 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/providers"
+)
+
+// DefaultAzureAPIVersion 未配置 api-version 时使用的 Azure OpenAI API 版本。
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// NewAzureProvider 创建一个 Azure OpenAI 提供商。
+// baseURL 为资源终结点（如 https://{resource}.openai.azure.com），
+// deployments 将网关模型名映射到 Azure 部署名，未映射的模型直接使用模型名作为部署名。
+func NewAzureProvider(id int64, name, apiKey, baseURL, apiVersion string, deployments map[string]string, client *http.Client, l logger.Logger) *Provider {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	if name == "" {
+		name = "azure-openai"
+	}
+	p := NewProvider(id, name, apiKey, strings.TrimSuffix(baseURL, "/"), client, l)
+	p.typ = "azure-openai"
+	p.chatURL = func(model string) string {
+		deployment := model
+		if d, ok := deployments[model]; ok && d != "" {
+			deployment = d
+		}
+		return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			p.baseURL, url.PathEscape(deployment), url.QueryEscape(apiVersion))
+	}
+	p.setAuth = func(req *http.Request) { req.Header.Set("api-key", p.apiKey) }
+	p.parseError = p.parseAzureError
+	p.listModels = func(context.Context) ([]string, error) {
+		// Azure 没有按资源列出部署的数据面接口，直接返回已配置的模型映射
+		models := make([]string, 0, len(deployments))
+		for m := range deployments {
+			models = append(models, m)
+		}
+		sort.Strings(models)
+		return models, nil
+	}
+	return p
+}
+
+// Azure OpenAI 错误响应类型
+type azureErrorResponse struct {
+	Error struct {
+		Code       string           `json:"code"`
+		Message    string           `json:"message"`
+		Param      string           `json:"param"`
+		Type       string           `json:"type"`
+		InnerError *azureInnerError `json:"innererror,omitempty"`
+	} `json:"error"`
+}
+
+type azureInnerError struct {
+	Code                string                             `json:"code"`
+	ContentFilterResult map[string]azureContentFilterEntry `json:"content_filter_result,omitempty"`
+}
+
+type azureContentFilterEntry struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
+	Detected *bool  `json:"detected,omitempty"`
+}
+
+// parseAzureError 解析 Azure OpenAI 错误响应。
+// 内容过滤（content_filter / ResponsibleAIPolicyViolation）映射为 errs.ErrContentFiltered，
+// 并在错误信息中附带被触发的过滤类别。
+func (p *Provider) parseAzureError(resp *http.Response, body []byte) error {
+	var r azureErrorResponse
+	_ = json.Unmarshal(body, &r)
+
+	e := r.Error
+	ue := providers.NewUpstreamError(p.name, resp.StatusCode, resp.Header, e.Type, e.Code, e.Message)
+
+	inner := e.InnerError
+	if e.Code == "content_filter" || inner != nil && inner.Code == "ResponsibleAIPolicyViolation" {
+		var categories []string
+		if inner != nil {
+			for name, r := range inner.ContentFilterResult {
+				if r.Filtered {
+					if r.Severity != "" {
+						name += "(" + r.Severity + ")"
+					}
+					categories = append(categories, name)
+				}
+			}
+		}
+		sort.Strings(categories)
+		if len(categories) > 0 {
+			ue.Message = fmt.Sprintf("%s [%s]", e.Message, strings.Join(categories, ", "))
+		}
+		ue.Type = "content_filter"
+		ue.Err = errs.ErrContentFiltered
+	}
+	return ue
+}
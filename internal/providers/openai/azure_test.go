@@ -0,0 +1,133 @@
+package openai
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/providers"
+)
+
+func newTestAzureProvider(apiVersion string) *Provider {
+	deployments := map[string]string{"gpt-4o": "prod-gpt4o", "gpt-4o-mini": ""}
+	return NewAzureProvider(1, "", "azure-key", "https://res.openai.azure.com/", apiVersion, deployments, nil, logger.NewNopLogger())
+}
+
+func TestAzureProvider_ChatURL(t *testing.T) {
+	cases := []struct {
+		name       string
+		apiVersion string
+		model      string
+		want       string
+	}{
+		{
+			name:  "mapped deployment and default api version",
+			model: "gpt-4o",
+			want:  "https://res.openai.azure.com/openai/deployments/prod-gpt4o/chat/completions?api-version=" + DefaultAzureAPIVersion,
+		},
+		{
+			name:       "empty mapping falls back to model name",
+			apiVersion: "2025-01-01-preview",
+			model:      "gpt-4o-mini",
+			want:       "https://res.openai.azure.com/openai/deployments/gpt-4o-mini/chat/completions?api-version=2025-01-01-preview",
+		},
+		{
+			name:  "unmapped model is escaped",
+			model: "my model/v2",
+			want:  "https://res.openai.azure.com/openai/deployments/my%20model%2Fv2/chat/completions?api-version=" + DefaultAzureAPIVersion,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestAzureProvider(tc.apiVersion)
+			assert.Equal(t, tc.want, p.chatURL(tc.model))
+		})
+	}
+}
+
+func TestAzureProvider_SetAuth(t *testing.T) {
+	p := newTestAzureProvider("")
+	req, err := http.NewRequest(http.MethodPost, p.chatURL("gpt-4o"), nil)
+	require.NoError(t, err)
+
+	p.setAuth(req)
+	assert.Equal(t, "azure-key", req.Header.Get("api-key"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestAzureProvider_ParseError(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		header    http.Header
+		body      string
+		sentinel  error
+		retryable bool
+		message   string
+		after     time.Duration
+	}{
+		{
+			name:   "content filter",
+			status: http.StatusBadRequest,
+			body: `{"error": {"code": "content_filter", "message": "The response was filtered",
+				"innererror": {"code": "ResponsibleAIPolicyViolation", "content_filter_result": {
+					"violence": {"filtered": true, "severity": "high"},
+					"hate": {"filtered": true, "severity": "medium"},
+					"sexual": {"filtered": false, "severity": "safe"},
+					"jailbreak": {"filtered": true, "detected": true}
+				}}}}`,
+			sentinel: errs.ErrContentFiltered,
+			message:  "The response was filtered [hate(medium), jailbreak, violence(high)]",
+		},
+		{
+			name:     "policy violation without top-level code",
+			status:   http.StatusBadRequest,
+			body:     `{"error": {"message": "blocked", "innererror": {"code": "ResponsibleAIPolicyViolation"}}}`,
+			sentinel: errs.ErrContentFiltered,
+			message:  "blocked",
+		},
+		{
+			name:      "rate limited",
+			status:    http.StatusTooManyRequests,
+			header:    http.Header{"Retry-After": []string{"3"}},
+			body:      `{"error": {"code": "429", "message": "Requests to the deployment have exceeded the rate limit"}}`,
+			sentinel:  errs.ErrRateLimited,
+			retryable: true,
+			message:   "Requests to the deployment have exceeded the rate limit",
+			after:     3 * time.Second,
+		},
+		{
+			name:      "server error with non-json body",
+			status:    http.StatusInternalServerError,
+			body:      `<html>internal error</html>`,
+			sentinel:  errs.ErrProviderError,
+			retryable: true,
+		},
+		{
+			name:     "bad request",
+			status:   http.StatusBadRequest,
+			body:     `{"error": {"code": "DeploymentNotFound", "message": "The API deployment for this resource does not exist"}}`,
+			sentinel: errs.ErrProviderError,
+			message:  "The API deployment for this resource does not exist",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestAzureProvider("")
+			err := p.parseError(&http.Response{StatusCode: tc.status, Header: tc.header}, []byte(tc.body))
+
+			var ue *providers.UpstreamError
+			require.ErrorAs(t, err, &ue)
+			assert.ErrorIs(t, err, tc.sentinel)
+			assert.Equal(t, tc.status, ue.StatusCode)
+			assert.Equal(t, tc.retryable, providers.IsRetryable(err))
+			assert.Equal(t, tc.message, ue.Message)
+			assert.Equal(t, tc.after, ue.RetryAfter)
+		})
+	}
+}
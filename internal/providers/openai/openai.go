@@ -14,34 +14,57 @@ import (
 	"ai-gateway/internal/pkg/logger"
 
 	"ai-gateway/internal/domain"
-	"ai-gateway/internal/errs"
+	"ai-gateway/internal/providers"
 )
 
 // Provider 为 OpenAI API 实现 Provider 接口。
+// 同一套请求/响应映射也用于 Azure OpenAI，差异（URL、认证、错误格式）通过下面的钩子注入。
 type Provider struct {
+	id      int64
+	name    string
+	typ     string
 	apiKey  string
 	baseURL string
 	client  *http.Client
 	logger  logger.Logger
+
+	chatURL    func(model string) string
+	setAuth    func(req *http.Request)
+	parseError func(resp *http.Response, body []byte) error
+	listModels func(ctx context.Context) ([]string, error)
 }
 
 // NewProvider 创建一个新的 OpenAI 提供商。
-func NewProvider(apiKey, baseURL string, client *http.Client, l logger.Logger) *Provider {
+// id 和 name 标识已配置的供应商实例，name 为空时使用 "openai"。
+func NewProvider(id int64, name, apiKey, baseURL string, client *http.Client, l logger.Logger) *Provider {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
 	if client == nil {
 		client = http.DefaultClient
 	}
-	return &Provider{
+	if name == "" {
+		name = "openai"
+	}
+	p := &Provider{
+		id:      id,
+		name:    name,
+		typ:     "openai",
 		apiKey:  apiKey,
 		baseURL: baseURL,
 		client:  client,
-		logger:  l.With(logger.String("provider", "openai")),
+		logger:  l.With(logger.String("provider", name)),
 	}
+	p.chatURL = func(string) string { return p.baseURL + "/chat/completions" }
+	p.setAuth = func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+p.apiKey) }
+	p.parseError = p.parseOpenAIError
+	p.listModels = p.listOpenAIModels
+	return p
 }
 
-func (p *Provider) Name() string { return "openai" }
+func (p *Provider) ID() int64    { return p.id }
+func (p *Provider) Name() string { return p.name }
+func (p *Provider) Type() string { return p.typ }
 
 func (p *Provider) SupportsStreaming() bool { return true }
 func (p *Provider) SupportsTools() bool     { return true }
@@ -154,7 +177,7 @@ func (p *Provider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.C
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.chatURL(req.Model), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -172,7 +195,7 @@ func (p *Provider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.C
 			logger.Int("status", resp.StatusCode),
 			logger.String("body", string(respBody)),
 		)
-		return nil, fmt.Errorf("%w: status %d", errs.ErrProviderError, resp.StatusCode)
+		return nil, p.parseError(resp, respBody)
 	}
 
 	var oaiResp chatResponse
@@ -194,7 +217,7 @@ func (p *Provider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-c
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.chatURL(req.Model), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -212,7 +235,7 @@ func (p *Provider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-c
 			logger.Int("status", resp.StatusCode),
 			logger.String("body", string(respBody)),
 		)
-		return nil, fmt.Errorf("%w: status %d", errs.ErrProviderError, resp.StatusCode)
+		return nil, p.parseError(resp, respBody)
 	}
 
 	ch := make(chan domain.StreamDelta, 100)
@@ -310,7 +333,7 @@ func (p *Provider) deltaFromChoice(c *choice, u *usage) domain.StreamDelta {
 
 func (p *Provider) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	p.setAuth(req)
 }
 
 func (p *Provider) toOpenAIRequest(req *domain.ChatRequest) *chatRequest {
@@ -516,6 +539,10 @@ func (p *Provider) fromOpenAIResponse(resp *chatResponse) *domain.ChatResponse {
 
 // ListModels 返回可用模型列表。
 func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	return p.listModels(ctx)
+}
+
+func (p *Provider) listOpenAIModels(ctx context.Context) ([]string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
 	if err != nil {
 		return nil, err
@@ -543,3 +570,23 @@ func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
 	}
 	return models, nil
 }
+
+// errorResponse OpenAI 错误响应：{"error":{"message":"...","type":"...","code":"..."}}
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"` // 字符串、数字或 null
+	} `json:"error"`
+}
+
+// parseOpenAIError 将非 200 响应转换为带类型的上游错误。
+func (p *Provider) parseOpenAIError(resp *http.Response, body []byte) error {
+	var e errorResponse
+	_ = json.Unmarshal(body, &e)
+	code := ""
+	if e.Error.Code != nil {
+		code = fmt.Sprint(e.Error.Code)
+	}
+	return providers.NewUpstreamError(p.name, resp.StatusCode, resp.Header, e.Error.Type, code, e.Error.Message)
+}
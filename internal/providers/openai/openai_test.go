@@ -0,0 +1,50 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/providers"
+)
+
+func TestProvider_ChatEOF(t *testing.T) {
+	cases := []struct {
+		name      string
+		handler   http.HandlerFunc
+		retryable bool
+	}{
+		{
+			// 上游已返回 200，重试可能重复生成与计费
+			name:    "eof after 200",
+			handler: func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) },
+		},
+		{
+			name: "connection closed before response",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					_ = conn.Close()
+				}
+			},
+			retryable: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			p := NewProvider(1, "", "key", srv.URL, srv.Client(), logger.NewNopLogger())
+			_, err := p.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+			require.Error(t, err)
+			assert.Equal(t, tc.retryable, providers.IsRetryable(err), err.Error())
+		})
+	}
+}
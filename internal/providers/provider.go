@@ -10,9 +10,15 @@ import (
 
 // Provider 是所有 LLM 提供商必须实现的接口。
 type Provider interface {
-	// Name 返回提供商名称（例如 "openai"、"anthropic"）
+	// ID 返回供应商实例在数据库中的 ID，未持久化的实例返回 0。
+	ID() int64
+
+	// Name 返回已配置的供应商实例名称（例如 "openai-us-east"），用于归属成本、错误与延迟。
 	Name() string
 
+	// Type 返回提供商类型（例如 "openai"、"anthropic"、"gemini"、"azure-openai"）
+	Type() string
+
 	// Chat 发送聊天补全请求并返回响应。
 	// 用于非流式请求。
 	Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository/cache"
 	"ai-gateway/internal/repository/dao"
 )
@@ -21,7 +22,7 @@ type APIKeyRepository interface {
 	ListByUserID(ctx context.Context, userID int64) ([]domain.APIKey, error)
 	Validate(ctx context.Context, key string) (bool, *domain.APIKey, error)
 	UpdateLastUsed(ctx context.Context, id int64) error
-	IncrementUsage(ctx context.Context, id int64, amount float64) error
+	IncrementUsage(ctx context.Context, id int64, amount money.Amount) error
 }
 
 // apiKeyRepository 是 APIKeyRepository 的默认实现。
@@ -189,6 +190,6 @@ func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id int64) error {
 	return r.dao.UpdateLastUsed(ctx, id)
 }
 
-func (r *apiKeyRepository) IncrementUsage(ctx context.Context, id int64, amount float64) error {
+func (r *apiKeyRepository) IncrementUsage(ctx context.Context, id int64, amount money.Amount) error {
 	return r.dao.IncrementUsage(ctx, id, amount)
 }
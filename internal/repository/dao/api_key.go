@@ -6,21 +6,23 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // APIKey 是网关 API 密钥的数据库模型。
 type APIKey struct {
-	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID     int64      `gorm:"index;not null" json:"userId"`
-	Key        string     `gorm:"uniqueIndex;size:128;not null" json:"key"` // 原始 Key (通常不存，但此处为了展示暂存)
-	KeyHash    string     `gorm:"uniqueIndex;size:128;not null" json:"-"`   // Key 的哈希值，用于验证
-	Name       string     `gorm:"size:64;not null" json:"name"`
-	Enabled    bool       `gorm:"default:true;index" json:"enabled"`
-	Quota      *float64   `gorm:"default:null" json:"quota"`   // 额度限制
-	UsedAmount float64    `gorm:"default:0" json:"usedAmount"` // 已使用额度
-	ExpiresAt  *time.Time `gorm:"default:null" json:"expiresAt,omitempty"`
-	LastUsedAt *time.Time `gorm:"default:null" json:"lastUsedAt,omitempty"`
-	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	ID         int64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int64         `gorm:"index;not null" json:"userId"`
+	Key        string        `gorm:"uniqueIndex;size:128;not null" json:"key"` // 原始 Key (通常不存，但此处为了展示暂存)
+	KeyHash    string        `gorm:"uniqueIndex;size:128;not null" json:"-"`   // Key 的哈希值，用于验证
+	Name       string        `gorm:"size:64;not null" json:"name"`
+	Enabled    bool          `gorm:"default:true;index" json:"enabled"`
+	Quota      *money.Amount `gorm:"type:decimal(20,6);default:null" json:"quota"`            // 额度限制
+	UsedAmount money.Amount  `gorm:"type:decimal(20,6);not null;default:0" json:"usedAmount"` // 已使用额度
+	ExpiresAt  *time.Time    `gorm:"default:null" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time    `gorm:"default:null" json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time     `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 // TableName 返回 APIKey 的表名。
@@ -38,7 +40,7 @@ type APIKeyDAO interface {
 	List(ctx context.Context) ([]APIKey, error)
 	ListByUserID(ctx context.Context, userID int64) ([]APIKey, error)
 	UpdateLastUsed(ctx context.Context, id int64) error
-	IncrementUsage(ctx context.Context, id int64, amount float64) error
+	IncrementUsage(ctx context.Context, id int64, amount money.Amount) error
 }
 
 // GormAPIKeyDAO 是 APIKeyDAO 的 GORM 实现。
@@ -80,7 +82,7 @@ func (d *GormAPIKeyDAO) List(ctx context.Context) ([]APIKey, error) {
 
 func (d *GormAPIKeyDAO) GetByID(ctx context.Context, id int64) (*APIKey, error) {
 	var k APIKey
-	err := d.db.WithContext(ctx).First(&k, id).Error
+	err := conn(ctx, d.db).First(&k, id).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -97,8 +99,8 @@ func (d *GormAPIKeyDAO) UpdateLastUsed(ctx context.Context, id int64) error {
 	return d.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
 }
 
-func (d *GormAPIKeyDAO) IncrementUsage(ctx context.Context, id int64, amount float64) error {
-	return d.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("used_amount", gorm.Expr("used_amount + ?", amount)).Error
+func (d *GormAPIKeyDAO) IncrementUsage(ctx context.Context, id int64, amount money.Amount) error {
+	return conn(ctx, d.db).Model(&APIKey{}).Where("id = ?", id).Update("used_amount", gorm.Expr("used_amount + CAST(? AS DECIMAL(20,6))", amount)).Error
 }
 
 var _ APIKeyDAO = (*GormAPIKeyDAO)(nil)
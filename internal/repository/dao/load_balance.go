@@ -13,7 +13,7 @@ type LoadBalanceGroup struct {
 	ID           int64     `gorm:"primaryKey;autoIncrement"`
 	Name         string    `gorm:"uniqueIndex;size:64;not null"`
 	ModelPattern string    `gorm:"size:128;not null;index"`
-	Strategy     string    `gorm:"size:32;not null"` // round-robin, random, failover, weighted
+	Strategy     string    `gorm:"size:32;not null"` // round-robin, random, failover, weighted, least-outstanding, peak-ewma, consistent-hash
 	Enabled      bool      `gorm:"default:true;index"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
@@ -114,7 +114,7 @@ func (d *GormLoadBalanceDAO) GetMembers(ctx context.Context, groupID int64) ([]L
 	var members []LoadBalanceMember
 	err := d.db.WithContext(ctx).
 		Where("group_id = ?", groupID).
-		Order("priority ASC").
+		Order("priority DESC").
 		Find(&members).Error
 	return members, err
 }
@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ModelCapability 模型能力数据库模型
+type ModelCapability struct {
+	ID               int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ModelPattern     string    `gorm:"size:128;not null;uniqueIndex:idx_model_provider" json:"modelPattern"`
+	ProviderName     string    `gorm:"size:64;not null;default:'';uniqueIndex:idx_model_provider" json:"providerName"`
+	Vision           bool      `gorm:"default:false" json:"vision"`
+	Tools            bool      `gorm:"default:false" json:"tools"`
+	Thinking         bool      `gorm:"default:false" json:"thinking"`
+	JSONSchema       bool      `gorm:"column:json_schema;default:false" json:"jsonSchema"`
+	ContextWindow    int       `gorm:"default:0" json:"contextWindow"`
+	MaxOutputTokens  int       `gorm:"default:0" json:"maxOutputTokens"`
+	LongContextModel string    `gorm:"size:128;not null;default:''" json:"longContextModel"`
+	Enabled          bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (ModelCapability) TableName() string {
+	return "model_capabilities"
+}
+
+// ModelCapabilityDAO 模型能力 DAO 接口
+type ModelCapabilityDAO interface {
+	Create(ctx context.Context, c *ModelCapability) error
+	Update(ctx context.Context, c *ModelCapability) error
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*ModelCapability, error)
+	List(ctx context.Context) ([]ModelCapability, error)
+	GetAllEnabled(ctx context.Context) ([]ModelCapability, error)
+}
+
+type GormModelCapabilityDAO struct {
+	db *gorm.DB
+}
+
+func NewGormModelCapabilityDAO(db *gorm.DB) ModelCapabilityDAO {
+	return &GormModelCapabilityDAO{db: db}
+}
+
+func (d *GormModelCapabilityDAO) Create(ctx context.Context, c *ModelCapability) error {
+	return d.db.WithContext(ctx).Create(c).Error
+}
+
+func (d *GormModelCapabilityDAO) Update(ctx context.Context, c *ModelCapability) error {
+	return d.db.WithContext(ctx).Save(c).Error
+}
+
+func (d *GormModelCapabilityDAO) Delete(ctx context.Context, id int64) error {
+	return d.db.WithContext(ctx).Delete(&ModelCapability{}, id).Error
+}
+
+func (d *GormModelCapabilityDAO) GetByID(ctx context.Context, id int64) (*ModelCapability, error) {
+	var c ModelCapability
+	err := d.db.WithContext(ctx).First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (d *GormModelCapabilityDAO) List(ctx context.Context) ([]ModelCapability, error) {
+	var list []ModelCapability
+	err := d.db.WithContext(ctx).Order("model_pattern ASC, provider_name ASC").Find(&list).Error
+	return list, err
+}
+
+func (d *GormModelCapabilityDAO) GetAllEnabled(ctx context.Context) ([]ModelCapability, error) {
+	var list []ModelCapability
+	err := d.db.WithContext(ctx).Where("enabled = ?", true).Find(&list).Error
+	return list, err
+}
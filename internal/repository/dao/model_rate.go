@@ -5,17 +5,19 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // ModelRate 模型费率数据库模型
 type ModelRate struct {
-	ID              int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ModelPattern    string    `gorm:"size:128;not null;uniqueIndex" json:"modelPattern"`
-	PromptPrice     float64   `gorm:"type:decimal(20,8);default:0" json:"promptPrice"`
-	CompletionPrice float64   `gorm:"type:decimal(20,8);default:0" json:"completionPrice"`
-	Enabled         bool      `gorm:"default:true" json:"enabled"`
-	CreatedAt       time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+	ID              int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	ModelPattern    string       `gorm:"size:128;not null;uniqueIndex" json:"modelPattern"`
+	PromptPrice     money.Amount `gorm:"type:decimal(20,6);default:0" json:"promptPrice"`
+	CompletionPrice money.Amount `gorm:"type:decimal(20,6);default:0" json:"completionPrice"`
+	Enabled         bool         `gorm:"default:true" json:"enabled"`
+	CreatedAt       time.Time    `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time    `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
 func (ModelRate) TableName() string {
@@ -10,17 +10,30 @@ import (
 
 // Provider 是提供商配置的数据库模型。
 type Provider struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement"`
-	Name      string    `gorm:"uniqueIndex;size:64;not null"`
-	Type      string    `gorm:"size:32;not null"` // openai, anthropic
-	APIKey    string    `gorm:"size:512;not null"`
-	BaseURL   string    `gorm:"size:256;not null"`
-	Models    string    `gorm:"type:text;serializer:json"` // JSON encoded list of models
-	TimeoutMs int       `gorm:"default:60000"`
-	IsDefault bool      `gorm:"default:false"`
-	Enabled   bool      `gorm:"default:true;index"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID                      int64     `gorm:"primaryKey;autoIncrement"`
+	Name                    string    `gorm:"uniqueIndex;size:64;not null"`
+	Type                    string    `gorm:"size:32;not null"`   // openai, anthropic, gemini, azure-openai
+	APIKey                  string    `gorm:"size:1024;not null"` // 配置主密钥后为信封加密的密文
+	APIKeys                 string    `gorm:"type:text"`          // JSON encoded key pool, keys encrypted like APIKey
+	KeyStrategy             string    `gorm:"size:32"`            // round_robin, least_used
+	BaseURL                 string    `gorm:"size:256;not null"`
+	Models                  string    `gorm:"type:text;serializer:json"` // JSON encoded list of models
+	APIVersion              string    `gorm:"size:32"`                   // Azure OpenAI api-version
+	Deployments             string    `gorm:"type:text;serializer:json"` // JSON encoded model -> deployment map
+	TimeoutMs               int       `gorm:"default:60000"`
+	ConnectTimeoutMs        int       `gorm:"default:0"`
+	ResponseHeaderTimeoutMs int       `gorm:"default:0"`
+	MaxIdleConns            int       `gorm:"default:0"`
+	MaxIdleConnsPerHost     int       `gorm:"default:0"`
+	ProxyURL                string    `gorm:"size:256"`
+	RetryMaxAttempts        int       `gorm:"default:0"`
+	RetryInitialDelayMs     int       `gorm:"default:0"`
+	RetryMaxDelayMs         int       `gorm:"default:0"`
+	FirstTokenTimeoutMs     int       `gorm:"default:0"`
+	IsDefault               bool      `gorm:"default:false"`
+	Enabled                 bool      `gorm:"default:true;index"`
+	CreatedAt               time.Time `gorm:"autoCreateTime"`
+	UpdatedAt               time.Time `gorm:"autoUpdateTime"`
 }
 
 // TableName 返回 Provider 的表名。
@@ -36,6 +49,10 @@ type ProviderDAO interface {
 	GetByID(ctx context.Context, id int64) (*Provider, error)
 	GetByName(ctx context.Context, name string) (*Provider, error)
 	List(ctx context.Context) ([]Provider, error)
+	// ListAll 返回包括已禁用在内的全部供应商。
+	ListAll(ctx context.Context) ([]Provider, error)
+	// UpdateSecrets 只更新供应商的上游 Key 列，不修改 updated_at。
+	UpdateSecrets(ctx context.Context, id int64, apiKey, apiKeys string) error
 	GetDefaultByType(ctx context.Context, providerType string) (*Provider, error)
 }
 
@@ -102,6 +119,17 @@ func (d *GormProviderDAO) List(ctx context.Context) ([]Provider, error) {
 	return providers, err
 }
 
+func (d *GormProviderDAO) ListAll(ctx context.Context) ([]Provider, error) {
+	var providers []Provider
+	err := d.db.WithContext(ctx).Order("id ASC").Find(&providers).Error
+	return providers, err
+}
+
+func (d *GormProviderDAO) UpdateSecrets(ctx context.Context, id int64, apiKey, apiKeys string) error {
+	return d.db.WithContext(ctx).Model(&Provider{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"api_key": apiKey, "api_keys": apiKeys}).Error
+}
+
 func (d *GormProviderDAO) GetDefaultByType(ctx context.Context, providerType string) (*Provider, error) {
 	var p Provider
 	err := d.db.WithContext(ctx).
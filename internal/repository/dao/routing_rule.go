@@ -11,11 +11,14 @@ import (
 // RoutingRule 是路由规则的数据库模型。
 type RoutingRule struct {
 	ID           int64     `gorm:"primaryKey;autoIncrement"`
-	RuleType     string    `gorm:"size:16;not null;index"` // exact, prefix, wildcard
+	RuleType     string    `gorm:"size:16;not null;index"` // exact, prefix, wildcard, regex
 	Pattern      string    `gorm:"size:128;not null;index"`
 	ProviderName string    `gorm:"size:64;not null"`
 	ActualModel  string    `gorm:"size:128"`
 	Priority     int       `gorm:"default:0;index"`
+	Fallbacks    string    `gorm:"type:text"` // JSON encoded fallback chain
+	Variants     string    `gorm:"type:text"` // JSON encoded A/B variants
+	Shadow       string    `gorm:"type:text"` // JSON encoded shadow traffic target
 	Enabled      bool      `gorm:"default:true;index"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShadowResult 影子流量对比记录数据库模型
+type ShadowResult struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	RequestID string `gorm:"size:64;index"`
+	Model     string `gorm:"size:128;not null;index"`
+
+	PrimaryProvider     string `gorm:"size:64;not null"`
+	PrimaryModel        string `gorm:"size:128;not null"`
+	PrimaryLatencyMs    int    `gorm:"default:0"`
+	PrimaryInputTokens  int    `gorm:"default:0"`
+	PrimaryOutputTokens int    `gorm:"default:0"`
+	PrimaryContent      string `gorm:"type:mediumtext"`
+	PrimaryError        string `gorm:"size:1024;not null;default:''"`
+
+	ShadowProvider     string `gorm:"size:64;not null;index"`
+	ShadowModel        string `gorm:"size:128;not null"`
+	ShadowLatencyMs    int    `gorm:"default:0"`
+	ShadowInputTokens  int    `gorm:"default:0"`
+	ShadowOutputTokens int    `gorm:"default:0"`
+	ShadowContent      string `gorm:"type:mediumtext"`
+	ShadowError        string `gorm:"size:1024;not null;default:''"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (ShadowResult) TableName() string {
+	return "shadow_results"
+}
+
+// ShadowStats 影子流量对比汇总 (DAO)
+type ShadowStats struct {
+	Model                  string
+	ShadowProvider         string
+	ShadowModel            string
+	Requests               int64
+	PrimaryErrors          int64
+	ShadowErrors           int64
+	PrimaryAvgLatencyMs    int64
+	ShadowAvgLatencyMs     int64
+	PrimaryAvgOutputTokens int64
+	ShadowAvgOutputTokens  int64
+}
+
+// ShadowResultDAO 影子流量对比记录 DAO 接口
+type ShadowResultDAO interface {
+	Create(ctx context.Context, r *ShadowResult) error
+	List(ctx context.Context, page, pageSize int, model string) ([]ShadowResult, int64, error)
+	Stats(ctx context.Context, days int) ([]ShadowStats, error)
+}
+
+type GormShadowResultDAO struct {
+	db *gorm.DB
+}
+
+func NewGormShadowResultDAO(db *gorm.DB) ShadowResultDAO {
+	return &GormShadowResultDAO{db: db}
+}
+
+func (d *GormShadowResultDAO) Create(ctx context.Context, r *ShadowResult) error {
+	return d.db.WithContext(ctx).Create(r).Error
+}
+
+func (d *GormShadowResultDAO) List(ctx context.Context, page, pageSize int, model string) ([]ShadowResult, int64, error) {
+	var results []ShadowResult
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&ShadowResult{})
+	if model != "" {
+		query = query.Where("model = ?", model)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&results).Error
+	return results, total, err
+}
+
+func (d *GormShadowResultDAO) Stats(ctx context.Context, days int) ([]ShadowStats, error) {
+	var stats []ShadowStats
+	err := d.db.WithContext(ctx).Model(&ShadowResult{}).
+		Select(`
+			model,
+			shadow_provider,
+			shadow_model,
+			COUNT(*) as requests,
+			CAST(COALESCE(SUM(primary_error <> ''), 0) AS UNSIGNED) as primary_errors,
+			CAST(COALESCE(SUM(shadow_error <> ''), 0) AS UNSIGNED) as shadow_errors,
+			CAST(COALESCE(AVG(primary_latency_ms), 0) AS UNSIGNED) as primary_avg_latency_ms,
+			CAST(COALESCE(AVG(shadow_latency_ms), 0) AS UNSIGNED) as shadow_avg_latency_ms,
+			CAST(COALESCE(AVG(primary_output_tokens), 0) AS UNSIGNED) as primary_avg_output_tokens,
+			CAST(COALESCE(AVG(shadow_output_tokens), 0) AS UNSIGNED) as shadow_avg_output_tokens
+		`).
+		Where("created_at >= DATE_SUB(NOW(), INTERVAL ? DAY)", days).
+		Group("model, shadow_provider, shadow_model").
+		Order("requests DESC").
+		Scan(&stats).Error
+	return stats, err
+}
+
+var _ ShadowResultDAO = (*GormShadowResultDAO)(nil)
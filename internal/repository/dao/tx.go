@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey 是 context 中保存事务 *gorm.DB 的键
+type txKey struct{}
+
+// transaction 在一个数据库事务中执行 fn，事务保存在传给 fn 的 ctx 中，
+// 各 DAO 通过 conn 取连接，因此 fn 内跨表的写入都在同一事务中提交或回滚。
+func transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	return conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn 返回 ctx 中的事务连接，不在事务中时返回普通连接
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -6,23 +6,31 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // UsageLog 是使用记录的数据库模型。
 type UsageLog struct {
-	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID       int64     `gorm:"index;not null" json:"userId"`
-	APIKeyID     *int64    `gorm:"index" json:"apiKeyId,omitempty"`
-	Model        string    `gorm:"size:64" json:"model"`
-	Provider     string    `gorm:"size:32" json:"provider"`
-	InputTokens  int       `gorm:"default:0" json:"inputTokens"`
-	OutputTokens int       `gorm:"default:0" json:"outputTokens"`
-	LatencyMs    int       `gorm:"" json:"latencyMs"`
-	StatusCode   int       `gorm:"" json:"statusCode"`
-	ClientIP     string    `gorm:"size:45;index" json:"clientIp"`
-	UserAgent    string    `gorm:"size:512" json:"userAgent"`
-	RequestID    string    `gorm:"size:64" json:"requestId"`
-	CreatedAt    time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+	ID           int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       int64        `gorm:"index;not null" json:"userId"`
+	APIKeyID     *int64       `gorm:"index" json:"apiKeyId,omitempty"`
+	Model        string       `gorm:"size:64" json:"model"`
+	Provider     string       `gorm:"size:64;index" json:"provider"`
+	ProviderID   int64        `gorm:"index" json:"providerId"`
+	FallbackHop  int          `gorm:"default:0" json:"fallbackHop"`
+	Variant      string       `gorm:"size:64;index" json:"variant"`
+	InputTokens  int          `gorm:"default:0" json:"inputTokens"`
+	OutputTokens int          `gorm:"default:0" json:"outputTokens"`
+	Cost         money.Amount `gorm:"type:decimal(20,6);not null;default:0" json:"cost"`
+	Charged      money.Amount `gorm:"type:decimal(20,6);not null;default:0" json:"charged"`
+	LatencyMs    int          `gorm:"" json:"latencyMs"`
+	StatusCode   int          `gorm:"" json:"statusCode"`
+	ClientIP     string       `gorm:"size:45;index" json:"clientIp"`
+	UserAgent    string       `gorm:"size:512" json:"userAgent"`
+	RequestID    string       `gorm:"uniqueIndex;size:64" json:"requestId"`
+	CreatedAt    time.Time    `gorm:"autoCreateTime;index" json:"createdAt"`
 }
 
 // TableName 返回 UsageLog 的表名。
@@ -58,7 +66,12 @@ type LeaderboardEntry struct {
 
 // UsageLogDAO 定义使用记录的数据访问操作。
 type UsageLogDAO interface {
-	Create(ctx context.Context, log *UsageLog) error
+	// CreateBatch 批量写入使用记录，已有相同请求 ID 的记录被忽略，返回实际写入的请求 ID
+	CreateBatch(ctx context.Context, logs []*UsageLog) ([]string, error)
+	// FindRequestIDs 返回 requestIDs 中已有使用记录的请求 ID
+	FindRequestIDs(ctx context.Context, requestIDs []string) ([]string, error)
+	// UpdateCharged 更新请求实际收取的费用
+	UpdateCharged(ctx context.Context, requestID string, charged money.Amount) error
 	GetStatsByUserID(ctx context.Context, userID int64) (*UsageStats, error)
 	GetDailyUsageByUserID(ctx context.Context, userID int64, days int) ([]DailyUsage, error)
 
@@ -79,8 +92,31 @@ func NewGormUsageLogDAO(db *gorm.DB) UsageLogDAO {
 	return &GormUsageLogDAO{db: db}
 }
 
-func (d *GormUsageLogDAO) Create(ctx context.Context, log *UsageLog) error {
-	return d.db.WithContext(ctx).Create(log).Error
+func (d *GormUsageLogDAO) CreateBatch(ctx context.Context, logs []*UsageLog) ([]string, error) {
+	// 依赖 request_id 唯一索引去重。逐条写入以便通过影响行数得知哪些记录被忽略：
+	// 并发事务已写入同一请求 ID 时，本事务的写入会等待其提交后被忽略
+	db := conn(ctx, d.db)
+	inserted := make([]string, 0, len(logs))
+	for _, log := range logs {
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(log)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected > 0 {
+			inserted = append(inserted, log.RequestID)
+		}
+	}
+	return inserted, nil
+}
+
+func (d *GormUsageLogDAO) FindRequestIDs(ctx context.Context, requestIDs []string) ([]string, error) {
+	var found []string
+	err := conn(ctx, d.db).Model(&UsageLog{}).Where("request_id IN ?", requestIDs).Pluck("request_id", &found).Error
+	return found, err
+}
+
+func (d *GormUsageLogDAO) UpdateCharged(ctx context.Context, requestID string, charged money.Amount) error {
+	return conn(ctx, d.db).Model(&UsageLog{}).Where("request_id = ?", requestID).UpdateColumn("charged", charged).Error
 }
 
 func (d *GormUsageLogDAO) GetStatsByUserID(ctx context.Context, userID int64) (*UsageStats, error) {
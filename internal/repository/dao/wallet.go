@@ -5,15 +5,18 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"ai-gateway/internal/pkg/money"
 )
 
 // Wallet 钱包数据库模型
 type Wallet struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID    int64     `gorm:"uniqueIndex;not null" json:"userId"`
-	Balance   float64   `gorm:"type:decimal(20,8);default:0" json:"balance"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+	ID        int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64        `gorm:"uniqueIndex;not null" json:"userId"`
+	Balance   money.Amount `gorm:"type:decimal(20,6);not null;default:0" json:"balance"`
+	CreatedAt time.Time    `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
 func (Wallet) TableName() string {
@@ -22,30 +25,70 @@ func (Wallet) TableName() string {
 
 // WalletTransaction 钱包交易记录数据库模型
 type WalletTransaction struct {
-	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	WalletID      int64     `gorm:"index;not null" json:"walletId"`
-	Type          string    `gorm:"size:32;not null" json:"type"`
-	Amount        float64   `gorm:"type:decimal(20,8);not null" json:"amount"`
-	BalanceBefore float64   `gorm:"type:decimal(20,8);not null" json:"balanceBefore"`
-	BalanceAfter  float64   `gorm:"type:decimal(20,8);not null" json:"balanceAfter"`
-	ReferenceID   string    `gorm:"size:128" json:"referenceId"`
-	Description   string    `gorm:"size:255" json:"description"`
-	CreatedAt     time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	ID            int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	WalletID      int64        `gorm:"index;not null" json:"walletId"`
+	Type          string       `gorm:"size:32;not null" json:"type"`
+	Amount        money.Amount `gorm:"type:decimal(20,6);not null" json:"amount"`
+	BalanceBefore money.Amount `gorm:"type:decimal(20,6);not null" json:"balanceBefore"`
+	BalanceAfter  money.Amount `gorm:"type:decimal(20,6);not null" json:"balanceAfter"`
+	ReferenceID   string       `gorm:"size:128" json:"referenceId"`
+	Description   string       `gorm:"size:255" json:"description"`
+	CreatedAt     time.Time    `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 func (WalletTransaction) TableName() string {
 	return "wallet_transactions"
 }
 
+// WalletHold 钱包预授权数据库模型
+type WalletHold struct {
+	ID        int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	RequestID string       `gorm:"uniqueIndex;size:128;not null" json:"requestId"`
+	UserID    int64        `gorm:"not null" json:"userId"`
+	WalletID  int64        `gorm:"index:idx_wallet_holds_wallet_status;not null" json:"walletId"`
+	APIKeyID  *int64       `gorm:"index" json:"apiKeyId"`
+	Amount    money.Amount `gorm:"type:decimal(20,6);not null" json:"amount"`
+	Status    string       `gorm:"size:16;not null;index:idx_wallet_holds_wallet_status" json:"status"`
+	ExpiresAt time.Time    `gorm:"not null" json:"expiresAt"`
+	CreatedAt time.Time    `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (WalletHold) TableName() string {
+	return "wallet_holds"
+}
+
+// 预授权状态，与 domain.HoldStatus 取值一致
+const (
+	holdStatusActive  = "active"
+	holdStatusExpired = "expired"
+)
+
 // WalletDAO 钱包 DAO 接口
 type WalletDAO interface {
 	GetByUserID(ctx context.Context, userID int64) (*Wallet, error)
+	// GetByUserIDForUpdate 查询并锁定钱包行（SELECT ... FOR UPDATE），须在 Transaction 内调用
+	GetByUserIDForUpdate(ctx context.Context, userID int64) (*Wallet, error)
+	// Create 创建钱包，用户已有钱包时不做任何操作
 	Create(ctx context.Context, wallet *Wallet) error
-	UpdateBalance(ctx context.Context, walletID int64, amount float64) error
+	UpdateBalance(ctx context.Context, walletID int64, amount money.Amount) error
 	CreateTransaction(ctx context.Context, tx *WalletTransaction) error
 	GetTransactions(ctx context.Context, walletID int64, limit, offset int) ([]WalletTransaction, int64, error)
-	// Transaction 支持事务
+	// Transaction 在一个数据库事务中执行 fn，fn 内使用传入的 ctx 调用的 DAO 方法都在该事务中执行
 	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	CreateHold(ctx context.Context, hold *WalletHold) error
+	GetHoldByRequestID(ctx context.Context, requestID string) (*WalletHold, error)
+	// UpdateHoldStatus 仅当预授权处于 from 状态时更新为 to，返回是否更新
+	UpdateHoldStatus(ctx context.Context, id int64, from []string, to string) (bool, error)
+	// ExtendHold 将请求的有效预授权的到期时间改为 expiresAt，返回是否更新
+	ExtendHold(ctx context.Context, requestID string, expiresAt time.Time) (bool, error)
+	// SumActiveHolds 统计钱包在 now 时仍有效的预授权金额
+	SumActiveHolds(ctx context.Context, walletID int64, now time.Time) (money.Amount, error)
+	// SumActiveHoldsByAPIKey 统计 API Key 在 now 时仍有效的预授权金额
+	SumActiveHoldsByAPIKey(ctx context.Context, apiKeyID int64, now time.Time) (money.Amount, error)
+	// ExpireHolds 将钱包在 now 时已过期的有效预授权标记为过期，返回标记条数
+	ExpireHolds(ctx context.Context, walletID int64, now time.Time) (int64, error)
 }
 
 // GormWalletDAO GORM 实现
@@ -59,7 +102,17 @@ func NewGormWalletDAO(db *gorm.DB) WalletDAO {
 
 func (d *GormWalletDAO) GetByUserID(ctx context.Context, userID int64) (*Wallet, error) {
 	var wallet Wallet
-	err := d.db.WithContext(ctx).Where("user_id = ?", userID).First(&wallet).Error
+	err := conn(ctx, d.db).Where("user_id = ?", userID).First(&wallet).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (d *GormWalletDAO) GetByUserIDForUpdate(ctx context.Context, userID int64) (*Wallet, error) {
+	var wallet Wallet
+	err := conn(ctx, d.db).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&wallet).Error
 	if err != nil {
 		return nil, err
 	}
@@ -67,23 +120,24 @@ func (d *GormWalletDAO) GetByUserID(ctx context.Context, userID int64) (*Wallet,
 }
 
 func (d *GormWalletDAO) Create(ctx context.Context, wallet *Wallet) error {
-	return d.db.WithContext(ctx).Create(wallet).Error
+	// 并发创建同一用户的钱包时依赖 user_id 唯一索引去重
+	return conn(ctx, d.db).Clauses(clause.OnConflict{DoNothing: true}).Create(wallet).Error
 }
 
-func (d *GormWalletDAO) UpdateBalance(ctx context.Context, walletID int64, amount float64) error {
-	// 使用 GORM 的 update 语句原子更新
-	return d.db.WithContext(ctx).Model(&Wallet{}).Where("id = ?", walletID).
-		UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
+func (d *GormWalletDAO) UpdateBalance(ctx context.Context, walletID int64, amount money.Amount) error {
+	// 显式转换为 DECIMAL，避免 MySQL 把字符串参数按浮点数参与运算
+	return conn(ctx, d.db).Model(&Wallet{}).Where("id = ?", walletID).
+		UpdateColumn("balance", gorm.Expr("balance + CAST(? AS DECIMAL(20,6))", amount)).Error
 }
 
 func (d *GormWalletDAO) CreateTransaction(ctx context.Context, tx *WalletTransaction) error {
-	return d.db.WithContext(ctx).Create(tx).Error
+	return conn(ctx, d.db).Create(tx).Error
 }
 
 func (d *GormWalletDAO) GetTransactions(ctx context.Context, walletID int64, limit, offset int) ([]WalletTransaction, int64, error) {
 	var txs []WalletTransaction
 	var total int64
-	db := d.db.WithContext(ctx).Model(&WalletTransaction{}).Where("wallet_id = ?", walletID)
+	db := conn(ctx, d.db).Model(&WalletTransaction{}).Where("wallet_id = ?", walletID)
 
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -94,27 +148,54 @@ func (d *GormWalletDAO) GetTransactions(ctx context.Context, walletID int64, lim
 }
 
 func (d *GormWalletDAO) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return fn(context.WithValue(ctx, "tx", tx)) // 这里简化处理，实际上 GORM Transaction 内部会自动处理 context 传递
-		// 但由于我们 DAO 方法没有重新从 context 取 tx，而是直接用 d.db，这会导致事务失效。
-		// 正确的做法是每次 DAO 操作都应该基于 context 中的 db (如果有) 或者 d.db。
-		// 由于 GORM 的设计，通常是在 Service 层开启事务，并传入 *gorm.DB 或者封装后的 TransactionManager。
-		// 为了简单起见，这里假设 UpdateBalance 是原子的，如果需要复杂事务（扣费+记录流水），我们需要确保它们在同一个 DB 会话中。
-		// 修正：我们将在 Service 层使用 Transaction 方法，这里只需透传。
-		// 不过为了让 DAO 方法能感知事务，我们需要一种机制。
-		// 常见的 pattern 是：DAO 方法接收 *gorm.DB 参数，或者 Context 携带 *gorm.DB。
-		// GORM 的 WithContext 并不会自动切换 DB 连接。
-		// 鉴于此架构，我们暂时在 Service 层通过 Transaction 方法来保证，但要注意 updateBalance + createTransaction 的原子性。
-	})
-}
-
-// 为了支持事务，我们需要让 DAO 的 db 能够被替换，或者方法接受 DB。
-// 这里的 Transaction 实现比较简陋。更健壮的方式是实现一个 TransactionManager。
-// 鉴于 UpdateBalance 是单条 SQL 原子操作，我们主要关注 "扣费" 和 "记录流水" 的一致性。
-// 我们可以在 UpdateBalanceInTx 这种方法中传入 tx。
-// 或者，我们约定 Service 层调用 Transaction 时，传入的 ctx 包含 tx session，
-// 然后我们在 DAO 的 Helper 方法中提取。
-// 但 GORM 官方推荐 db.Transaction(func(tx *gorm.DB) error { ... })
-// 在闭包内使用 tx 进行操作。
-// 为了简化，我们暂时不在 Interface 定义 Transaction，而是留给 Service 层处理，
-// 或者在 DAO 中提供一个 ExecuteInTransaction 方法，闭包传入一个新 DAO (绑定了 tx)。
+	return transaction(ctx, d.db, fn)
+}
+
+func (d *GormWalletDAO) CreateHold(ctx context.Context, hold *WalletHold) error {
+	return conn(ctx, d.db).Create(hold).Error
+}
+
+func (d *GormWalletDAO) GetHoldByRequestID(ctx context.Context, requestID string) (*WalletHold, error) {
+	var hold WalletHold
+	err := conn(ctx, d.db).Where("request_id = ?", requestID).First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (d *GormWalletDAO) UpdateHoldStatus(ctx context.Context, id int64, from []string, to string) (bool, error) {
+	res := conn(ctx, d.db).Model(&WalletHold{}).Where("id = ? AND status IN ?", id, from).
+		UpdateColumns(map[string]any{"status": to, "updated_at": time.Now()})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (d *GormWalletDAO) ExtendHold(ctx context.Context, requestID string, expiresAt time.Time) (bool, error) {
+	res := conn(ctx, d.db).Model(&WalletHold{}).Where("request_id = ? AND status = ?", requestID, holdStatusActive).
+		UpdateColumns(map[string]any{"expires_at": expiresAt, "updated_at": time.Now()})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (d *GormWalletDAO) SumActiveHolds(ctx context.Context, walletID int64, now time.Time) (money.Amount, error) {
+	return d.sumActiveHolds(ctx, "wallet_id = ?", walletID, now)
+}
+
+func (d *GormWalletDAO) SumActiveHoldsByAPIKey(ctx context.Context, apiKeyID int64, now time.Time) (money.Amount, error) {
+	return d.sumActiveHolds(ctx, "api_key_id = ?", apiKeyID, now)
+}
+
+func (d *GormWalletDAO) sumActiveHolds(ctx context.Context, cond string, id int64, now time.Time) (money.Amount, error) {
+	var sum money.Amount
+	err := conn(ctx, d.db).Model(&WalletHold{}).
+		Where(cond, id).
+		Where("status = ? AND expires_at > ?", holdStatusActive, now).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&sum)
+	return sum, err
+}
+
+func (d *GormWalletDAO) ExpireHolds(ctx context.Context, walletID int64, now time.Time) (int64, error) {
+	res := conn(ctx, d.db).Model(&WalletHold{}).
+		Where("wallet_id = ? AND status = ? AND expires_at <= ?", walletID, holdStatusActive, now).
+		UpdateColumns(map[string]any{"status": holdStatusExpired, "updated_at": now})
+	return res.RowsAffected, res.Error
+}
@@ -1,13 +1,15 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: ai-gateway/internal/repository (interfaces: UserRepository,UsageLogRepository,APIKeyRepository)
+// Source: ai-gateway/internal/repository (interfaces: UserRepository,UsageLogRepository,APIKeyRepository,WalletRepository)
 
 // Package mocks is a generated GoMock package.
 package mocks
 
 import (
 	domain "ai-gateway/internal/domain"
+	money "ai-gateway/internal/pkg/money"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -160,18 +162,34 @@ func (m *MockUsageLogRepository) EXPECT() *MockUsageLogRepositoryMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method.
-func (m *MockUsageLogRepository) Create(arg0 context.Context, arg1 *domain.UsageLog) error {
+// CreateBatch mocks base method.
+func (m *MockUsageLogRepository) CreateBatch(arg0 context.Context, arg1 []*domain.UsageLog) (map[string]bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "CreateBatch", arg0, arg1)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockUsageLogRepositoryMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockUsageLogRepositoryMockRecorder) CreateBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockUsageLogRepository)(nil).CreateBatch), arg0, arg1)
+}
+
+// FindRequestIDs mocks base method.
+func (m *MockUsageLogRepository) FindRequestIDs(arg0 context.Context, arg1 []string) (map[string]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRequestIDs", arg0, arg1)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRequestIDs indicates an expected call of FindRequestIDs.
+func (mr *MockUsageLogRepositoryMockRecorder) FindRequestIDs(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsageLogRepository)(nil).Create), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRequestIDs", reflect.TypeOf((*MockUsageLogRepository)(nil).FindRequestIDs), arg0, arg1)
 }
 
 // GetDailyUsageByUserID mocks base method.
@@ -280,6 +298,20 @@ func (mr *MockUsageLogRepositoryMockRecorder) List(arg0, arg1, arg2, arg3 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUsageLogRepository)(nil).List), arg0, arg1, arg2, arg3)
 }
 
+// UpdateCharged mocks base method.
+func (m *MockUsageLogRepository) UpdateCharged(arg0 context.Context, arg1 string, arg2 money.Amount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCharged", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCharged indicates an expected call of UpdateCharged.
+func (mr *MockUsageLogRepositoryMockRecorder) UpdateCharged(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCharged", reflect.TypeOf((*MockUsageLogRepository)(nil).UpdateCharged), arg0, arg1, arg2)
+}
+
 // MockAPIKeyRepository is a mock of APIKeyRepository interface.
 type MockAPIKeyRepository struct {
 	ctrl     *gomock.Controller
@@ -362,7 +394,7 @@ func (mr *MockAPIKeyRepositoryMockRecorder) GetByKey(arg0, arg1 interface{}) *go
 }
 
 // IncrementUsage mocks base method.
-func (m *MockAPIKeyRepository) IncrementUsage(arg0 context.Context, arg1 int64, arg2 float64) error {
+func (m *MockAPIKeyRepository) IncrementUsage(arg0 context.Context, arg1 int64, arg2 money.Amount) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IncrementUsage", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
@@ -448,3 +480,232 @@ func (mr *MockAPIKeyRepositoryMockRecorder) Validate(arg0, arg1 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockAPIKeyRepository)(nil).Validate), arg0, arg1)
 }
+
+// MockWalletRepository is a mock of WalletRepository interface.
+type MockWalletRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWalletRepositoryMockRecorder
+}
+
+// MockWalletRepositoryMockRecorder is the mock recorder for MockWalletRepository.
+type MockWalletRepositoryMockRecorder struct {
+	mock *MockWalletRepository
+}
+
+// NewMockWalletRepository creates a new mock instance.
+func NewMockWalletRepository(ctrl *gomock.Controller) *MockWalletRepository {
+	mock := &MockWalletRepository{ctrl: ctrl}
+	mock.recorder = &MockWalletRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWalletRepository) EXPECT() *MockWalletRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockWalletRepository) Create(arg0 context.Context, arg1 *domain.Wallet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWalletRepositoryMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWalletRepository)(nil).Create), arg0, arg1)
+}
+
+// CreateHold mocks base method.
+func (m *MockWalletRepository) CreateHold(arg0 context.Context, arg1 *domain.WalletHold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockWalletRepositoryMockRecorder) CreateHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockWalletRepository)(nil).CreateHold), arg0, arg1)
+}
+
+// CreateTransaction mocks base method.
+func (m *MockWalletRepository) CreateTransaction(arg0 context.Context, arg1 *domain.WalletTransaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTransaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateTransaction indicates an expected call of CreateTransaction.
+func (mr *MockWalletRepositoryMockRecorder) CreateTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransaction", reflect.TypeOf((*MockWalletRepository)(nil).CreateTransaction), arg0, arg1)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockWalletRepository) ExpireHolds(arg0 context.Context, arg1 int64, arg2 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockWalletRepositoryMockRecorder) ExpireHolds(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockWalletRepository)(nil).ExpireHolds), arg0, arg1, arg2)
+}
+
+// ExtendHold mocks base method.
+func (m *MockWalletRepository) ExtendHold(arg0 context.Context, arg1 string, arg2 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtendHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtendHold indicates an expected call of ExtendHold.
+func (mr *MockWalletRepositoryMockRecorder) ExtendHold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendHold", reflect.TypeOf((*MockWalletRepository)(nil).ExtendHold), arg0, arg1, arg2)
+}
+
+// GetByUserID mocks base method.
+func (m *MockWalletRepository) GetByUserID(arg0 context.Context, arg1 int64) (*domain.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserID", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserID indicates an expected call of GetByUserID.
+func (mr *MockWalletRepositoryMockRecorder) GetByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockWalletRepository)(nil).GetByUserID), arg0, arg1)
+}
+
+// GetByUserIDForUpdate mocks base method.
+func (m *MockWalletRepository) GetByUserIDForUpdate(arg0 context.Context, arg1 int64) (*domain.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserIDForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserIDForUpdate indicates an expected call of GetByUserIDForUpdate.
+func (mr *MockWalletRepositoryMockRecorder) GetByUserIDForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserIDForUpdate", reflect.TypeOf((*MockWalletRepository)(nil).GetByUserIDForUpdate), arg0, arg1)
+}
+
+// GetHoldByRequestID mocks base method.
+func (m *MockWalletRepository) GetHoldByRequestID(arg0 context.Context, arg1 string) (*domain.WalletHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHoldByRequestID", arg0, arg1)
+	ret0, _ := ret[0].(*domain.WalletHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHoldByRequestID indicates an expected call of GetHoldByRequestID.
+func (mr *MockWalletRepositoryMockRecorder) GetHoldByRequestID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHoldByRequestID", reflect.TypeOf((*MockWalletRepository)(nil).GetHoldByRequestID), arg0, arg1)
+}
+
+// GetTransactions mocks base method.
+func (m *MockWalletRepository) GetTransactions(arg0 context.Context, arg1 int64, arg2, arg3 int) ([]domain.WalletTransaction, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactions", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]domain.WalletTransaction)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTransactions indicates an expected call of GetTransactions.
+func (mr *MockWalletRepositoryMockRecorder) GetTransactions(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockWalletRepository)(nil).GetTransactions), arg0, arg1, arg2, arg3)
+}
+
+// SumActiveHolds mocks base method.
+func (m *MockWalletRepository) SumActiveHolds(arg0 context.Context, arg1 int64, arg2 time.Time) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumActiveHolds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumActiveHolds indicates an expected call of SumActiveHolds.
+func (mr *MockWalletRepositoryMockRecorder) SumActiveHolds(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumActiveHolds", reflect.TypeOf((*MockWalletRepository)(nil).SumActiveHolds), arg0, arg1, arg2)
+}
+
+// SumActiveHoldsByAPIKey mocks base method.
+func (m *MockWalletRepository) SumActiveHoldsByAPIKey(arg0 context.Context, arg1 int64, arg2 time.Time) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumActiveHoldsByAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumActiveHoldsByAPIKey indicates an expected call of SumActiveHoldsByAPIKey.
+func (mr *MockWalletRepositoryMockRecorder) SumActiveHoldsByAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumActiveHoldsByAPIKey", reflect.TypeOf((*MockWalletRepository)(nil).SumActiveHoldsByAPIKey), arg0, arg1, arg2)
+}
+
+// Transaction mocks base method.
+func (m *MockWalletRepository) Transaction(arg0 context.Context, arg1 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockWalletRepositoryMockRecorder) Transaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockWalletRepository)(nil).Transaction), arg0, arg1)
+}
+
+// UpdateBalance mocks base method.
+func (m *MockWalletRepository) UpdateBalance(arg0 context.Context, arg1 int64, arg2 money.Amount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBalance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBalance indicates an expected call of UpdateBalance.
+func (mr *MockWalletRepositoryMockRecorder) UpdateBalance(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBalance", reflect.TypeOf((*MockWalletRepository)(nil).UpdateBalance), arg0, arg1, arg2)
+}
+
+// UpdateHoldStatus mocks base method.
+func (m *MockWalletRepository) UpdateHoldStatus(arg0 context.Context, arg1 int64, arg2 []domain.HoldStatus, arg3 domain.HoldStatus) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateHoldStatus", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateHoldStatus indicates an expected call of UpdateHoldStatus.
+func (mr *MockWalletRepositoryMockRecorder) UpdateHoldStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHoldStatus", reflect.TypeOf((*MockWalletRepository)(nil).UpdateHoldStatus), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/repository/dao"
+)
+
+// ModelCapabilityRepository 模型能力仓储接口
+type ModelCapabilityRepository interface {
+	Create(ctx context.Context, c *domain.ModelCapability) error
+	Update(ctx context.Context, c *domain.ModelCapability) error
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*domain.ModelCapability, error)
+	List(ctx context.Context) ([]domain.ModelCapability, error)
+	GetAllEnabled(ctx context.Context) ([]domain.ModelCapability, error)
+}
+
+type modelCapabilityRepository struct {
+	dao dao.ModelCapabilityDAO
+}
+
+func NewModelCapabilityRepository(dao dao.ModelCapabilityDAO) ModelCapabilityRepository {
+	return &modelCapabilityRepository{dao: dao}
+}
+
+func (r *modelCapabilityRepository) toDomain(d *dao.ModelCapability) *domain.ModelCapability {
+	if d == nil {
+		return nil
+	}
+	return &domain.ModelCapability{
+		ID:               d.ID,
+		ModelPattern:     d.ModelPattern,
+		ProviderName:     d.ProviderName,
+		Vision:           d.Vision,
+		Tools:            d.Tools,
+		Thinking:         d.Thinking,
+		JSONSchema:       d.JSONSchema,
+		ContextWindow:    d.ContextWindow,
+		MaxOutputTokens:  d.MaxOutputTokens,
+		LongContextModel: d.LongContextModel,
+		Enabled:          d.Enabled,
+		CreatedAt:        d.CreatedAt,
+		UpdatedAt:        d.UpdatedAt,
+	}
+}
+
+func (r *modelCapabilityRepository) toDAO(c *domain.ModelCapability) *dao.ModelCapability {
+	if c == nil {
+		return nil
+	}
+	return &dao.ModelCapability{
+		ID:               c.ID,
+		ModelPattern:     c.ModelPattern,
+		ProviderName:     c.ProviderName,
+		Vision:           c.Vision,
+		Tools:            c.Tools,
+		Thinking:         c.Thinking,
+		JSONSchema:       c.JSONSchema,
+		ContextWindow:    c.ContextWindow,
+		MaxOutputTokens:  c.MaxOutputTokens,
+		LongContextModel: c.LongContextModel,
+		Enabled:          c.Enabled,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+	}
+}
+
+func (r *modelCapabilityRepository) Create(ctx context.Context, c *domain.ModelCapability) error {
+	d := r.toDAO(c)
+	if err := r.dao.Create(ctx, d); err != nil {
+		return err
+	}
+	c.ID = d.ID
+	c.CreatedAt = d.CreatedAt
+	c.UpdatedAt = d.UpdatedAt
+	return nil
+}
+
+func (r *modelCapabilityRepository) Update(ctx context.Context, c *domain.ModelCapability) error {
+	return r.dao.Update(ctx, r.toDAO(c))
+}
+
+func (r *modelCapabilityRepository) Delete(ctx context.Context, id int64) error {
+	return r.dao.Delete(ctx, id)
+}
+
+func (r *modelCapabilityRepository) GetByID(ctx context.Context, id int64) (*domain.ModelCapability, error) {
+	d, err := r.dao.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomain(d), nil
+}
+
+func (r *modelCapabilityRepository) List(ctx context.Context) ([]domain.ModelCapability, error) {
+	list, err := r.dao.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomains(list), nil
+}
+
+func (r *modelCapabilityRepository) GetAllEnabled(ctx context.Context) ([]domain.ModelCapability, error) {
+	list, err := r.dao.GetAllEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomains(list), nil
+}
+
+func (r *modelCapabilityRepository) toDomains(list []dao.ModelCapability) []domain.ModelCapability {
+	caps := make([]domain.ModelCapability, len(list))
+	for i := range list {
+		caps[i] = *r.toDomain(&list[i])
+	}
+	return caps
+}
@@ -5,8 +5,11 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/envelope"
+	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/repository/cache"
 	"ai-gateway/internal/repository/dao"
 )
@@ -20,41 +23,114 @@ type ProviderRepository interface {
 	GetByName(ctx context.Context, name string) (*domain.Provider, error)
 	List(ctx context.Context) ([]domain.Provider, error)
 	GetDefaultByType(ctx context.Context, providerType string) (*domain.Provider, error)
+	// RotateSecrets 用当前主密钥重新加密所有未加密或由旧主密钥加密的上游 Key，返回更新的供应商数。
+	RotateSecrets(ctx context.Context) (int, error)
 }
 
 // providerRepository 是 ProviderRepository 的默认实现。
+// 上游 Key 在写入数据库前加密，读出后解密；Redis 缓存中只保存当前主密钥加密的密文，
+// 尚未重新加密的明文或旧主密钥密文在写入缓存前加密。未配置主密钥（keyring 为 nil）时 Key 为明文，不写入缓存。
 type providerRepository struct {
-	dao   dao.ProviderDAO
-	cache cache.ProviderCache
+	dao     dao.ProviderDAO
+	cache   cache.ProviderCache
+	keyring *envelope.Keyring
+	logger  logger.Logger
 }
 
 // NewProviderRepository 创建一个新的 ProviderRepository。
-func NewProviderRepository(providerDAO dao.ProviderDAO, cache cache.ProviderCache) ProviderRepository {
+func NewProviderRepository(providerDAO dao.ProviderDAO, cache cache.ProviderCache, keyring *envelope.Keyring, l logger.Logger) ProviderRepository {
+	if keyring == nil {
+		cache = nil
+	}
 	return &providerRepository{
-		dao:   providerDAO,
-		cache: cache,
+		dao:     providerDAO,
+		cache:   cache,
+		keyring: keyring,
+		logger:  l.With(logger.String("repository", "provider")),
 	}
 }
 
-// toDAO 将 domain.Provider 转换为 dao.Provider
-func (r *providerRepository) toDAO(p *domain.Provider) *dao.Provider {
+// toDAO 将 domain.Provider 转换为 dao.Provider，并加密上游 Key
+func (r *providerRepository) toDAO(p *domain.Provider) (*dao.Provider, error) {
 	modelsJSON, _ := json.Marshal(p.Models)
+	var deploymentsJSON []byte
+	if len(p.Deployments) > 0 {
+		deploymentsJSON, _ = json.Marshal(p.Deployments)
+	}
+	sealed, err := r.seal(p)
+	if err != nil {
+		return nil, err
+	}
+	var keysJSON []byte
+	if len(sealed.APIKeys) > 0 {
+		keysJSON, _ = json.Marshal(sealed.APIKeys)
+	}
 	return &dao.Provider{
-		ID:        p.ID,
-		Name:      p.Name,
-		Type:      p.Type,
-		APIKey:    p.APIKey,
-		BaseURL:   p.BaseURL,
-		Models:    string(modelsJSON),
-		TimeoutMs: p.TimeoutMs,
-		IsDefault: p.IsDefault,
-		Enabled:   p.Enabled,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
+		ID:                      p.ID,
+		Name:                    p.Name,
+		Type:                    p.Type,
+		APIKey:                  sealed.APIKey,
+		APIKeys:                 string(keysJSON),
+		KeyStrategy:             p.KeyStrategy,
+		BaseURL:                 p.BaseURL,
+		Models:                  string(modelsJSON),
+		APIVersion:              p.APIVersion,
+		Deployments:             string(deploymentsJSON),
+		TimeoutMs:               p.TimeoutMs,
+		ConnectTimeoutMs:        p.ConnectTimeoutMs,
+		ResponseHeaderTimeoutMs: p.ResponseHeaderTimeoutMs,
+		MaxIdleConns:            p.MaxIdleConns,
+		MaxIdleConnsPerHost:     p.MaxIdleConnsPerHost,
+		ProxyURL:                p.ProxyURL,
+		RetryMaxAttempts:        p.RetryMaxAttempts,
+		RetryInitialDelayMs:     p.RetryInitialDelayMs,
+		RetryMaxDelayMs:         p.RetryMaxDelayMs,
+		FirstTokenTimeoutMs:     p.FirstTokenTimeoutMs,
+		IsDefault:               p.IsDefault,
+		Enabled:                 p.Enabled,
+		CreatedAt:               p.CreatedAt,
+		UpdatedAt:               p.UpdatedAt,
+	}, nil
+}
+
+// seal 返回上游 Key 已加密的副本。
+func (r *providerRepository) seal(p *domain.Provider) (*domain.Provider, error) {
+	out := *p
+	var err error
+	if out.APIKey, err = r.keyring.Encrypt(p.APIKey); err != nil {
+		return nil, fmt.Errorf("encrypt api key of provider %s: %w", p.Name, err)
 	}
+	out.APIKeys = make([]domain.ProviderKey, len(p.APIKeys))
+	for i, k := range p.APIKeys {
+		if k.Key, err = r.keyring.Encrypt(k.Key); err != nil {
+			return nil, fmt.Errorf("encrypt api key %s of provider %s: %w", k.Name, p.Name, err)
+		}
+		out.APIKeys[i] = k
+	}
+	return &out, nil
 }
 
-// toDomain 将 dao.Provider 转换为 domain.Provider
+// open 返回上游 Key 已解密的副本。
+func (r *providerRepository) open(p *domain.Provider) (*domain.Provider, error) {
+	if p == nil {
+		return nil, nil
+	}
+	out := *p
+	var err error
+	if out.APIKey, err = r.keyring.Decrypt(p.APIKey); err != nil {
+		return nil, fmt.Errorf("decrypt api key of provider %s: %w", p.Name, err)
+	}
+	out.APIKeys = make([]domain.ProviderKey, len(p.APIKeys))
+	for i, k := range p.APIKeys {
+		if k.Key, err = r.keyring.Decrypt(k.Key); err != nil {
+			return nil, fmt.Errorf("decrypt api key %s of provider %s: %w", k.Name, p.Name, err)
+		}
+		out.APIKeys[i] = k
+	}
+	return &out, nil
+}
+
+// toDomain 将 dao.Provider 转换为 domain.Provider，上游 Key 保持数据库中的形式（可能为密文）
 func (r *providerRepository) toDomain(p *dao.Provider) *domain.Provider {
 	if p == nil {
 		return nil
@@ -63,23 +139,47 @@ func (r *providerRepository) toDomain(p *dao.Provider) *domain.Provider {
 	if p.Models != "" {
 		_ = json.Unmarshal([]byte(p.Models), &models)
 	}
+	var deployments map[string]string
+	if p.Deployments != "" {
+		_ = json.Unmarshal([]byte(p.Deployments), &deployments)
+	}
+	var keys []domain.ProviderKey
+	if p.APIKeys != "" {
+		_ = json.Unmarshal([]byte(p.APIKeys), &keys)
+	}
 	return &domain.Provider{
-		ID:        p.ID,
-		Name:      p.Name,
-		Type:      p.Type,
-		APIKey:    p.APIKey,
-		BaseURL:   p.BaseURL,
-		Models:    models,
-		TimeoutMs: p.TimeoutMs,
-		IsDefault: p.IsDefault,
-		Enabled:   p.Enabled,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
+		ID:                      p.ID,
+		Name:                    p.Name,
+		Type:                    p.Type,
+		APIKey:                  p.APIKey,
+		APIKeys:                 keys,
+		KeyStrategy:             p.KeyStrategy,
+		BaseURL:                 p.BaseURL,
+		Models:                  models,
+		APIVersion:              p.APIVersion,
+		Deployments:             deployments,
+		TimeoutMs:               p.TimeoutMs,
+		ConnectTimeoutMs:        p.ConnectTimeoutMs,
+		ResponseHeaderTimeoutMs: p.ResponseHeaderTimeoutMs,
+		MaxIdleConns:            p.MaxIdleConns,
+		MaxIdleConnsPerHost:     p.MaxIdleConnsPerHost,
+		ProxyURL:                p.ProxyURL,
+		RetryMaxAttempts:        p.RetryMaxAttempts,
+		RetryInitialDelayMs:     p.RetryInitialDelayMs,
+		RetryMaxDelayMs:         p.RetryMaxDelayMs,
+		FirstTokenTimeoutMs:     p.FirstTokenTimeoutMs,
+		IsDefault:               p.IsDefault,
+		Enabled:                 p.Enabled,
+		CreatedAt:               p.CreatedAt,
+		UpdatedAt:               p.UpdatedAt,
 	}
 }
 
 func (r *providerRepository) Create(ctx context.Context, p *domain.Provider) error {
-	daoProvider := r.toDAO(p)
+	daoProvider, err := r.toDAO(p)
+	if err != nil {
+		return err
+	}
 	if err := r.dao.Create(ctx, daoProvider); err != nil {
 		return err
 	}
@@ -94,7 +194,11 @@ func (r *providerRepository) Create(ctx context.Context, p *domain.Provider) err
 }
 
 func (r *providerRepository) Update(ctx context.Context, p *domain.Provider) error {
-	err := r.dao.Update(ctx, r.toDAO(p))
+	daoProvider, err := r.toDAO(p)
+	if err != nil {
+		return err
+	}
+	err = r.dao.Update(ctx, daoProvider)
 	if err == nil && r.cache != nil {
 		_ = r.cache.Invalidate(ctx)
 	}
@@ -114,7 +218,7 @@ func (r *providerRepository) GetByID(ctx context.Context, id int64) (*domain.Pro
 	if err != nil {
 		return nil, err
 	}
-	return r.toDomain(daoProvider), nil
+	return r.open(r.toDomain(daoProvider))
 }
 
 func (r *providerRepository) GetByName(ctx context.Context, name string) (*domain.Provider, error) {
@@ -122,31 +226,57 @@ func (r *providerRepository) GetByName(ctx context.Context, name string) (*domai
 	if err != nil {
 		return nil, err
 	}
-	return r.toDomain(daoProvider), nil
+	return r.open(r.toDomain(daoProvider))
 }
 
 func (r *providerRepository) List(ctx context.Context) ([]domain.Provider, error) {
+	sealed, ok := []domain.Provider(nil), false
 	if r.cache != nil {
-		if providers, ok := r.cache.GetAll(ctx); ok {
-			return providers, nil
+		sealed, ok = r.cache.GetAll(ctx)
+	}
+	if !ok {
+		daoProviders, err := r.dao.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sealed = make([]domain.Provider, 0, len(daoProviders))
+		for i := range daoProviders {
+			p, err := r.reseal(r.toDomain(&daoProviders[i]))
+			if err != nil {
+				r.logger.Error("skipping provider with unreadable api key", logger.String("provider", daoProviders[i].Name), logger.Error(err))
+				continue
+			}
+			sealed = append(sealed, *p)
+		}
+		if r.cache != nil {
+			_ = r.cache.SetAll(ctx, sealed)
 		}
 	}
 
-	daoProviders, err := r.dao.List(ctx)
-	if err != nil {
-		return nil, err
+	// 个别供应商的 Key 无法解密（如主密钥已下线）时跳过该供应商，不影响其他供应商
+	providers := make([]domain.Provider, 0, len(sealed))
+	for i := range sealed {
+		p, err := r.open(&sealed[i])
+		if err != nil {
+			r.logger.Error("skipping provider with unreadable api key", logger.String("provider", sealed[i].Name), logger.Error(err))
+			continue
+		}
+		providers = append(providers, *p)
 	}
+	return providers, nil
+}
 
-	providers := make([]domain.Provider, len(daoProviders))
-	for i, p := range daoProviders {
-		providers[i] = *r.toDomain(&p)
+// reseal 返回只含当前主密钥密文的副本：数据库中尚未由 rotatekeys 重新加密的明文或旧主密钥密文
+// 先解密再用当前主密钥加密，保证写入缓存的 Key 都是密文。数据库中的值不变。
+func (r *providerRepository) reseal(p *domain.Provider) (*domain.Provider, error) {
+	if !r.needsRotation(p) {
+		return p, nil
 	}
-
-	if r.cache != nil {
-		_ = r.cache.SetAll(ctx, providers)
+	plain, err := r.open(p)
+	if err != nil {
+		return nil, err
 	}
-
-	return providers, nil
+	return r.seal(plain)
 }
 
 func (r *providerRepository) GetDefaultByType(ctx context.Context, providerType string) (*domain.Provider, error) {
@@ -154,5 +284,52 @@ func (r *providerRepository) GetDefaultByType(ctx context.Context, providerType
 	if err != nil {
 		return nil, err
 	}
-	return r.toDomain(daoProvider), nil
+	return r.open(r.toDomain(daoProvider))
+}
+
+func (r *providerRepository) RotateSecrets(ctx context.Context) (int, error) {
+	if r.keyring == nil {
+		return 0, envelope.ErrNoMasterKey
+	}
+	daoProviders, err := r.dao.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for i := range daoProviders {
+		stored := r.toDomain(&daoProviders[i])
+		if !r.needsRotation(stored) {
+			continue
+		}
+		plain, err := r.open(stored)
+		if err != nil {
+			return rotated, err
+		}
+		sealed, err := r.toDAO(plain)
+		if err != nil {
+			return rotated, err
+		}
+		if err := r.dao.UpdateSecrets(ctx, sealed.ID, sealed.APIKey, sealed.APIKeys); err != nil {
+			return rotated, fmt.Errorf("update api keys of provider %s: %w", stored.Name, err)
+		}
+		rotated++
+	}
+
+	if rotated > 0 && r.cache != nil {
+		_ = r.cache.Invalidate(ctx)
+	}
+	return rotated, nil
+}
+
+func (r *providerRepository) needsRotation(p *domain.Provider) bool {
+	if r.keyring.NeedsRotation(p.APIKey) {
+		return true
+	}
+	for _, k := range p.APIKeys {
+		if r.keyring.NeedsRotation(k.Key) {
+			return true
+		}
+	}
+	return false
 }
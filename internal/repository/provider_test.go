@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/envelope"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/repository/dao"
+)
+
+// fakeProviderDAO 返回预置的数据库行，其余方法未实现。
+type fakeProviderDAO struct {
+	dao.ProviderDAO
+	rows []dao.Provider
+}
+
+func (d *fakeProviderDAO) List(context.Context) ([]dao.Provider, error) { return d.rows, nil }
+
+// fakeProviderCache 保存 SetAll 写入的内容，模拟 Redis。
+type fakeProviderCache struct {
+	providers []domain.Provider
+	set       bool
+}
+
+func (c *fakeProviderCache) GetAll(context.Context) ([]domain.Provider, bool) {
+	return c.providers, c.set
+}
+
+func (c *fakeProviderCache) SetAll(_ context.Context, providers []domain.Provider) error {
+	c.providers, c.set = providers, true
+	return nil
+}
+
+func (c *fakeProviderCache) Invalidate(context.Context) error {
+	c.providers, c.set = nil, false
+	return nil
+}
+
+func newKeyring(t *testing.T) *envelope.Keyring {
+	t.Helper()
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	k, err := envelope.NewKeyring(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	return k
+}
+
+func TestProviderRepository_ListNeverCachesPlaintext(t *testing.T) {
+	keyring := newKeyring(t)
+	sealed, err := keyring.Encrypt("sk-sealed-0001")
+	require.NoError(t, err)
+	// 另一个主密钥加密的 Key 无法解密
+	foreign, err := newKeyring(t).Encrypt("sk-foreign-0002")
+	require.NoError(t, err)
+
+	// legacy 是尚未运行 rotatekeys 的明文行
+	d := &fakeProviderDAO{rows: []dao.Provider{
+		{ID: 1, Name: "legacy", APIKey: "sk-legacy-plain", APIKeys: `[{"name":"b","key":"sk-legacy-pool"}]`},
+		{ID: 2, Name: "sealed", APIKey: sealed},
+		{ID: 3, Name: "broken", APIKey: foreign},
+	}}
+	c := &fakeProviderCache{}
+	repo := NewProviderRepository(d, c, keyring, logger.NewNopLogger())
+
+	providers, err := repo.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+	assert.Equal(t, "sk-legacy-plain", providers[0].APIKey)
+	assert.Equal(t, "sk-legacy-pool", providers[0].APIKeys[0].Key)
+	assert.Equal(t, "sk-sealed-0001", providers[1].APIKey)
+
+	require.True(t, c.set)
+	require.Len(t, c.providers, 2)
+	for _, p := range c.providers {
+		assert.True(t, envelope.IsEncrypted(p.APIKey), p.Name)
+		assert.False(t, strings.Contains(p.APIKey, "sk-"), p.Name)
+		for _, k := range p.APIKeys {
+			assert.True(t, envelope.IsEncrypted(k.Key), p.Name)
+		}
+	}
+
+	// 从缓存读取的结果与数据库一致
+	d.rows = nil
+	cached, err := repo.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, providers, cached)
+}
@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/repository/cache"
@@ -36,6 +37,18 @@ func NewRoutingRuleRepository(routingRuleDAO dao.RoutingRuleDAO, cache cache.Rou
 
 // toDAO 将 domain.RoutingRule 转换为 dao.RoutingRule
 func (r *routingRuleRepository) toDAO(rule *domain.RoutingRule) *dao.RoutingRule {
+	var fallbacksJSON []byte
+	if len(rule.Fallbacks) > 0 {
+		fallbacksJSON, _ = json.Marshal(rule.Fallbacks)
+	}
+	var variantsJSON []byte
+	if len(rule.Variants) > 0 {
+		variantsJSON, _ = json.Marshal(rule.Variants)
+	}
+	var shadowJSON []byte
+	if rule.Shadow != nil {
+		shadowJSON, _ = json.Marshal(rule.Shadow)
+	}
 	return &dao.RoutingRule{
 		ID:           rule.ID,
 		RuleType:     rule.RuleType,
@@ -43,6 +56,9 @@ func (r *routingRuleRepository) toDAO(rule *domain.RoutingRule) *dao.RoutingRule
 		ProviderName: rule.ProviderName,
 		ActualModel:  rule.ActualModel,
 		Priority:     rule.Priority,
+		Fallbacks:    string(fallbacksJSON),
+		Variants:     string(variantsJSON),
+		Shadow:       string(shadowJSON),
 		Enabled:      rule.Enabled,
 		CreatedAt:    rule.CreatedAt,
 		UpdatedAt:    rule.UpdatedAt,
@@ -54,6 +70,18 @@ func (r *routingRuleRepository) toDomain(rule *dao.RoutingRule) *domain.RoutingR
 	if rule == nil {
 		return nil
 	}
+	var fallbacks []domain.RouteTarget
+	if rule.Fallbacks != "" {
+		_ = json.Unmarshal([]byte(rule.Fallbacks), &fallbacks)
+	}
+	var variants []domain.RouteVariant
+	if rule.Variants != "" {
+		_ = json.Unmarshal([]byte(rule.Variants), &variants)
+	}
+	var shadow *domain.ShadowTarget
+	if rule.Shadow != "" {
+		_ = json.Unmarshal([]byte(rule.Shadow), &shadow)
+	}
 	return &domain.RoutingRule{
 		ID:           rule.ID,
 		RuleType:     rule.RuleType,
@@ -61,6 +89,9 @@ func (r *routingRuleRepository) toDomain(rule *dao.RoutingRule) *domain.RoutingR
 		ProviderName: rule.ProviderName,
 		ActualModel:  rule.ActualModel,
 		Priority:     rule.Priority,
+		Fallbacks:    fallbacks,
+		Variants:     variants,
+		Shadow:       shadow,
 		Enabled:      rule.Enabled,
 		CreatedAt:    rule.CreatedAt,
 		UpdatedAt:    rule.UpdatedAt,
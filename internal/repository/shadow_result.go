@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/repository/dao"
+)
+
+// ShadowResultRepository 影子流量对比记录仓储接口
+type ShadowResultRepository interface {
+	Create(ctx context.Context, r *domain.ShadowResult) error
+	List(ctx context.Context, page, pageSize int, model string) ([]domain.ShadowResult, int64, error)
+	Stats(ctx context.Context, days int) ([]domain.ShadowStats, error)
+}
+
+type shadowResultRepository struct {
+	dao dao.ShadowResultDAO
+}
+
+func NewShadowResultRepository(dao dao.ShadowResultDAO) ShadowResultRepository {
+	return &shadowResultRepository{dao: dao}
+}
+
+func (r *shadowResultRepository) toDomain(d *dao.ShadowResult) domain.ShadowResult {
+	return domain.ShadowResult{
+		ID:                  d.ID,
+		RequestID:           d.RequestID,
+		Model:               d.Model,
+		PrimaryProvider:     d.PrimaryProvider,
+		PrimaryModel:        d.PrimaryModel,
+		PrimaryLatencyMs:    d.PrimaryLatencyMs,
+		PrimaryInputTokens:  d.PrimaryInputTokens,
+		PrimaryOutputTokens: d.PrimaryOutputTokens,
+		PrimaryContent:      d.PrimaryContent,
+		PrimaryError:        d.PrimaryError,
+		ShadowProvider:      d.ShadowProvider,
+		ShadowModel:         d.ShadowModel,
+		ShadowLatencyMs:     d.ShadowLatencyMs,
+		ShadowInputTokens:   d.ShadowInputTokens,
+		ShadowOutputTokens:  d.ShadowOutputTokens,
+		ShadowContent:       d.ShadowContent,
+		ShadowError:         d.ShadowError,
+		CreatedAt:           d.CreatedAt,
+	}
+}
+
+func (r *shadowResultRepository) toDAO(d *domain.ShadowResult) *dao.ShadowResult {
+	return &dao.ShadowResult{
+		ID:                  d.ID,
+		RequestID:           d.RequestID,
+		Model:               d.Model,
+		PrimaryProvider:     d.PrimaryProvider,
+		PrimaryModel:        d.PrimaryModel,
+		PrimaryLatencyMs:    d.PrimaryLatencyMs,
+		PrimaryInputTokens:  d.PrimaryInputTokens,
+		PrimaryOutputTokens: d.PrimaryOutputTokens,
+		PrimaryContent:      d.PrimaryContent,
+		PrimaryError:        d.PrimaryError,
+		ShadowProvider:      d.ShadowProvider,
+		ShadowModel:         d.ShadowModel,
+		ShadowLatencyMs:     d.ShadowLatencyMs,
+		ShadowInputTokens:   d.ShadowInputTokens,
+		ShadowOutputTokens:  d.ShadowOutputTokens,
+		ShadowContent:       d.ShadowContent,
+		ShadowError:         d.ShadowError,
+		CreatedAt:           d.CreatedAt,
+	}
+}
+
+func (r *shadowResultRepository) Create(ctx context.Context, result *domain.ShadowResult) error {
+	d := r.toDAO(result)
+	if err := r.dao.Create(ctx, d); err != nil {
+		return err
+	}
+	result.ID = d.ID
+	result.CreatedAt = d.CreatedAt
+	return nil
+}
+
+func (r *shadowResultRepository) List(ctx context.Context, page, pageSize int, model string) ([]domain.ShadowResult, int64, error) {
+	list, total, err := r.dao.List(ctx, page, pageSize, model)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]domain.ShadowResult, len(list))
+	for i := range list {
+		results[i] = r.toDomain(&list[i])
+	}
+	return results, total, nil
+}
+
+func (r *shadowResultRepository) Stats(ctx context.Context, days int) ([]domain.ShadowStats, error) {
+	list, err := r.dao.Stats(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]domain.ShadowStats, len(list))
+	for i, s := range list {
+		stats[i] = domain.ShadowStats{
+			Model:                  s.Model,
+			ShadowProvider:         s.ShadowProvider,
+			ShadowModel:            s.ShadowModel,
+			Requests:               s.Requests,
+			PrimaryErrors:          s.PrimaryErrors,
+			ShadowErrors:           s.ShadowErrors,
+			PrimaryAvgLatencyMs:    s.PrimaryAvgLatencyMs,
+			ShadowAvgLatencyMs:     s.ShadowAvgLatencyMs,
+			PrimaryAvgOutputTokens: s.PrimaryAvgOutputTokens,
+			ShadowAvgOutputTokens:  s.ShadowAvgOutputTokens,
+		}
+	}
+	return stats, nil
+}
@@ -5,12 +5,18 @@ import (
 	"context"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository/dao"
 )
 
 // UsageLogRepository 定义使用记录的存储库接口。
 type UsageLogRepository interface {
-	Create(ctx context.Context, log *domain.UsageLog) error
+	// CreateBatch 批量写入使用记录，以请求 ID 去重：已有相同请求 ID 的记录被忽略，返回实际写入的请求 ID
+	CreateBatch(ctx context.Context, logs []*domain.UsageLog) (map[string]bool, error)
+	// FindRequestIDs 返回 requestIDs 中已有使用记录的请求 ID
+	FindRequestIDs(ctx context.Context, requestIDs []string) (map[string]bool, error)
+	// UpdateCharged 更新请求实际收取的费用
+	UpdateCharged(ctx context.Context, requestID string, charged money.Amount) error
 	GetStatsByUserID(ctx context.Context, userID int64) (*domain.UsageStats, error)
 	GetDailyUsageByUserID(ctx context.Context, userID int64, days int) ([]domain.DailyUsage, error)
 	GetGlobalStats(ctx context.Context) (*domain.UsageStats, error)
@@ -38,17 +44,35 @@ func (r *usageLogRepository) toDAO(log *domain.UsageLog) *dao.UsageLog {
 		APIKeyID:     log.APIKeyID,
 		Model:        log.Model,
 		Provider:     log.Provider,
+		ProviderID:   log.ProviderID,
+		FallbackHop:  log.FallbackHop,
+		Variant:      log.Variant,
 		InputTokens:  log.InputTokens,
 		OutputTokens: log.OutputTokens,
+		Cost:         log.Cost,
+		Charged:      log.Charged,
 		LatencyMs:    log.LatencyMs,
 		StatusCode:   log.StatusCode,
 		ClientIP:     log.ClientIP,
-		UserAgent:    log.UserAgent,
+		UserAgent:    truncateRunes(log.UserAgent, userAgentMaxLen),
 		RequestID:    log.RequestID,
 		CreatedAt:    log.CreatedAt,
 	}
 }
 
+// userAgentMaxLen usage_logs.user_agent 列的长度，超长的 User-Agent 会使整批写入失败
+const userAgentMaxLen = 512
+
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
 // toDomain 将 dao.UsageLog 转换为 domain.UsageLog。
 func (r *usageLogRepository) toDomain(log *dao.UsageLog) *domain.UsageLog {
 	return &domain.UsageLog{
@@ -57,8 +81,13 @@ func (r *usageLogRepository) toDomain(log *dao.UsageLog) *domain.UsageLog {
 		APIKeyID:     log.APIKeyID,
 		Model:        log.Model,
 		Provider:     log.Provider,
+		ProviderID:   log.ProviderID,
+		FallbackHop:  log.FallbackHop,
+		Variant:      log.Variant,
 		InputTokens:  log.InputTokens,
 		OutputTokens: log.OutputTokens,
+		Cost:         log.Cost,
+		Charged:      log.Charged,
 		LatencyMs:    log.LatencyMs,
 		StatusCode:   log.StatusCode,
 		ClientIP:     log.ClientIP,
@@ -68,14 +97,36 @@ func (r *usageLogRepository) toDomain(log *dao.UsageLog) *domain.UsageLog {
 	}
 }
 
-func (r *usageLogRepository) Create(ctx context.Context, log *domain.UsageLog) error {
-	daoLog := r.toDAO(log)
-	if err := r.dao.Create(ctx, daoLog); err != nil {
-		return err
+func (r *usageLogRepository) CreateBatch(ctx context.Context, logs []*domain.UsageLog) (map[string]bool, error) {
+	daoLogs := make([]*dao.UsageLog, len(logs))
+	for i, log := range logs {
+		daoLogs[i] = r.toDAO(log)
+	}
+	ids, err := r.dao.CreateBatch(ctx, daoLogs)
+	if err != nil {
+		return nil, err
+	}
+	inserted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		inserted[id] = true
+	}
+	return inserted, nil
+}
+
+func (r *usageLogRepository) UpdateCharged(ctx context.Context, requestID string, charged money.Amount) error {
+	return r.dao.UpdateCharged(ctx, requestID, charged)
+}
+
+func (r *usageLogRepository) FindRequestIDs(ctx context.Context, requestIDs []string) (map[string]bool, error) {
+	found, err := r.dao.FindRequestIDs(ctx, requestIDs)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]bool, len(found))
+	for _, id := range found {
+		m[id] = true
 	}
-	log.ID = daoLog.ID
-	log.CreatedAt = daoLog.CreatedAt
-	return nil
+	return m, nil
 }
 
 func (r *usageLogRepository) GetStatsByUserID(ctx context.Context, userID int64) (*domain.UsageStats, error) {
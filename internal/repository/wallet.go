@@ -2,22 +2,38 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository/dao"
-
-	"gorm.io/gorm"
 )
 
 // WalletRepository 钱包仓储接口
 type WalletRepository interface {
 	GetByUserID(ctx context.Context, userID int64) (*domain.Wallet, error)
+	// GetByUserIDForUpdate 查询并锁定钱包行，须在 Transaction 内调用
+	GetByUserIDForUpdate(ctx context.Context, userID int64) (*domain.Wallet, error)
+	// Create 创建钱包，用户已有钱包时不做任何操作
 	Create(ctx context.Context, wallet *domain.Wallet) error
-	UpdateBalance(ctx context.Context, walletID int64, amount float64) error
+	UpdateBalance(ctx context.Context, walletID int64, amount money.Amount) error
 	CreateTransaction(ctx context.Context, tx *domain.WalletTransaction) error
 	GetTransactions(ctx context.Context, walletID int64, limit, offset int) ([]domain.WalletTransaction, int64, error)
-	// WithTx 支持事务
-	WithTx(tx *gorm.DB) WalletRepository
+	// Transaction 在一个数据库事务中执行 fn，fn 内须使用传入的 ctx
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	CreateHold(ctx context.Context, hold *domain.WalletHold) error
+	GetHoldByRequestID(ctx context.Context, requestID string) (*domain.WalletHold, error)
+	// UpdateHoldStatus 仅当预授权处于 from 状态之一时更新为 to，返回是否更新
+	UpdateHoldStatus(ctx context.Context, id int64, from []domain.HoldStatus, to domain.HoldStatus) (bool, error)
+	// ExtendHold 将请求的有效预授权的到期时间改为 expiresAt，返回是否更新
+	ExtendHold(ctx context.Context, requestID string, expiresAt time.Time) (bool, error)
+	// SumActiveHolds 统计钱包在 now 时仍有效的预授权金额
+	SumActiveHolds(ctx context.Context, walletID int64, now time.Time) (money.Amount, error)
+	// SumActiveHoldsByAPIKey 统计 API Key 在 now 时仍有效的预授权金额
+	SumActiveHoldsByAPIKey(ctx context.Context, apiKeyID int64, now time.Time) (money.Amount, error)
+	// ExpireHolds 将钱包已过期的有效预授权标记为过期
+	ExpireHolds(ctx context.Context, walletID int64, now time.Time) (int64, error)
 }
 
 type walletRepository struct {
@@ -28,18 +44,6 @@ func NewWalletRepository(dao dao.WalletDAO) WalletRepository {
 	return &walletRepository{dao: dao}
 }
 
-// WithTx 返回一个新的 Repository 实例，使用传入的事务 DB
-// 这是一个简化的事务支持方式，依赖于 DAO 支持或是我们直接在这里 Hack
-// 由于 DAO 接口没有暴露 WithDB，我们需要转换类型或者在 DAO 增加方法
-// 这里为了简单，我们假设 DAO 能够处理，或者我们暂时不实现 WithTx
-// 更好的方式是：Repository 方法接收 context，Service 层负责在 context 中注入 tx
-// 目前先留空 WithTx，后面在 Service 层通过 TransactionManager 统一处理
-func (r *walletRepository) WithTx(tx *gorm.DB) WalletRepository {
-	// TODO: 实现基于 TX 的 DAO 切换
-	// 实际项目中，通常会有一个 NewWalletDAO(tx)
-	return r
-}
-
 func (r *walletRepository) toDomainWallet(w *dao.Wallet) *domain.Wallet {
 	if w == nil {
 		return nil
@@ -108,6 +112,14 @@ func (r *walletRepository) GetByUserID(ctx context.Context, userID int64) (*doma
 	return r.toDomainWallet(w), nil
 }
 
+func (r *walletRepository) GetByUserIDForUpdate(ctx context.Context, userID int64) (*domain.Wallet, error) {
+	w, err := r.dao.GetByUserIDForUpdate(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomainWallet(w), nil
+}
+
 func (r *walletRepository) Create(ctx context.Context, wallet *domain.Wallet) error {
 	daoWallet := r.toDAOWallet(wallet)
 	if err := r.dao.Create(ctx, daoWallet); err != nil {
@@ -119,7 +131,7 @@ func (r *walletRepository) Create(ctx context.Context, wallet *domain.Wallet) er
 	return nil
 }
 
-func (r *walletRepository) UpdateBalance(ctx context.Context, walletID int64, amount float64) error {
+func (r *walletRepository) UpdateBalance(ctx context.Context, walletID int64, amount money.Amount) error {
 	return r.dao.UpdateBalance(ctx, walletID, amount)
 }
 
@@ -145,3 +157,67 @@ func (r *walletRepository) GetTransactions(ctx context.Context, walletID int64,
 	}
 	return txs, total, nil
 }
+
+func (r *walletRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.dao.Transaction(ctx, fn)
+}
+
+func (r *walletRepository) CreateHold(ctx context.Context, hold *domain.WalletHold) error {
+	daoHold := &dao.WalletHold{
+		RequestID: hold.RequestID,
+		UserID:    hold.UserID,
+		WalletID:  hold.WalletID,
+		APIKeyID:  hold.APIKeyID,
+		Amount:    hold.Amount,
+		Status:    string(hold.Status),
+		ExpiresAt: hold.ExpiresAt,
+	}
+	if err := r.dao.CreateHold(ctx, daoHold); err != nil {
+		return err
+	}
+	hold.ID = daoHold.ID
+	hold.CreatedAt = daoHold.CreatedAt
+	return nil
+}
+
+func (r *walletRepository) GetHoldByRequestID(ctx context.Context, requestID string) (*domain.WalletHold, error) {
+	h, err := r.dao.GetHoldByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WalletHold{
+		ID:        h.ID,
+		RequestID: h.RequestID,
+		UserID:    h.UserID,
+		WalletID:  h.WalletID,
+		APIKeyID:  h.APIKeyID,
+		Amount:    h.Amount,
+		Status:    domain.HoldStatus(h.Status),
+		ExpiresAt: h.ExpiresAt,
+		CreatedAt: h.CreatedAt,
+	}, nil
+}
+
+func (r *walletRepository) UpdateHoldStatus(ctx context.Context, id int64, from []domain.HoldStatus, to domain.HoldStatus) (bool, error) {
+	statuses := make([]string, len(from))
+	for i, st := range from {
+		statuses[i] = string(st)
+	}
+	return r.dao.UpdateHoldStatus(ctx, id, statuses, string(to))
+}
+
+func (r *walletRepository) ExtendHold(ctx context.Context, requestID string, expiresAt time.Time) (bool, error) {
+	return r.dao.ExtendHold(ctx, requestID, expiresAt)
+}
+
+func (r *walletRepository) SumActiveHolds(ctx context.Context, walletID int64, now time.Time) (money.Amount, error) {
+	return r.dao.SumActiveHolds(ctx, walletID, now)
+}
+
+func (r *walletRepository) SumActiveHoldsByAPIKey(ctx context.Context, apiKeyID int64, now time.Time) (money.Amount, error) {
+	return r.dao.SumActiveHoldsByAPIKey(ctx, apiKeyID, now)
+}
+
+func (r *walletRepository) ExpireHolds(ctx context.Context, walletID int64, now time.Time) (int64, error) {
+	return r.dao.ExpireHolds(ctx, walletID, now)
+}
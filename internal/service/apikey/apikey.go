@@ -11,6 +11,7 @@ import (
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository"
 )
 
@@ -23,13 +24,13 @@ type Service interface {
 	// RecordUsage 记录 API key 使用（异步）
 	RecordUsage(ctx context.Context, apiKeyID int64) error
 	// IncrementUsage 增加 API key 使用量
-	IncrementUsage(ctx context.Context, apiKeyID int64, amount float64) error
+	IncrementUsage(ctx context.Context, apiKeyID int64, amount money.Amount) error
 
 	// --- 用户级 API Key 管理 ---
 	// ListByUserID 获取指定用户的 API Key 列表
 	ListByUserID(ctx context.Context, userID int64) ([]domain.APIKey, error)
 	// Create 创建 API Key（返回完整密钥）
-	Create(ctx context.Context, userID int64, name string, enabled *bool, quota *float64, expiresAt *time.Time) (*domain.APIKey, string, error)
+	Create(ctx context.Context, userID int64, name string, enabled *bool, quota *money.Amount, expiresAt *time.Time) (*domain.APIKey, string, error)
 	// Delete 删除用户的 API Key（需验证所有权）
 	Delete(ctx context.Context, userID int64, keyID int64) error
 
@@ -102,7 +103,7 @@ func (s *service) ValidateAPIKey(ctx context.Context, key string) (*domain.APIKe
 		s.logger.Warn("API key quota exceeded",
 			logger.Int64("key_id", apiKey.ID),
 			logger.String("key_prefix", maskAPIKey(key)),
-			logger.Float64("used", apiKey.UsedAmount),
+			logger.String("used", apiKey.UsedAmount.String()),
 			logger.Any("quota", apiKey.Quota),
 		)
 		return nil, errs.ErrAPIKeyQuotaExceeded
@@ -135,7 +136,7 @@ func (s *service) RecordUsage(ctx context.Context, apiKeyID int64) error {
 }
 
 // IncrementUsage 增加 API key 使用量。
-func (s *service) IncrementUsage(ctx context.Context, apiKeyID int64, amount float64) error {
+func (s *service) IncrementUsage(ctx context.Context, apiKeyID int64, amount money.Amount) error {
 	if amount <= 0 {
 		return nil
 	}
@@ -167,7 +168,7 @@ func (s *service) ListByUserID(ctx context.Context, userID int64) ([]domain.APIK
 }
 
 // Create 创建 API Key。
-func (s *service) Create(ctx context.Context, userID int64, name string, enabled *bool, quota *float64, expiresAt *time.Time) (*domain.APIKey, string, error) {
+func (s *service) Create(ctx context.Context, userID int64, name string, enabled *bool, quota *money.Amount, expiresAt *time.Time) (*domain.APIKey, string, error) {
 	// 生成随机 Key
 	bytes := make([]byte, 32)
 	rand.Read(bytes)
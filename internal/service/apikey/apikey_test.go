@@ -12,6 +12,7 @@ import (
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository/mocks"
 )
 
@@ -97,12 +98,12 @@ func TestService_ValidateAPIKey(t *testing.T) {
 	})
 
 	t.Run("QuotaExceeded", func(t *testing.T) {
-		quota := 100.0
+		quota := money.Dollars(100)
 		key := &domain.APIKey{
 			ID:         1,
 			Enabled:    true,
 			Quota:      &quota,
-			UsedAmount: 150.0, // 已超过配额
+			UsedAmount: money.Dollars(150), // 已超过配额
 		}
 		mockRepo.EXPECT().GetByKey(ctx, "over-quota-key").Return(key, nil)
 
@@ -6,6 +6,7 @@ package apikeymocks
 
 import (
 	domain "ai-gateway/internal/domain"
+	money "ai-gateway/internal/pkg/money"
 	context "context"
 	reflect "reflect"
 	time "time"
@@ -37,7 +38,7 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockService) Create(ctx context.Context, userID int64, name string, enabled *bool, quota *float64, expiresAt *time.Time) (*domain.APIKey, string, error) {
+func (m *MockService) Create(ctx context.Context, userID int64, name string, enabled *bool, quota *money.Amount, expiresAt *time.Time) (*domain.APIKey, string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, userID, name, enabled, quota, expiresAt)
 	ret0, _ := ret[0].(*domain.APIKey)
@@ -81,7 +82,7 @@ func (mr *MockServiceMockRecorder) DeleteByID(ctx, keyID interface{}) *gomock.Ca
 }
 
 // IncrementUsage mocks base method.
-func (m *MockService) IncrementUsage(ctx context.Context, apiKeyID int64, amount float64) error {
+func (m *MockService) IncrementUsage(ctx context.Context, apiKeyID int64, amount money.Amount) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IncrementUsage", ctx, apiKeyID, amount)
 	ret0, _ := ret[0].(error)
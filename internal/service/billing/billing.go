@@ -0,0 +1,256 @@
+// Package billing 统一计算请求费用，并以请求 ID 幂等地记账。
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
+	"ai-gateway/internal/repository"
+	"ai-gateway/internal/service/modelrate"
+)
+
+// defaultMaxTokens 未配置 billing.defaultMaxTokens 时，请求未指定 max_tokens 用于预估费用的输出 token 数
+const defaultMaxTokens = 4096
+
+// Service 计费服务：每个请求只计算一次费用，用量日志、钱包流水与 API Key 用量在同一事务中写入。
+//
+//go:generate mockgen -source=./billing.go -destination=./mocks/billing.mock.go -package=billingmocks Service
+type Service interface {
+	// Estimate 预估请求的最大费用，用于发出请求前的预授权；maxTokens 为 0 时取配置的默认值
+	Estimate(ctx context.Context, model string, promptTokens, maxTokens int) (money.Amount, error)
+
+	// Charge 按实际用量计算一批请求的费用并记账：写入用量日志、结算预授权、扣除钱包余额并累加 Key 用量。
+	// 整批在一个事务中提交；以请求 ID 幂等，已记账的请求被跳过，重试不会重复扣费。
+	// 用量日志的 Charged、钱包流水与 Key 用量使用同一金额，开启 NoOverdraft 时未收取的差额只体现为 Cost 与 Charged 之差。
+	Charge(ctx context.Context, logs []*domain.UsageLog) error
+}
+
+type service struct {
+	walletRepo   repository.WalletRepository
+	apiKeyRepo   repository.APIKeyRepository
+	usageLogRepo repository.UsageLogRepository
+	modelRateSvc modelrate.Service
+	cfg          config.BillingConfig
+	logger       logger.Logger
+}
+
+func NewService(
+	walletRepo repository.WalletRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	usageLogRepo repository.UsageLogRepository,
+	modelRateSvc modelrate.Service,
+	cfg config.BillingConfig,
+	l logger.Logger,
+) Service {
+	return &service{
+		walletRepo:   walletRepo,
+		apiKeyRepo:   apiKeyRepo,
+		usageLogRepo: usageLogRepo,
+		modelRateSvc: modelRateSvc,
+		cfg:          cfg,
+		logger:       l.With(logger.String("service", "billing")),
+	}
+}
+
+func (s *service) Estimate(ctx context.Context, model string, promptTokens, maxTokens int) (money.Amount, error) {
+	if maxTokens <= 0 {
+		maxTokens = s.cfg.DefaultMaxTokens
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	return s.cost(ctx, model, promptTokens, maxTokens)
+}
+
+// Charge 先按用户 ID 顺序锁定涉及的钱包行，再查询哪些请求已有用量日志，跳过已记账的请求。
+// 用户没有钱包行时不会锁住任何行，同一请求的并发记账（如预写日志重放与正常写入）可能同时通过查询，
+// 因此以用量日志的写入结果为准：只有本事务实际写入用量日志的请求才结算预授权、写入扣费流水并累加 Key 用量。
+// 任一步失败整批回滚。
+func (s *service) Charge(ctx context.Context, logs []*domain.UsageLog) error {
+	logs = uniqueRequests(logs)
+	if len(logs) == 0 {
+		return nil
+	}
+	requestIDs := make([]string, len(logs))
+	for i, log := range logs {
+		if log.RequestID == "" {
+			return errors.New("billing: request id is required")
+		}
+		requestIDs[i] = log.RequestID
+		cost, err := s.cost(ctx, log.Model, log.InputTokens, log.OutputTokens)
+		if err != nil {
+			return err
+		}
+		log.Cost, log.Charged = cost, cost
+	}
+
+	return s.walletRepo.Transaction(ctx, func(ctx context.Context) error {
+		wallets, err := s.lockWallets(ctx, logs)
+		if err != nil {
+			return err
+		}
+		recorded, err := s.usageLogRepo.FindRequestIDs(ctx, requestIDs)
+		if err != nil {
+			return err
+		}
+		fresh := logs[:0:0]
+		for _, log := range logs {
+			if !recorded[log.RequestID] {
+				fresh = append(fresh, log)
+			}
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+		inserted, err := s.usageLogRepo.CreateBatch(ctx, fresh)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range fresh {
+			if !inserted[log.RequestID] {
+				continue // 并发记账已写入同一请求
+			}
+			if err := s.settleHold(ctx, log); err != nil {
+				return err
+			}
+			if wallet := wallets[log.UserID]; wallet != nil {
+				if err := s.deduct(ctx, wallet, log); err != nil {
+					return err
+				}
+			}
+			if log.APIKeyID != nil && log.Charged > 0 {
+				if err := s.apiKeyRepo.IncrementUsage(ctx, *log.APIKeyID, log.Charged); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// uniqueRequests 去掉批次中请求 ID 重复的记录（如预写日志重放），保留第一条
+func uniqueRequests(logs []*domain.UsageLog) []*domain.UsageLog {
+	seen := make(map[string]bool, len(logs))
+	out := make([]*domain.UsageLog, 0, len(logs))
+	for _, log := range logs {
+		if log.RequestID != "" && seen[log.RequestID] {
+			continue
+		}
+		seen[log.RequestID] = true
+		out = append(out, log)
+	}
+	return out
+}
+
+// lockWallets 按用户 ID 升序锁定钱包行，固定加锁顺序避免并发批次之间死锁；没有钱包的用户只记录用量
+func (s *service) lockWallets(ctx context.Context, logs []*domain.UsageLog) (map[int64]*domain.Wallet, error) {
+	var userIDs []int64
+	wallets := make(map[int64]*domain.Wallet)
+	for _, log := range logs {
+		if _, ok := wallets[log.UserID]; !ok && log.UserID > 0 {
+			wallets[log.UserID] = nil
+			userIDs = append(userIDs, log.UserID)
+		}
+	}
+	slices.Sort(userIDs)
+	for _, userID := range userIDs {
+		w, err := s.walletRepo.GetByUserIDForUpdate(ctx, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		wallets[userID] = w
+	}
+	return wallets, nil
+}
+
+// cost 按模型费率计算费用。费率查询失败时返回错误：按 0 记账后用量日志以请求 ID 落库，重试也无法更正
+func (s *service) cost(ctx context.Context, model string, inputTokens, outputTokens int) (money.Amount, error) {
+	promptPrice, completionPrice, err := s.modelRateSvc.GetRateForModel(ctx, model)
+	if err != nil {
+		return 0, fmt.Errorf("billing: rate for model %q: %w", model, err)
+	}
+	return money.TokenCost(inputTokens, outputTokens, promptPrice, completionPrice), nil
+}
+
+// settleHold 把请求的预授权标记为已结算，已过期的预授权同样结算（请求已完成，费用照常扣除）
+func (s *service) settleHold(ctx context.Context, log *domain.UsageLog) error {
+	hold, err := s.walletRepo.GetHoldByRequestID(ctx, log.RequestID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if log.Cost > hold.Amount {
+		s.logger.Warn("actual cost exceeds hold",
+			logger.String("requestID", log.RequestID),
+			logger.String("hold", hold.Amount.String()),
+			logger.String("cost", log.Cost.String()),
+		)
+	}
+	_, err = s.walletRepo.UpdateHoldStatus(ctx, hold.ID,
+		[]domain.HoldStatus{domain.HoldStatusActive, domain.HoldStatusExpired}, domain.HoldStatusSettled)
+	return err
+}
+
+// deduct 扣除已加锁钱包的余额并写入流水，同步更新 wallet.Balance 供同批次后续记录使用。
+// 开启 NoOverdraft 时请求已经完成，不能再拒绝：余额最多扣至 0，超出的部分不再收取，
+// 用量日志的 Charged 改为实际扣除的金额，流水说明中注明未收取的差额。
+func (s *service) deduct(ctx context.Context, wallet *domain.Wallet, log *domain.UsageLog) error {
+	if log.Cost <= 0 {
+		return nil
+	}
+	description := fmt.Sprintf("Usage: %s (In:%d, Out:%d)", log.Model, log.InputTokens, log.OutputTokens)
+	if s.cfg.NoOverdraft && log.Cost > wallet.Balance {
+		log.Charged = max(wallet.Balance, 0)
+		unpaid := log.Cost - log.Charged
+		description += fmt.Sprintf(", unpaid %s", unpaid)
+		s.logger.Warn("insufficient balance, charge capped",
+			logger.Int64("userID", log.UserID),
+			logger.String("requestID", log.RequestID),
+			logger.String("cost", log.Cost.String()),
+			logger.String("unpaid", unpaid.String()),
+		)
+		if err := s.usageLogRepo.UpdateCharged(ctx, log.RequestID, log.Charged); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("deducting wallet",
+		logger.Int64("userID", log.UserID),
+		logger.String("requestID", log.RequestID),
+		logger.String("cost", log.Cost.String()),
+		logger.String("charged", log.Charged.String()),
+		logger.String("model", log.Model),
+	)
+
+	if log.Charged > 0 {
+		if err := s.walletRepo.UpdateBalance(ctx, wallet.ID, -log.Charged); err != nil {
+			return err
+		}
+	}
+	// 余额为 0 时同样写入一条金额为 0 的流水，每个请求在流水中都有记录
+	err := s.walletRepo.CreateTransaction(ctx, &domain.WalletTransaction{
+		WalletID:      wallet.ID,
+		Type:          domain.TransactionTypeDeduct,
+		Amount:        -log.Charged,
+		BalanceBefore: wallet.Balance,
+		BalanceAfter:  wallet.Balance - log.Charged,
+		ReferenceID:   log.RequestID,
+		Description:   description,
+	})
+	if err != nil {
+		return err
+	}
+	wallet.Balance -= log.Charged
+	return nil
+}
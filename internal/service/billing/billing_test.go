@@ -0,0 +1,187 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
+	"ai-gateway/internal/repository/mocks"
+	modelratemocks "ai-gateway/internal/service/modelrate/mocks"
+)
+
+type testDeps struct {
+	wallets *mocks.MockWalletRepository
+	keys    *mocks.MockAPIKeyRepository
+	logs    *mocks.MockUsageLogRepository
+}
+
+func newTestService(t *testing.T, cfg config.BillingConfig) (Service, testDeps) {
+	ctrl := gomock.NewController(t)
+	deps := testDeps{
+		wallets: mocks.NewMockWalletRepository(ctrl),
+		keys:    mocks.NewMockAPIKeyRepository(ctrl),
+		logs:    mocks.NewMockUsageLogRepository(ctrl),
+	}
+	rates := modelratemocks.NewMockService(ctrl)
+	// $10 / $30 每 1M tokens
+	rates.EXPECT().GetRateForModel(gomock.Any(), "gpt-4o").Return(money.Dollars(10), money.Dollars(30), nil).AnyTimes()
+	rates.EXPECT().GetRateForModel(gomock.Any(), "o1").Return(money.Amount(0), money.Amount(0), errors.New("connection refused")).AnyTimes()
+	deps.wallets.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error { return fn(ctx) }).AnyTimes()
+	return NewService(deps.wallets, deps.keys, deps.logs, rates, cfg, logger.NewNopLogger()), deps
+}
+
+func newUsageLog() *domain.UsageLog {
+	keyID := int64(7)
+	return &domain.UsageLog{UserID: 1, APIKeyID: &keyID, Model: "gpt-4o", InputTokens: 1000, OutputTokens: 500, RequestID: "req-1"}
+}
+
+func TestService_Charge(t *testing.T) {
+	svc, deps := newTestService(t, config.BillingConfig{})
+	ctx := context.Background()
+
+	// 1000 × $10 / 1M + 500 × $30 / 1M = $0.025
+	cost := money.Amount(25_000)
+	deps.wallets.EXPECT().GetByUserIDForUpdate(gomock.Any(), int64(1)).Return(&domain.Wallet{ID: 3, UserID: 1, Balance: money.Dollars(1)}, nil)
+	deps.logs.EXPECT().FindRequestIDs(gomock.Any(), []string{"req-1"}).Return(map[string]bool{}, nil)
+	deps.logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, logs []*domain.UsageLog) (map[string]bool, error) {
+		require.Len(t, logs, 1)
+		assert.Equal(t, cost, logs[0].Cost)
+		assert.Equal(t, cost, logs[0].Charged)
+		return map[string]bool{"req-1": true}, nil
+	})
+	deps.wallets.EXPECT().GetHoldByRequestID(gomock.Any(), "req-1").Return(&domain.WalletHold{ID: 9, Amount: money.Amount(50_000)}, nil)
+	deps.wallets.EXPECT().UpdateHoldStatus(gomock.Any(), int64(9),
+		[]domain.HoldStatus{domain.HoldStatusActive, domain.HoldStatusExpired}, domain.HoldStatusSettled).Return(true, nil)
+	deps.wallets.EXPECT().UpdateBalance(gomock.Any(), int64(3), -cost).Return(nil)
+	deps.wallets.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *domain.WalletTransaction) error {
+		assert.Equal(t, -cost, tx.Amount)
+		assert.Equal(t, money.Dollars(1), tx.BalanceBefore)
+		assert.Equal(t, money.Amount(975_000), tx.BalanceAfter)
+		assert.Equal(t, "req-1", tx.ReferenceID)
+		return nil
+	})
+	deps.keys.EXPECT().IncrementUsage(gomock.Any(), int64(7), cost).Return(nil)
+
+	require.NoError(t, svc.Charge(ctx, []*domain.UsageLog{newUsageLog()}))
+}
+
+func TestService_Charge_AlreadyRecorded(t *testing.T) {
+	svc, deps := newTestService(t, config.BillingConfig{})
+
+	// 同一请求 ID 的用量日志已存在：不再结算、扣费或累加 Key 用量
+	deps.wallets.EXPECT().GetByUserIDForUpdate(gomock.Any(), int64(1)).Return(&domain.Wallet{ID: 3, UserID: 1, Balance: money.Dollars(1)}, nil)
+	deps.logs.EXPECT().FindRequestIDs(gomock.Any(), []string{"req-1"}).Return(map[string]bool{"req-1": true}, nil)
+
+	// 批次内重复的请求 ID 只记账一次
+	require.NoError(t, svc.Charge(context.Background(), []*domain.UsageLog{newUsageLog(), newUsageLog()}))
+	assert.Error(t, svc.Charge(context.Background(), []*domain.UsageLog{{UserID: 1, Model: "gpt-4o"}}))
+}
+
+func TestService_Charge_Batch(t *testing.T) {
+	svc, deps := newTestService(t, config.BillingConfig{})
+
+	// 同一用户的两条记录只锁一次钱包，第二条流水接着第一条的余额
+	deps.wallets.EXPECT().GetByUserIDForUpdate(gomock.Any(), int64(1)).Return(&domain.Wallet{ID: 3, UserID: 1, Balance: money.Dollars(1)}, nil)
+	deps.logs.EXPECT().FindRequestIDs(gomock.Any(), []string{"req-1", "req-2", "req-3"}).Return(map[string]bool{"req-3": true}, nil)
+	deps.logs.EXPECT().CreateBatch(gomock.Any(), gomock.Len(2)).Return(map[string]bool{"req-1": true, "req-2": true}, nil)
+	deps.wallets.EXPECT().GetHoldByRequestID(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound).Times(2)
+	deps.wallets.EXPECT().UpdateBalance(gomock.Any(), int64(3), money.Amount(-25_000)).Return(nil).Times(2)
+	var after []money.Amount
+	deps.wallets.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *domain.WalletTransaction) error {
+		after = append(after, tx.BalanceAfter)
+		return nil
+	}).Times(2)
+	deps.keys.EXPECT().IncrementUsage(gomock.Any(), int64(7), money.Amount(25_000)).Return(nil).Times(2)
+
+	logs := []*domain.UsageLog{newUsageLog(), newUsageLog(), newUsageLog()}
+	logs[1].RequestID, logs[2].RequestID = "req-2", "req-3"
+	require.NoError(t, svc.Charge(context.Background(), logs))
+	assert.Equal(t, []money.Amount{975_000, 950_000}, after)
+}
+
+func TestService_Charge_ConcurrentDuplicate(t *testing.T) {
+	svc, deps := newTestService(t, config.BillingConfig{})
+
+	// 用户没有钱包行时不加锁，并发记账的另一事务在查询之后写入了 req-1：
+	// 用量日志写入被忽略，本事务只结算实际写入的 req-2
+	deps.wallets.EXPECT().GetByUserIDForUpdate(gomock.Any(), int64(1)).Return(nil, gorm.ErrRecordNotFound)
+	deps.logs.EXPECT().FindRequestIDs(gomock.Any(), []string{"req-1", "req-2"}).Return(map[string]bool{}, nil)
+	deps.logs.EXPECT().CreateBatch(gomock.Any(), gomock.Len(2)).Return(map[string]bool{"req-2": true}, nil)
+	deps.wallets.EXPECT().GetHoldByRequestID(gomock.Any(), "req-2").Return(&domain.WalletHold{ID: 9, Amount: money.Amount(50_000)}, nil)
+	deps.wallets.EXPECT().UpdateHoldStatus(gomock.Any(), int64(9), gomock.Any(), domain.HoldStatusSettled).Return(true, nil)
+	deps.keys.EXPECT().IncrementUsage(gomock.Any(), int64(7), money.Amount(25_000)).Return(nil)
+
+	logs := []*domain.UsageLog{newUsageLog(), newUsageLog()}
+	logs[1].RequestID = "req-2"
+	require.NoError(t, svc.Charge(context.Background(), logs))
+}
+
+func TestService_Charge_NoOverdraft(t *testing.T) {
+	svc, deps := newTestService(t, config.BillingConfig{NoOverdraft: true})
+
+	// 余额不足时最多扣至 0：用量日志的 Charged、流水与 Key 用量都只计实际扣除的 0.01，差额 0.015 不再收取
+	deps.wallets.EXPECT().GetByUserIDForUpdate(gomock.Any(), int64(1)).Return(&domain.Wallet{ID: 3, UserID: 1, Balance: money.Amount(10_000)}, nil)
+	deps.logs.EXPECT().FindRequestIDs(gomock.Any(), gomock.Any()).Return(map[string]bool{}, nil)
+	deps.logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Return(map[string]bool{"req-1": true, "req-2": true}, nil)
+	deps.wallets.EXPECT().GetHoldByRequestID(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound).Times(2)
+	deps.logs.EXPECT().UpdateCharged(gomock.Any(), "req-1", money.Amount(10_000)).Return(nil)
+	deps.logs.EXPECT().UpdateCharged(gomock.Any(), "req-2", money.Amount(0)).Return(nil)
+	deps.wallets.EXPECT().UpdateBalance(gomock.Any(), int64(3), money.Amount(-10_000)).Return(nil)
+	var txs []*domain.WalletTransaction
+	deps.wallets.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *domain.WalletTransaction) error {
+		txs = append(txs, tx)
+		return nil
+	}).Times(2)
+	deps.keys.EXPECT().IncrementUsage(gomock.Any(), int64(7), money.Amount(10_000)).Return(nil)
+
+	logs := []*domain.UsageLog{newUsageLog(), newUsageLog()}
+	logs[1].RequestID = "req-2"
+	require.NoError(t, svc.Charge(context.Background(), logs))
+
+	assert.Equal(t, money.Amount(25_000), logs[0].Cost)
+	assert.Equal(t, money.Amount(10_000), logs[0].Charged)
+	assert.Equal(t, money.Amount(0), logs[1].Charged)
+	require.Len(t, txs, 2)
+	assert.Equal(t, money.Amount(-10_000), txs[0].Amount)
+	assert.Contains(t, txs[0].Description, "unpaid 0.015")
+	// 余额为 0 后仍写入金额为 0 的流水
+	assert.Equal(t, money.Amount(0), txs[1].Amount)
+	assert.Equal(t, money.Amount(0), txs[1].BalanceAfter)
+	assert.Contains(t, txs[1].Description, "unpaid 0.025")
+}
+
+func TestService_Estimate(t *testing.T) {
+	svc, _ := newTestService(t, config.BillingConfig{})
+	ctx := context.Background()
+
+	// 100 × $10 / 1M + 500 × $30 / 1M
+	amount, err := svc.Estimate(ctx, "gpt-4o", 100, 500)
+	require.NoError(t, err)
+	assert.Equal(t, money.Amount(16_000), amount)
+	// 未指定 max_tokens 时按 4096 预估
+	amount, err = svc.Estimate(ctx, "gpt-4o", 100, 0)
+	require.NoError(t, err)
+	assert.Equal(t, money.Amount(123_880), amount)
+
+	_, err = svc.Estimate(ctx, "o1", 100, 0)
+	assert.Error(t, err)
+}
+
+func TestService_Charge_RateError(t *testing.T) {
+	svc, _ := newTestService(t, config.BillingConfig{})
+
+	// 费率查询失败时整批不记账，不能按 0 写入用量日志
+	logs := []*domain.UsageLog{newUsageLog(), newUsageLog()}
+	logs[1].RequestID, logs[1].Model = "req-2", "o1"
+	assert.Error(t, svc.Charge(context.Background(), logs))
+}
@@ -0,0 +1,370 @@
+package billing
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/spool"
+)
+
+// 记账队列的默认设置
+const (
+	defaultQueueSize     = 10000
+	defaultQueueWorkers  = 4
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultMaxAttempts   = 5
+
+	chargeTimeout = 10 * time.Second
+	maxRetryDelay = 30 * time.Second
+
+	deadLetterFile = "dead-letter.jsonl"
+)
+
+// Recorder 异步记账：请求结束后把用量放入有界队列，由固定数量的 worker 批量调用 Charge。
+//
+// 配置 billing.queue.spoolDir 后，用量先追加到本地预写日志再进入队列，记账成功后才确认；
+// 数据库不可用期间记录保留在磁盘上，恢复后继续写入，进程重启后重放未确认的记录（Charge 以请求 ID 幂等）。
+// 未配置时队列写满、或关闭服务时未能在 billing.queue.drainTimeout 内记账的记录直接丢弃，
+// 只记录错误日志并累计到 Dropped；不允许丢失用量的部署必须配置 spoolDir。
+//
+// 单条记录反复记账失败（数据不合法等，连接类错误除外）超过 billing.queue.maxAttempts 次后转入死信：
+// 记录错误日志，配置了预写日志时另追加到其目录下的 dead-letter.jsonl 以便人工补记，不再阻塞同批的其他记录。
+//
+//go:generate mockgen -source=./recorder.go -destination=./mocks/recorder.mock.go -package=billingmocks Recorder
+type Recorder interface {
+	// Record 提交一个请求的用量，不阻塞调用方
+	Record(log *domain.UsageLog)
+
+	// Shutdown 停止接收新记录并等待队列排空，ctx 结束时放弃剩余记录（已写入预写日志的记录下次启动时重放）
+	Shutdown(ctx context.Context) error
+
+	// Dropped 返回自启动以来未能记账且不会重放的记录数
+	Dropped() uint64
+}
+
+type item struct {
+	log      *domain.UsageLog
+	entry    *spool.Entry // 来自预写日志的记录，记账成功或转入死信后确认
+	attempts int          // 单独记账失败的次数
+}
+
+type recorder struct {
+	svc           Service
+	spool         *spool.Spool
+	deadLetters   *os.File
+	deadMu        sync.Mutex
+	queue         chan item
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	logger        logger.Logger
+	dropped       atomic.Uint64
+
+	mu      sync.RWMutex
+	closed  bool
+	stop    chan struct{} // 关闭后预写日志读取协程退出
+	abort   chan struct{} // 关闭后 worker 放弃重试
+	readers sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+// NewRecorder 创建记账队列并启动 worker，配置了 spoolDir 时打开预写日志并重放上次未确认的记录。
+func NewRecorder(svc Service, cfg config.BillingConfig, l logger.Logger) (Recorder, error) {
+	qc := cfg.Queue
+	size, workers, batchSize, flushInterval, maxAttempts := qc.Size, qc.Workers, qc.BatchSize, qc.FlushInterval, qc.MaxAttempts
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	if workers <= 0 {
+		workers = defaultQueueWorkers
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	r := &recorder{
+		svc:           svc,
+		queue:         make(chan item, size),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxAttempts:   maxAttempts,
+		logger:        l.With(logger.String("service", "billing.recorder")),
+		stop:          make(chan struct{}),
+		abort:         make(chan struct{}),
+	}
+	if qc.SpoolDir != "" {
+		sp, err := spool.Open(qc.SpoolDir, qc.SpoolSegmentBytes)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(filepath.Join(qc.SpoolDir, deadLetterFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			_ = sp.Close()
+			return nil, err
+		}
+		r.spool, r.deadLetters = sp, f
+		r.readers.Add(1)
+		go r.readSpool()
+	}
+	for i := 0; i < workers; i++ {
+		r.workers.Add(1)
+		go r.work()
+	}
+	return r, nil
+}
+
+func (r *recorder) Record(log *domain.UsageLog) {
+	if r.spool != nil {
+		data, err := json.Marshal(log)
+		if err == nil {
+			err = r.spool.Append(data)
+		}
+		if err != nil {
+			r.dropped.Add(1)
+			r.logger.Error("failed to spool usage, dropped", logger.String("requestID", log.RequestID), logger.Error(err))
+		}
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		r.dropped.Add(1)
+		r.logger.Error("usage recorder closed, dropped", logger.String("requestID", log.RequestID))
+		return
+	}
+	select {
+	case r.queue <- item{log: log}:
+	default:
+		r.dropped.Add(1)
+		r.logger.Error("usage queue full, dropped", logger.String("requestID", log.RequestID))
+	}
+}
+
+func (r *recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+func (r *recorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stop)
+	r.mu.Unlock()
+
+	r.readers.Wait()
+	close(r.queue)
+
+	done := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		close(r.abort)
+		<-done
+		err = ctx.Err()
+	}
+
+	if r.spool != nil {
+		err = errors.Join(err, r.spool.Close(), r.deadLetters.Close())
+	}
+	return err
+}
+
+// readSpool 按写入顺序把预写日志中的记录送入队列，队列已满时阻塞，记录留在磁盘上
+func (r *recorder) readSpool() {
+	defer r.readers.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.stop
+		cancel()
+	}()
+
+	delay := time.Second
+	for {
+		e, err := r.spool.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, spool.ErrClosed) {
+				return
+			}
+			r.logger.Error("failed to read usage spool, retrying", logger.Duration("retryIn", delay), logger.Error(err))
+			select {
+			case <-time.After(delay):
+				delay = min(delay*2, maxRetryDelay)
+				continue
+			case <-r.stop:
+				return
+			}
+		}
+		delay = time.Second
+		var log domain.UsageLog
+		if err := json.Unmarshal(e.Data, &log); err != nil {
+			r.logger.Error("malformed usage spool record, skipped", logger.Error(err))
+			r.spool.Ack(e)
+			continue
+		}
+		select {
+		case r.queue <- item{log: &log, entry: &e}:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *recorder) work() {
+	defer r.workers.Done()
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]item, 0, r.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case it, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, it)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush 记账一批记录，失败时按指数退避重试，直到全部记账、转入死信或 Shutdown 超时放弃
+func (r *recorder) flush(batch []item) {
+	delay := time.Second
+	for {
+		failed, err := r.charge(batch, nil)
+		pending := failed[:0]
+		for _, it := range failed {
+			if it.attempts >= r.maxAttempts {
+				r.deadLetter(it, err)
+				continue
+			}
+			pending = append(pending, it)
+		}
+		if len(pending) == 0 {
+			return
+		}
+		batch = pending
+
+		r.logger.Error("failed to charge usage batch, retrying",
+			logger.Int("size", len(batch)),
+			logger.Duration("retryIn", delay),
+			logger.Error(err),
+		)
+		select {
+		case <-time.After(delay):
+			delay = min(delay*2, maxRetryDelay)
+		case <-r.abort:
+			if r.spool == nil {
+				r.dropped.Add(uint64(len(batch)))
+			}
+			r.logger.Error("usage batch not charged before shutdown",
+				logger.Int("size", len(batch)),
+				logger.Bool("spooled", r.spool != nil),
+			)
+			return
+		}
+	}
+}
+
+// charge 记账一批记录，把未能记账的记录追加到 failed 后返回。
+// 连接类错误整批返回；其他错误二分拆开重试，定位到无法记账的单条记录并累计其失败次数，其余记录照常记账。
+func (r *recorder) charge(batch, failed []item) ([]item, error) {
+	logs := make([]*domain.UsageLog, len(batch))
+	for i, it := range batch {
+		logs[i] = it.log
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chargeTimeout)
+	err := r.svc.Charge(ctx, logs)
+	cancel()
+
+	switch {
+	case err == nil:
+		for _, it := range batch {
+			r.ack(it)
+		}
+		return failed, nil
+	case isTransient(err):
+		return append(failed, batch...), err
+	case len(batch) == 1:
+		it := batch[0]
+		it.attempts++
+		return append(failed, it), err
+	}
+	mid := len(batch) / 2
+	failed, lerr := r.charge(batch[:mid], failed)
+	failed, rerr := r.charge(batch[mid:], failed)
+	return failed, errors.Join(lerr, rerr)
+}
+
+// deadLetter 放弃一条无法记账的记录
+func (r *recorder) deadLetter(it item, cause error) {
+	data, _ := json.Marshal(it.log)
+	r.logger.Error("usage dead-lettered",
+		logger.String("requestID", it.log.RequestID),
+		logger.Int("attempts", it.attempts),
+		logger.String("record", string(data)),
+		logger.Error(cause),
+	)
+	if r.deadLetters == nil {
+		return
+	}
+	r.deadMu.Lock()
+	_, err := r.deadLetters.Write(append(data, '\n'))
+	r.deadMu.Unlock()
+	if err != nil {
+		r.logger.Error("failed to write usage dead letter", logger.String("requestID", it.log.RequestID), logger.Error(err))
+		return
+	}
+	r.ack(it)
+}
+
+func (r *recorder) ack(it item) {
+	if it.entry != nil {
+		r.spool.Ack(*it.entry)
+	}
+}
+
+// isTransient 判断是否为数据库不可用一类的临时错误，这类错误与具体记录无关，不计入失败次数
+func isTransient(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
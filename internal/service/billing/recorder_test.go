@@ -0,0 +1,154 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCharger 记录成功记账的请求 ID。fail 为 true 时模拟数据库不可用；
+// 批次中含有 poison 里的请求 ID 时整批失败
+type fakeCharger struct {
+	mu      sync.Mutex
+	fail    bool
+	poison  map[string]bool
+	charged map[string]int
+}
+
+func (f *fakeCharger) Estimate(ctx context.Context, model string, promptTokens, maxTokens int) (money.Amount, error) {
+	return 0, nil
+}
+
+func (f *fakeCharger) Charge(ctx context.Context, logs []*domain.UsageLog) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	for _, log := range logs {
+		if f.poison[log.RequestID] {
+			return errors.New("data too long for column 'model'")
+		}
+	}
+	if f.charged == nil {
+		f.charged = make(map[string]int)
+	}
+	for _, log := range logs {
+		f.charged[log.RequestID]++
+	}
+	return nil
+}
+
+func usageLogs(n int) []*domain.UsageLog {
+	logs := make([]*domain.UsageLog, n)
+	for i := range logs {
+		logs[i] = &domain.UsageLog{RequestID: fmt.Sprintf("req-%d", i), UserID: 1, Model: "gpt-4o"}
+	}
+	return logs
+}
+
+func TestRecorder_DrainOnShutdown(t *testing.T) {
+	svc := &fakeCharger{}
+	r, err := NewRecorder(svc, config.BillingConfig{Queue: config.BillingQueueConfig{Workers: 2, BatchSize: 16, FlushInterval: time.Hour}}, logger.NewNopLogger())
+	require.NoError(t, err)
+
+	for _, log := range usageLogs(250) {
+		r.Record(log)
+	}
+	require.NoError(t, r.Shutdown(context.Background()))
+	assert.Len(t, svc.charged, 250)
+	assert.Zero(t, r.Dropped())
+
+	// 关闭后提交的记录直接丢弃
+	r.Record(&domain.UsageLog{RequestID: "late"})
+	assert.NotContains(t, svc.charged, "late")
+	assert.Equal(t, uint64(1), r.Dropped())
+}
+
+func TestRecorder_DroppedOnDrainTimeout(t *testing.T) {
+	// 未配置预写日志时，停机超时仍未记账的记录计入丢弃数
+	svc := &fakeCharger{fail: true}
+	r, err := NewRecorder(svc, config.BillingConfig{Queue: config.BillingQueueConfig{Workers: 1, BatchSize: 16, FlushInterval: time.Millisecond}}, logger.NewNopLogger())
+	require.NoError(t, err)
+
+	for _, log := range usageLogs(10) {
+		r.Record(log)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, r.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Equal(t, uint64(10), r.Dropped())
+}
+
+func TestRecorder_SpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.BillingConfig{Queue: config.BillingQueueConfig{SpoolDir: dir, FlushInterval: 10 * time.Millisecond}}
+
+	// 数据库不可用：记录留在预写日志中，Shutdown 超时放弃（读取协程尚未送入队列时直接返回）
+	down := &fakeCharger{fail: true}
+	r, err := NewRecorder(down, cfg, logger.NewNopLogger())
+	require.NoError(t, err)
+	for _, log := range usageLogs(20) {
+		r.Record(log)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = r.Shutdown(ctx)
+
+	// 重启后重放全部未确认的记录
+	up := &fakeCharger{}
+	r, err = NewRecorder(up, cfg, logger.NewNopLogger())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		up.mu.Lock()
+		defer up.mu.Unlock()
+		return len(up.charged) == 20
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRecorder_DeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	svc := &fakeCharger{poison: map[string]bool{"req-3": true}}
+	cfg := config.BillingConfig{Queue: config.BillingQueueConfig{BatchSize: 10, FlushInterval: 10 * time.Millisecond, MaxAttempts: 2, SpoolDir: dir}}
+	r, err := NewRecorder(svc, cfg, logger.NewNopLogger())
+	require.NoError(t, err)
+
+	// 同批的其他记录照常记账，无法记账的记录重试后转入死信
+	for _, log := range usageLogs(10) {
+		r.Record(log)
+	}
+	deadLetters := filepath.Join(dir, deadLetterFile)
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(deadLetters)
+		return len(data) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, r.Shutdown(context.Background()))
+
+	assert.Len(t, svc.charged, 9)
+	assert.NotContains(t, svc.charged, "req-3")
+	data, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"requestId":"req-3"`)
+
+	// 死信记录已确认，重启后不再重放
+	svc = &fakeCharger{}
+	r, err = NewRecorder(svc, cfg, logger.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, r.Shutdown(context.Background()))
+	assert.Empty(t, svc.charged)
+}
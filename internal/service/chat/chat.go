@@ -3,15 +3,20 @@ package chat
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
 	"time"
 
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
-	"ai-gateway/internal/service/apikey"
+	"ai-gateway/internal/pkg/money"
+	"ai-gateway/internal/service/billing"
 	"ai-gateway/internal/service/gateway"
-	"ai-gateway/internal/service/modelrate"
-	"ai-gateway/internal/service/usage"
 	"ai-gateway/internal/service/wallet"
 )
 
@@ -19,9 +24,10 @@ import (
 type RequestMeta struct {
 	UserID    int64
 	APIKeyID  *int64
-	RequestID string
+	RequestID string // 网关生成的计费幂等键，不同于客户端的 X-Request-ID
 	ClientIP  string
 	UserAgent string
+	SessionID string // 客户端提供的会话标识，用于会话亲和路由
 }
 
 // Service 统一封装 Chat + 计费/用量记录。
@@ -33,65 +39,77 @@ type Service interface {
 }
 
 type service struct {
-	gw           gateway.GatewayService
-	walletSvc    wallet.Service
-	usageSvc     usage.Service
-	apiKeySvc    apikey.Service
-	modelRateSvc modelrate.Service
-	logger       logger.Logger
+	gw         gateway.GatewayService
+	walletSvc  wallet.Service
+	billingSvc billing.Service
+	recorder   billing.Recorder
+	logger     logger.Logger
 }
 
 func NewService(
 	gw gateway.GatewayService,
 	walletSvc wallet.Service,
-	usageSvc usage.Service,
-	apiKeySvc apikey.Service,
-	modelRateSvc modelrate.Service,
+	billingSvc billing.Service,
+	recorder billing.Recorder,
 	l logger.Logger,
 ) Service {
 	return &service{
-		gw:           gw,
-		walletSvc:    walletSvc,
-		usageSvc:     usageSvc,
-		apiKeySvc:    apiKeySvc,
-		modelRateSvc: modelRateSvc,
-		logger:       l.With(logger.String("service", "chat")),
+		gw:         gw,
+		walletSvc:  walletSvc,
+		billingSvc: billingSvc,
+		recorder:   recorder,
+		logger:     l.With(logger.String("service", "chat")),
 	}
 }
 
 func (s *service) Chat(ctx context.Context, req *domain.ChatRequest, meta RequestMeta) (*domain.ChatResponse, error) {
-	if err := s.preflight(ctx, meta.UserID); err != nil {
+	req.SessionKey = sessionKey(req, meta)
+	req.SplitKey = splitKey(meta)
+	req.RequestID = meta.RequestID
+	hold, err := s.preflight(ctx, req, meta)
+	if err != nil {
 		return nil, err
 	}
+	stop := s.keepHold(hold)
+	defer stop()
 
 	start := time.Now()
 	resp, err := s.gw.Chat(ctx, req)
 	if err != nil {
+		s.release(meta)
 		return nil, err
 	}
 
 	model := req.Model // 注意：gateway 会把 model 重写成实际模型
-	provider := resp.Provider
+	provider := domain.ProviderRef{ID: resp.ProviderID, Name: resp.Provider, FallbackHop: resp.FallbackHop, Variant: resp.Variant}
 	usageData := resp.Usage
 	latency := int(time.Since(start).Milliseconds())
 
 	if usageData != nil {
-		s.recordAsync(meta, model, provider, usageData.PromptTokens, usageData.CompletionTokens, httpStatusOK, latency)
+		s.record(meta, model, provider, usageData.PromptTokens, usageData.CompletionTokens, httpStatusOK, latency)
 	} else {
-		s.recordAsync(meta, model, provider, 0, 0, httpStatusOK, latency)
+		s.record(meta, model, provider, 0, 0, httpStatusOK, latency)
 	}
 
 	return resp, nil
 }
 
 func (s *service) ChatStream(ctx context.Context, req *domain.ChatRequest, meta RequestMeta) (<-chan domain.StreamDelta, string, error) {
-	if err := s.preflight(ctx, meta.UserID); err != nil {
+	req.SessionKey = sessionKey(req, meta)
+	req.SplitKey = splitKey(meta)
+	req.RequestID = meta.RequestID
+	hold, err := s.preflight(ctx, req, meta)
+	if err != nil {
 		return nil, "", err
 	}
+	// 流式输出可能远长于预授权有效期，转发期间持续顺延
+	stop := s.keepHold(hold)
 
 	start := time.Now()
 	in, provider, err := s.gw.ChatStream(ctx, req)
 	if err != nil {
+		stop()
+		s.release(meta)
 		return nil, "", err
 	}
 
@@ -100,6 +118,7 @@ func (s *service) ChatStream(ctx context.Context, req *domain.ChatRequest, meta
 
 	go func() {
 		defer close(out)
+		defer stop()
 
 		var inputTokens, outputTokens int
 		statusCode := httpStatusOK
@@ -109,12 +128,12 @@ func (s *service) ChatStream(ctx context.Context, req *domain.ChatRequest, meta
 			case <-ctx.Done():
 				statusCode = httpStatusClientClosed
 				latency := int(time.Since(start).Milliseconds())
-				s.recordAsync(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
+				s.record(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
 				return
 			case delta, ok := <-in:
 				if !ok {
 					latency := int(time.Since(start).Milliseconds())
-					s.recordAsync(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
+					s.record(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
 					return
 				}
 
@@ -131,38 +150,148 @@ func (s *service) ChatStream(ctx context.Context, req *domain.ChatRequest, meta
 				case <-ctx.Done():
 					statusCode = httpStatusClientClosed
 					latency := int(time.Since(start).Milliseconds())
-					s.recordAsync(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
+					s.record(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
 					return
 				case out <- delta:
 				}
 
 				if delta.Type == "done" {
 					latency := int(time.Since(start).Milliseconds())
-					s.recordAsync(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
+					s.record(meta, model, provider, inputTokens, outputTokens, statusCode, latency)
 					return
 				}
 			}
 		}
 	}()
 
-	return out, provider, nil
+	return out, provider.Name, nil
 }
 
-func (s *service) preflight(ctx context.Context, userID int64) error {
-	if userID <= 0 {
-		return nil
+// sessionKey 生成会话亲和键，依次使用：客户端会话标识、系统提示词与首条消息的哈希、API Key ID。
+// 同一会话的后续请求共享相同的前缀，因此会落到同一个上游账号，命中其提示词缓存。
+func sessionKey(req *domain.ChatRequest, meta RequestMeta) string {
+	if meta.SessionID != "" {
+		return "session:" + meta.SessionID
+	}
+	if req.System != "" || len(req.Messages) > 0 {
+		h := sha256.New()
+		h.Write([]byte(req.System))
+		if len(req.Messages) > 0 {
+			first, _ := json.Marshal(req.Messages[0])
+			h.Write(first)
+		}
+		return "prefix:" + hex.EncodeToString(h.Sum(nil)[:16])
+	}
+	if meta.APIKeyID != nil {
+		return "key:" + strconv.FormatInt(*meta.APIKeyID, 10)
+	}
+	return ""
+}
+
+// splitKey 生成 A/B 分流键：优先按用户分组，未关联用户时按 API Key 分组，
+// 使同一调用方在实验期间始终使用同一个模型，体验与统计都不会在组间来回切换。
+func splitKey(meta RequestMeta) string {
+	if meta.UserID > 0 {
+		return "user:" + strconv.FormatInt(meta.UserID, 10)
+	}
+	if meta.APIKeyID != nil {
+		return "key:" + strconv.FormatInt(*meta.APIKeyID, 10)
+	}
+	return ""
+}
+
+// preflight 按提示词长度与 max_tokens 预估本次请求的最大费用，并在钱包与 API Key 额度上预授权该金额，
+// 请求结束后由 record 提交用量按实际费用记账，请求失败时释放。返回的预授权为 nil 表示无需预授权。
+func (s *service) preflight(ctx context.Context, req *domain.ChatRequest, meta RequestMeta) (*domain.WalletHold, error) {
+	if meta.UserID <= 0 {
+		return nil, nil
 	}
 	if s.walletSvc == nil {
-		return nil
+		return nil, nil
 	}
-	has, err := s.walletSvc.HasBalance(ctx, userID)
+	amount, err := s.estimate(ctx, req)
 	if err != nil {
-		return errs.Wrap(errs.CodeInternalError, "Failed to check balance", err)
+		return nil, errs.Wrap(errs.CodeInternalError, "Failed to estimate cost", err)
+	}
+	hold := &domain.WalletHold{
+		RequestID: meta.RequestID,
+		UserID:    meta.UserID,
+		APIKeyID:  meta.APIKeyID,
+		Amount:    amount,
+	}
+	err = s.walletSvc.Reserve(ctx, hold)
+	switch {
+	case err == nil:
+		return hold, nil
+	case errors.Is(err, errs.ErrInsufficientBalance):
+		return nil, errs.New(errs.CodeInsufficientBalance, "Insufficient balance. Please top up your wallet.")
+	case errors.Is(err, errs.ErrAPIKeyQuotaExceeded):
+		return nil, err
+	default:
+		return nil, errs.Wrap(errs.CodeInternalError, "Failed to reserve balance", err)
+	}
+}
+
+// estimate 按请求实际可能使用的模型预估费用。req.Model 可能是别名、通配规则或 A/B 实验，
+// 用量按最终服务请求的实际模型记账，因此取路由结果（主路由、回退链与长上下文模型）中最贵的一个；
+// 路由解析失败时按请求的模型预估，随后的网关调用会返回同样的错误并释放预授权。
+func (s *service) estimate(ctx context.Context, req *domain.ChatRequest) (money.Amount, error) {
+	models, err := s.gw.RouteModels(req)
+	if err != nil || len(models) == 0 {
+		models = []string{req.Model}
+	}
+	promptTokens := req.EstimatePromptTokens()
+	var highest money.Amount
+	for _, model := range models {
+		amount, err := s.billingSvc.Estimate(ctx, model, promptTokens, req.MaxTokens)
+		if err != nil {
+			return 0, err
+		}
+		highest = max(highest, amount)
+	}
+	return highest, nil
+}
+
+// keepHold 在请求进行期间每隔半个有效期顺延预授权，直到调用返回的 stop。hold 为 nil 时什么也不做。
+func (s *service) keepHold(hold *domain.WalletHold) (stop func()) {
+	interval := time.Duration(0)
+	if hold != nil {
+		interval = time.Until(hold.ExpiresAt) / 2
+	}
+	if interval <= 0 {
+		return func() {}
 	}
-	if !has {
-		return errs.New(errs.CodeInsufficientBalance, "Insufficient balance. Please top up your wallet.")
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := s.walletSvc.Extend(ctx, hold.RequestID); err != nil {
+					s.logger.Warn("failed to extend hold", logger.String("requestID", hold.RequestID), logger.Error(err))
+				}
+				cancel()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// release 释放请求失败时的预授权，未释放的预授权也会在有效期后自动失效
+func (s *service) release(meta RequestMeta) {
+	if meta.UserID <= 0 || s.walletSvc == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.walletSvc.Release(ctx, meta.RequestID); err != nil {
+		s.logger.Error("failed to release hold", logger.String("requestID", meta.RequestID), logger.Error(err))
 	}
-	return nil
 }
 
 const (
@@ -170,57 +299,28 @@ const (
 	httpStatusClientClosed = 499
 )
 
-func (s *service) recordAsync(meta RequestMeta, model, provider string, inputTokens, outputTokens, statusCode, latency int) {
+// record 把请求用量提交到记账队列，由 billing.Recorder 批量记账
+func (s *service) record(meta RequestMeta, model string, provider domain.ProviderRef, inputTokens, outputTokens, statusCode, latency int) {
 	// 未认证/未关联用户时不记录
 	if meta.UserID <= 0 {
 		return
 	}
 
-	// 与请求生命周期解耦，避免 ctx cancel 导致记录丢失
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		log := &domain.UsageLog{
-			UserID:       meta.UserID,
-			APIKeyID:     meta.APIKeyID,
-			Model:        model,
-			Provider:     provider,
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			LatencyMs:    latency,
-			StatusCode:   statusCode,
-			ClientIP:     meta.ClientIP,
-			UserAgent:    meta.UserAgent,
-			RequestID:    meta.RequestID,
-		}
-
-		if err := s.usageSvc.LogRequest(ctx, log); err != nil {
-			s.logger.Error("failed to log usage", logger.Error(err))
-		}
-
-		if meta.APIKeyID == nil {
-			return
-		}
-
-		promptPrice, completionPrice, err := s.modelRateSvc.GetRateForModel(ctx, model)
-		if err != nil {
-			// modelrate service 已经做过降级，这里只记录日志
-			s.logger.Warn("failed to get model rate", logger.String("model", model), logger.Error(err))
-			return
-		}
-
-		cost := (float64(inputTokens)/1_000_000.0)*promptPrice + (float64(outputTokens)/1_000_000.0)*completionPrice
-		if cost <= 0 {
-			return
-		}
-
-		if err := s.apiKeySvc.IncrementUsage(ctx, *meta.APIKeyID, cost); err != nil {
-			s.logger.Error("failed to increment api key usage",
-				logger.Error(err),
-				logger.Int64("key_id", *meta.APIKeyID),
-				logger.Float64("cost", cost),
-			)
-		}
-	}()
+	s.recorder.Record(&domain.UsageLog{
+		UserID:       meta.UserID,
+		APIKeyID:     meta.APIKeyID,
+		Model:        model,
+		Provider:     provider.Name,
+		ProviderID:   provider.ID,
+		FallbackHop:  provider.FallbackHop,
+		Variant:      provider.Variant,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		LatencyMs:    latency,
+		StatusCode:   statusCode,
+		ClientIP:     meta.ClientIP,
+		UserAgent:    meta.UserAgent,
+		RequestID:    meta.RequestID,
+		CreatedAt:    time.Now(),
+	})
 }
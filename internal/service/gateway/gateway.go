@@ -2,9 +2,16 @@
 package gateway
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
 	"net/http"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -13,11 +20,15 @@ import (
 	"ai-gateway/config"
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/circuitbreaker"
+	"ai-gateway/internal/pkg/httpclient"
+	"ai-gateway/internal/pkg/keypool"
 	"ai-gateway/internal/pkg/loadbalancer"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/pkg/retry"
 	"ai-gateway/internal/providers"
 	"ai-gateway/internal/providers/anthropic"
+	"ai-gateway/internal/providers/gemini"
 	"ai-gateway/internal/providers/openai"
 	"ai-gateway/internal/repository"
 )
@@ -27,17 +38,29 @@ import (
 //go:generate mockgen -source=./gateway.go -destination=./mocks/gateway.mock.go -package=gatewaymocks GatewayService
 type GatewayService interface {
 	Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error)
-	ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, string, error)
+	ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, domain.ProviderRef, error)
 	ListModels(ctx context.Context) ([]string, error)
 	GetProvider(model string) (providers.Provider, string, error)
+	// ExplainRoute 返回模型路由的解析过程，不发起上游请求。
+	ExplainRoute(model string) *domain.RouteExplanation
+	// RouteModels 返回请求会依次尝试的实际模型，不发起上游请求，也不改变负载均衡状态。
+	RouteModels(req *domain.ChatRequest) ([]string, error)
 	// Reload 从数据库重新加载配置。
 	Reload(ctx context.Context) error
+	// CircuitBreakers 返回各供应商熔断器的状态快照，按名称排序。
+	CircuitBreakers() []circuitbreaker.Snapshot
+	// ProviderKeys 返回供应商 Key 池中各 Key 的状态与用量，未配置 Key 池时返回 nil。
+	ProviderKeys(name string) []keypool.Snapshot
+	// EnableProviderKey 重新启用供应商 Key 池中被自动禁用的 Key。
+	EnableProviderKey(name, key string) error
 }
 
 // providerNode 包装了一个 Provider 以实现 loadbalancer.Node 接口。
+// name 为供应商实例名称，与 provider.Name() 一致。
 type providerNode struct {
 	provider providers.Provider
 	name     string
+	priority int // 负载均衡成员优先级，值越大越优先
 }
 
 func (n *providerNode) ID() string {
@@ -48,6 +71,9 @@ type gatewayService struct {
 	providerRepo    repository.ProviderRepository
 	routingRuleRepo repository.RoutingRuleRepository
 	loadBalanceRepo repository.LoadBalanceRepository
+	capabilityRepo  repository.ModelCapabilityRepository
+	shadowRepo      repository.ShadowResultRepository
+	shadowSem       chan struct{} // 限制同时进行的影子请求数
 
 	mu               sync.RWMutex                                        // 保护配置数据的读写锁
 	providers        map[string]providers.Provider                       // 名称 -> 供应商
@@ -55,15 +81,53 @@ type gatewayService struct {
 	typeDefaults     map[string]string                                   // 类型 -> 默认供应商名称
 	routes           map[string]config.ModelRoute                        // 精确的模型路由
 	prefixRoutes     []prefixRouteEntry                                  // 按优先级排序
+	patternRoutes    []patternRouteEntry                                 // wildcard / regex 路由，按优先级排序
 	loadBalancers    map[string]loadbalancer.LoadBalancer[*providerNode] // 模型模式 -> 负载均衡器
-	httpClient       *http.Client
+	balancerSpecs    map[string]string                                   // 模型模式 -> 构建负载均衡器的参数，Reload 时未变则复用以保留健康与延迟状态
+	clients          map[string]*providerClient                          // 供应商名称 -> 专用 HTTP 客户端
+	breakers         map[string]*circuitbreaker.Breaker                  // 供应商名称 -> 熔断器
+	retryPolicies    map[string]retry.Config                             // 供应商名称 -> 重试策略
+	timeouts         map[string]time.Duration                            // 供应商名称 -> 非流式请求总超时
+	firstTokenLimits map[string]time.Duration                            // 供应商名称 -> 流式首个增量超时
+	keyPools         map[string]*keypool.Pool                            // 供应商名称 -> 上游 Key 池，仅配置了多个 Key 的供应商
+	capabilities     []domain.ModelCapability                            // 模型能力元数据
 	logger           logger.Logger
 }
 
+// providerClient 记录构建 HTTP 客户端时使用的配置，Reload 时配置未变则复用以保留连接池。
+type providerClient struct {
+	cfg    httpclient.Config
+	client *http.Client
+}
+
 type prefixRouteEntry struct {
-	prefix   string
-	provider string
-	priority int
+	prefix    string
+	provider  string
+	priority  int
+	fallbacks []config.ModelRoute
+	variants  []config.ModelVariant
+	shadow    *config.ShadowRoute
+}
+
+// patternRouteEntry 是编译后的 wildcard / regex 路由，actualModel 中可引用捕获组。
+type patternRouteEntry struct {
+	ruleType    string
+	pattern     string
+	re          *regexp.Regexp
+	provider    string
+	actualModel string
+	priority    int
+	fallbacks   []config.ModelRoute
+	variants    []config.ModelVariant
+	shadow      *config.ShadowRoute
+}
+
+// expand 将模板中的 $1、${name} 替换为模型名匹配到的捕获组，模板为空时返回模型名本身。
+func (e *patternRouteEntry) expand(template, model string, match []int) string {
+	if template == "" {
+		return model
+	}
+	return string(e.re.ExpandString(nil, template, model, match))
 }
 
 var _ GatewayService = (*gatewayService)(nil)
@@ -73,18 +137,29 @@ func NewGatewayService(
 	providerRepo repository.ProviderRepository,
 	routingRuleRepo repository.RoutingRuleRepository,
 	loadBalanceRepo repository.LoadBalanceRepository,
+	capabilityRepo repository.ModelCapabilityRepository,
+	shadowRepo repository.ShadowResultRepository,
 	l logger.Logger,
 ) GatewayService {
 	g := &gatewayService{
 		providerRepo:     providerRepo,
 		routingRuleRepo:  routingRuleRepo,
 		loadBalanceRepo:  loadBalanceRepo,
+		capabilityRepo:   capabilityRepo,
+		shadowRepo:       shadowRepo,
+		shadowSem:        make(chan struct{}, maxInflightShadows),
 		providers:        make(map[string]providers.Provider),
 		configuredModels: make(map[string][]string),
 		typeDefaults:     make(map[string]string),
 		routes:           make(map[string]config.ModelRoute),
 		loadBalancers:    make(map[string]loadbalancer.LoadBalancer[*providerNode]),
-		httpClient:       &http.Client{Timeout: 120 * time.Second},
+		balancerSpecs:    make(map[string]string),
+		clients:          make(map[string]*providerClient),
+		breakers:         make(map[string]*circuitbreaker.Breaker),
+		retryPolicies:    make(map[string]retry.Config),
+		timeouts:         make(map[string]time.Duration),
+		firstTokenLimits: make(map[string]time.Duration),
+		keyPools:         make(map[string]*keypool.Pool),
 		logger:           l.With(logger.String("service", "gateway")),
 	}
 
@@ -108,34 +183,72 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 	newProviders := make(map[string]providers.Provider)
 	newConfiguredModels := make(map[string][]string)
 	newTypeDefaults := make(map[string]string)
+	newClients := make(map[string]*providerClient)
+	newBreakers := make(map[string]*circuitbreaker.Breaker)
+	newRetryPolicies := make(map[string]retry.Config)
+	newTimeouts := make(map[string]time.Duration)
+	newFirstTokenLimits := make(map[string]time.Duration)
+	newKeyPools := make(map[string]*keypool.Pool)
+
+	g.mu.RLock()
+	oldClients := g.clients
+	oldBreakers := g.breakers
+	oldKeyPools := g.keyPools
+	g.mu.RUnlock()
 
 	for _, p := range dbProviders {
-		if p.APIKey == "" {
+		keys := p.Keys()
+		if len(keys) == 0 {
 			continue
 		}
 
-		var provider providers.Provider
-		switch p.Type {
-		case "openai":
-			provider = openai.NewProvider(
-				p.APIKey,
-				p.BaseURL,
-				g.httpClient,
-				g.logger,
-			)
-		case "anthropic":
-			provider = anthropic.NewProvider(
-				p.APIKey,
-				p.BaseURL,
-				g.httpClient,
-				g.logger,
+		pc, err := g.buildClient(&p, oldClients[p.Name])
+		if err != nil {
+			g.logger.Warn("failed to build http client for provider",
+				logger.String("name", p.Name),
+				logger.Error(err),
 			)
-		default:
+			continue
+		}
+		httpClient := pc.client
+
+		provider := g.newProvider(&p, keys[0].Key, httpClient)
+		if provider == nil {
 			g.logger.Warn("unknown provider type", logger.String("type", p.Type))
 			continue
 		}
+		// 配置了多个 Key 时为每个 Key 创建一个实例，按 Key 池策略轮换
+		if len(keys) > 1 {
+			pooled := &pooledProvider{
+				Provider: provider,
+				members:  []providers.Provider{provider},
+				logger:   g.logger.With(logger.String("provider", p.Name)),
+			}
+			poolKeys := []keypool.Key{{Name: keys[0].Name, Secret: keys[0].Key}}
+			for _, k := range keys[1:] {
+				pooled.members = append(pooled.members, g.newProvider(&p, k.Key, httpClient))
+				poolKeys = append(poolKeys, keypool.Key{Name: k.Name, Secret: k.Key})
+			}
+			pooled.pool = keypool.New(p.KeyStrategy, poolKeys, oldKeyPools[p.Name])
+			newKeyPools[p.Name] = pooled.pool
+			provider = pooled
+		}
 
 		newProviders[p.Name] = provider
+		newClients[p.Name] = pc
+		newRetryPolicies[p.Name] = retryPolicy(&p)
+		if p.TimeoutMs > 0 {
+			newTimeouts[p.Name] = time.Duration(p.TimeoutMs) * time.Millisecond
+		}
+		if p.FirstTokenTimeoutMs > 0 {
+			newFirstTokenLimits[p.Name] = time.Duration(p.FirstTokenTimeoutMs) * time.Millisecond
+		}
+		// 熔断器状态跨 Reload 保留
+		if b, ok := oldBreakers[p.Name]; ok {
+			newBreakers[p.Name] = b
+		} else {
+			newBreakers[p.Name] = circuitbreaker.New(p.Name, circuitbreaker.DefaultConfig)
+		}
 		// 存储配置的模型列表
 		if len(p.Models) > 0 {
 			newConfiguredModels[p.Name] = p.Models
@@ -164,22 +277,73 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 
 	newRoutes := make(map[string]config.ModelRoute)
 	var newPrefixRoutes []prefixRouteEntry
+	var newPatternRoutes []patternRouteEntry
 
 	for _, rule := range routingRules {
-		if rule.RuleType == "exact" {
+		fallbacks := make([]config.ModelRoute, 0, len(rule.Fallbacks))
+		for _, hop := range rule.Fallbacks {
+			fallbacks = append(fallbacks, config.ModelRoute{Provider: hop.Provider, ActualModel: hop.ActualModel})
+		}
+		var variants []config.ModelVariant
+		for _, v := range rule.Variants {
+			variants = append(variants, config.ModelVariant{Name: v.Name, Provider: v.Provider, ActualModel: v.ActualModel, Percent: v.Percent})
+		}
+		var shadow *config.ShadowRoute
+		if rule.Shadow != nil {
+			shadow = &config.ShadowRoute{Provider: rule.Shadow.Provider, ActualModel: rule.Shadow.ActualModel, Percent: rule.Shadow.Percent}
+		}
+		switch rule.RuleType {
+		case domain.RuleTypeExact:
 			newRoutes[rule.Pattern] = config.ModelRoute{
 				Provider:    rule.ProviderName,
 				ActualModel: rule.ActualModel,
+				Fallbacks:   fallbacks,
+				Variants:    variants,
+				Shadow:      shadow,
 			}
-		} else if rule.RuleType == "prefix" {
+		case domain.RuleTypePrefix:
 			newPrefixRoutes = append(newPrefixRoutes, prefixRouteEntry{
-				prefix:   rule.Pattern,
-				provider: rule.ProviderName,
-				priority: rule.Priority,
+				prefix:    rule.Pattern,
+				provider:  rule.ProviderName,
+				priority:  rule.Priority,
+				fallbacks: fallbacks,
+				variants:  variants,
+				shadow:    shadow,
+			})
+		case domain.RuleTypeWildcard, domain.RuleTypeRegex:
+			re, err := rule.CompilePattern()
+			if err != nil {
+				g.logger.Warn("skipping routing rule with invalid pattern",
+					logger.Int64("id", rule.ID),
+					logger.String("pattern", rule.Pattern),
+					logger.Error(err),
+				)
+				continue
+			}
+			newPatternRoutes = append(newPatternRoutes, patternRouteEntry{
+				ruleType:    rule.RuleType,
+				pattern:     rule.Pattern,
+				re:          re,
+				provider:    rule.ProviderName,
+				actualModel: rule.ActualModel,
+				priority:    rule.Priority,
+				fallbacks:   fallbacks,
+				variants:    variants,
+				shadow:      shadow,
 			})
+		default:
+			g.logger.Warn("unknown routing rule type",
+				logger.Int64("id", rule.ID),
+				logger.String("type", rule.RuleType),
+			)
 		}
 	}
 
+	// 按优先级降序排序 wildcard / regex 路由，同优先级保持规则顺序
+	sort.SliceStable(newPatternRoutes, func(i, j int) bool {
+		return newPatternRoutes[i].priority > newPatternRoutes[j].priority
+	})
+
 	// 按优先级降序排序前缀路由，然后按长度降序排序
 	sort.Slice(newPrefixRoutes, func(i, j int) bool {
 		if newPrefixRoutes[i].priority != newPrefixRoutes[j].priority {
@@ -194,7 +358,20 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 		return fmt.Errorf("从数据库加载负载均衡组失败: %w", err)
 	}
 
+	// 熔断器打开的成员在选择时被跳过。选择总在持有读锁时进行，
+	// 因此按当前配置查找熔断器，复用的负载均衡器也能看到新建的熔断器
+	breakerFilter := loadbalancer.WithFilter(func(name string) bool {
+		b, ok := g.breakers[name]
+		return !ok || b.Ready()
+	})
+
+	g.mu.RLock()
+	oldLoadBalancers := g.loadBalancers
+	oldBalancerSpecs := g.balancerSpecs
+	g.mu.RUnlock()
+
 	newLoadBalancers := make(map[string]loadbalancer.LoadBalancer[*providerNode])
+	newBalancerSpecs := make(map[string]string)
 	for _, group := range lbGroups {
 		members, err := g.loadBalanceRepo.GetMembers(ctx, group.ID)
 		if err != nil {
@@ -207,11 +384,18 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 
 		var nodes []*providerNode
 		var weights []int
+		priorities := make(map[string]int)
+		tiers := make(map[int]bool)
+		var spec strings.Builder
+		spec.WriteString(group.Strategy)
 
 		for _, member := range members {
 			if p, ok := newProviders[member.ProviderName]; ok {
-				nodes = append(nodes, &providerNode{provider: p, name: member.ProviderName})
+				nodes = append(nodes, &providerNode{provider: p, name: member.ProviderName, priority: member.Priority})
 				weights = append(weights, member.Weight)
+				priorities[member.ProviderName] = member.Priority
+				tiers[member.Priority] = true
+				fmt.Fprintf(&spec, "|%s:%d:%d", member.ProviderName, member.Weight, member.Priority)
 			}
 		}
 
@@ -219,28 +403,55 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 			continue
 		}
 
+		// 策略与成员都未变化时复用原负载均衡器，保留节点的健康、半开探测和延迟统计
+		if old, ok := oldLoadBalancers[group.ModelPattern]; ok && oldBalancerSpecs[group.ModelPattern] == spec.String() {
+			newLoadBalancers[group.ModelPattern] = old
+			newBalancerSpecs[group.ModelPattern] = spec.String()
+			continue
+		}
+
+		opts := []loadbalancer.Option{breakerFilter}
+		// 成员优先级不同时分层：只在最高优先级的可用层内分配流量，整层不可用才溢出到下一层
+		if len(tiers) > 1 {
+			opts = append(opts, loadbalancer.WithPriority(func(name string) int { return priorities[name] }))
+		}
+
 		var lb loadbalancer.LoadBalancer[*providerNode]
 		switch group.Strategy {
 		case "round-robin":
-			lb = loadbalancer.NewRoundRobin(nodes)
+			lb = loadbalancer.NewRoundRobin(nodes, opts...)
 		case "random":
-			lb = loadbalancer.NewRandom(nodes)
+			lb = loadbalancer.NewRandom(nodes, opts...)
 		case "failover":
-			lb = loadbalancer.NewFailover(nodes)
+			lb = loadbalancer.NewFailover(nodes, opts...)
 		case "weighted":
-			lb = loadbalancer.NewWeighted(nodes, weights)
+			lb = loadbalancer.NewWeighted(nodes, weights, opts...)
+		case "least-outstanding":
+			lb = loadbalancer.NewLeastOutstanding(nodes, opts...)
+		case "peak-ewma":
+			lb = loadbalancer.NewPeakEWMA(nodes, opts...)
+		case "consistent-hash":
+			lb = loadbalancer.NewConsistentHash(nodes, opts...)
 		default:
-			lb = loadbalancer.NewRoundRobin(nodes)
+			lb = loadbalancer.NewRoundRobin(nodes, opts...)
 		}
 
 		newLoadBalancers[group.ModelPattern] = lb
+		newBalancerSpecs[group.ModelPattern] = spec.String()
 		g.logger.Info("created load balancer from database",
 			logger.String("model", group.ModelPattern),
 			logger.String("strategy", group.Strategy),
 			logger.Int("providers", len(nodes)),
+			logger.Int("tiers", len(tiers)),
 		)
 	}
 
+	// 从数据库加载模型能力
+	newCapabilities, err := g.capabilityRepo.GetAllEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("从数据库加载模型能力失败: %w", err)
+	}
+
 	// 使用写锁原子更新配置
 	g.mu.Lock()
 	g.providers = newProviders
@@ -248,49 +459,541 @@ func (g *gatewayService) Reload(ctx context.Context) error {
 	g.typeDefaults = newTypeDefaults
 	g.routes = newRoutes
 	g.prefixRoutes = newPrefixRoutes
+	g.patternRoutes = newPatternRoutes
 	g.loadBalancers = newLoadBalancers
+	g.balancerSpecs = newBalancerSpecs
+	// 复用的负载均衡器的节点改为指向本次新建的供应商实例
+	for _, lb := range newLoadBalancers {
+		for _, n := range lb.Nodes() {
+			n.provider = newProviders[n.name]
+		}
+	}
+	g.clients = newClients
+	g.breakers = newBreakers
+	g.retryPolicies = newRetryPolicies
+	g.timeouts = newTimeouts
+	g.firstTokenLimits = newFirstTokenLimits
+	g.capabilities = newCapabilities
+	g.keyPools = newKeyPools
 	g.mu.Unlock()
 
+	// 关闭已被替换或删除的客户端的空闲连接，进行中的请求不受影响
+	for name, old := range oldClients {
+		if cur, ok := newClients[name]; !ok || cur != old {
+			old.client.CloseIdleConnections()
+		}
+	}
+
 	g.logger.Info("configuration reloaded from database",
 		logger.Int("providers", len(g.providers)),
 		logger.Int("routes", len(g.routes)),
 		logger.Int("prefixRoutes", len(g.prefixRoutes)),
+		logger.Int("patternRoutes", len(g.patternRoutes)),
 		logger.Int("loadBalancers", len(g.loadBalancers)),
+		logger.Int("capabilities", len(g.capabilities)),
 	)
 
 	return nil
 }
 
+// newProvider 使用给定的上游 Key 创建供应商实例，未知类型返回 nil。
+func (g *gatewayService) newProvider(p *domain.Provider, apiKey string, client *http.Client) providers.Provider {
+	switch p.Type {
+	case "openai":
+		return openai.NewProvider(p.ID, p.Name, apiKey, p.BaseURL, client, g.logger)
+	case "anthropic":
+		return anthropic.NewProvider(p.ID, p.Name, apiKey, p.BaseURL, client, g.logger)
+	case "gemini":
+		return gemini.NewProvider(p.ID, p.Name, apiKey, p.BaseURL, client, g.logger)
+	case "azure-openai":
+		return openai.NewAzureProvider(p.ID, p.Name, apiKey, p.BaseURL, p.APIVersion, p.Deployments, client, g.logger)
+	}
+	return nil
+}
+
+// buildClient 根据供应商配置构建 HTTP 客户端，配置未变化时复用旧客户端。
+func (g *gatewayService) buildClient(p *domain.Provider, old *providerClient) (*providerClient, error) {
+	cfg := httpclient.Config{
+		ConnectTimeout:        time.Duration(p.ConnectTimeoutMs) * time.Millisecond,
+		ResponseHeaderTimeout: time.Duration(p.ResponseHeaderTimeoutMs) * time.Millisecond,
+		MaxIdleConns:          p.MaxIdleConns,
+		MaxIdleConnsPerHost:   p.MaxIdleConnsPerHost,
+		ProxyURL:              p.ProxyURL,
+	}
+	if old != nil && old.cfg == cfg {
+		return old, nil
+	}
+	client, err := httpclient.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &providerClient{cfg: cfg, client: client}, nil
+}
+
+// routeTarget 描述一次路由解析的结果。
+type routeTarget struct {
+	provider    providers.Provider
+	actualModel string
+	lb          loadbalancer.LoadBalancer[*providerNode] // 非 nil 表示由负载均衡选出
+	node        *providerNode
+	tracker     loadbalancer.LoadTracker[*providerNode] // 非 nil 表示策略按在途请求数 / 延迟选择，请求结束后需 release
+	released    sync.Once
+	breaker     *circuitbreaker.Breaker
+	retry       retry.Config
+	timeout     time.Duration           // 非流式请求每次尝试的总超时，0 表示不限制
+	firstToken  time.Duration           // 流式首个增量超时，0 表示不限制
+	hop         int                     // 在回退链中的位置，0 表示主路由
+	fallbacks   []*routeTarget          // 主路由失败时依次尝试的回退目标
+	variant     string                  // A/B 分流的实验组，规则未配置分流时为空
+	shadow      *config.ShadowRoute     // 规则的影子流量配置，nil 表示不镜像
+	missing     []string                // 请求需要而该目标不支持的能力，非空时跳过
+	catalog     *domain.ModelCapability // 目标模型的模型目录条目，nil 表示未配置
+}
+
+// allow 通过熔断器申请一次上游调用，熔断时快速失败。
+func (r *routeTarget) allow() (func(success bool), error) {
+	if r.breaker == nil {
+		return func(bool) {}, nil
+	}
+	done, err := r.breaker.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errs.ErrProviderUnavailable, r.provider.Name(), err)
+	}
+	return done, nil
+}
+
+// withTimeout 为一次非流式尝试施加供应商配置的总超时。
+// 流式请求不使用它，而是依靠响应头超时和首 token 超时，避免截断长时间的输出。
+func (r *routeTarget) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// release 结束本次请求在负载均衡节点上的占用，latency > 0 时作为延迟样本。重复调用无副作用。
+func (r *routeTarget) release(latency time.Duration) {
+	if r.tracker == nil {
+		return
+	}
+	r.released.Do(func() { r.tracker.Release(r.node, latency) })
+}
+
+// reportSuccess 向负载均衡器回报请求成功。
+func (r *routeTarget) reportSuccess() {
+	if r.lb != nil {
+		r.lb.ReportSuccess(r.node)
+	}
+}
+
+// reportFailure 向负载均衡器回报请求失败。
+func (r *routeTarget) reportFailure() {
+	if r.lb != nil {
+		r.lb.ReportFailure(r.node)
+	}
+}
+
+// report 根据请求结果回报节点健康状态。
+func (r *routeTarget) report(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		r.reportSuccess()
+	case isUpstreamFailure(ctx, err):
+		r.reportFailure()
+	}
+}
+
+// canFallback 判断失败后是否应切换到回退链的下一跳：
+// 上游可重试错误（重试已耗尽）、熔断打开或 Key 池中的 Key 全部被禁用时切换，
+// 请求本身的问题和客户端取消不切换。
+func canFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, keypool.ErrNoKey) || providers.IsRetryable(err)
+}
+
+// isUpstreamFailure 判断错误是否说明上游不健康：与可重试错误一致（408 / 429 / 5xx / 瞬时网络错误），
+// 以及 Key 池中的 Key 全部被禁用。
+// 客户端取消、请求本身的问题（400、内容过滤等）以及熔断快速失败都不计入上游失败。
+func isUpstreamFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, circuitbreaker.ErrOpen) {
+		return false
+	}
+	return errors.Is(err, keypool.ErrNoKey) || providers.IsRetryable(err)
+}
+
+// retryPolicy 根据供应商配置生成重试策略，只重试 408 / 429 / 5xx 和瞬时网络错误。
+func retryPolicy(p *domain.Provider) retry.Config {
+	cfg := retry.DefaultConfig
+	cfg.Retryable = providers.IsRetryable
+	if p.RetryMaxAttempts > 0 {
+		cfg.MaxAttempts = p.RetryMaxAttempts
+	}
+	if p.RetryInitialDelayMs > 0 {
+		cfg.InitialDelay = time.Duration(p.RetryInitialDelayMs) * time.Millisecond
+	}
+	if p.RetryMaxDelayMs > 0 {
+		cfg.MaxDelay = time.Duration(p.RetryMaxDelayMs) * time.Millisecond
+	}
+	return cfg
+}
+
 // GetProvider 返回给定模型的供应商。
-// 优先级：精确匹配 -> 负载均衡 -> 前缀匹配 -> 类型默认
+// 优先级：精确匹配 -> 负载均衡 -> 通配符 / 正则 -> 前缀匹配 -> 类型默认
 func (g *gatewayService) GetProvider(model string) (providers.Provider, string, error) {
+	r, err := g.resolve(&domain.ChatRequest{Model: model}, domain.Requirements{})
+	if err != nil {
+		return nil, "", err
+	}
+	// 调用方自行发起请求，网关无法观测其结束时间，不计入在途请求
+	r.release(0)
+	return r.provider, r.actualModel, nil
+}
+
+// route 解析请求路由并跳过不支持请求能力的目标。所有目标都只是容量不足（提示词或输出超出模型限制）时，
+// 若主路由的模型目录条目配置了长上下文模型，则改用该模型重新路由一次，否则提前拒绝，不再为注定失败的请求付费。
+func (g *gatewayService) route(req *domain.ChatRequest) ([]*routeTarget, error) {
+	need := req.Requirements()
+	target, err := g.resolve(req, need)
+	if err != nil {
+		return nil, err
+	}
+	hops, err := capableHops(need, target)
+	if err == nil || !domain.IsCapacityOnly(target.missing) || target.catalog.LongContextModel == "" {
+		return hops, err
+	}
+
+	g.logger.Info("request exceeds model capacity, routing to long-context model",
+		logger.String("model", req.Model),
+		logger.String("longContextModel", target.catalog.LongContextModel),
+		logger.Int("promptTokens", need.PromptTokens),
+		logger.Int("maxTokens", need.MaxTokens),
+	)
+	req.Model = target.catalog.LongContextModel
+	if target, err = g.resolve(req, need); err != nil {
+		return nil, err
+	}
+	return capableHops(need, target)
+}
+
+// resolve 解析请求模型对应的路由，优先级同 GetProvider，并标记每个目标不支持的请求能力。
+// 请求带有会话键时，一致性哈希负载均衡组会把同一会话固定到同一个节点。
+func (g *gatewayService) resolve(req *domain.ChatRequest, need domain.Requirements) (*routeTarget, error) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
+	target, err := g.resolveLocked(req, &need, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.annotateLocked(target, need)
+	return target, nil
+}
+
+// annotateLocked 让回退链沿用主路由的实验组与影子配置，并标记每个目标不支持的请求能力，调用方需持有读锁。
+func (g *gatewayService) annotateLocked(target *routeTarget, need domain.Requirements) {
+	for _, t := range append([]*routeTarget{target}, target.fallbacks...) {
+		t.variant = target.variant // 回退链沿用主路由的实验组，用量按分组统计
+		t.shadow = target.shadow
+		t.missing = g.missingLocked(t.provider, t.actualModel, need)
+		t.catalog = g.capabilityLocked(t.provider.Name(), t.actualModel)
+	}
+}
+
+// RouteModels 按与 route 相同的规则解析请求会依次尝试的实际模型（含 A/B 分组、回退链与长上下文模型），
+// 供请求前按其中最贵的模型预估费用。以解释模式解析，不选择负载均衡节点，也不占用在途计数或探测名额；
+// 负载均衡组的成员使用同一模型名，不影响结果。
+func (g *gatewayService) RouteModels(req *domain.ChatRequest) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	need := req.Requirements()
+	peek := func(model string) (*routeTarget, error) {
+		target, err := g.resolveLocked(&domain.ChatRequest{Model: model, SplitKey: req.SplitKey}, nil, &routeTrace{})
+		if err != nil {
+			return nil, err
+		}
+		g.annotateLocked(target, need)
+		return target, nil
+	}
+	target, err := peek(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	hops, err := capableHops(need, target)
+	if err != nil && domain.IsCapacityOnly(target.missing) && target.catalog.LongContextModel != "" {
+		if target, err = peek(target.catalog.LongContextModel); err != nil {
+			return nil, err
+		}
+		hops, err = capableHops(need, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		if !slices.Contains(models, hop.actualModel) {
+			models = append(models, hop.actualModel)
+		}
+	}
+	return models, nil
+}
+
+// ExplainRoute 以只读方式解析模型路由并返回完整的解析过程，不发起上游请求，也不改变负载均衡状态。
+func (g *gatewayService) ExplainRoute(model string) *domain.RouteExplanation {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	e := &domain.RouteExplanation{Model: model, Steps: []domain.RouteStep{}}
+	target, err := g.resolveLocked(&domain.ChatRequest{Model: model}, nil, (*routeTrace)(e))
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	for _, hop := range target.fallbacks {
+		e.Fallbacks = append(e.Fallbacks, g.candidateLocked(hop.provider.Name(), hop.actualModel, hop.hop, hop.breaker == nil || hop.breaker.Ready()))
+	}
+	return e
+}
+
+// routeTrace 记录路由解析过程，为 nil 时不记录（正常请求路径）。
+type routeTrace domain.RouteExplanation
+
+func (t *routeTrace) step(stage string, matched bool, format string, args ...any) {
+	if t != nil {
+		t.Steps = append(t.Steps, domain.RouteStep{Stage: stage, Matched: matched, Detail: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (t *routeTrace) shadow(sh *config.ShadowRoute, model string) {
+	if t != nil && sh != nil {
+		t.step("shadow", true, "%d%% mirrored to %s (%s), responses are compared but never returned", sh.Percent, sh.Provider, cmp.Or(sh.ActualModel, model))
+	}
+}
+
+func (t *routeTrace) match(by, rule string, target *routeTarget) {
+	if t != nil {
+		t.MatchedBy, t.Rule = by, rule
+		t.Provider, t.ActualModel = target.provider.Name(), target.actualModel
+	}
+}
+
+// selectLocked 从负载均衡组中选择节点：策略支持按键选择且请求带有会话键时按会话固定节点。
+// accept 非 nil 时只在其接受的成员中选择，不接受的成员不会被选中，也不占用其半开探测名额。
+func (g *gatewayService) selectLocked(lb loadbalancer.LoadBalancer[*providerNode], sessionKey string, accept func(*providerNode) bool) (*providerNode, error) {
+	if ks, ok := lb.(loadbalancer.KeySelector[*providerNode]); ok && sessionKey != "" {
+		return ks.SelectKeyFilter(sessionKey, accept)
+	}
+	if fs, ok := lb.(loadbalancer.FilterSelector[*providerNode]); ok {
+		return fs.SelectFilter(accept)
+	}
+	return lb.Select()
+}
+
+// missingLocked 返回供应商上的模型不支持的请求能力，同时检查供应商适配器的能力和模型能力元数据，调用方需持有读锁。
+func (g *gatewayService) missingLocked(provider providers.Provider, model string, need domain.Requirements) []string {
+	var missing []string
+	if need.Streaming && !provider.SupportsStreaming() {
+		missing = append(missing, domain.CapabilityStreaming)
+	}
+	if need.Vision && !provider.SupportsVision() {
+		missing = append(missing, domain.CapabilityVision)
+	}
+	if need.Tools && !provider.SupportsTools() {
+		missing = append(missing, domain.CapabilityTools)
+	}
+	if c := g.capabilityLocked(provider.Name(), model); c != nil {
+		for _, m := range c.Missing(need) {
+			if !slices.Contains(missing, m) {
+				missing = append(missing, m)
+			}
+		}
+	}
+	return missing
+}
+
+// capabilityLocked 查找供应商上模型的能力元数据：指定供应商的条目优先于通用条目，
+// 同类条目中精确匹配优先于最长前缀。没有匹配的条目时返回 nil，表示不做限制。
+func (g *gatewayService) capabilityLocked(providerName, model string) *domain.ModelCapability {
+	var best *domain.ModelCapability
+	bestScore, bestSpecific := -1, false
+	for i := range g.capabilities {
+		c := &g.capabilities[i]
+		if c.ProviderName != "" && c.ProviderName != providerName {
+			continue
+		}
+		score, specific := c.Match(model), c.ProviderName != ""
+		if score < 0 || bestSpecific && !specific || bestSpecific == specific && score <= bestScore {
+			continue
+		}
+		best, bestScore, bestSpecific = c, score, specific
+	}
+	return best
+}
+
+// capableHops 跳过不支持请求所需能力的路由目标。没有目标可用时按主路由缺少的能力返回
+// CodeContextLengthExceeded 或 CodeUnsupportedFeature 错误，避免把请求发到上游后才得到难以理解的 400。
+func capableHops(need domain.Requirements, target *routeTarget) ([]*routeTarget, error) {
+	var hops []*routeTarget
+	for _, hop := range append([]*routeTarget{target}, target.fallbacks...) {
+		if len(hop.missing) > 0 {
+			hop.release(0)
+			continue
+		}
+		hops = append(hops, hop)
+	}
+	if len(hops) > 0 {
+		return hops, nil
+	}
+	if domain.IsCapacityOnly(target.missing) {
+		return nil, capacityError(need, target)
+	}
+	return nil, errs.New(errs.CodeUnsupportedFeature, fmt.Sprintf("model %s on provider %s does not support: %s",
+		target.actualModel, target.provider.Name(), strings.Join(target.missing, ", ")))
+}
+
+// capacityError 说明请求超出了哪项容量限制，客户端可据此截断上下文或调小 max_tokens。
+func capacityError(need domain.Requirements, target *routeTarget) error {
+	c := target.catalog
+	if slices.Contains(target.missing, domain.CapabilityContextWindow) {
+		return errs.New(errs.CodeContextLengthExceeded, fmt.Sprintf(
+			"request needs about %d prompt tokens plus %d output tokens, exceeding the %d-token context window of model %s on provider %s",
+			need.PromptTokens, need.MaxTokens, c.ContextWindow, target.actualModel, target.provider.Name()))
+	}
+	return errs.New(errs.CodeContextLengthExceeded, fmt.Sprintf(
+		"max_tokens %d exceeds the %d-token output limit of model %s on provider %s",
+		need.MaxTokens, c.MaxOutputTokens, target.actualModel, target.provider.Name()))
+}
+
+// candidateLocked 描述一个候选供应商的当前状态，调用方需持有读锁。
+func (g *gatewayService) candidateLocked(name, actualModel string, hop int, available bool) domain.RouteCandidate {
+	c := domain.RouteCandidate{Provider: name, ActualModel: actualModel, Hop: hop, Available: available}
+	if b, ok := g.breakers[name]; ok {
+		c.Breaker = b.State().String()
+	}
+	return c
+}
+
+// resolveLocked 按优先级解析路由，调用方需持有读锁。
+// need 非 nil 时负载均衡组优先选择满足请求能力的成员；trace 非 nil 时记录每个阶段的结果。
+func (g *gatewayService) resolveLocked(req *domain.ChatRequest, need *domain.Requirements, trace *routeTrace) (*routeTarget, error) {
+	model := req.Model
+
 	// 1. 检查精确路由
 	if route, ok := g.routes[model]; ok {
 		provider, ok := g.providers[route.Provider]
 		if !ok {
-			return nil, "", fmt.Errorf("%w: %s", errs.ErrProviderNotFound, route.Provider)
+			trace.step("exact", true, "rule %q points to unknown provider %q", model, route.Provider)
+			return nil, fmt.Errorf("%w: %s", errs.ErrProviderNotFound, route.Provider)
 		}
 		actualModel := route.ActualModel
 		if actualModel == "" {
 			actualModel = model
 		}
 		g.logger.Debug("using exact route", logger.String("model", model), logger.String("provider", route.Provider))
-		return provider, actualModel, nil
+		trace.step("exact", true, "rule %q -> %s", model, route.Provider)
+		target := g.splitLocked(model, model, req.SplitKey, route.Variants, g.targetLocked(provider, actualModel), trace)
+		target.fallbacks = g.fallbacksLocked(model, route.Fallbacks)
+		target.shadow = route.Shadow
+		trace.shadow(route.Shadow, model)
+		trace.match("exact", model, target)
+		return target, nil
 	}
+	trace.step("exact", false, "no exact rule")
 
 	// 2. 检查负载均衡
 	if lb, ok := g.loadBalancers[model]; ok {
-		node, err := lb.Select()
-		if err == nil && node != nil {
-			g.logger.Debug("using load balancer", logger.String("model", model), logger.String("provider", node.ID()))
-			return node.provider, model, nil
+		if trace != nil {
+			// 解释模式不调用 Select，避免推进轮询计数或占用半开探测名额
+			available := lb.Available()
+			ids := make(map[string]bool, len(available))
+			for _, n := range available {
+				ids[n.ID()] = true
+			}
+			for _, n := range lb.Nodes() {
+				c := g.candidateLocked(n.name, model, 0, ids[n.ID()])
+				c.Priority = n.priority
+				trace.Candidates = append(trace.Candidates, c)
+			}
+			if len(available) > 0 {
+				target := g.targetLocked(available[0].provider, model)
+				trace.step("loadBalance", true, "group %q: %d of %d members available, selected per request",
+					model, len(available), len(lb.Nodes()))
+				trace.match("loadBalance", model, target)
+				if len(available) > 1 {
+					trace.Provider = ""
+				}
+				return target, nil
+			}
+			trace.step("loadBalance", false, "group %q: no available members", model)
+		} else {
+			// 只在支持请求所需能力的成员中选择
+			var accept func(*providerNode) bool
+			if need != nil {
+				accept = func(n *providerNode) bool { return len(g.missingLocked(n.provider, model, *need)) == 0 }
+			}
+			node, err := g.selectLocked(lb, req.SessionKey, accept)
+			if err == nil && node != nil {
+				g.logger.Debug("using load balancer", logger.String("model", model), logger.String("provider", node.ID()))
+				target := g.targetLocked(node.provider, model)
+				target.lb, target.node = lb, node
+				target.tracker, _ = lb.(loadbalancer.LoadTracker[*providerNode])
+				return target, nil
+			}
+			if available := lb.Available(); accept != nil && len(available) > 0 {
+				// 有可用成员但都不支持所需能力：返回第一个可用成员用于生成能力错误，
+				// 它不会被请求，因此不关联负载均衡器，也不占用在途计数或探测名额
+				return g.targetLocked(available[0].provider, model), nil
+			}
 		}
+	} else {
+		trace.step("loadBalance", false, "no load balance group")
 	}
 
-	// 3. 检查前缀路由
+	// 3. 检查 wildcard / regex 路由
+	for i := range g.patternRoutes {
+		entry := &g.patternRoutes[i]
+		match := entry.re.FindStringSubmatchIndex(model)
+		if match == nil {
+			continue
+		}
+		provider, ok := g.providers[entry.provider]
+		if !ok {
+			trace.step("pattern", false, "%s rule %q matched but provider %q is unknown", entry.ruleType, entry.pattern, entry.provider)
+			continue
+		}
+		actualModel := entry.expand(entry.actualModel, model, match)
+		g.logger.Debug("using pattern route",
+			logger.String("model", model),
+			logger.String("pattern", entry.pattern),
+			logger.String("provider", entry.provider),
+			logger.String("actualModel", actualModel),
+		)
+		fallbacks := make([]config.ModelRoute, len(entry.fallbacks))
+		for j, hop := range entry.fallbacks {
+			fallbacks[j] = config.ModelRoute{Provider: hop.Provider, ActualModel: entry.expand(hop.ActualModel, model, match)}
+		}
+		variants := make([]config.ModelVariant, len(entry.variants))
+		for j, v := range entry.variants {
+			variants[j] = v
+			variants[j].ActualModel = entry.expand(v.ActualModel, model, match)
+		}
+		trace.step("pattern", true, "%s rule %q -> %s (%s)", entry.ruleType, entry.pattern, entry.provider, actualModel)
+		target := g.splitLocked(entry.pattern, model, req.SplitKey, variants, g.targetLocked(provider, actualModel), trace)
+		target.fallbacks = g.fallbacksLocked(model, fallbacks)
+		if entry.shadow != nil {
+			shadow := *entry.shadow
+			shadow.ActualModel = entry.expand(shadow.ActualModel, model, match)
+			target.shadow = &shadow
+		}
+		trace.shadow(target.shadow, model)
+		trace.match(entry.ruleType, entry.pattern, target)
+		return target, nil
+	}
+	trace.step("pattern", false, "no wildcard / regex rule matched (%d checked)", len(g.patternRoutes))
+
+	// 4. 检查前缀路由
 	for _, entry := range g.prefixRoutes {
 		if strings.HasPrefix(strings.ToLower(model), strings.ToLower(entry.prefix)) {
 			provider, ok := g.providers[entry.provider]
@@ -300,21 +1003,31 @@ func (g *gatewayService) GetProvider(model string) (providers.Provider, string,
 					logger.String("prefix", entry.prefix),
 					logger.String("provider", entry.provider),
 				)
-				return provider, model, nil
+				trace.step("prefix", true, "prefix %q -> %s", entry.prefix, entry.provider)
+				target := g.splitLocked(entry.prefix, model, req.SplitKey, entry.variants, g.targetLocked(provider, model), trace)
+				target.fallbacks = g.fallbacksLocked(model, entry.fallbacks)
+				target.shadow = entry.shadow
+				trace.shadow(entry.shadow, model)
+				trace.match("prefix", entry.prefix, target)
+				return target, nil
 			}
+			trace.step("prefix", false, "prefix %q matched but provider %q is unknown", entry.prefix, entry.provider)
 		}
 	}
+	trace.step("prefix", false, "no prefix rule matched (%d checked)", len(g.prefixRoutes))
 
-	// 4. 回退到类型默认值
+	// 5. 回退到类型默认值
 	providerType := g.detectProviderType(model)
 	providerName := g.typeDefaults[providerType]
 	if providerName == "" {
-		return nil, "", fmt.Errorf("%w: no provider for type %s", errs.ErrProviderNotFound, providerType)
+		trace.step("typeDefault", false, "no provider for type %s", providerType)
+		return nil, fmt.Errorf("%w: no provider for type %s", errs.ErrProviderNotFound, providerType)
 	}
 
 	provider, ok := g.providers[providerName]
 	if !ok {
-		return nil, "", fmt.Errorf("%w: %s", errs.ErrProviderNotFound, providerName)
+		trace.step("typeDefault", false, "default provider %q for type %s is unknown", providerName, providerType)
+		return nil, fmt.Errorf("%w: %s", errs.ErrProviderNotFound, providerName)
 	}
 
 	g.logger.Debug("using type default",
@@ -322,7 +1035,104 @@ func (g *gatewayService) GetProvider(model string) (providers.Provider, string,
 		logger.String("type", providerType),
 		logger.String("provider", providerName),
 	)
-	return provider, model, nil
+	target := g.targetLocked(provider, model)
+	trace.step("typeDefault", true, "type %s -> %s", providerType, providerName)
+	trace.match("typeDefault", providerType, target)
+	return target, nil
+}
+
+// targetLocked 为指定供应商构建路由目标，调用方需持有读锁。
+func (g *gatewayService) targetLocked(provider providers.Provider, actualModel string) *routeTarget {
+	name := provider.Name()
+	return &routeTarget{
+		provider:    provider,
+		actualModel: actualModel,
+		breaker:     g.breakers[name],
+		retry:       g.retryPolicies[name],
+		timeout:     g.timeouts[name],
+		firstToken:  g.firstTokenLimits[name],
+	}
+}
+
+// splitLocked 为配置了 A/B 分流的规则选择实验组，未配置分流时原样返回 control，调用方需持有读锁。
+// 请求按分流键确定性地分组；未分到实验组、或实验组的供应商未注册时使用对照组（规则本身的目标）。
+func (g *gatewayService) splitLocked(rule, model, splitKey string, variants []config.ModelVariant, control *routeTarget, trace *routeTrace) *routeTarget {
+	if len(variants) == 0 {
+		return control
+	}
+	control.variant = domain.VariantControl
+	if trace != nil && splitKey == "" {
+		// 解释路由时没有分流键，只列出各组的流量占比
+		rest := 100
+		arms := make([]string, 0, len(variants))
+		for _, v := range variants {
+			rest -= v.Percent
+			arms = append(arms, fmt.Sprintf("%s %d%% -> %s (%s)", v.Name, v.Percent, v.Provider, cmp.Or(v.ActualModel, model)))
+		}
+		trace.step("split", true, "%s %d%%, %s; assigned per user or API key", domain.VariantControl, rest, strings.Join(arms, ", "))
+		return control
+	}
+
+	v := pickVariant(rule, splitKey, variants)
+	if v == nil {
+		return control
+	}
+	provider, ok := g.providers[v.Provider]
+	if !ok {
+		g.logger.Warn("variant provider not found, using control",
+			logger.String("rule", rule),
+			logger.String("variant", v.Name),
+			logger.String("provider", v.Provider),
+		)
+		return control
+	}
+	target := g.targetLocked(provider, cmp.Or(v.ActualModel, model))
+	target.variant = v.Name
+	return target
+}
+
+// pickVariant 把分流键哈希到 [0, 100) 的桶中，按实验组顺序累加百分比确定所属的组，返回 nil 表示对照组。
+// 哈希包含规则模式，不同实验之间的分组相互独立；分流键为空（匿名请求）时随机分组。
+func pickVariant(rule, splitKey string, variants []config.ModelVariant) *config.ModelVariant {
+	var bucket int
+	if splitKey == "" {
+		bucket = rand.IntN(100)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(rule))
+		h.Write([]byte{0})
+		h.Write([]byte(splitKey))
+		bucket = int(h.Sum32() % 100)
+	}
+	for i := range variants {
+		if bucket -= variants[i].Percent; bucket < 0 {
+			return &variants[i]
+		}
+	}
+	return nil
+}
+
+// fallbacksLocked 将回退链解析为路由目标，未注册的供应商被跳过，调用方需持有读锁。
+func (g *gatewayService) fallbacksLocked(model string, chain []config.ModelRoute) []*routeTarget {
+	var targets []*routeTarget
+	for i, hop := range chain {
+		provider, ok := g.providers[hop.Provider]
+		if !ok {
+			g.logger.Debug("skipping unknown fallback provider",
+				logger.String("model", model),
+				logger.String("provider", hop.Provider),
+			)
+			continue
+		}
+		actualModel := hop.ActualModel
+		if actualModel == "" {
+			actualModel = model
+		}
+		target := g.targetLocked(provider, actualModel)
+		target.hop = i + 1
+		targets = append(targets, target)
+	}
+	return targets
 }
 
 func (g *gatewayService) detectProviderType(model string) string {
@@ -332,6 +1142,8 @@ func (g *gatewayService) detectProviderType(model string) string {
 		return "openai"
 	case strings.HasPrefix(lower, "claude"):
 		return "anthropic"
+	case strings.HasPrefix(lower, "gemini"):
+		return "gemini"
 	default:
 		return "openai"
 	}
@@ -339,58 +1151,292 @@ func (g *gatewayService) detectProviderType(model string) string {
 
 // Chat 处理非流式聊天请求。
 func (g *gatewayService) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
-	provider, actualModel, err := g.GetProvider(req.Model)
+	start := time.Now()
+	requested := *req // 路由与 chatOnce 会改写 req.Model，影子请求使用客户端原始请求
+	// 依次尝试主路由与回退链中支持请求能力的目标，直到成功或遇到不可切换的错误
+	hops, err := g.route(req)
 	if err != nil {
 		return nil, err
 	}
+	shadow := g.startShadow(&requested, hops[0].shadow)
+	for i, hop := range hops {
+		if i > 0 {
+			g.logger.Warn("provider failed, falling back",
+				logger.String("failed", hops[i-1].provider.Name()),
+				logger.String("provider", hop.provider.Name()),
+				logger.Int("hop", hop.hop),
+				logger.Error(err),
+			)
+		}
+		var resp *domain.ChatResponse
+		resp, err = g.chatOnce(ctx, req, hop)
+		if err == nil {
+			resp.FallbackHop = hop.hop
+			resp.Variant = hop.variant
+			shadow.finish(chatOutcome(resp, hop.actualModel, time.Since(start), nil))
+			return resp, nil
+		}
+		if !canFallback(ctx, err) {
+			break
+		}
+	}
+	shadow.finish(chatOutcome(nil, req.Model, time.Since(start), err))
+	return nil, err
+}
 
-	req.Model = actualModel
+// chatOnce 在单个路由目标上执行请求，按该供应商的重试策略重试。
+func (g *gatewayService) chatOnce(ctx context.Context, req *domain.ChatRequest, target *routeTarget) (*domain.ChatResponse, error) {
+	provider := target.provider
+	req.Model = target.actualModel
 
 	g.logger.Info("routing chat request",
 		logger.String("model", req.Model),
 		logger.String("provider", provider.Name()),
+		logger.String("type", provider.Type()),
+		logger.Int("hop", target.hop),
 		logger.Any("stream", req.Stream),
 	)
 
 	var resp *domain.ChatResponse
-	err = retry.Do(ctx, retry.DefaultConfig, func() error {
-		var e error
-		resp, e = provider.Chat(ctx, req)
-		// 如果需要，可以在这里决定哪些错误不需要重试
+	var latency time.Duration
+	err := retry.Do(ctx, target.retry, func() error {
+		done, e := target.allow()
+		if e != nil {
+			// 熔断打开时不再消耗重试次数
+			return retry.Permanent(e)
+		}
+		start := time.Now()
+		attemptCtx, cancel := target.withTimeout(ctx)
+		resp, e = provider.Chat(attemptCtx, req)
+		cancel()
+		latency = time.Since(start)
+		done(!isUpstreamFailure(ctx, e))
 		return e
 	})
+	target.report(ctx, err)
+	if err != nil {
+		latency = 0 // 失败请求的耗时不作为延迟样本
+	}
+	target.release(latency)
 
 	if err != nil {
 		return nil, err
 	}
 	resp.Provider = provider.Name()
+	resp.ProviderID = provider.ID()
 	return resp, nil
 }
 
 // ChatStream 处理流式聊天请求。
-// 注意：流式请求不使用重试，因为一旦开始流式传输，重试会导致数据丢失或重复。
-func (g *gatewayService) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, string, error) {
-	provider, actualModel, err := g.GetProvider(req.Model)
+// 在首个增量到达之前尚未向客户端发送任何数据，此时的连接错误、非 200 响应和首 token 超时
+// 可以安全地在同一供应商上重试或切换到回退链的下一跳；一旦开始转发便不再重试，
+// 以免客户端收到重复或乱序的数据。
+func (g *gatewayService) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, domain.ProviderRef, error) {
+	start := time.Now()
+	requested := *req
+	hops, err := g.route(req)
 	if err != nil {
-		return nil, "", err
+		return nil, domain.ProviderRef{}, err
+	}
+	shadow := g.startShadow(&requested, hops[0].shadow)
+	for i, hop := range hops {
+		if i > 0 {
+			g.logger.Warn("provider failed before first token, falling back",
+				logger.String("failed", hops[i-1].provider.Name()),
+				logger.String("provider", hop.provider.Name()),
+				logger.Int("hop", hop.hop),
+				logger.Error(err),
+			)
+		}
+		var s *upstreamStream
+		s, err = g.streamOnce(ctx, req, hop)
+		if err == nil {
+			ref := domain.ProviderRef{ID: hop.provider.ID(), Name: hop.provider.Name(), FallbackHop: hop.hop, Variant: hop.variant}
+			return g.watchStream(ctx, s, hop, shadow, start), ref, nil
+		}
+		if !canFallback(ctx, err) {
+			break
+		}
 	}
+	shadow.finish(chatOutcome(nil, req.Model, time.Since(start), err))
+	return nil, domain.ProviderRef{}, err
+}
 
-	req.Model = actualModel
+// upstreamStream 是已收到首个增量的上游流。
+type upstreamStream struct {
+	first  domain.StreamDelta
+	ch     <-chan domain.StreamDelta
+	cancel context.CancelFunc // 取消本次上游请求
+	done   func(success bool) // 熔断器回调
+	ttft   time.Duration      // 首个增量到达耗时
+}
+
+// streamOnce 在单个路由目标上建立流式连接，首个增量到达前的失败按该供应商的重试策略重试。
+func (g *gatewayService) streamOnce(ctx context.Context, req *domain.ChatRequest, target *routeTarget) (*upstreamStream, error) {
+	provider := target.provider
+	req.Model = target.actualModel
 
 	g.logger.Info("routing streaming chat request",
 		logger.String("model", req.Model),
 		logger.String("provider", provider.Name()),
+		logger.String("type", provider.Type()),
+		logger.Int("hop", target.hop),
 	)
 
-	// 流式请求不重试 - 重试会导致：
-	// 1. 之前的 channel 可能已经开始发送数据
-	// 2. 客户端可能收到重复或乱序的数据
-	// 3. 无法保证数据完整性
-	ch, err := provider.ChatStream(ctx, req)
+	var s *upstreamStream
+	err := retry.Do(ctx, target.retry, func() error {
+		done, e := target.allow()
+		if e != nil {
+			return retry.Permanent(e)
+		}
+		s, e = g.openStream(ctx, req, target)
+		if e != nil {
+			done(!isUpstreamFailure(ctx, e))
+			return e
+		}
+		s.done = done
+		return nil
+	})
+	if err != nil {
+		target.report(ctx, err)
+		target.release(0)
+		return nil, err
+	}
+	return s, nil
+}
+
+// openStream 发起流式请求并等待首个增量。上游在首个增量之前关闭流或超过首 token 超时
+// 都返回可重试的错误，被放弃的上游请求会被取消。
+func (g *gatewayService) openStream(ctx context.Context, req *domain.ChatRequest, target *routeTarget) (*upstreamStream, error) {
+	start := time.Now()
+	attemptCtx, cancel := context.WithCancel(ctx)
+	ch, err := target.provider.ChatStream(attemptCtx, req)
 	if err != nil {
-		return nil, "", err
+		cancel()
+		return nil, err
 	}
-	return ch, provider.Name(), nil
+
+	var timeout <-chan time.Time
+	if target.firstToken > 0 {
+		timer := time.NewTimer(target.firstToken)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case first, ok := <-ch:
+		if ok {
+			return &upstreamStream{first: first, ch: ch, cancel: cancel, ttft: time.Since(start)}, nil
+		}
+		err = fmt.Errorf("%w: %s: stream closed before first token: %w",
+			errs.ErrProviderError, target.provider.Name(), io.ErrUnexpectedEOF)
+	case <-timeout:
+		err = fmt.Errorf("%w: %s: no first token within %s: %w",
+			errs.ErrProviderTimeout, target.provider.Name(), target.firstToken, context.DeadlineExceeded)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cancel()
+	// 排空上游以便其 goroutine 退出
+	go func() {
+		for range ch {
+		}
+	}()
+	return nil, err
+}
+
+// watchStream 转发流式增量并根据流的结束方式回报节点健康状态与熔断器结果：
+// 收到 done 视为成功；上游在 done 之前关闭流视为失败；客户端取消不计入失败。
+// 本次请求被影子流量采样时，同时收集输出文本与用量，流结束后交给影子请求对比。
+func (g *gatewayService) watchStream(ctx context.Context, s *upstreamStream, target *routeTarget, shadow *shadowCall, start time.Time) <-chan domain.StreamDelta {
+	out := make(chan domain.StreamDelta, cap(s.ch))
+	go func() {
+		defer close(out)
+		defer s.cancel()
+		// 流式请求以首 token 时间作为延迟样本，在整个流结束后才释放在途占用
+		defer target.release(s.ttft)
+
+		reported := false
+		var content strings.Builder
+		primary := shadowOutcome{provider: target.provider.Name(), model: target.actualModel}
+		if shadow != nil {
+			defer func() {
+				primary.latency, primary.content = time.Since(start), content.String()
+				if !reported && primary.err == "" {
+					primary.err = "stream ended before completion"
+				}
+				shadow.finish(primary)
+			}()
+		}
+		forward := func(delta domain.StreamDelta) bool {
+			if shadow != nil {
+				if delta.Content != nil {
+					content.WriteString(delta.Content.Text)
+				}
+				if delta.Usage != nil {
+					primary.inputTokens, primary.outputTokens = delta.Usage.PromptTokens, delta.Usage.CompletionTokens
+				}
+			}
+			if delta.Type == "done" && !reported {
+				reported = true
+				s.done(true)
+				target.reportSuccess()
+			}
+			select {
+			case out <- delta:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		ok := forward(s.first)
+		for ok {
+			delta, open := <-s.ch
+			if !open {
+				break
+			}
+			ok = forward(delta)
+		}
+		if !ok {
+			// 消费方已离开，排空上游以便其 goroutine 退出
+			for range s.ch {
+			}
+			s.done(true)
+			if !reported {
+				primary.err = "client canceled"
+			}
+			return
+		}
+		if reported {
+			return
+		}
+		if ctx.Err() != nil {
+			s.done(true)
+			primary.err = "client canceled"
+			return
+		}
+		g.logger.Warn("stream ended before completion",
+			logger.String("provider", target.provider.Name()),
+			logger.String("model", target.actualModel),
+		)
+		s.done(false)
+		target.reportFailure()
+	}()
+	return out
+}
+
+// CircuitBreakers 返回各供应商熔断器的状态快照，按名称排序。
+func (g *gatewayService) CircuitBreakers() []circuitbreaker.Snapshot {
+	g.mu.RLock()
+	snapshots := make([]circuitbreaker.Snapshot, 0, len(g.breakers))
+	for _, b := range g.breakers {
+		snapshots = append(snapshots, b.Snapshot())
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
 }
 
 // ListModels 返回所有供应商提供的所有可用模型。
@@ -0,0 +1,876 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/circuitbreaker"
+	"ai-gateway/internal/pkg/keypool"
+	"ai-gateway/internal/pkg/loadbalancer"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/retry"
+	"ai-gateway/internal/providers"
+	"ai-gateway/internal/repository"
+)
+
+// fakeProvider 按预设结果返回的测试供应商，记录收到的模型名。
+type fakeProvider struct {
+	name   string
+	err    error
+	hang   bool                                                // 为 true 时 Chat 阻塞到 ctx 结束
+	stream func(ctx context.Context) <-chan domain.StreamDelta // 为 nil 时返回一个完整的流
+	models []string
+}
+
+func (p *fakeProvider) ID() int64    { return 0 }
+func (p *fakeProvider) Name() string { return p.name }
+func (p *fakeProvider) Type() string { return "openai" }
+
+func (p *fakeProvider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+	p.models = append(p.models, req.Model)
+	if p.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &domain.ChatResponse{Model: req.Model}, nil
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, error) {
+	p.models = append(p.models, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.stream != nil {
+		return p.stream(ctx), nil
+	}
+	ch := make(chan domain.StreamDelta, 2)
+	ch <- domain.StreamDelta{Type: "content", Content: &domain.ContentPart{Type: "text", Text: "hi"}}
+	ch <- domain.StreamDelta{Type: "done"}
+	close(ch)
+	return ch, nil
+}
+
+func (p *fakeProvider) ListModels(context.Context) ([]string, error) { return nil, nil }
+func (p *fakeProvider) SupportsStreaming() bool                      { return true }
+func (p *fakeProvider) SupportsTools() bool                          { return true }
+func (p *fakeProvider) SupportsVision() bool                         { return true }
+
+func newTestGateway(route config.ModelRoute, model string, ps ...*fakeProvider) *gatewayService {
+	g := &gatewayService{
+		providers:     make(map[string]providers.Provider),
+		routes:        map[string]config.ModelRoute{model: route},
+		breakers:      make(map[string]*circuitbreaker.Breaker),
+		retryPolicies: make(map[string]retry.Config),
+		logger:        logger.NewNopLogger(),
+	}
+	for _, p := range ps {
+		g.providers[p.name] = p
+		g.breakers[p.name] = circuitbreaker.New(p.name, circuitbreaker.DefaultConfig)
+		g.retryPolicies[p.name] = retry.Config{MaxAttempts: 1, Retryable: providers.IsRetryable}
+	}
+	return g
+}
+
+func TestGatewayService_ChatFallback(t *testing.T) {
+	route := config.ModelRoute{
+		Provider:    "openai-main",
+		ActualModel: "gpt-4o",
+		Fallbacks: []config.ModelRoute{
+			{Provider: "missing"},
+			{Provider: "azure", ActualModel: "gpt-4o-azure"},
+			{Provider: "anthropic", ActualModel: "claude-sonnet"},
+		},
+	}
+
+	t.Run("RetryableErrorWalksChain", func(t *testing.T) {
+		primary := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusServiceUnavailable, nil, "", "", "overloaded")}
+		azure := &fakeProvider{name: "azure", err: providers.NewUpstreamError("azure", http.StatusTooManyRequests, nil, "", "", "slow down")}
+		anthropic := &fakeProvider{name: "anthropic"}
+		g := newTestGateway(route, "gpt-4o", primary, azure, anthropic)
+
+		req := &domain.ChatRequest{Model: "gpt-4o"}
+		resp, err := g.Chat(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", resp.Provider)
+		assert.Equal(t, 3, resp.FallbackHop) // 未注册的 "missing" 被跳过，但跳数按规则中的位置计算
+		assert.Equal(t, "claude-sonnet", req.Model)
+		assert.Equal(t, []string{"gpt-4o"}, primary.models)
+		assert.Equal(t, []string{"gpt-4o-azure"}, azure.models)
+	})
+
+	t.Run("NonRetryableErrorStops", func(t *testing.T) {
+		primary := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusBadRequest, nil, "", "", "bad request")}
+		anthropic := &fakeProvider{name: "anthropic"}
+		g := newTestGateway(route, "gpt-4o", primary, anthropic)
+
+		_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+		assert.ErrorIs(t, err, errs.ErrProviderError)
+		assert.Empty(t, anthropic.models)
+	})
+
+	t.Run("ChainExhausted", func(t *testing.T) {
+		primary := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusBadGateway, nil, "", "", "")}
+		anthropic := &fakeProvider{name: "anthropic", err: providers.NewUpstreamError("anthropic", 529, nil, "overloaded_error", "", "")}
+		g := newTestGateway(route, "gpt-4o", primary, anthropic)
+
+		_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+		assert.ErrorIs(t, err, errs.ErrProviderOverloaded)
+		assert.Len(t, anthropic.models, 1)
+	})
+
+	t.Run("TimeoutWalksChain", func(t *testing.T) {
+		primary := &fakeProvider{name: "openai-main", hang: true}
+		anthropic := &fakeProvider{name: "anthropic"}
+		g := newTestGateway(route, "gpt-4o", primary, anthropic)
+		g.timeouts = map[string]time.Duration{"openai-main": 20 * time.Millisecond}
+
+		resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", resp.Provider)
+	})
+}
+
+func TestGatewayService_ChatStreamFailover(t *testing.T) {
+	route := config.ModelRoute{
+		Provider: "openai-main",
+		Fallbacks: []config.ModelRoute{
+			{Provider: "anthropic", ActualModel: "claude-sonnet"},
+		},
+	}
+
+	t.Run("ErrorBeforeFirstToken", func(t *testing.T) {
+		primary := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusBadGateway, nil, "", "", "")}
+		anthropic := &fakeProvider{name: "anthropic"}
+		g := newTestGateway(route, "gpt-4o", primary, anthropic)
+
+		ch, ref, err := g.ChatStream(context.Background(), &domain.ChatRequest{Model: "gpt-4o", Stream: true})
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", ref.Name)
+		assert.Equal(t, 1, ref.FallbackHop)
+
+		var types []string
+		for delta := range ch {
+			types = append(types, delta.Type)
+		}
+		assert.Equal(t, []string{"content", "done"}, types)
+	})
+
+	t.Run("FirstTokenTimeout", func(t *testing.T) {
+		aborted := make(chan struct{})
+		primary := &fakeProvider{name: "openai-main", stream: func(ctx context.Context) <-chan domain.StreamDelta {
+			ch := make(chan domain.StreamDelta)
+			go func() {
+				defer close(ch)
+				<-ctx.Done()
+				close(aborted)
+			}()
+			return ch
+		}}
+		anthropic := &fakeProvider{name: "anthropic"}
+		g := newTestGateway(route, "gpt-4o", primary, anthropic)
+		g.firstTokenLimits = map[string]time.Duration{"openai-main": 20 * time.Millisecond}
+
+		ch, ref, err := g.ChatStream(context.Background(), &domain.ChatRequest{Model: "gpt-4o", Stream: true})
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", ref.Name)
+		for range ch {
+		}
+
+		select {
+		case <-aborted:
+		case <-time.After(time.Second):
+			t.Fatal("stalled upstream was not cancelled")
+		}
+	})
+
+	t.Run("TotalTimeoutNotApplied", func(t *testing.T) {
+		// 非流式请求的总超时不截断输出时间更长的流
+		primary := &fakeProvider{name: "openai-main", stream: func(ctx context.Context) <-chan domain.StreamDelta {
+			ch := make(chan domain.StreamDelta)
+			go func() {
+				defer close(ch)
+				for _, d := range []domain.StreamDelta{
+					{Type: "content", Content: &domain.ContentPart{Type: "text", Text: "a"}},
+					{Type: "content", Content: &domain.ContentPart{Type: "text", Text: "b"}},
+					{Type: "done"},
+				} {
+					select {
+					case <-time.After(20 * time.Millisecond):
+					case <-ctx.Done():
+						return
+					}
+					ch <- d
+				}
+			}()
+			return ch
+		}}
+		g := newTestGateway(route, "gpt-4o", primary)
+		g.timeouts = map[string]time.Duration{"openai-main": 10 * time.Millisecond}
+
+		ch, _, err := g.ChatStream(context.Background(), &domain.ChatRequest{Model: "gpt-4o", Stream: true})
+		require.NoError(t, err)
+		var types []string
+		for delta := range ch {
+			types = append(types, delta.Type)
+		}
+		assert.Equal(t, []string{"content", "content", "done"}, types)
+	})
+}
+
+func TestGatewayService_PatternRoutes(t *testing.T) {
+	g := newTestGateway(config.ModelRoute{Provider: "openai-main"}, "gpt-4o",
+		&fakeProvider{name: "openai-main"}, &fakeProvider{name: "anthropic"})
+
+	for _, rule := range []domain.RoutingRule{
+		{RuleType: domain.RuleTypeRegex, Pattern: "my-(.*)", ProviderName: "openai-main", ActualModel: "gpt-$1",
+			Fallbacks: []domain.RouteTarget{{Provider: "anthropic", ActualModel: "claude-${1}"}}},
+		{RuleType: domain.RuleTypeWildcard, Pattern: "claude-*-sonnet-*", ProviderName: "anthropic", ActualModel: "claude-sonnet-$2"},
+		{RuleType: domain.RuleTypeRegex, Pattern: "sonnet", ProviderName: "anthropic"},
+	} {
+		re, err := rule.CompilePattern()
+		require.NoError(t, err)
+		entry := patternRouteEntry{pattern: rule.Pattern, re: re, provider: rule.ProviderName, actualModel: rule.ActualModel}
+		for _, hop := range rule.Fallbacks {
+			entry.fallbacks = append(entry.fallbacks, config.ModelRoute{Provider: hop.Provider, ActualModel: hop.ActualModel})
+		}
+		g.patternRoutes = append(g.patternRoutes, entry)
+	}
+
+	tests := []struct {
+		model, provider, actualModel string
+	}{
+		{"my-4o-mini", "openai-main", "gpt-4o-mini"},
+		{"Claude-3-Sonnet-20240229", "anthropic", "claude-sonnet-20240229"},
+		{"gpt-4o", "openai-main", "gpt-4o"}, // 精确路由优先
+	}
+	for _, tt := range tests {
+		target, err := g.resolve(&domain.ChatRequest{Model: tt.model}, domain.Requirements{})
+		require.NoError(t, err, tt.model)
+		assert.Equal(t, tt.provider, target.provider.Name(), tt.model)
+		assert.Equal(t, tt.actualModel, target.actualModel, tt.model)
+	}
+
+	// 回退链中的实际模型同样展开捕获组
+	target, err := g.resolve(&domain.ChatRequest{Model: "my-sonnet-x"}, domain.Requirements{})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-sonnet-x", target.actualModel)
+	require.Len(t, target.fallbacks, 1)
+	assert.Equal(t, "claude-sonnet-x", target.fallbacks[0].actualModel)
+
+	// 正则自动锚定，部分匹配不生效
+	_, err = g.resolve(&domain.ChatRequest{Model: "sonnet-x"}, domain.Requirements{})
+	assert.ErrorIs(t, err, errs.ErrProviderNotFound)
+}
+
+func TestGatewayService_ExplainRoute(t *testing.T) {
+	primary := &fakeProvider{name: "openai-main"}
+	backup := &fakeProvider{name: "openai-backup"}
+	g := newTestGateway(config.ModelRoute{Provider: "openai-main", Fallbacks: []config.ModelRoute{{Provider: "openai-backup"}}},
+		"gpt-4o", primary, backup)
+	lb := loadbalancer.NewRoundRobin([]*providerNode{
+		{provider: primary, name: primary.name},
+		{provider: backup, name: backup.name},
+	})
+	g.loadBalancers = map[string]loadbalancer.LoadBalancer[*providerNode]{"gpt-4o-mini": lb}
+	g.typeDefaults = map[string]string{}
+
+	t.Run("Exact", func(t *testing.T) {
+		e := g.ExplainRoute("gpt-4o")
+		assert.Equal(t, "exact", e.MatchedBy)
+		assert.Equal(t, "openai-main", e.Provider)
+		assert.Equal(t, "gpt-4o", e.ActualModel)
+		require.Len(t, e.Fallbacks, 1)
+		assert.Equal(t, domain.RouteCandidate{Provider: "openai-backup", ActualModel: "gpt-4o", Hop: 1, Available: true, Breaker: "closed"}, e.Fallbacks[0])
+	})
+
+	t.Run("LoadBalanceDoesNotSelect", func(t *testing.T) {
+		e := g.ExplainRoute("gpt-4o-mini")
+		assert.Equal(t, "loadBalance", e.MatchedBy)
+		assert.Empty(t, e.Provider) // 多个可用节点时按请求选择
+		require.Len(t, e.Candidates, 2)
+
+		// 解释不推进轮询计数：随后的真实选择仍从第一个节点开始
+		node, err := lb.Select()
+		require.NoError(t, err)
+		assert.Equal(t, "openai-main", node.name)
+
+		lb.ReportFailure(&providerNode{provider: backup, name: backup.name})
+		e = g.ExplainRoute("gpt-4o-mini")
+		assert.Equal(t, "openai-main", e.Provider)
+		assert.True(t, e.Candidates[0].Available)
+		assert.False(t, e.Candidates[1].Available)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		e := g.ExplainRoute("mistral-large")
+		assert.Contains(t, e.Error, errs.ErrProviderNotFound.Error())
+		stages := make([]string, 0, len(e.Steps))
+		for _, s := range e.Steps {
+			assert.False(t, s.Matched)
+			stages = append(stages, s.Stage)
+		}
+		assert.Equal(t, []string{"exact", "loadBalance", "pattern", "prefix", "typeDefault"}, stages)
+	})
+}
+
+func TestGatewayService_LeastOutstanding(t *testing.T) {
+	release := make(chan struct{})
+	a := &fakeProvider{name: "openai-a", stream: func(ctx context.Context) <-chan domain.StreamDelta {
+		ch := make(chan domain.StreamDelta)
+		go func() {
+			defer close(ch)
+			ch <- domain.StreamDelta{Type: "content", Content: &domain.ContentPart{Type: "text", Text: "hi"}}
+			<-release
+			ch <- domain.StreamDelta{Type: "done"}
+		}()
+		return ch
+	}}
+	b := &fakeProvider{name: "openai-b"}
+	g := newTestGateway(config.ModelRoute{}, "unused", a, b)
+	g.loadBalancers = map[string]loadbalancer.LoadBalancer[*providerNode]{
+		"gpt-4o": loadbalancer.NewLeastOutstanding([]*providerNode{
+			{provider: a, name: a.name},
+			{provider: b, name: b.name},
+		}),
+	}
+	ctx := context.Background()
+
+	// 进行中的流占用 a，随后的请求都落到 b
+	ch, ref, err := g.ChatStream(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai-a", ref.Name)
+	for i := 0; i < 2; i++ {
+		resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-b", resp.Provider)
+	}
+
+	// 流结束后释放 a 的占用
+	close(release)
+	for range ch {
+	}
+	assert.Eventually(t, func() bool {
+		resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+		return err == nil && resp.Provider == "openai-a"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGatewayService_StickySession(t *testing.T) {
+	ps := []*fakeProvider{{name: "openai-a"}, {name: "openai-b"}, {name: "openai-c"}}
+	g := newTestGateway(config.ModelRoute{}, "unused", ps...)
+	nodes := make([]*providerNode, len(ps))
+	for i, p := range ps {
+		nodes[i] = &providerNode{provider: p, name: p.name}
+	}
+	g.loadBalancers = map[string]loadbalancer.LoadBalancer[*providerNode]{
+		"gpt-4o": loadbalancer.NewConsistentHash(nodes),
+	}
+	ctx := context.Background()
+
+	pinned := make(map[string]string)
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"session:1", "session:2", "session:3", "session:4"} {
+			resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o", SessionKey: key})
+			require.NoError(t, err)
+			if i == 0 {
+				pinned[key] = resp.Provider
+				continue
+			}
+			assert.Equal(t, pinned[key], resp.Provider, key)
+		}
+	}
+
+	// 无会话键的请求按轮询分配
+	seen := make(map[string]bool)
+	for i := 0; i < len(ps); i++ {
+		resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+		seen[resp.Provider] = true
+	}
+	assert.Len(t, seen, len(ps))
+}
+
+func TestGatewayService_CapabilityRouting(t *testing.T) {
+	primary := &fakeProvider{name: "openai-main"}
+	backup := &fakeProvider{name: "openai-backup"}
+	route := config.ModelRoute{
+		Provider:  "openai-main",
+		Fallbacks: []config.ModelRoute{{Provider: "openai-backup", ActualModel: "gpt-4o"}},
+	}
+	g := newTestGateway(route, "gpt-4o-mini", primary, backup)
+	g.capabilities = []domain.ModelCapability{
+		{ModelPattern: "gpt-4o-mini", Tools: true, ContextWindow: 1000},
+		{ModelPattern: "gpt-4o*", Vision: true, Tools: true},
+	}
+	image := []domain.Message{{Role: domain.RoleUser, Content: []domain.ContentPart{{Type: domain.ContentTypeImage, URL: "https://example.com/a.png"}}}}
+
+	t.Run("SkipsIncapablePrimary", func(t *testing.T) {
+		resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", Messages: image})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-backup", resp.Provider)
+		assert.Equal(t, 1, resp.FallbackHop)
+		assert.Empty(t, primary.models)
+	})
+
+	t.Run("ContextWindow", func(t *testing.T) {
+		backup.models = nil
+		long := []domain.Message{domain.NewTextMessage(domain.RoleUser, strings.Repeat("x", 8000))}
+		resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", Messages: long})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-backup", resp.Provider)
+	})
+
+	t.Run("NoCapableCandidate", func(t *testing.T) {
+		backup.models = nil
+		req := &domain.ChatRequest{
+			Model:          "gpt-4o-mini",
+			ResponseFormat: &domain.ResponseFormat{Type: domain.ResponseFormatJSONSchema},
+		}
+		_, err := g.Chat(context.Background(), req)
+		require.Error(t, err)
+		assert.Equal(t, errs.CodeUnsupportedFeature, errs.GetCode(err))
+		assert.Contains(t, err.Error(), domain.CapabilityJSONSchema)
+		assert.Empty(t, primary.models)
+		assert.Empty(t, backup.models)
+	})
+
+	t.Run("ProviderSpecificEntryWins", func(t *testing.T) {
+		g.capabilities = append(g.capabilities, domain.ModelCapability{ModelPattern: "gpt-4o", ProviderName: "openai-backup", Tools: true})
+		_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", Messages: image})
+		assert.Equal(t, errs.CodeUnsupportedFeature, errs.GetCode(err))
+	})
+}
+
+func TestGatewayService_LoadBalanceCapability(t *testing.T) {
+	mini := &fakeProvider{name: "openai-mini"}
+	full := &fakeProvider{name: "openai-full"}
+	g := newTestGateway(config.ModelRoute{}, "unused", mini, full)
+	lb := loadbalancer.NewLeastOutstanding([]*providerNode{
+		{provider: mini, name: mini.name},
+		{provider: full, name: full.name},
+	})
+	g.loadBalancers = map[string]loadbalancer.LoadBalancer[*providerNode]{"gpt-4o": lb}
+	g.capabilities = []domain.ModelCapability{{ModelPattern: "gpt-4o", ProviderName: "openai-mini", Tools: true}}
+	image := []domain.Message{{Role: domain.RoleUser, Content: []domain.ContentPart{{Type: domain.ContentTypeImage, URL: "https://example.com/a.png"}}}}
+
+	// 需要视觉能力的请求只在支持的成员中选择，且成员的在途计数正常释放
+	for i := 0; i < 3; i++ {
+		resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o", Messages: image})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-full", resp.Provider)
+	}
+	assert.Empty(t, mini.models)
+
+	// 没有成员支持时返回能力错误，不请求上游
+	g.capabilities = append(g.capabilities, domain.ModelCapability{ModelPattern: "gpt-4o", ProviderName: "openai-full", Tools: true})
+	_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o", Messages: image})
+	assert.Equal(t, errs.CodeUnsupportedFeature, errs.GetCode(err))
+	assert.Len(t, full.models, 3)
+
+	// 普通请求仍在两个成员间分配
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+		seen[resp.Provider] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestGatewayService_LongContextSibling(t *testing.T) {
+	p := &fakeProvider{name: "openai-main"}
+	g := newTestGateway(config.ModelRoute{Provider: "openai-main"}, "gpt-4o-mini", p)
+	g.routes["gpt-4.1"] = config.ModelRoute{Provider: "openai-main"}
+	g.capabilities = []domain.ModelCapability{
+		{ModelPattern: "gpt-4o-mini", ContextWindow: 1000, MaxOutputTokens: 200, LongContextModel: "gpt-4.1"},
+		{ModelPattern: "gpt-4.1", ContextWindow: 100000, MaxOutputTokens: 4000},
+	}
+	long := []domain.Message{domain.NewTextMessage(domain.RoleUser, strings.Repeat("x", 8000))}
+
+	t.Run("FitsPrimary", func(t *testing.T) {
+		p.models = nil
+		req := &domain.ChatRequest{Model: "gpt-4o-mini", Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}}
+		_, err := g.Chat(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o-mini"}, p.models)
+	})
+
+	t.Run("PromptOverflowRoutesToSibling", func(t *testing.T) {
+		p.models = nil
+		req := &domain.ChatRequest{Model: "gpt-4o-mini", Messages: long}
+		_, err := g.Chat(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4.1"}, p.models)
+		assert.Equal(t, "gpt-4.1", req.Model)
+	})
+
+	t.Run("MaxTokensOverflowRoutesToSibling", func(t *testing.T) {
+		p.models = nil
+		_, _, err := g.ChatStream(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", MaxTokens: 1000, Stream: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4.1"}, p.models)
+	})
+
+	t.Run("RejectedWithoutSibling", func(t *testing.T) {
+		p.models = nil
+		g.capabilities[0].LongContextModel = ""
+		_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", Messages: long, MaxTokens: 100})
+		require.Error(t, err)
+		assert.Equal(t, errs.CodeContextLengthExceeded, errs.GetCode(err))
+		assert.Contains(t, err.Error(), "plus 100 output tokens")
+		assert.Contains(t, err.Error(), "1000-token context window")
+		assert.Empty(t, p.models)
+
+		_, err = g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", MaxTokens: 500})
+		assert.Equal(t, errs.CodeContextLengthExceeded, errs.GetCode(err))
+		assert.Contains(t, err.Error(), "200-token output limit")
+	})
+
+	t.Run("SiblingTooSmall", func(t *testing.T) {
+		g.capabilities[0].LongContextModel = "gpt-4.1"
+		_, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o-mini", MaxTokens: 5000})
+		assert.Equal(t, errs.CodeContextLengthExceeded, errs.GetCode(err))
+		assert.Contains(t, err.Error(), "model gpt-4.1")
+		assert.Empty(t, p.models)
+	})
+
+	t.Run("RouteModels", func(t *testing.T) {
+		models, err := g.RouteModels(&domain.ChatRequest{Model: "gpt-4o-mini", MaxTokens: 100})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o-mini"}, models)
+
+		// 与 Chat 一样在超出容量时改用长上下文模型
+		models, err = g.RouteModels(&domain.ChatRequest{Model: "gpt-4o-mini", Messages: long})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4.1"}, models)
+
+		_, err = g.RouteModels(&domain.ChatRequest{Model: "gpt-4o-mini", MaxTokens: 5000})
+		assert.Equal(t, errs.CodeContextLengthExceeded, errs.GetCode(err))
+	})
+}
+
+func TestGatewayService_VariantSplit(t *testing.T) {
+	anthropic := &fakeProvider{name: "anthropic"}
+	backup := &fakeProvider{name: "bedrock", err: providers.NewUpstreamError("bedrock", http.StatusServiceUnavailable, nil, "", "", "overloaded")}
+	route := config.ModelRoute{
+		Provider:    "anthropic",
+		ActualModel: "claude-sonnet-4",
+		Variants:    []config.ModelVariant{{Name: "sonnet-4.5", Provider: "bedrock", ActualModel: "claude-sonnet-4.5", Percent: 10}},
+		Fallbacks:   []config.ModelRoute{{Provider: "anthropic", ActualModel: "claude-sonnet-4"}},
+	}
+	g := newTestGateway(route, "sonnet", anthropic, backup)
+	ctx := context.Background()
+
+	t.Run("DeterministicPerKey", func(t *testing.T) {
+		counts := make(map[string]int)
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("user:%d", i)
+			first, err := g.Chat(ctx, &domain.ChatRequest{Model: "sonnet", SplitKey: key})
+			require.NoError(t, err)
+			again, err := g.Chat(ctx, &domain.ChatRequest{Model: "sonnet", SplitKey: key})
+			require.NoError(t, err)
+			assert.Equal(t, first.Variant, again.Variant, key)
+			counts[first.Variant]++
+		}
+		assert.Len(t, counts, 2)
+		assert.InDelta(t, 100, counts["sonnet-4.5"], 40)
+		assert.Equal(t, 1000, counts["sonnet-4.5"]+counts[domain.VariantControl])
+	})
+
+	t.Run("VariantKeptOnFallback", func(t *testing.T) {
+		for i := 0; ; i++ {
+			key := fmt.Sprintf("user:%d", i)
+			if v := pickVariant("sonnet", key, route.Variants); v == nil {
+				continue
+			}
+			anthropic.models = nil
+			req := &domain.ChatRequest{Model: "sonnet", SplitKey: key}
+			resp, err := g.Chat(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, "sonnet-4.5", resp.Variant)
+			assert.Equal(t, 1, resp.FallbackHop) // bedrock 失败后回退到 anthropic
+			assert.Equal(t, []string{"claude-sonnet-4"}, anthropic.models)
+			return
+		}
+	})
+
+	t.Run("NoSplitWithoutVariants", func(t *testing.T) {
+		g.routes["plain"] = config.ModelRoute{Provider: "anthropic"}
+		resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "plain", SplitKey: "user:1"})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Variant)
+	})
+
+	t.Run("RouteModelsFollowAssignment", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			req := &domain.ChatRequest{Model: "sonnet", SplitKey: fmt.Sprintf("user:%d", i)}
+			models, err := g.RouteModels(req)
+			require.NoError(t, err)
+			resp, err := g.Chat(ctx, req)
+			require.NoError(t, err)
+			if resp.Variant == "sonnet-4.5" {
+				assert.Equal(t, []string{"claude-sonnet-4.5", "claude-sonnet-4"}, models)
+			} else {
+				assert.Equal(t, []string{"claude-sonnet-4"}, models)
+			}
+		}
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		e := g.ExplainRoute("sonnet")
+		assert.Equal(t, "anthropic", e.Provider)
+		assert.Equal(t, "split", e.Steps[1].Stage)
+		assert.Contains(t, e.Steps[1].Detail, "control 90%, sonnet-4.5 10% -> bedrock (claude-sonnet-4.5)")
+	})
+}
+
+// fakeShadowRepo 把保存的影子对比记录发送到通道，供测试等待异步写入。
+type fakeShadowRepo struct {
+	results chan *domain.ShadowResult
+}
+
+func (r *fakeShadowRepo) Create(_ context.Context, result *domain.ShadowResult) error {
+	r.results <- result
+	return nil
+}
+
+func (r *fakeShadowRepo) List(context.Context, int, int, string) ([]domain.ShadowResult, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeShadowRepo) Stats(context.Context, int) ([]domain.ShadowStats, error) { return nil, nil }
+
+func TestGatewayService_Shadow(t *testing.T) {
+	primary := &fakeProvider{name: "openai-main"}
+	candidate := &fakeProvider{name: "self-hosted"}
+	route := config.ModelRoute{
+		Provider:    "openai-main",
+		ActualModel: "gpt-4o-2024-08-06",
+		Shadow:      &config.ShadowRoute{Provider: "self-hosted", ActualModel: "qwen3-32b", Percent: 100},
+	}
+	g := newTestGateway(route, "gpt-4o", primary, candidate)
+	repo := &fakeShadowRepo{results: make(chan *domain.ShadowResult, 1)}
+	g.shadowRepo, g.shadowSem = repo, make(chan struct{}, maxInflightShadows)
+	ctx := context.Background()
+
+	wait := func(t *testing.T) *domain.ShadowResult {
+		select {
+		case r := <-repo.results:
+			return r
+		case <-time.After(time.Second):
+			t.Fatal("shadow result not saved")
+			return nil
+		}
+	}
+
+	t.Run("Chat", func(t *testing.T) {
+		resp, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o", RequestID: "req-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-main", resp.Provider)
+
+		r := wait(t)
+		assert.Equal(t, "req-1", r.RequestID)
+		assert.Equal(t, "gpt-4o", r.Model)
+		assert.Equal(t, "openai-main", r.PrimaryProvider)
+		assert.Equal(t, "gpt-4o-2024-08-06", r.PrimaryModel)
+		assert.Equal(t, "self-hosted", r.ShadowProvider)
+		assert.Equal(t, "qwen3-32b", r.ShadowModel)
+		assert.Empty(t, r.ShadowError)
+		assert.Equal(t, []string{"qwen3-32b"}, candidate.models)
+	})
+
+	t.Run("StreamCollectsPrimaryContent", func(t *testing.T) {
+		ch, ref, err := g.ChatStream(ctx, &domain.ChatRequest{Model: "gpt-4o", Stream: true})
+		require.NoError(t, err)
+		assert.Equal(t, "openai-main", ref.Name)
+		for range ch {
+		}
+
+		r := wait(t)
+		assert.Equal(t, "hi", r.PrimaryContent)
+		assert.Empty(t, r.PrimaryError)
+		assert.Equal(t, "qwen3-32b", r.ShadowModel)
+	})
+
+	t.Run("ShadowFailureDoesNotAffectClient", func(t *testing.T) {
+		candidate.err = providers.NewUpstreamError("self-hosted", http.StatusInternalServerError, nil, "", "", "boom")
+		defer func() { candidate.err = nil }()
+
+		_, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+		require.NoError(t, err)
+		r := wait(t)
+		assert.Empty(t, r.PrimaryError)
+		assert.Contains(t, r.ShadowError, "boom")
+	})
+
+	t.Run("PrimaryFailureRecorded", func(t *testing.T) {
+		primary.err = providers.NewUpstreamError("openai-main", http.StatusBadRequest, nil, "", "", "bad request")
+		defer func() { primary.err = nil }()
+
+		_, err := g.Chat(ctx, &domain.ChatRequest{Model: "gpt-4o"})
+		require.Error(t, err)
+		r := wait(t)
+		assert.Contains(t, r.PrimaryError, "bad request")
+	})
+
+	t.Run("RecordsRequestedModelAfterReroute", func(t *testing.T) {
+		// 超出输出上限时主请求改走长上下文模型，影子请求仍按客户端请求的模型发送和归类
+		g.routes["gpt-4.1"] = config.ModelRoute{Provider: "openai-main", Shadow: &config.ShadowRoute{Provider: "self-hosted", Percent: 100}}
+		g.capabilities = []domain.ModelCapability{{ModelPattern: "gpt-4o-2024-08-06", ContextWindow: 100000, MaxOutputTokens: 200, LongContextModel: "gpt-4.1"}}
+		defer func() { g.capabilities = nil }()
+		candidate.models = nil
+
+		req := &domain.ChatRequest{Model: "gpt-4o", MaxTokens: 1000}
+		_, err := g.Chat(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4.1", req.Model)
+
+		r := wait(t)
+		assert.Equal(t, "gpt-4o", r.Model)
+		assert.Equal(t, "gpt-4.1", r.PrimaryModel)
+		assert.Equal(t, "gpt-4o", r.ShadowModel)
+		assert.Equal(t, []string{"gpt-4o"}, candidate.models)
+	})
+
+	t.Run("NotMirroredWithoutShadow", func(t *testing.T) {
+		g.routes["plain"] = config.ModelRoute{Provider: "openai-main"}
+		_, err := g.Chat(ctx, &domain.ChatRequest{Model: "plain"})
+		require.NoError(t, err)
+		select {
+		case <-repo.results:
+			t.Fatal("unexpected shadow result")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestGatewayService_KeyPool(t *testing.T) {
+	revoked := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusUnauthorized, nil, "invalid_request_error", "invalid_api_key", "Incorrect API key")}
+	limited := &fakeProvider{name: "openai-main", err: providers.NewUpstreamError("openai-main", http.StatusTooManyRequests, http.Header{"Retry-After": {"20"}}, "rate_limit_error", "", "slow down")}
+	healthy := &fakeProvider{name: "openai-main"}
+	backup := &fakeProvider{name: "openai-backup"}
+
+	route := config.ModelRoute{Provider: "openai-main", Fallbacks: []config.ModelRoute{{Provider: "openai-backup"}}}
+	g := newTestGateway(route, "gpt-4o", backup)
+	pool := keypool.New(keypool.StrategyRoundRobin, []keypool.Key{
+		{Name: "default", Secret: "sk-revoked-0000"}, {Name: "org-b", Secret: "sk-limited-1111"}, {Name: "org-c", Secret: "sk-healthy-2222"},
+	}, nil)
+	g.providers["openai-main"] = &pooledProvider{
+		Provider: revoked,
+		members:  []providers.Provider{revoked, limited, healthy},
+		pool:     pool,
+		logger:   logger.NewNopLogger(),
+	}
+	g.keyPools = map[string]*keypool.Pool{"openai-main": pool}
+	g.breakers["openai-main"] = circuitbreaker.New("openai-main", circuitbreaker.DefaultConfig)
+	g.retryPolicies["openai-main"] = retry.Config{MaxAttempts: 1, Retryable: providers.IsRetryable}
+
+	// 401 与 429 的 Key 被跳过，同一次请求换到健康的 Key
+	resp, err := g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai-main", resp.Provider)
+	keys := g.ProviderKeys("openai-main")
+	require.Len(t, keys, 3)
+	assert.Equal(t, "disabled", keys[0].State)
+	assert.Equal(t, "cooldown", keys[1].State)
+	assert.WithinDuration(t, time.Now().Add(20*time.Second), *keys[1].CooldownUntil, 2*time.Second)
+	assert.Equal(t, int64(1), keys[2].Successes)
+	assert.Equal(t, "sk-…2222", keys[2].Key)
+
+	// 被禁用和冷却中的 Key 不再被选中
+	_, err = g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Len(t, revoked.models, 1)
+	assert.Len(t, limited.models, 1)
+	assert.Len(t, healthy.models, 2)
+
+	// 最后一个 Key 也被限流后切换到回退链
+	healthy.err = limited.err
+	resp, err = g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai-backup", resp.Provider)
+	resp, err = g.Chat(context.Background(), &domain.ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai-backup", resp.Provider)
+	assert.Len(t, healthy.models, 3, "cooling keys are not called")
+
+	require.NoError(t, g.EnableProviderKey("openai-main", "default"))
+	assert.Equal(t, "active", g.ProviderKeys("openai-main")[0].State)
+	assert.Error(t, g.EnableProviderKey("openai-main", "missing"))
+}
+
+// 以下仓储为 Reload 提供固定配置，未用到的方法未实现。
+type reloadProviders struct {
+	repository.ProviderRepository
+	providers []domain.Provider
+}
+
+func (r *reloadProviders) List(context.Context) ([]domain.Provider, error) { return r.providers, nil }
+
+type reloadRules struct {
+	repository.RoutingRuleRepository
+}
+
+func (reloadRules) List(context.Context) ([]domain.RoutingRule, error) { return nil, nil }
+
+type reloadBalancers struct {
+	repository.LoadBalanceRepository
+	members []domain.LoadBalanceMember
+}
+
+func (r *reloadBalancers) ListGroups(context.Context) ([]domain.LoadBalanceGroup, error) {
+	return []domain.LoadBalanceGroup{{ID: 1, ModelPattern: "gpt-4o", Strategy: "peak-ewma", Enabled: true}}, nil
+}
+
+func (r *reloadBalancers) GetMembers(context.Context, int64) ([]domain.LoadBalanceMember, error) {
+	return r.members, nil
+}
+
+type reloadCapabilities struct {
+	repository.ModelCapabilityRepository
+}
+
+func (reloadCapabilities) GetAllEnabled(context.Context) ([]domain.ModelCapability, error) {
+	return nil, nil
+}
+
+func TestGatewayService_ReloadKeepsLoadBalancerState(t *testing.T) {
+	ps := &reloadProviders{providers: []domain.Provider{
+		{Name: "a", Type: "openai", APIKey: "sk-a", Enabled: true},
+		{Name: "b", Type: "openai", APIKey: "sk-b", Enabled: true},
+		{Name: "c", Type: "openai", APIKey: "sk-c", Enabled: true},
+	}}
+	lbs := &reloadBalancers{members: []domain.LoadBalanceMember{{ProviderName: "a", Weight: 1}, {ProviderName: "b", Weight: 1}}}
+	g := NewGatewayService(ps, reloadRules{}, lbs, reloadCapabilities{}, nil, logger.NewNopLogger()).(*gatewayService)
+	lb := g.loadBalancers["gpt-4o"]
+	require.NotNil(t, lb)
+	lb.ReportFailure(lb.Nodes()[0])
+	require.Len(t, lb.Available(), 1)
+
+	// 成员未变化时复用负载均衡器，被摘除的节点仍被摘除，节点指向新建的供应商实例
+	oldProvider := lb.Nodes()[1].provider
+	require.NoError(t, g.Reload(context.Background()))
+	assert.Same(t, lb, g.loadBalancers["gpt-4o"])
+	assert.Len(t, lb.Available(), 1)
+	assert.Same(t, g.providers["b"], lb.Nodes()[1].provider)
+	assert.NotSame(t, oldProvider, lb.Nodes()[1].provider)
+
+	// 成员变化时重建
+	lbs.members = append(lbs.members, domain.LoadBalanceMember{ProviderName: "c", Weight: 1})
+	require.NoError(t, g.Reload(context.Background()))
+	assert.NotSame(t, lb, g.loadBalancers["gpt-4o"])
+	assert.Len(t, g.loadBalancers["gpt-4o"].Available(), 3)
+}
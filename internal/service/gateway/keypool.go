@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/keypool"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/providers"
+)
+
+// pooledProvider 在供应商的多个上游 Key 之间分摊请求。每个 Key 对应一个独立的供应商实例
+// （共享同一个 HTTP 客户端），嵌入的第一个实例提供 ID、Name、Type 等元数据。
+//
+// 某个 Key 返回 429 / 额度不足时进入冷却，返回 401 时被禁用，并立即换下一个 Key 重发，
+// 客户端不会感知到单个 Key 的问题；所有 Key 都不可用时才把错误交给重试与回退链处理。
+type pooledProvider struct {
+	providers.Provider
+	members []providers.Provider
+	pool    *keypool.Pool
+	logger  logger.Logger
+}
+
+func (p *pooledProvider) Chat(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+	var tried []int
+	var lastErr error
+	for {
+		lease, err := p.acquire(tried, lastErr)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.members[lease.Index].Chat(ctx, req)
+		if err == nil {
+			if resp.Usage != nil {
+				lease.AddUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+			}
+			lease.Done(keypool.Success, 0, "")
+			return resp, nil
+		}
+		if !p.settle(ctx, lease, err) {
+			return nil, err
+		}
+		tried, lastErr = append(tried, lease.Index), err
+	}
+}
+
+func (p *pooledProvider) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, error) {
+	var tried []int
+	var lastErr error
+	for {
+		lease, err := p.acquire(tried, lastErr)
+		if err != nil {
+			return nil, err
+		}
+		ch, err := p.members[lease.Index].ChatStream(ctx, req)
+		if err == nil {
+			return p.track(ctx, lease, ch), nil
+		}
+		if !p.settle(ctx, lease, err) {
+			return nil, err
+		}
+		tried, lastErr = append(tried, lease.Index), err
+	}
+}
+
+func (p *pooledProvider) ListModels(ctx context.Context) ([]string, error) {
+	lease, err := p.acquire(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	models, err := p.members[lease.Index].ListModels(ctx)
+	if err != nil {
+		p.settle(ctx, lease, err)
+		return nil, err
+	}
+	lease.Done(keypool.Success, 0, "")
+	return models, nil
+}
+
+// acquire 租用一个尚未在本次请求中尝试过的 Key。没有可用 Key 时，若已尝试过其他 Key 则返回
+// 最后一个上游错误；否则冷却中返回可重试的 429（附带最早结束冷却的等待时间），全部禁用返回 ErrNoKey。
+func (p *pooledProvider) acquire(tried []int, lastErr error) (*keypool.Lease, error) {
+	lease, wait, err := p.pool.Acquire(tried...)
+	switch {
+	case err == nil:
+		return lease, nil
+	case lastErr != nil:
+		return nil, lastErr
+	case errors.Is(err, keypool.ErrCoolingDown):
+		return nil, &providers.UpstreamError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusTooManyRequests,
+			Type:       "key_pool_cooldown",
+			Message:    err.Error(),
+			RetryAfter: wait,
+			Err:        errs.ErrRateLimited,
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s: %w", errs.ErrProviderUnavailable, p.Name(), err)
+	}
+}
+
+// settle 回报失败的请求，返回是否应换下一个 Key 重发（Key 被限流或禁用且客户端仍在等待）。
+func (p *pooledProvider) settle(ctx context.Context, lease *keypool.Lease, err error) bool {
+	outcome, cooldown := keyOutcome(err)
+	lease.Done(outcome, cooldown, err.Error())
+	switch outcome {
+	case keypool.Unauthorized:
+		p.logger.Error("upstream api key rejected, disabled",
+			logger.String("key", lease.Name()),
+			logger.Error(err),
+		)
+	case keypool.RateLimited:
+		p.logger.Warn("upstream api key rate limited, cooling down",
+			logger.String("key", lease.Name()),
+			logger.Duration("cooldown", cooldown),
+		)
+	default:
+		return false
+	}
+	return ctx.Err() == nil
+}
+
+// track 转发流式增量并统计 token 用量，流结束后归还 Key。
+// Key 已通过认证，流中途断开不计入该 Key 的失败。
+func (p *pooledProvider) track(ctx context.Context, lease *keypool.Lease, in <-chan domain.StreamDelta) <-chan domain.StreamDelta {
+	out := make(chan domain.StreamDelta, cap(in))
+	go func() {
+		defer close(out)
+		var usage domain.TokenUsage
+		defer func() {
+			lease.AddUsage(usage.PromptTokens, usage.CompletionTokens)
+			lease.Done(keypool.Success, 0, "")
+		}()
+		for delta := range in {
+			if delta.Usage != nil {
+				usage = *delta.Usage
+			}
+			select {
+			case out <- delta:
+			case <-ctx.Done():
+				// 消费方已离开，排空上游以便其 goroutine 退出
+				for range in {
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// keyOutcome 把上游错误映射为 Key 的结果：401 禁用；额度耗尽长时间冷却；
+// 429 按 Retry-After（未提供时为默认时长）冷却；其他错误与 Key 无关。
+func keyOutcome(err error) (keypool.Outcome, time.Duration) {
+	var upstream *providers.UpstreamError
+	if !errors.As(err, &upstream) {
+		return keypool.Failure, 0
+	}
+	switch {
+	case upstream.StatusCode == http.StatusUnauthorized:
+		return keypool.Unauthorized, 0
+	case upstream.StatusCode == http.StatusPaymentRequired ||
+		strings.Contains(strings.ToLower(upstream.Code+" "+upstream.Type), "quota"):
+		return keypool.RateLimited, max(upstream.RetryAfter, keypool.QuotaCooldown)
+	case upstream.StatusCode == http.StatusTooManyRequests:
+		return keypool.RateLimited, upstream.RetryAfter
+	}
+	return keypool.Failure, 0
+}
+
+// ProviderKeys 返回供应商 Key 池中各 Key 的状态与用量，供应商未配置 Key 池时返回 nil。
+func (g *gatewayService) ProviderKeys(name string) []keypool.Snapshot {
+	g.mu.RLock()
+	pool := g.keyPools[name]
+	g.mu.RUnlock()
+	if pool == nil {
+		return nil
+	}
+	return pool.Snapshots()
+}
+
+// EnableProviderKey 重新启用被自动禁用的 Key 并清除其冷却。
+func (g *gatewayService) EnableProviderKey(name, key string) error {
+	g.mu.RLock()
+	pool := g.keyPools[name]
+	g.mu.RUnlock()
+	if pool == nil || !pool.Enable(key) {
+		return errs.New(errs.CodeNotFound, fmt.Sprintf("api key %q not found on provider %s", key, name))
+	}
+	return nil
+}
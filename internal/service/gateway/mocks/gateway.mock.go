@@ -6,6 +6,8 @@ package gatewaymocks
 
 import (
 	domain "ai-gateway/internal/domain"
+	circuitbreaker "ai-gateway/internal/pkg/circuitbreaker"
+	keypool "ai-gateway/internal/pkg/keypool"
 	providers "ai-gateway/internal/providers"
 	context "context"
 	reflect "reflect"
@@ -52,11 +54,11 @@ func (mr *MockGatewayServiceMockRecorder) Chat(ctx, req interface{}) *gomock.Cal
 }
 
 // ChatStream mocks base method.
-func (m *MockGatewayService) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, string, error) {
+func (m *MockGatewayService) ChatStream(ctx context.Context, req *domain.ChatRequest) (<-chan domain.StreamDelta, domain.ProviderRef, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ChatStream", ctx, req)
 	ret0, _ := ret[0].(<-chan domain.StreamDelta)
-	ret1, _ := ret[1].(string)
+	ret1, _ := ret[1].(domain.ProviderRef)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
@@ -67,6 +69,48 @@ func (mr *MockGatewayServiceMockRecorder) ChatStream(ctx, req interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatStream", reflect.TypeOf((*MockGatewayService)(nil).ChatStream), ctx, req)
 }
 
+// CircuitBreakers mocks base method.
+func (m *MockGatewayService) CircuitBreakers() []circuitbreaker.Snapshot {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CircuitBreakers")
+	ret0, _ := ret[0].([]circuitbreaker.Snapshot)
+	return ret0
+}
+
+// CircuitBreakers indicates an expected call of CircuitBreakers.
+func (mr *MockGatewayServiceMockRecorder) CircuitBreakers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CircuitBreakers", reflect.TypeOf((*MockGatewayService)(nil).CircuitBreakers))
+}
+
+// EnableProviderKey mocks base method.
+func (m *MockGatewayService) EnableProviderKey(name, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableProviderKey", name, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableProviderKey indicates an expected call of EnableProviderKey.
+func (mr *MockGatewayServiceMockRecorder) EnableProviderKey(name, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableProviderKey", reflect.TypeOf((*MockGatewayService)(nil).EnableProviderKey), name, key)
+}
+
+// ExplainRoute mocks base method.
+func (m *MockGatewayService) ExplainRoute(model string) *domain.RouteExplanation {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExplainRoute", model)
+	ret0, _ := ret[0].(*domain.RouteExplanation)
+	return ret0
+}
+
+// ExplainRoute indicates an expected call of ExplainRoute.
+func (mr *MockGatewayServiceMockRecorder) ExplainRoute(model interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExplainRoute", reflect.TypeOf((*MockGatewayService)(nil).ExplainRoute), model)
+}
+
 // GetProvider mocks base method.
 func (m *MockGatewayService) GetProvider(model string) (providers.Provider, string, error) {
 	m.ctrl.T.Helper()
@@ -98,6 +142,20 @@ func (mr *MockGatewayServiceMockRecorder) ListModels(ctx interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModels", reflect.TypeOf((*MockGatewayService)(nil).ListModels), ctx)
 }
 
+// ProviderKeys mocks base method.
+func (m *MockGatewayService) ProviderKeys(name string) []keypool.Snapshot {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProviderKeys", name)
+	ret0, _ := ret[0].([]keypool.Snapshot)
+	return ret0
+}
+
+// ProviderKeys indicates an expected call of ProviderKeys.
+func (mr *MockGatewayServiceMockRecorder) ProviderKeys(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProviderKeys", reflect.TypeOf((*MockGatewayService)(nil).ProviderKeys), name)
+}
+
 // Reload mocks base method.
 func (m *MockGatewayService) Reload(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -111,3 +169,18 @@ func (mr *MockGatewayServiceMockRecorder) Reload(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockGatewayService)(nil).Reload), ctx)
 }
+
+// RouteModels mocks base method.
+func (m *MockGatewayService) RouteModels(req *domain.ChatRequest) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RouteModels", req)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RouteModels indicates an expected call of RouteModels.
+func (mr *MockGatewayServiceMockRecorder) RouteModels(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RouteModels", reflect.TypeOf((*MockGatewayService)(nil).RouteModels), req)
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/providers"
+)
+
+const (
+	// maxInflightShadows 同时进行的影子请求上限，超出时放弃本次采样，避免镜像流量占满网关资源。
+	maxInflightShadows = 32
+	// shadowTimeout 单次影子请求的最长耗时。
+	shadowTimeout = 2 * time.Minute
+	// shadowPrimaryWait 影子请求结束后等待主请求结果的最长时间，流式主请求可能远晚于影子请求结束。
+	shadowPrimaryWait = 10 * time.Minute
+)
+
+// shadowOutcome 主请求或影子请求一侧的结果。
+type shadowOutcome struct {
+	provider     string
+	model        string
+	latency      time.Duration
+	inputTokens  int
+	outputTokens int
+	content      string
+	err          string
+}
+
+// shadowCall 一次进行中的影子请求。影子请求独立于主请求执行，不经过重试、熔断器和负载均衡，
+// 结果不返回给客户端、不写入用量日志，因此不会向用户计费；主请求结束后两侧结果一并保存。
+type shadowCall struct {
+	primary chan shadowOutcome
+	once    sync.Once
+}
+
+// finish 交付主请求的结果。m 为 nil（本次请求未被采样）时什么也不做，重复调用只保留第一次的结果。
+func (m *shadowCall) finish(o shadowOutcome) {
+	if m == nil {
+		return
+	}
+	m.once.Do(func() { m.primary <- o })
+}
+
+// startShadow 按规则的采样百分比决定是否镜像本次请求，命中时立即异步发起影子请求。
+// req 必须是路由改写 Model 之前的客户端请求副本，对比记录按客户端请求的模型归类，
+// 影子规则未指定实际模型时也以该模型发往影子供应商。未命中采样、影子供应商未注册或并发已满时返回 nil。
+func (g *gatewayService) startShadow(req *domain.ChatRequest, sh *config.ShadowRoute) *shadowCall {
+	if sh == nil || g.shadowRepo == nil || rand.IntN(100) >= sh.Percent {
+		return nil
+	}
+	g.mu.RLock()
+	provider, ok := g.providers[sh.Provider]
+	g.mu.RUnlock()
+	if !ok {
+		g.logger.Debug("skipping shadow request for unknown provider", logger.String("provider", sh.Provider))
+		return nil
+	}
+	select {
+	case g.shadowSem <- struct{}{}:
+	default:
+		g.logger.Debug("too many in-flight shadow requests, skipping", logger.String("model", req.Model))
+		return nil
+	}
+
+	shadowReq := *req
+	shadowReq.Model = cmp.Or(sh.ActualModel, req.Model)
+	shadowReq.Stream = false // 影子请求总是非流式，按完整响应计时
+	m := &shadowCall{primary: make(chan shadowOutcome, 1)}
+	go g.runShadow(provider, req.Model, &shadowReq, m)
+	return m
+}
+
+// runShadow 执行影子请求，等待主请求结束后保存对比记录。
+func (g *gatewayService) runShadow(provider providers.Provider, model string, req *domain.ChatRequest, m *shadowCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	start := time.Now()
+	resp, err := provider.Chat(ctx, req)
+	cancel()
+	<-g.shadowSem
+
+	shadow := shadowOutcome{provider: provider.Name(), model: req.Model, latency: time.Since(start)}
+	if err != nil {
+		shadow.err = err.Error()
+	} else {
+		shadow.content = responseText(resp.Content)
+		if resp.Usage != nil {
+			shadow.inputTokens, shadow.outputTokens = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+		}
+	}
+
+	var primary shadowOutcome
+	select {
+	case primary = <-m.primary:
+	case <-time.After(shadowPrimaryWait):
+		primary.err = "primary request did not finish in time"
+	}
+
+	result := &domain.ShadowResult{
+		RequestID:           req.RequestID,
+		Model:               model,
+		PrimaryProvider:     primary.provider,
+		PrimaryModel:        primary.model,
+		PrimaryLatencyMs:    int(primary.latency.Milliseconds()),
+		PrimaryInputTokens:  primary.inputTokens,
+		PrimaryOutputTokens: primary.outputTokens,
+		PrimaryContent:      primary.content,
+		PrimaryError:        primary.err,
+		ShadowProvider:      shadow.provider,
+		ShadowModel:         shadow.model,
+		ShadowLatencyMs:     int(shadow.latency.Milliseconds()),
+		ShadowInputTokens:   shadow.inputTokens,
+		ShadowOutputTokens:  shadow.outputTokens,
+		ShadowContent:       shadow.content,
+		ShadowError:         shadow.err,
+	}
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := g.shadowRepo.Create(saveCtx, result); err != nil {
+		g.logger.Error("failed to save shadow result", logger.String("model", model), logger.Error(err))
+	}
+}
+
+// chatOutcome 将非流式主请求的结果转换为对比记录的一侧。
+func chatOutcome(resp *domain.ChatResponse, model string, latency time.Duration, err error) shadowOutcome {
+	o := shadowOutcome{model: model, latency: latency}
+	if err != nil {
+		o.err = err.Error()
+		return o
+	}
+	o.provider, o.content = resp.Provider, responseText(resp.Content)
+	if resp.Usage != nil {
+		o.inputTokens, o.outputTokens = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	}
+	return o
+}
+
+// responseText 拼接响应中的文本内容，用于对比主请求与影子请求的输出。
+func responseText(parts []domain.ContentPart) string {
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type == domain.ContentTypeText {
+			b.WriteString(p.Text)
+		}
+	}
+	return b.String()
+}
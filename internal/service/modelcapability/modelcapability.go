@@ -0,0 +1,80 @@
+package modelcapability
+
+import (
+	"context"
+	"strings"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/repository"
+)
+
+// Service 模型能力服务接口
+//
+//go:generate mockgen -source=./modelcapability.go -destination=./mocks/modelcapability.mock.go -package=modelcapabilitymocks Service
+type Service interface {
+	Create(ctx context.Context, c *domain.ModelCapability) error
+	Update(ctx context.Context, c *domain.ModelCapability) error
+	Delete(ctx context.Context, id int64) error
+	GetByID(ctx context.Context, id int64) (*domain.ModelCapability, error)
+	List(ctx context.Context) ([]domain.ModelCapability, error)
+}
+
+type service struct {
+	repo   repository.ModelCapabilityRepository
+	logger logger.Logger
+}
+
+func NewService(repo repository.ModelCapabilityRepository, l logger.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: l.With(logger.String("service", "modelcapability")),
+	}
+}
+
+func (s *service) Create(ctx context.Context, c *domain.ModelCapability) error {
+	if err := validate(c); err != nil {
+		return err
+	}
+	s.logger.Info("creating model capability", logger.String("pattern", c.ModelPattern), logger.String("provider", c.ProviderName))
+	return s.repo.Create(ctx, c)
+}
+
+func (s *service) Update(ctx context.Context, c *domain.ModelCapability) error {
+	if err := validate(c); err != nil {
+		return err
+	}
+	s.logger.Info("updating model capability", logger.Int64("id", c.ID))
+	return s.repo.Update(ctx, c)
+}
+
+func (s *service) Delete(ctx context.Context, id int64) error {
+	s.logger.Info("deleting model capability", logger.Int64("id", id))
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *service) GetByID(ctx context.Context, id int64) (*domain.ModelCapability, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *service) List(ctx context.Context) ([]domain.ModelCapability, error) {
+	return s.repo.List(ctx)
+}
+
+// validate 校验模型模式与容量，通配符只支持末尾的 *。长上下文模型不能匹配自身模式，避免改路由后仍落到同一条目。
+func validate(c *domain.ModelCapability) error {
+	if c.ModelPattern == "" {
+		return errs.New(errs.CodeInvalidParameter, "modelPattern is required")
+	}
+	if strings.Contains(strings.TrimSuffix(c.ModelPattern, "*"), "*") {
+		return errs.New(errs.CodeInvalidParameter, "modelPattern only supports a trailing *")
+	}
+	if c.ContextWindow < 0 || c.MaxOutputTokens < 0 {
+		return errs.New(errs.CodeInvalidParameter, "contextWindow and maxOutputTokens must not be negative")
+	}
+	if c.LongContextModel != "" && c.Match(c.LongContextModel) >= 0 {
+		return errs.New(errs.CodeInvalidParameter, "longContextModel must not match the entry's own modelPattern")
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ package modelratemocks
 
 import (
 	domain "ai-gateway/internal/domain"
+	money "ai-gateway/internal/pkg/money"
 	context "context"
 	reflect "reflect"
 
@@ -79,11 +80,11 @@ func (mr *MockServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
 }
 
 // GetRateForModel mocks base method.
-func (m *MockService) GetRateForModel(ctx context.Context, modelName string) (float64, float64, error) {
+func (m *MockService) GetRateForModel(ctx context.Context, modelName string) (money.Amount, money.Amount, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetRateForModel", ctx, modelName)
-	ret0, _ := ret[0].(float64)
-	ret1, _ := ret[1].(float64)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(money.Amount)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
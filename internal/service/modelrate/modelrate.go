@@ -2,9 +2,11 @@ package modelrate
 
 import (
 	"context"
+	"fmt"
 
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository"
 )
 
@@ -17,7 +19,7 @@ type Service interface {
 	Delete(ctx context.Context, id int64) error
 	GetByID(ctx context.Context, id int64) (*domain.ModelRate, error)
 	List(ctx context.Context) ([]domain.ModelRate, error)
-	GetRateForModel(ctx context.Context, modelName string) (promptPrice, completionPrice float64, err error)
+	GetRateForModel(ctx context.Context, modelName string) (promptPrice, completionPrice money.Amount, err error)
 }
 
 type service struct {
@@ -58,11 +60,11 @@ func (s *service) List(ctx context.Context) ([]domain.ModelRate, error) {
 // GetRateForModel 获取指定模型的费率
 // 匹配逻辑：完全匹配 > 前缀匹配（通配符） > 默认（0.0）
 // 目前简单实现：遍历所有启用的规则，找到最长匹配
-func (s *service) GetRateForModel(ctx context.Context, modelName string) (float64, float64, error) {
+// 只有没有配置匹配的费率时才返回 0；查询失败返回错误，由调用方决定是否降级，计费不能按 0 记账
+func (s *service) GetRateForModel(ctx context.Context, modelName string) (money.Amount, money.Amount, error) {
 	rates, err := s.repo.GetAllEnabled(ctx)
 	if err != nil {
-		s.logger.Error("failed to get enabled rates", logger.Error(err))
-		return 0, 0, nil // 出错降级为默认费率
+		return 0, 0, fmt.Errorf("get enabled model rates: %w", err)
 	}
 
 	var bestMatch *domain.ModelRate
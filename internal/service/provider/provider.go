@@ -3,8 +3,11 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/keypool"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/repository"
 )
@@ -57,15 +60,47 @@ func (s *service) GetByID(ctx context.Context, id int64) (*domain.Provider, erro
 // Create 创建 Provider。
 func (s *service) Create(ctx context.Context, provider *domain.Provider) error {
 	s.logger.Info("creating provider", logger.String("name", provider.Name))
+	if err := validate(provider); err != nil {
+		return err
+	}
 	return s.providerRepo.Create(ctx, provider)
 }
 
 // Update 更新 Provider。
 func (s *service) Update(ctx context.Context, provider *domain.Provider) error {
 	s.logger.Info("updating provider", logger.Int64("id", provider.ID))
+	if err := validate(provider); err != nil {
+		return err
+	}
 	return s.providerRepo.Update(ctx, provider)
 }
 
+// validate 校验供应商的上游 Key：至少配置一个 Key；Key 池中的每个 Key 必须有唯一的名称
+// （default 保留给 APIKey）且不能为空；Key 池选择策略必须受支持。
+func validate(p *domain.Provider) error {
+	if len(p.Keys()) == 0 {
+		return errs.New(errs.CodeInvalidParameter, "apiKey or apiKeys is required")
+	}
+	names := map[string]bool{domain.DefaultKeyName: true}
+	for i, k := range p.APIKeys {
+		switch {
+		case k.Name == "":
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("apiKeys[%d]: name is required", i))
+		case names[k.Name]:
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("apiKeys[%d]: duplicate or reserved name %q", i, k.Name))
+		case k.Key == "":
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("apiKeys[%d]: key is required", i))
+		}
+		names[k.Name] = true
+	}
+	switch p.KeyStrategy {
+	case "", keypool.StrategyRoundRobin, keypool.StrategyLeastUsed:
+	default:
+		return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("unsupported key strategy %q", p.KeyStrategy))
+	}
+	return nil
+}
+
 // Delete 删除 Provider。
 func (s *service) Delete(ctx context.Context, id int64) error {
 	s.logger.Info("deleting provider", logger.Int64("id", id))
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+)
+
+func TestValidate(t *testing.T) {
+	pool := []domain.ProviderKey{{Name: "org-b", Key: "sk-b"}, {Name: "org-c", Key: "sk-c"}}
+	tests := []struct {
+		name     string
+		provider domain.Provider
+		wantErr  bool
+	}{
+		{"SingleKey", domain.Provider{APIKey: "sk-a"}, false},
+		{"Pool", domain.Provider{APIKey: "sk-a", APIKeys: pool, KeyStrategy: "least_used"}, false},
+		{"PoolOnly", domain.Provider{APIKeys: pool}, false},
+		{"NoKey", domain.Provider{}, true},
+		{"DuplicateName", domain.Provider{APIKeys: []domain.ProviderKey{{Name: "a", Key: "sk-1"}, {Name: "a", Key: "sk-2"}}}, true},
+		{"ReservedName", domain.Provider{APIKey: "sk-a", APIKeys: []domain.ProviderKey{{Name: domain.DefaultKeyName, Key: "sk-b"}}}, true},
+		{"EmptyKey", domain.Provider{APIKeys: []domain.ProviderKey{{Name: "org-b"}}}, true},
+		{"UnknownStrategy", domain.Provider{APIKey: "sk-a", KeyStrategy: "random"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(&tt.provider)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *errs.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, errs.CodeInvalidParameter, appErr.Code)
+		})
+	}
+}
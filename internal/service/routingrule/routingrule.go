@@ -3,8 +3,10 @@ package routingrule
 
 import (
 	"context"
+	"fmt"
 
 	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/repository"
 )
@@ -49,15 +51,66 @@ func (s *service) List(ctx context.Context) ([]domain.RoutingRule, error) {
 // Create 创建路由规则。
 func (s *service) Create(ctx context.Context, rule *domain.RoutingRule) error {
 	s.logger.Info("creating routing rule", logger.String("pattern", rule.Pattern))
+	if err := validate(rule); err != nil {
+		return err
+	}
 	return s.routingRuleRepo.Create(ctx, rule)
 }
 
 // Update 更新路由规则。
 func (s *service) Update(ctx context.Context, rule *domain.RoutingRule) error {
 	s.logger.Info("updating routing rule", logger.Int64("id", rule.ID))
+	if err := validate(rule); err != nil {
+		return err
+	}
 	return s.routingRuleRepo.Update(ctx, rule)
 }
 
+// validate 校验路由规则：规则类型必须受支持，wildcard / regex 模式必须能编译，
+// 回退链的每一跳都必须指定供应商；A/B 实验组必须有唯一的名称和供应商，百分比之和不超过 100；
+// 影子流量必须指定供应商，采样百分比在 1-100 之间。
+func validate(rule *domain.RoutingRule) error {
+	switch rule.RuleType {
+	case domain.RuleTypeExact, domain.RuleTypePrefix, domain.RuleTypeWildcard, domain.RuleTypeRegex:
+	default:
+		return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("unsupported rule type %q", rule.RuleType))
+	}
+	if _, err := rule.CompilePattern(); err != nil {
+		return errs.New(errs.CodeInvalidParameter, err.Error())
+	}
+	for i, hop := range rule.Fallbacks {
+		if hop.Provider == "" {
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("fallbacks[%d]: provider is required", i))
+		}
+	}
+	names := map[string]bool{domain.VariantControl: true}
+	for i, v := range rule.Variants {
+		switch {
+		case v.Name == "":
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("variants[%d]: name is required", i))
+		case names[v.Name]:
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("variants[%d]: duplicate or reserved name %q", i, v.Name))
+		case v.Provider == "":
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("variants[%d]: provider is required", i))
+		case v.Percent <= 0 || v.Percent > 100:
+			return errs.New(errs.CodeInvalidParameter, fmt.Sprintf("variants[%d]: percent must be between 1 and 100", i))
+		}
+		names[v.Name] = true
+	}
+	if rule.ControlPercent() < 0 {
+		return errs.New(errs.CodeInvalidParameter, "variant percentages must not exceed 100 in total")
+	}
+	if sh := rule.Shadow; sh != nil {
+		if sh.Provider == "" {
+			return errs.New(errs.CodeInvalidParameter, "shadow: provider is required")
+		}
+		if sh.Percent <= 0 || sh.Percent > 100 {
+			return errs.New(errs.CodeInvalidParameter, "shadow: percent must be between 1 and 100")
+		}
+	}
+	return nil
+}
+
 // Delete 删除路由规则。
 func (s *service) Delete(ctx context.Context, id int64) error {
 	s.logger.Info("deleting routing rule", logger.Int64("id", id))
@@ -0,0 +1,50 @@
+package routingrule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    domain.RoutingRule
+		wantErr bool
+	}{
+		{"Exact", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "gpt-4o"}, false},
+		{"Wildcard", domain.RoutingRule{RuleType: domain.RuleTypeWildcard, Pattern: "claude-*-sonnet-*"}, false},
+		{"Regex", domain.RoutingRule{RuleType: domain.RuleTypeRegex, Pattern: "my-(.*)", ActualModel: "gpt-$1"}, false},
+		{"InvalidRegex", domain.RoutingRule{RuleType: domain.RuleTypeRegex, Pattern: "my-(.*"}, true},
+		{"UnknownType", domain.RoutingRule{RuleType: "glob", Pattern: "gpt-*"}, true},
+		{"FallbackWithoutProvider", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "gpt-4o",
+			Fallbacks: []domain.RouteTarget{{ActualModel: "gpt-4o"}}}, true},
+		{"Variants", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "claude-sonnet-4",
+			Variants: []domain.RouteVariant{{Name: "sonnet-4.5", Provider: "anthropic", ActualModel: "claude-sonnet-4-5", Percent: 10}}}, false},
+		{"VariantsOver100", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "claude-sonnet-4",
+			Variants: []domain.RouteVariant{{Name: "a", Provider: "anthropic", Percent: 60}, {Name: "b", Provider: "bedrock", Percent: 50}}}, true},
+		{"VariantReservedName", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "claude-sonnet-4",
+			Variants: []domain.RouteVariant{{Name: domain.VariantControl, Provider: "anthropic", Percent: 10}}}, true},
+		{"VariantWithoutPercent", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "claude-sonnet-4",
+			Variants: []domain.RouteVariant{{Name: "a", Provider: "anthropic"}}}, true},
+		{"Shadow", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "gpt-4o",
+			Shadow: &domain.ShadowTarget{Provider: "self-hosted", ActualModel: "qwen3-32b", Percent: 5}}, false},
+		{"ShadowWithoutPercent", domain.RoutingRule{RuleType: domain.RuleTypeExact, Pattern: "gpt-4o",
+			Shadow: &domain.ShadowTarget{Provider: "self-hosted"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(&tt.rule)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *errs.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, errs.CodeInvalidParameter, appErr.Code)
+		})
+	}
+}
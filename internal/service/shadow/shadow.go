@@ -0,0 +1,41 @@
+// Package shadow 提供影子流量对比记录的查询服务。
+package shadow
+
+import (
+	"context"
+
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/repository"
+)
+
+// Service 影子流量对比服务接口。影子请求由网关在路由时发起并保存，这里只负责查询。
+//
+//go:generate mockgen -source=./shadow.go -destination=./mocks/shadow.mock.go -package=shadowmocks Service
+type Service interface {
+	// List 分页获取对比记录，model 非空时只返回该请求模型的记录
+	List(ctx context.Context, page, pageSize int, model string) ([]domain.ShadowResult, int64, error)
+	// Stats 按请求模型与影子目标汇总最近 days 天的延迟、输出 token 与错误数
+	Stats(ctx context.Context, days int) ([]domain.ShadowStats, error)
+}
+
+type service struct {
+	repo   repository.ShadowResultRepository
+	logger logger.Logger
+}
+
+// NewService 创建影子流量对比服务实例。
+func NewService(repo repository.ShadowResultRepository, l logger.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: l.With(logger.String("service", "shadow")),
+	}
+}
+
+func (s *service) List(ctx context.Context, page, pageSize int, model string) ([]domain.ShadowResult, int64, error) {
+	return s.repo.List(ctx, page, pageSize, model)
+}
+
+func (s *service) Stats(ctx context.Context, days int) ([]domain.ShadowStats, error) {
+	return s.repo.Stats(ctx, days)
+}
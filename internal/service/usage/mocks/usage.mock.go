@@ -95,17 +95,3 @@ func (mr *MockServiceMockRecorder) ListLogs(ctx, page, pageSize, filters interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLogs", reflect.TypeOf((*MockService)(nil).ListLogs), ctx, page, pageSize, filters)
 }
-
-// LogRequest mocks base method.
-func (m *MockService) LogRequest(ctx context.Context, log *domain.UsageLog) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LogRequest", ctx, log)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// LogRequest indicates an expected call of LogRequest.
-func (mr *MockServiceMockRecorder) LogRequest(ctx, log interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogRequest", reflect.TypeOf((*MockService)(nil).LogRequest), ctx, log)
-}
@@ -8,7 +8,6 @@ import (
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
 	"ai-gateway/internal/repository"
-	"ai-gateway/internal/service/wallet"
 )
 
 // Service 使用统计服务接口。
@@ -19,8 +18,6 @@ type Service interface {
 	GetGlobalStats(ctx context.Context) (*domain.UsageStats, error)
 	// GetDailyUsage 获取全局每日使用统计（管理员）
 	GetGlobalDailyUsage(ctx context.Context, days int) ([]domain.DailyUsage, error)
-	// LogRequest 记录请求使用情况
-	LogRequest(ctx context.Context, log *domain.UsageLog) error
 	// ListLogs 获取日志列表
 	ListLogs(ctx context.Context, page, pageSize int, filters map[string]interface{}) ([]*domain.UsageLog, int64, error)
 	// GetLeaderboard 获取使用量排行榜
@@ -30,19 +27,16 @@ type Service interface {
 // service 使用统计服务实现。
 type service struct {
 	usageLogRepo repository.UsageLogRepository
-	walletSvc    wallet.Service
 	logger       logger.Logger
 }
 
 // NewService 创建使用统计服务实例。
 func NewService(
 	usageLogRepo repository.UsageLogRepository,
-	walletSvc wallet.Service,
 	l logger.Logger,
 ) Service {
 	return &service{
 		usageLogRepo: usageLogRepo,
-		walletSvc:    walletSvc,
 		logger:       l.With(logger.String("service", "usage")),
 	}
 }
@@ -69,42 +63,6 @@ func (s *service) GetGlobalDailyUsage(ctx context.Context, days int) ([]domain.D
 	return []domain.DailyUsage{}, nil
 }
 
-// LogRequest 记录请求使用情况。
-func (s *service) LogRequest(ctx context.Context, log *domain.UsageLog) error {
-	// 异步记录，避免阻塞
-	// 注意：这里我们使用传入的 ctx，但在调用方应该传入一个独立的 context 或者
-	// 我们在这里创建一个新的 context，但这取决于调用方是否已经处理了。
-	// 简单起见，我们直接调用 repo，假设调用方已经处理了异步逻辑或者就在同步链路中记录
-	// 为了不影响主请求延迟，建议调用方 go func() 调用，或者最好在这里 go func
-	// 但在这里 go func 需要 context 不被 cancel。
-
-	// 1. 尝试扣费 (同步执行，确保有余额)
-	// 注意：如果 LogRequest 是异步调用的，这里也会异步执行。
-	// 1. 尝试扣费 (同步执行，确保有余额)
-	// 注意：如果 LogRequest 是异步调用的，这里也会异步执行。
-
-	totalTokens := log.InputTokens + log.OutputTokens
-	if log.UserID > 0 && totalTokens > 0 {
-		if err := s.walletSvc.Deduct(ctx, log.UserID, log.InputTokens, log.OutputTokens, log.Model); err != nil {
-			// 扣费失败仅记录日志，暂不阻断（取决于策略，如果是预付费严格校验，应该在网关入口检查余额）
-			// 但这里是 LogRequest，请求已经完成了。
-			s.logger.Error("failed to deduct wallet balance",
-				logger.Int64("userID", log.UserID),
-				logger.String("model", log.Model),
-				logger.Int("tokens", totalTokens),
-				logger.Error(err),
-			)
-		}
-	}
-
-	// 暂时同步写入，在这个阶段确保数据一致性优先
-	if err := s.usageLogRepo.Create(ctx, log); err != nil {
-		s.logger.Error("failed to log request usage", logger.Error(err))
-		return err
-	}
-	return nil
-}
-
 // ListLogs 获取日志列表。
 func (s *service) ListLogs(ctx context.Context, page, pageSize int, filters map[string]interface{}) ([]*domain.UsageLog, int64, error) {
 	return s.usageLogRepo.List(ctx, page, pageSize, filters)
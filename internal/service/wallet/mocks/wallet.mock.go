@@ -6,6 +6,7 @@ package walletmocks
 
 import (
 	domain "ai-gateway/internal/domain"
+	money "ai-gateway/internal/pkg/money"
 	context "context"
 	reflect "reflect"
 
@@ -35,18 +36,18 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
-// Deduct mocks base method.
-func (m *MockService) Deduct(ctx context.Context, userID int64, inputTokens, outputTokens int, modelName string) error {
+// Extend mocks base method.
+func (m *MockService) Extend(ctx context.Context, requestID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deduct", ctx, userID, inputTokens, outputTokens, modelName)
+	ret := m.ctrl.Call(m, "Extend", ctx, requestID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Deduct indicates an expected call of Deduct.
-func (mr *MockServiceMockRecorder) Deduct(ctx, userID, inputTokens, outputTokens, modelName interface{}) *gomock.Call {
+// Extend indicates an expected call of Extend.
+func (mr *MockServiceMockRecorder) Extend(ctx, requestID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deduct", reflect.TypeOf((*MockService)(nil).Deduct), ctx, userID, inputTokens, outputTokens, modelName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Extend", reflect.TypeOf((*MockService)(nil).Extend), ctx, requestID)
 }
 
 // GetBalance mocks base method.
@@ -95,8 +96,36 @@ func (mr *MockServiceMockRecorder) HasBalance(ctx, userID interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasBalance", reflect.TypeOf((*MockService)(nil).HasBalance), ctx, userID)
 }
 
+// Release mocks base method.
+func (m *MockService) Release(ctx context.Context, requestID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, requestID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockServiceMockRecorder) Release(ctx, requestID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockService)(nil).Release), ctx, requestID)
+}
+
+// Reserve mocks base method.
+func (m *MockService) Reserve(ctx context.Context, hold *domain.WalletHold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockServiceMockRecorder) Reserve(ctx, hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockService)(nil).Reserve), ctx, hold)
+}
+
 // TopUp mocks base method.
-func (m *MockService) TopUp(ctx context.Context, userID int64, amount float64, referenceID string) error {
+func (m *MockService) TopUp(ctx context.Context, userID int64, amount money.Amount, referenceID string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "TopUp", ctx, userID, amount, referenceID)
 	ret0, _ := ret[0].(error)
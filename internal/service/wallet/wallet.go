@@ -3,15 +3,16 @@ package wallet
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
+	"ai-gateway/config"
 	"ai-gateway/internal/domain"
 	"ai-gateway/internal/errs"
 	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
 	"ai-gateway/internal/repository"
-	"ai-gateway/internal/service/modelrate"
 )
 
 // Service 钱包服务接口
@@ -22,30 +23,44 @@ type Service interface {
 	GetTransactions(ctx context.Context, userID int64, page, size int) ([]domain.WalletTransaction, int64, error)
 
 	// TopUp 充值
-	TopUp(ctx context.Context, userID int64, amount float64, referenceID string) error
-
-	// Deduct 扣费
-	Deduct(ctx context.Context, userID int64, inputTokens, outputTokens int, modelName string) error
+	TopUp(ctx context.Context, userID int64, amount money.Amount, referenceID string) error
 
 	// HasBalance 检查用户是否有充足余额
 	HasBalance(ctx context.Context, userID int64) (bool, error)
+
+	// Reserve 在请求发出前冻结预估的最大费用，同时占用钱包可用余额与 API Key 剩余额度。
+	// 可用余额不足返回 ErrInsufficientBalance，Key 额度不足返回 ErrAPIKeyQuotaExceeded。
+	Reserve(ctx context.Context, hold *domain.WalletHold) error
+
+	// Release 释放未产生费用的请求的预授权
+	Release(ctx context.Context, requestID string) error
+
+	// Extend 将进行中请求的预授权有效期从现在起顺延一个 HoldTTL，
+	// 耗时超过有效期的请求（如长时间的流式输出）据此在结算前一直占用余额
+	Extend(ctx context.Context, requestID string) error
 }
 
+// defaultHoldTTL 未配置 billing.holdTTL 时预授权的有效期
+const defaultHoldTTL = 10 * time.Minute
+
 type service struct {
-	walletRepo   repository.WalletRepository
-	modelRateSvc modelrate.Service
-	logger       logger.Logger
+	walletRepo repository.WalletRepository
+	apiKeyRepo repository.APIKeyRepository
+	cfg        config.BillingConfig
+	logger     logger.Logger
 }
 
 func NewService(
 	walletRepo repository.WalletRepository,
-	modelRateSvc modelrate.Service,
+	apiKeyRepo repository.APIKeyRepository,
+	cfg config.BillingConfig,
 	l logger.Logger,
 ) Service {
 	return &service{
-		walletRepo:   walletRepo,
-		modelRateSvc: modelRateSvc,
-		logger:       l.With(logger.String("service", "wallet")),
+		walletRepo: walletRepo,
+		apiKeyRepo: apiKeyRepo,
+		cfg:        cfg,
+		logger:     l.With(logger.String("service", "wallet")),
 	}
 }
 
@@ -76,119 +91,145 @@ func (s *service) GetTransactions(ctx context.Context, userID int64, page, size
 	return s.walletRepo.GetTransactions(ctx, wallet.ID, size, offset)
 }
 
-func (s *service) TopUp(ctx context.Context, userID int64, amount float64, referenceID string) error {
+func (s *service) TopUp(ctx context.Context, userID int64, amount money.Amount, referenceID string) error {
 	// 查找或创建钱包
-	wallet, err := s.walletRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		// 这里简化处理：通常 GORM 找不到记录会返回 error。
-		// 如果是 Not Found，我们创建钱包。需要具体 DAO 实现确认 error 类型。
-		// 简单起见，假设 GetByUserID 返回 nil, nil 表示没找到 (取决于 repository 实现)
-		// 如果 repository 遵循 gorm 习惯，可能是 record not found error.
-		// 这里暂且不做过细的 error type 断言，直接假设如果报错且不是 RecordNotFound 则返回。
-		s.logger.Warn("failed to get wallet, attempting create if not exists", logger.Error(err))
-	}
-
-	if wallet == nil {
-		s.logger.Info("creating wallet for user", logger.Int64("userID", userID))
-		wallet = &domain.Wallet{
-			UserID:  userID,
-			Balance: 0,
+	if _, err := s.walletRepo.GetByUserID(ctx, userID); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
 		}
-		if err := s.walletRepo.Create(ctx, wallet); err != nil {
+		s.logger.Info("creating wallet for user", logger.Int64("userID", userID))
+		if err := s.walletRepo.Create(ctx, &domain.Wallet{UserID: userID}); err != nil {
 			return err
 		}
 	}
 
-	s.logger.Info("top up wallet", logger.Int64("userID", userID), logger.Float64("amount", amount))
+	s.logger.Info("top up wallet", logger.Int64("userID", userID), logger.String("amount", amount.String()))
+
+	return s.apply(ctx, userID, amount, &domain.WalletTransaction{
+		Type:        domain.TransactionTypeTopUp,
+		ReferenceID: referenceID,
+		Description: "System Top Up",
+	})
+}
+
+// apply 在一个事务中锁定钱包行、变更余额并写入流水，流水的变更前后余额取自加锁后读到的余额，
+// 与 billing 的扣费按加锁顺序串行执行，流水金额之和始终等于余额。
+func (s *service) apply(ctx context.Context, userID int64, amount money.Amount, tx *domain.WalletTransaction) error {
+	return s.walletRepo.Transaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errs.ErrWalletNotFound
+			}
+			return err
+		}
+		return s.post(ctx, wallet, amount, tx)
+	})
+}
 
-	// 记录交易
-	balanceBefore := wallet.Balance
+// post 变更已加锁钱包的余额并写入流水，须在 Transaction 内调用
+func (s *service) post(ctx context.Context, wallet *domain.Wallet, amount money.Amount, tx *domain.WalletTransaction) error {
 	if err := s.walletRepo.UpdateBalance(ctx, wallet.ID, amount); err != nil {
 		return err
 	}
 
-	tx := &domain.WalletTransaction{
-		WalletID:      wallet.ID,
-		Type:          domain.TransactionTypeTopUp,
-		Amount:        amount,
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceBefore + amount,
-		ReferenceID:   referenceID,
-		Description:   "System Top Up",
-	}
-
+	tx.WalletID = wallet.ID
+	tx.Amount = amount
+	tx.BalanceBefore = wallet.Balance
+	tx.BalanceAfter = wallet.Balance + amount
 	return s.walletRepo.CreateTransaction(ctx, tx)
 }
 
-func (s *service) Deduct(ctx context.Context, userID int64, inputTokens, outputTokens int, modelName string) error {
-	// 1. 获取费率 (Price Per 1M tokens)
-	promptPrice, completionPrice, err := s.modelRateSvc.GetRateForModel(ctx, modelName)
-	if err != nil {
-		s.logger.Error("failed to get model rate", logger.Error(err))
-		// Fail open or closed? Here we fail open with 0 cost if error, but typically we want to charge.
-		// Given defaults are handled in service, err here is db error.
-		promptPrice = 0
-		completionPrice = 0
-	}
-
-	// Cost formula: (input / 1M * promptPrice) + (output / 1M * completionPrice)
-	inputCost := (float64(inputTokens) / 1_000_000.0) * promptPrice
-	outputCost := (float64(outputTokens) / 1_000_000.0) * completionPrice
-	totalCost := inputCost + outputCost
-
-	if totalCost <= 0 {
-		return nil
-	}
-
-	// 2. 获取钱包
+func (s *service) HasBalance(ctx context.Context, userID int64) (bool, error) {
 	wallet, err := s.walletRepo.GetByUserID(ctx, userID)
 	if err != nil {
-		return err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
 	}
 	if wallet == nil {
-		return errs.ErrWalletNotFound
+		return false, nil // Default to false if no wallet
 	}
+	return wallet.Balance > 0, nil
+}
 
-	// 3. 扣费
-	// 3. 扣费
-	s.logger.Info("deducting wallet",
-		logger.Int64("userID", userID),
-		logger.Float64("cost", totalCost), // Any? Go logger.Float64 doesn't exist yet? No, fields.go usually has Int64. Let's check if Float64 exists. User didn't ask to add Float64. I should use Any or add Float64. Let's use Any for now or add Float64. I'll use Any for safety as I didn't verify Float64 exists in fields.go previous read. Wait, fields.go read showed Int64 but not Float64. I added Int, Duration, Time. So Float64 is missing. I will use Any.
-		logger.String("model", modelName),
-		logger.Int("inputTokens", inputTokens),
-		logger.Int("outputTokens", outputTokens),
-		logger.Any("promptPrice", promptPrice),
-		logger.Any("completionPrice", completionPrice),
-	)
-
-	balanceBefore := wallet.Balance
-	if err := s.walletRepo.UpdateBalance(ctx, wallet.ID, -totalCost); err != nil {
-		return err
-	}
+// Reserve 锁定钱包行后检查可用余额（余额减去有效预授权）与 Key 剩余额度（额度减去已用与有效预授权），
+// 足够时写入预授权。Key 属于钱包所有者，钱包行锁同样串行化了同一 Key 的预授权。
+// 预授权超过有效期后不再计入占用，进程崩溃遗留的预授权会自动失效，并在下次预授权时标记为过期。
+func (s *service) Reserve(ctx context.Context, hold *domain.WalletHold) error {
+	now := time.Now()
+	hold.Status = domain.HoldStatusActive
+	hold.ExpiresAt = now.Add(s.holdTTL())
+
+	return s.walletRepo.Transaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, hold.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errs.ErrInsufficientBalance
+			}
+			return err
+		}
+		if _, err := s.walletRepo.ExpireHolds(ctx, wallet.ID, now); err != nil {
+			return err
+		}
 
-	tx := &domain.WalletTransaction{
-		WalletID:      wallet.ID,
-		Type:          domain.TransactionTypeDeduct,
-		Amount:        -totalCost,
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceBefore - totalCost,
-		ReferenceID:   "", // Could pass RequestID
-		Description:   fmt.Sprintf("Usage: %s (In:%d, Out:%d)", modelName, inputTokens, outputTokens),
-	}
+		held, err := s.walletRepo.SumActiveHolds(ctx, wallet.ID, now)
+		if err != nil {
+			return err
+		}
+		if !covers(wallet.Balance-held, hold.Amount) {
+			return errs.ErrInsufficientBalance
+		}
 
-	return s.walletRepo.CreateTransaction(ctx, tx)
+		if hold.APIKeyID != nil {
+			key, err := s.apiKeyRepo.GetByID(ctx, *hold.APIKeyID)
+			if err != nil {
+				return err
+			}
+			if key != nil && key.HasQuota() {
+				keyHeld, err := s.walletRepo.SumActiveHoldsByAPIKey(ctx, key.ID, now)
+				if err != nil {
+					return err
+				}
+				if !covers(*key.Quota-key.UsedAmount-keyHeld, hold.Amount) {
+					return errs.ErrAPIKeyQuotaExceeded
+				}
+			}
+		}
+
+		hold.WalletID = wallet.ID
+		return s.walletRepo.CreateHold(ctx, hold)
+	})
 }
 
-func (s *service) HasBalance(ctx context.Context, userID int64) (bool, error) {
-	wallet, err := s.walletRepo.GetByUserID(ctx, userID)
+// covers 可用金额须为正且不少于预授权金额；预估费用为 0（如未配置费率）时仍要求可用金额为正
+func covers(available, amount money.Amount) bool {
+	return available > 0 && available >= amount
+}
+
+func (s *service) Release(ctx context.Context, requestID string) error {
+	hold, err := s.walletRepo.GetHoldByRequestID(ctx, requestID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
+			return nil
 		}
-		return false, err
+		return err
 	}
-	if wallet == nil {
-		return false, nil // Default to false if no wallet
+	_, err = s.walletRepo.UpdateHoldStatus(ctx, hold.ID, []domain.HoldStatus{domain.HoldStatusActive}, domain.HoldStatusReleased)
+	return err
+}
+
+// Extend 只顺延仍处于有效状态的预授权，已结算、释放或标记为过期的预授权保持不变
+func (s *service) Extend(ctx context.Context, requestID string) error {
+	_, err := s.walletRepo.ExtendHold(ctx, requestID, time.Now().Add(s.holdTTL()))
+	return err
+}
+
+// holdTTL 返回预授权有效期，未配置时使用 defaultHoldTTL
+func (s *service) holdTTL() time.Duration {
+	if s.cfg.HoldTTL > 0 {
+		return s.cfg.HoldTTL
 	}
-	return wallet.Balance > 0, nil
+	return defaultHoldTTL
 }
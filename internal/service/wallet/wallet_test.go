@@ -0,0 +1,453 @@
+package wallet
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"ai-gateway/config"
+	"ai-gateway/internal/domain"
+	"ai-gateway/internal/errs"
+	"ai-gateway/internal/pkg/logger"
+	"ai-gateway/internal/pkg/money"
+	"ai-gateway/internal/repository"
+	"ai-gateway/internal/repository/dao"
+	repomocks "ai-gateway/internal/repository/mocks"
+	"ai-gateway/internal/service/billing"
+	modelratemocks "ai-gateway/internal/service/modelrate/mocks"
+)
+
+// memWalletRepo 是内存中的单钱包仓储：Transaction 模拟行锁（同一时间只有一个事务持有钱包），
+// fn 返回错误时回滚余额、流水与预授权。事务外的读写不加行锁，与数据库行为一致。
+type memWalletRepo struct {
+	rowLock sync.Mutex
+
+	mu     sync.Mutex
+	wallet *domain.Wallet
+	ledger []domain.WalletTransaction
+	holds  []domain.WalletHold
+}
+
+func (r *memWalletRepo) GetByUserID(ctx context.Context, userID int64) (*domain.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.wallet == nil || r.wallet.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	w := *r.wallet
+	return &w, nil
+}
+
+func (r *memWalletRepo) GetByUserIDForUpdate(ctx context.Context, userID int64) (*domain.Wallet, error) {
+	return r.GetByUserID(ctx, userID)
+}
+
+func (r *memWalletRepo) Create(ctx context.Context, wallet *domain.Wallet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.wallet == nil {
+		w := *wallet
+		w.ID = 1
+		r.wallet = &w
+	}
+	return nil
+}
+
+func (r *memWalletRepo) UpdateBalance(ctx context.Context, walletID int64, amount money.Amount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wallet.Balance += amount
+	return nil
+}
+
+func (r *memWalletRepo) CreateTransaction(ctx context.Context, tx *domain.WalletTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tx.ID = int64(len(r.ledger) + 1)
+	r.ledger = append(r.ledger, *tx)
+	return nil
+}
+
+func (r *memWalletRepo) GetTransactions(ctx context.Context, walletID int64, limit, offset int) ([]domain.WalletTransaction, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.WalletTransaction(nil), r.ledger...), int64(len(r.ledger)), nil
+}
+
+func (r *memWalletRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.rowLock.Lock()
+	defer r.rowLock.Unlock()
+
+	r.mu.Lock()
+	var balance money.Amount
+	if r.wallet != nil {
+		balance = r.wallet.Balance
+	}
+	n := len(r.ledger)
+	holds := append([]domain.WalletHold(nil), r.holds...)
+	r.mu.Unlock()
+
+	err := fn(ctx)
+	if err != nil {
+		r.mu.Lock()
+		if r.wallet != nil {
+			r.wallet.Balance = balance
+		}
+		r.ledger = r.ledger[:n]
+		r.holds = holds
+		r.mu.Unlock()
+	}
+	return err
+}
+
+func (r *memWalletRepo) CreateHold(ctx context.Context, hold *domain.WalletHold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hold.ID = int64(len(r.holds) + 1)
+	r.holds = append(r.holds, *hold)
+	return nil
+}
+
+func (r *memWalletRepo) GetHoldByRequestID(ctx context.Context, requestID string) (*domain.WalletHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range r.holds {
+		if h.RequestID == requestID {
+			return &h, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memWalletRepo) UpdateHoldStatus(ctx context.Context, id int64, from []domain.HoldStatus, to domain.HoldStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := &r.holds[id-1]
+	for _, st := range from {
+		if h.Status == st {
+			h.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memWalletRepo) ExtendHold(ctx context.Context, requestID string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.holds {
+		if h := &r.holds[i]; h.RequestID == requestID && h.Status == domain.HoldStatusActive {
+			h.ExpiresAt = expiresAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memWalletRepo) SumActiveHolds(ctx context.Context, walletID int64, now time.Time) (money.Amount, error) {
+	return r.sumActiveHolds(func(h domain.WalletHold) bool { return h.WalletID == walletID }, now), nil
+}
+
+func (r *memWalletRepo) SumActiveHoldsByAPIKey(ctx context.Context, apiKeyID int64, now time.Time) (money.Amount, error) {
+	return r.sumActiveHolds(func(h domain.WalletHold) bool { return h.APIKeyID != nil && *h.APIKeyID == apiKeyID }, now), nil
+}
+
+func (r *memWalletRepo) sumActiveHolds(match func(domain.WalletHold) bool, now time.Time) money.Amount {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sum money.Amount
+	for _, h := range r.holds {
+		if match(h) && h.Status == domain.HoldStatusActive && h.ExpiresAt.After(now) {
+			sum += h.Amount
+		}
+	}
+	return sum
+}
+
+func (r *memWalletRepo) ExpireHolds(ctx context.Context, walletID int64, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for i := range r.holds {
+		if h := &r.holds[i]; h.WalletID == walletID && h.Status == domain.HoldStatusActive && !h.ExpiresAt.After(now) {
+			h.Status = domain.HoldStatusExpired
+			n++
+		}
+	}
+	return n, nil
+}
+
+// assertLedgerConsistent 校验流水金额之和等于余额，且每条流水的变更前余额等于上一条的变更后余额。
+func assertLedgerConsistent(t *testing.T, repo *memWalletRepo) {
+	t.Helper()
+	var sum, prev money.Amount
+	for i, tx := range repo.ledger {
+		assert.Equal(t, prev, tx.BalanceBefore, "ledger #%d", i)
+		assert.Equal(t, tx.BalanceBefore+tx.Amount, tx.BalanceAfter, "ledger #%d", i)
+		sum += tx.Amount
+		prev = tx.BalanceAfter
+	}
+	assert.Equal(t, repo.wallet.Balance, sum)
+	assert.Equal(t, repo.wallet.Balance, prev)
+}
+
+func newTestService(t *testing.T, cfg config.BillingConfig) (Service, *memWalletRepo) {
+	ctrl := gomock.NewController(t)
+	repo := &memWalletRepo{}
+	return NewService(repo, repomocks.NewMockAPIKeyRepository(ctrl), cfg, logger.NewNopLogger()), repo
+}
+
+// newBillingService 创建与钱包服务共用 walletRepo 的计费服务，生产环境的扣费都经由 billing.Charge
+func newBillingService(t *testing.T, walletRepo repository.WalletRepository, usageLogRepo repository.UsageLogRepository, cfg config.BillingConfig) billing.Service {
+	ctrl := gomock.NewController(t)
+	rates := modelratemocks.NewMockService(ctrl)
+	// 1000 input tokens * $10 / 1M = $0.01
+	rates.EXPECT().GetRateForModel(gomock.Any(), "gpt-4o").Return(money.Dollars(10), money.Amount(0), nil).AnyTimes()
+	return billing.NewService(walletRepo, repomocks.NewMockAPIKeyRepository(ctrl), usageLogRepo, rates, cfg, logger.NewNopLogger())
+}
+
+// memUsageLogs 模拟 usage_logs 的 request_id 唯一索引
+func memUsageLogs(t *testing.T) repository.UsageLogRepository {
+	var (
+		mu       sync.Mutex
+		recorded = make(map[string]bool)
+	)
+	logs := repomocks.NewMockUsageLogRepository(gomock.NewController(t))
+	logs.EXPECT().FindRequestIDs(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ids []string) (map[string]bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		found := make(map[string]bool)
+		for _, id := range ids {
+			found[id] = recorded[id]
+		}
+		return found, nil
+	}).AnyTimes()
+	logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, batch []*domain.UsageLog) (map[string]bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		inserted := make(map[string]bool)
+		for _, log := range batch {
+			if !recorded[log.RequestID] {
+				recorded[log.RequestID] = true
+				inserted[log.RequestID] = true
+			}
+		}
+		return inserted, nil
+	}).AnyTimes()
+	logs.EXPECT().UpdateCharged(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return logs
+}
+
+func charge(billingSvc billing.Service, userID int64, requestID string) error {
+	return billingSvc.Charge(context.Background(), []*domain.UsageLog{{UserID: userID, Model: "gpt-4o", InputTokens: 1000, RequestID: requestID}})
+}
+
+func TestService_ConcurrentChargeAndTopUp(t *testing.T) {
+	svc, repo := newTestService(t, config.BillingConfig{})
+	billingSvc := newBillingService(t, repo, memUsageLogs(t), config.BillingConfig{})
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Amount(200_000), "init"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%10 == 0 {
+				assert.NoError(t, svc.TopUp(ctx, 1, money.Dollars(1), "topup"))
+				return
+			}
+			// 每个请求提交两次（如预写日志重放），只扣费一次
+			assert.NoError(t, charge(billingSvc, 1, "req-"+strconv.Itoa(i/2)))
+		}(i)
+	}
+	wg.Wait()
+
+	// 90 次提交共 50 个不同的请求 ID
+	assert.Len(t, repo.ledger, 1+10+50)
+	// 0.2 + 10 × 1 - 50 × 0.01
+	assert.Equal(t, money.Amount(9_700_000), repo.wallet.Balance)
+	assertLedgerConsistent(t, repo)
+}
+
+func TestService_ChargeNoOverdraft(t *testing.T) {
+	svc, repo := newTestService(t, config.BillingConfig{NoOverdraft: true})
+	billingSvc := newBillingService(t, repo, memUsageLogs(t), config.BillingConfig{NoOverdraft: true})
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Amount(100_000), "init"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			assert.NoError(t, charge(billingSvc, 1, id))
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	// 0.1 / 0.01 = 10 次扣费，之后余额为 0 不再扣除，余额不会变为负数；未收取的请求写入金额为 0 的流水
+	assert.Len(t, repo.ledger, 1+30)
+	assert.Equal(t, money.Amount(0), repo.wallet.Balance)
+	assertLedgerConsistent(t, repo)
+}
+
+func reserve(svc Service, requestID string, amount money.Amount, apiKeyID *int64) error {
+	return svc.Reserve(context.Background(), &domain.WalletHold{RequestID: requestID, UserID: 1, APIKeyID: apiKeyID, Amount: amount})
+}
+
+func TestService_ReserveAndRelease(t *testing.T) {
+	svc, repo := newTestService(t, config.BillingConfig{})
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Amount(100_000), "init"))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved []string
+	)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			err := reserve(svc, id, money.Amount(10_000), nil)
+			if err != nil {
+				assert.ErrorIs(t, err, errs.ErrInsufficientBalance)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			reserved = append(reserved, id)
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	// 0.1 / 0.01 = 10 个预授权成功，余额尚未扣除
+	require.Len(t, reserved, 10)
+	assert.Equal(t, money.Amount(100_000), repo.wallet.Balance)
+
+	// 释放后腾出 0.01，重复释放不重复腾出
+	require.NoError(t, svc.Release(ctx, reserved[0]))
+	require.NoError(t, svc.Release(ctx, reserved[0]))
+	require.NoError(t, svc.Release(ctx, "unknown"))
+	require.NoError(t, reserve(svc, "a", money.Amount(10_000), nil))
+	assert.ErrorIs(t, reserve(svc, "b", money.Amount(10_000), nil), errs.ErrInsufficientBalance)
+}
+
+func TestService_ReserveExpiredHold(t *testing.T) {
+	svc, repo := newTestService(t, config.BillingConfig{HoldTTL: time.Millisecond})
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Amount(10_000), "init"))
+
+	require.NoError(t, reserve(svc, "crashed", money.Amount(10_000), nil))
+	time.Sleep(5 * time.Millisecond)
+	// 过期的预授权不再占用余额
+	require.NoError(t, reserve(svc, "next", money.Amount(10_000), nil))
+	assert.Equal(t, domain.HoldStatusExpired, repo.holds[0].Status)
+	assert.Equal(t, money.Amount(10_000), repo.wallet.Balance)
+}
+
+func TestService_ExtendHold(t *testing.T) {
+	svc, repo := newTestService(t, config.BillingConfig{HoldTTL: 50 * time.Millisecond})
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Amount(10_000), "init"))
+
+	// 持续顺延的预授权超过最初的有效期后仍占用余额
+	require.NoError(t, reserve(svc, "streaming", money.Amount(10_000), nil))
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, svc.Extend(ctx, "streaming"))
+	}
+	assert.ErrorIs(t, reserve(svc, "next", money.Amount(10_000), nil), errs.ErrInsufficientBalance)
+	assert.Equal(t, domain.HoldStatusActive, repo.holds[0].Status)
+
+	// 已释放的预授权不再顺延
+	require.NoError(t, svc.Release(ctx, "streaming"))
+	require.NoError(t, svc.Extend(ctx, "streaming"))
+	assert.Equal(t, domain.HoldStatusReleased, repo.holds[0].Status)
+	require.NoError(t, reserve(svc, "next", money.Amount(10_000), nil))
+}
+
+func TestService_ReserveAPIKeyQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keys := repomocks.NewMockAPIKeyRepository(ctrl)
+	quota := money.Amount(50_000)
+	keys.EXPECT().GetByID(gomock.Any(), int64(7)).
+		Return(&domain.APIKey{ID: 7, Quota: &quota, UsedAmount: money.Amount(30_000)}, nil).AnyTimes()
+
+	repo := &memWalletRepo{}
+	svc := NewService(repo, keys, config.BillingConfig{}, logger.NewNopLogger())
+	ctx := context.Background()
+	require.NoError(t, svc.TopUp(ctx, 1, money.Dollars(1), "init"))
+
+	// Key 剩余额度 0.05 - 0.03 = 0.02
+	keyID := int64(7)
+	require.NoError(t, reserve(svc, "1", money.Amount(10_000), &keyID))
+	require.NoError(t, reserve(svc, "2", money.Amount(10_000), &keyID))
+	assert.ErrorIs(t, reserve(svc, "3", money.Amount(10_000), &keyID), errs.ErrAPIKeyQuotaExceeded)
+
+	require.NoError(t, svc.Release(ctx, "1"))
+	require.NoError(t, reserve(svc, "3", money.Amount(10_000), &keyID))
+}
+
+// TestService_ConcurrentCharge_MySQL 在真实 MySQL 上验证计费与充值并发时的行锁与幂等：设置 TEST_MYSQL_DSN 后运行，
+// 如 TEST_MYSQL_DSN="root:pass@tcp(localhost:3306)/ai_gateway_test?parseTime=True"
+func TestService_ConcurrentCharge_MySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dao.Wallet{}, &dao.WalletTransaction{}, &dao.WalletHold{}, &dao.UsageLog{}))
+
+	ctrl := gomock.NewController(t)
+	repo := repository.NewWalletRepository(dao.NewGormWalletDAO(db))
+	svc := NewService(repo, repomocks.NewMockAPIKeyRepository(ctrl), config.BillingConfig{}, logger.NewNopLogger())
+	billingSvc := newBillingService(t, repo, repository.NewUsageLogRepository(dao.NewGormUsageLogDAO(db)), config.BillingConfig{})
+
+	ctx := context.Background()
+	userID := time.Now().UnixNano()
+	require.NoError(t, svc.TopUp(ctx, userID, money.Dollars(1), "init"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%6 == 0 {
+				assert.NoError(t, svc.TopUp(ctx, userID, money.Dollars(1), "topup"))
+				return
+			}
+			// 50 次提交共 25 个不同的请求 ID，重复提交不重复扣费
+			assert.NoError(t, charge(billingSvc, userID, strconv.FormatInt(userID, 10)+"-"+strconv.Itoa((i-i/6-1)/2)))
+		}(i)
+	}
+	wg.Wait()
+
+	wallet, err := repo.GetByUserID(ctx, userID)
+	require.NoError(t, err)
+	// 1 + 10 × 1 - 25 × 0.01
+	assert.Equal(t, money.Amount(10_750_000), wallet.Balance)
+
+	var sum money.Amount
+	require.NoError(t, db.Model(&dao.WalletTransaction{}).Where("wallet_id = ?", wallet.ID).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&sum))
+	assert.Equal(t, wallet.Balance, sum)
+
+	var txs []dao.WalletTransaction
+	require.NoError(t, db.Where("wallet_id = ?", wallet.ID).Order("id").Find(&txs).Error)
+	require.Len(t, txs, 1+10+25)
+	for i := 1; i < len(txs); i++ {
+		assert.Equal(t, txs[i-1].BalanceAfter, txs[i].BalanceBefore, "ledger #%d", i)
+	}
+}
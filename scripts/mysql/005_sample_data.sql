@@ -19,3 +19,8 @@ INSERT INTO routing_rules (rule_type, pattern, provider_name, actual_model, prio
 -- INSERT INTO load_balance_members (group_id, provider_name, weight, priority) VALUES
 -- (1, 'siliconflow', 2, 0),
 -- (1, 'openrouter', 1, 0);
+-- priority 值越大越优先：只在最高优先级的可用层内按权重分配，整层不可用时才溢出到下一层
+-- INSERT INTO load_balance_members (group_id, provider_name, weight, priority) VALUES
+-- (1, 'openai-reserved-a', 1, 10),
+-- (1, 'openai-reserved-b', 1, 10),
+-- (1, 'openai-payg', 1, 0);
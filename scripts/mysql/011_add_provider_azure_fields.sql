@@ -0,0 +1,3 @@
+-- Add Azure OpenAI fields to providers
+ALTER TABLE providers ADD COLUMN api_version VARCHAR(32) DEFAULT NULL COMMENT 'Azure OpenAI api-version';
+ALTER TABLE providers ADD COLUMN deployments TEXT DEFAULT NULL COMMENT 'Azure OpenAI 模型名到部署名的映射 (JSON)';
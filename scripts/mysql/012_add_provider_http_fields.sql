@@ -0,0 +1,6 @@
+-- Add per-provider HTTP transport settings to providers
+ALTER TABLE providers ADD COLUMN connect_timeout_ms INT NOT NULL DEFAULT 0 COMMENT '连接超时 (ms)，0 使用默认值';
+ALTER TABLE providers ADD COLUMN response_header_timeout_ms INT NOT NULL DEFAULT 0 COMMENT '响应头超时 (ms)，0 不限制';
+ALTER TABLE providers ADD COLUMN max_idle_conns INT NOT NULL DEFAULT 0 COMMENT '最大空闲连接数，0 使用默认值';
+ALTER TABLE providers ADD COLUMN max_idle_conns_per_host INT NOT NULL DEFAULT 0 COMMENT '每主机最大空闲连接数，0 使用默认值';
+ALTER TABLE providers ADD COLUMN proxy_url VARCHAR(256) DEFAULT NULL COMMENT '出站 HTTP 代理';
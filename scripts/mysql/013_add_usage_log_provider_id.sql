@@ -0,0 +1,6 @@
+-- Record the configured provider instance (name + id) on usage_logs
+ALTER TABLE usage_logs MODIFY COLUMN provider VARCHAR(64) DEFAULT NULL COMMENT '供应商实例名称';
+ALTER TABLE usage_logs ADD COLUMN provider_id BIGINT NOT NULL DEFAULT 0 COMMENT '供应商实例 ID';
+
+ALTER TABLE usage_logs ADD INDEX idx_provider (provider);
+ALTER TABLE usage_logs ADD INDEX idx_provider_id (provider_id);
@@ -0,0 +1,4 @@
+-- Add per-provider retry policy to providers
+ALTER TABLE providers ADD COLUMN retry_max_attempts INT NOT NULL DEFAULT 0 COMMENT '最大尝试次数，0 使用默认值，1 不重试';
+ALTER TABLE providers ADD COLUMN retry_initial_delay_ms INT NOT NULL DEFAULT 0 COMMENT '首次重试间隔 (ms)，0 使用默认值';
+ALTER TABLE providers ADD COLUMN retry_max_delay_ms INT NOT NULL DEFAULT 0 COMMENT '最大重试间隔 (ms)，0 使用默认值';
@@ -0,0 +1,3 @@
+-- Add cross-provider fallback chains to routing rules and record the serving hop on usage_logs
+ALTER TABLE routing_rules ADD COLUMN fallbacks TEXT COMMENT '回退链 JSON: [{"provider":"...","actualModel":"..."}]';
+ALTER TABLE usage_logs ADD COLUMN fallback_hop INT NOT NULL DEFAULT 0 COMMENT '实际服务的回退链跳数，0 表示主路由';
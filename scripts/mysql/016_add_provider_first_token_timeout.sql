@@ -0,0 +1,2 @@
+-- Add streaming time-to-first-token timeout to providers
+ALTER TABLE providers ADD COLUMN first_token_timeout_ms INT NOT NULL DEFAULT 0 COMMENT '流式首个增量超时 (ms)，0 表示不限制';
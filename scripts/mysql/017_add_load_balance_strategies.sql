@@ -0,0 +1,2 @@
+-- Add least-outstanding and peak-ewma load balance strategies
+ALTER TABLE load_balance_groups MODIFY COLUMN strategy ENUM('round-robin', 'random', 'failover', 'weighted', 'least-outstanding', 'peak-ewma') NOT NULL DEFAULT 'round-robin' COMMENT '负载均衡策略';
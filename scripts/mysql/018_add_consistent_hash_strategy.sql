@@ -0,0 +1,2 @@
+-- Add consistent-hash (sticky session) load balance strategy
+ALTER TABLE load_balance_groups MODIFY COLUMN strategy ENUM('round-robin', 'random', 'failover', 'weighted', 'least-outstanding', 'peak-ewma', 'consistent-hash') NOT NULL DEFAULT 'round-robin' COMMENT '负载均衡策略';
@@ -0,0 +1,18 @@
+-- 019: 创建模型能力表
+-- 路由时按请求所需能力（图像、工具、思考、JSON Schema、上下文长度）过滤候选
+
+CREATE TABLE IF NOT EXISTS model_capabilities (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    model_pattern VARCHAR(128) NOT NULL COMMENT '模型匹配模式，支持末尾通配符 *',
+    provider_name VARCHAR(64) NOT NULL DEFAULT '' COMMENT '适用的供应商实例，空表示所有供应商',
+    vision BOOLEAN DEFAULT FALSE COMMENT '支持图像输入',
+    tools BOOLEAN DEFAULT FALSE COMMENT '支持工具调用',
+    thinking BOOLEAN DEFAULT FALSE COMMENT '支持扩展思考',
+    json_schema BOOLEAN DEFAULT FALSE COMMENT '支持 JSON Schema 结构化输出',
+    context_window INT NOT NULL DEFAULT 0 COMMENT '上下文窗口 (tokens)，0 表示不限制',
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY idx_model_provider (model_pattern, provider_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='模型能力表';
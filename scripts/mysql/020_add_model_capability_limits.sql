@@ -0,0 +1,4 @@
+-- Add output limits and long-context sibling models to the model catalog
+ALTER TABLE model_capabilities
+    ADD COLUMN max_output_tokens INT NOT NULL DEFAULT 0 COMMENT '单次最大输出 (tokens)，0 表示不限制' AFTER context_window,
+    ADD COLUMN long_context_model VARCHAR(128) NOT NULL DEFAULT '' COMMENT '请求超出容量时改用的模型，空表示直接拒绝' AFTER max_output_tokens;
@@ -0,0 +1,4 @@
+-- Add weighted A/B traffic splitting to routing rules and record the serving variant on usage_logs
+ALTER TABLE routing_rules ADD COLUMN variants TEXT COMMENT 'A/B 实验组 JSON: [{"name":"...","provider":"...","actualModel":"...","percent":10}]，余下的流量归对照组';
+ALTER TABLE usage_logs ADD COLUMN variant VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'A/B 分流的实验组，control 为对照组，空表示未分流';
+ALTER TABLE usage_logs ADD INDEX idx_variant (variant);
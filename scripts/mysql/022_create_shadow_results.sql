@@ -0,0 +1,33 @@
+-- 022: 影子流量
+-- 路由规则按百分比采样请求，异步复制到影子供应商；影子响应不返回客户端、不计费，与主请求的结果一并保存用于对比
+
+ALTER TABLE routing_rules ADD COLUMN shadow TEXT COMMENT '影子流量 JSON: {"provider":"...","actualModel":"...","percent":5}';
+
+CREATE TABLE IF NOT EXISTS shadow_results (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(64) COMMENT '网关请求 ID，可关联 usage_logs',
+    model VARCHAR(128) NOT NULL COMMENT '客户端请求的模型',
+
+    primary_provider VARCHAR(64) NOT NULL,
+    primary_model VARCHAR(128) NOT NULL,
+    primary_latency_ms INT DEFAULT 0,
+    primary_input_tokens INT DEFAULT 0,
+    primary_output_tokens INT DEFAULT 0,
+    primary_content MEDIUMTEXT COMMENT '主请求输出的文本',
+    primary_error VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '主请求失败原因，空表示成功',
+
+    shadow_provider VARCHAR(64) NOT NULL,
+    shadow_model VARCHAR(128) NOT NULL,
+    shadow_latency_ms INT DEFAULT 0,
+    shadow_input_tokens INT DEFAULT 0,
+    shadow_output_tokens INT DEFAULT 0,
+    shadow_content MEDIUMTEXT COMMENT '影子请求输出的文本',
+    shadow_error VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '影子请求失败原因，空表示成功',
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_request_id (request_id),
+    INDEX idx_model (model),
+    INDEX idx_shadow_provider (shadow_provider),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='影子流量对比表';
@@ -0,0 +1,3 @@
+-- Add upstream API key pools to providers
+ALTER TABLE providers ADD COLUMN api_keys TEXT COMMENT 'Key 池 JSON: [{"name":"org-b","key":"sk-..."}]，与 api_key 一起轮换使用';
+ALTER TABLE providers ADD COLUMN key_strategy VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'Key 池选择策略: round_robin（默认）, least_used';
@@ -0,0 +1,3 @@
+-- Widen providers.api_key to hold envelope-encrypted keys (enc:v1:...)
+-- 启用 encryption.masterKey 后运行 go run ./cmd/rotatekeys -config <path> 加密存量 Key
+ALTER TABLE providers MODIFY COLUMN api_key VARCHAR(1024) NOT NULL COMMENT 'API Key（配置主密钥后为信封加密密文）';
@@ -0,0 +1,20 @@
+-- Store money as DECIMAL(20,6), matching the gateway's integer micro-units (1e-6 USD)
+-- 先按四舍五入把存量金额规整到 6 位小数，再修改列类型，避免 ALTER 时截断
+UPDATE wallets SET balance = ROUND(balance, 6);
+ALTER TABLE wallets MODIFY COLUMN balance DECIMAL(20,6) NOT NULL DEFAULT 0 COMMENT '余额（美元）';
+
+UPDATE wallet_transactions SET amount = ROUND(amount, 6), balance_before = ROUND(balance_before, 6), balance_after = ROUND(balance_after, 6);
+ALTER TABLE wallet_transactions
+    MODIFY COLUMN amount DECIMAL(20,6) NOT NULL COMMENT '变更金额（美元）',
+    MODIFY COLUMN balance_before DECIMAL(20,6) NOT NULL COMMENT '变更前余额',
+    MODIFY COLUMN balance_after DECIMAL(20,6) NOT NULL COMMENT '变更后余额';
+
+UPDATE model_rates SET prompt_price = ROUND(prompt_price, 6), completion_price = ROUND(completion_price, 6);
+ALTER TABLE model_rates
+    MODIFY COLUMN prompt_price DECIMAL(20,6) DEFAULT 0 COMMENT '输入价格（美元 / 1M tokens）',
+    MODIFY COLUMN completion_price DECIMAL(20,6) DEFAULT 0 COMMENT '输出价格（美元 / 1M tokens）';
+
+UPDATE api_keys SET used_amount = 0 WHERE used_amount IS NULL;
+ALTER TABLE api_keys
+    MODIFY COLUMN quota DECIMAL(20,6) DEFAULT NULL COMMENT '额度限制（美元，null=无限）',
+    MODIFY COLUMN used_amount DECIMAL(20,6) NOT NULL DEFAULT 0 COMMENT '已使用额度（美元）';
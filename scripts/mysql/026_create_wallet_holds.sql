@@ -0,0 +1,20 @@
+-- 026: 钱包预授权
+-- 请求发出前按预估最大费用冻结余额与 API Key 额度，请求结束后按实际费用结算；
+-- 超过 expires_at 仍未结算（如进程崩溃）的预授权不再占用余额
+
+CREATE TABLE IF NOT EXISTS wallet_holds (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(128) NOT NULL COMMENT '网关请求 ID',
+    user_id BIGINT NOT NULL,
+    wallet_id BIGINT NOT NULL,
+    api_key_id BIGINT DEFAULT NULL,
+    amount DECIMAL(20,6) NOT NULL COMMENT '冻结金额（美元）',
+    status VARCHAR(16) NOT NULL COMMENT 'active / settled / released / expired',
+    expires_at DATETIME(3) NOT NULL COMMENT '有效期',
+    created_at DATETIME(3) DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at DATETIME(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+
+    UNIQUE INDEX idx_wallet_holds_request_id (request_id),
+    INDEX idx_wallet_holds_wallet_status (wallet_id, status),
+    INDEX idx_wallet_holds_api_key_id (api_key_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='钱包预授权表';
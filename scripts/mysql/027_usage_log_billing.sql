@@ -0,0 +1,14 @@
+-- 027: 统一计费
+-- 用量日志记录每个请求按费率计算的费用，并以 request_id 唯一索引作为计费幂等键：
+-- 同一请求重复记账时用量日志写入被忽略，钱包流水与 Key 用量也不会重复
+
+ALTER TABLE usage_logs ADD COLUMN cost DECIMAL(20,6) NOT NULL DEFAULT 0 COMMENT '费用（美元）' AFTER output_tokens;
+
+-- 历史数据中客户端传入的 X-Request-ID 可能重复，先追加日志 ID 去重，再建唯一索引
+UPDATE usage_logs u
+JOIN (SELECT request_id FROM usage_logs WHERE request_id IS NOT NULL AND request_id <> '' GROUP BY request_id HAVING COUNT(*) > 1) d
+    ON u.request_id = d.request_id
+SET u.request_id = LEFT(CONCAT(u.request_id, '#', u.id), 64);
+UPDATE usage_logs SET request_id = NULL WHERE request_id = '';
+
+ALTER TABLE usage_logs ADD UNIQUE INDEX idx_usage_logs_request_id (request_id);
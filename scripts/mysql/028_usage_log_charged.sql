@@ -0,0 +1,7 @@
+-- 028: 用量日志记录实际收取的费用
+-- cost 为按费率计算的费用；charged 为实际从钱包扣除并计入 Key 用量的金额，
+-- 开启 billing.noOverdraft 且余额不足时小于 cost，差额不再收取
+
+ALTER TABLE usage_logs ADD COLUMN charged DECIMAL(20,6) NOT NULL DEFAULT 0 COMMENT '实际收取的费用（美元）' AFTER cost;
+
+UPDATE usage_logs SET charged = cost;
@@ -0,0 +1,6 @@
+-- 029: 负载均衡成员优先级改为数值越大越优先，与路由规则一致
+-- 取反已有的值，保持现有分组的层级顺序不变
+
+ALTER TABLE load_balance_members MODIFY COLUMN priority INT DEFAULT 0 COMMENT '成员优先级，数值越大优先级越高';
+
+UPDATE load_balance_members SET priority = -priority;
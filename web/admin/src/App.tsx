@@ -7,6 +7,7 @@ import { Providers } from '@/pages/Providers'
 import { RoutingRules } from '@/pages/RoutingRules'
 import { LoadBalance } from '@/pages/LoadBalance'
 import { ModelRates } from '@/pages/ModelRates'
+import { ModelCapabilities } from '@/pages/ModelCapabilities'
 import { ApiKeys } from '@/pages/ApiKeys'
 import { Settings } from '@/pages/Settings'
 import { Login } from '@/pages/Login'
@@ -15,6 +16,7 @@ import { AuthProvider, useAuth } from '@/contexts/AuthContext'
 import { Users } from '@/pages/Users'
 import { AuditLogs } from '@/pages/AuditLogs'
 import { UsageLeaderboard } from '@/pages/UsageLeaderboard'
+import { ShadowTraffic } from '@/pages/ShadowTraffic'
 
 
 
@@ -95,6 +97,14 @@ function App() {
               </RequireAuth>
             } />
 
+            <Route path="/model-capabilities" element={
+              <RequireAuth roles={['admin']}>
+                <Layout>
+                  <ModelCapabilities />
+                </Layout>
+              </RequireAuth>
+            } />
+
             <Route path="/api-keys" element={
               <RequireAuth>
                 <Layout>
@@ -127,6 +137,14 @@ function App() {
               </RequireAuth>
             } />
 
+            <Route path="/admin/shadow-traffic" element={
+              <RequireAuth roles={['admin']}>
+                <Layout>
+                  <ShadowTraffic />
+                </Layout>
+              </RequireAuth>
+            } />
+
             <Route path="/admin/leaderboard" element={
               <RequireAuth roles={['admin']}>
                 <Layout>
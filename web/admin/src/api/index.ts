@@ -2,8 +2,10 @@ import apiClient from './client'
 import type {
     Provider,
     CreateProviderRequest,
+    ProviderKeyStatus,
     RoutingRule,
     CreateRoutingRuleRequest,
+    RouteExplanation,
     LoadBalanceGroup,
     CreateLoadBalanceGroupRequest,
     APIKey,
@@ -19,6 +21,11 @@ import type {
     DailyUsage,
     ModelRate,
     CreateModelRateRequest,
+    ModelCapability,
+    CreateModelCapabilityRequest,
+    ShadowResult,
+    ShadowStats,
+    PaginatedResponse,
     Wallet,
     WalletTransaction,
     ModelWithPricing,
@@ -151,6 +158,15 @@ export const providerApi = {
     delete: async (id: number): Promise<void> => {
         await apiClient.delete(`/admin/providers/${id}`)
     },
+
+    keys: async (id: number): Promise<ProviderKeyStatus[]> => {
+        const res = await apiClient.get<ApiResponse<ProviderKeyStatus[]>>(`/admin/providers/${id}/keys`)
+        return res.data.data
+    },
+
+    enableKey: async (id: number, name: string): Promise<void> => {
+        await apiClient.post(`/admin/providers/${id}/keys/${encodeURIComponent(name)}/enable`)
+    },
 }
 
 // ========== Admin API (Routing Rules) ==========
@@ -174,6 +190,11 @@ export const routingRuleApi = {
     delete: async (id: number): Promise<void> => {
         await apiClient.delete(`/admin/routing-rules/${id}`)
     },
+
+    explain: async (model: string): Promise<RouteExplanation> => {
+        const res = await apiClient.get<ApiResponse<RouteExplanation>>('/admin/routing/explain', { params: { model } })
+        return res.data.data
+    },
 }
 
 // ========== Admin API (Load Balance) ==========
@@ -222,6 +243,45 @@ export const modelRateApi = {
     },
 }
 
+// ========== Admin API (Model Capabilities) ==========
+
+export const modelCapabilityApi = {
+    list: async (): Promise<ModelCapability[]> => {
+        const res = await apiClient.get<ApiResponse<ModelCapability[]>>('/admin/model-capabilities')
+        return res.data.data
+    },
+
+    create: async (data: CreateModelCapabilityRequest): Promise<ModelCapability> => {
+        const res = await apiClient.post<ApiResponse<ModelCapability>>('/admin/model-capabilities', data)
+        return res.data.data
+    },
+
+    update: async (id: number, data: CreateModelCapabilityRequest): Promise<ModelCapability> => {
+        const res = await apiClient.put<ApiResponse<ModelCapability>>(`/admin/model-capabilities/${id}`, data)
+        return res.data.data
+    },
+
+    delete: async (id: number): Promise<void> => {
+        await apiClient.delete(`/admin/model-capabilities/${id}`)
+    },
+}
+
+// ========== Admin API (Shadow Traffic) ==========
+
+export const shadowApi = {
+    list: async (page: number, pageSize: number, model?: string): Promise<PaginatedResponse<ShadowResult>> => {
+        const res = await apiClient.get<ApiResponse<PaginatedResponse<ShadowResult>>>('/admin/shadow-results', {
+            params: { page, pageSize, model: model || undefined },
+        })
+        return res.data.data
+    },
+
+    stats: async (days = 7): Promise<ShadowStats[]> => {
+        const res = await apiClient.get<ApiResponse<ShadowStats[]>>('/admin/shadow-results/stats', { params: { days } })
+        return res.data.data
+    },
+}
+
 // ========== Admin API (API Keys) ==========
 
 export const adminApiKeyApi = {
@@ -12,7 +12,9 @@ import {
     LogOut,
     User,
     Shield,
-    Trophy
+    Trophy,
+    Sparkles,
+    GitCompare
 } from 'lucide-react'
 import { cn } from '@/lib/utils'
 import { useAuth } from '@/contexts/AuthContext'
@@ -28,10 +30,12 @@ export function Layout({ children }: { children: React.ReactNode }) {
         { path: '/routing-rules', label: '路由规则', icon: GitBranch, roles: ['admin'] },
         { path: '/load-balance', label: '负载均衡', icon: Scale, roles: ['admin'] },
         { path: '/model-rates', label: '模型费率', icon: Coins, roles: ['admin'] },
+        { path: '/model-capabilities', label: '模型目录', icon: Sparkles, roles: ['admin'] },
         { path: '/api-keys', label: '我的密钥', icon: Key, roles: ['admin', 'user'] },
         { path: '/admin/api-keys', label: '系统密钥', icon: Key, roles: ['admin'] },
         { path: '/admin/users', label: '用户管理', icon: Users, roles: ['admin'] },
         { path: '/admin/audit-logs', label: '审计日志', icon: Shield, roles: ['admin'] },
+        { path: '/admin/shadow-traffic', label: '影子流量', icon: GitCompare, roles: ['admin'] },
         { path: '/admin/leaderboard', label: '排行榜', icon: Trophy, roles: ['admin'] },
         { path: '/settings', label: '设置', icon: Settings, roles: ['admin', 'user'] },
     ]
@@ -129,7 +129,10 @@ export function AuditLogs() {
                                     <TableCell>
                                         <div className="flex flex-col">
                                             <span className="font-medium">{log.model}</span>
-                                            <span className="text-xs text-muted-foreground">{log.provider}</span>
+                                            <span className="text-xs text-muted-foreground">
+                                                {log.provider}
+                                                {log.variant && ` · ${log.variant}`}
+                                            </span>
                                         </div>
                                     </TableCell>
                                     <TableCell>
@@ -141,7 +144,10 @@ export function AuditLogs() {
                                         <span className="text-xs text-muted-foreground">{log.latencyMs}ms</span>
                                     </TableCell>
                                     <TableCell className="font-mono text-xs">
-                                        {log.inputTokens} / {log.outputTokens}
+                                        <div className="flex flex-col">
+                                            <span>{log.inputTokens} / {log.outputTokens}</span>
+                                            <span className="text-muted-foreground">${log.cost}</span>
+                                        </div>
                                     </TableCell>
                                     <TableCell>
                                         <div className="flex flex-col text-xs">
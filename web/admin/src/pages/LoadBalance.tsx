@@ -94,6 +94,10 @@ export function LoadBalance() {
                     <p><strong>random</strong>: 随机策略，随机选择一个提供商</p>
                     <p><strong>weighted</strong>: 加权策略，按权重随机选择</p>
                     <p><strong>failover</strong>: 故障转移，优先使用主要提供商，失败时切换备用</p>
+                    <p><strong>least-outstanding</strong>: 最少在途请求，选择当前进行中请求最少的提供商（流式请求在整个流结束后才释放）</p>
+                    <p><strong>peak-ewma</strong>: 延迟感知，按 延迟 EWMA × (在途请求数 + 1) 选择；非流式按响应耗时、流式按首 token 时间计算，延迟突增立即生效，空闲后逐渐衰减以便重新探测</p>
+                    <p><strong>consistent-hash</strong>: 会话亲和，按一致性哈希把同一会话固定到同一个提供商，便于命中上游提示词缓存。会话键依次取请求头 X-Session-ID、系统提示词与首条消息的哈希、API Key；节点增减只迁移少量会话，节点不可用时顺延到下一个节点</p>
+                    <p><strong>优先级分层</strong>: 成员 priority 值越大越优先（与路由规则一致），流量只在最高优先级的可用层内按策略分配，整层不可用（故障、熔断或限流冷却）时才溢出到下一层；不按在途请求数判断饱和</p>
                 </CardContent>
             </Card>
         </div>
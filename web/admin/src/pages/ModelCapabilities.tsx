@@ -0,0 +1,296 @@
+import { useState } from 'react'
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
+import { Plus, Pencil, Trash2 } from 'lucide-react'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { modelCapabilityApi } from '@/api'
+import type { ModelCapability, CreateModelCapabilityRequest } from '@/types'
+
+// Simple Modal Component
+function Modal({ isOpen, onClose, title, children }: { isOpen: boolean; onClose: () => void; title: string; children: React.ReactNode }) {
+    if (!isOpen) return null
+    return (
+        <div className="fixed inset-0 z-50 flex items-center justify-center bg-black/50">
+            <div className="bg-background rounded-lg shadow-lg w-full max-w-md p-6 relative">
+                <h3 className="text-lg font-semibold mb-4">{title}</h3>
+                {children}
+                <button onClick={onClose} className="absolute top-4 right-4 text-gray-500 hover:text-gray-700">
+                    ✕
+                </button>
+            </div>
+        </div>
+    )
+}
+
+const capabilityFlags = [
+    { key: 'vision', label: '图像输入' },
+    { key: 'tools', label: '工具调用' },
+    { key: 'thinking', label: '扩展思考' },
+    { key: 'jsonSchema', label: 'JSON Schema' },
+] as const
+
+function CapabilityForm({ initial, pending, submitLabel, onSubmit, onCancel }: {
+    initial?: ModelCapability
+    pending: boolean
+    submitLabel: string
+    onSubmit: (data: CreateModelCapabilityRequest) => void
+    onCancel: () => void
+}) {
+    const handleSubmit = (e: React.FormEvent<HTMLFormElement>) => {
+        e.preventDefault()
+        const formData = new FormData(e.currentTarget)
+        onSubmit({
+            modelPattern: formData.get('modelPattern') as string,
+            providerName: (formData.get('providerName') as string).trim(),
+            vision: formData.get('vision') === 'on',
+            tools: formData.get('tools') === 'on',
+            thinking: formData.get('thinking') === 'on',
+            jsonSchema: formData.get('jsonSchema') === 'on',
+            contextWindow: parseInt(formData.get('contextWindow') as string) || 0,
+            maxOutputTokens: parseInt(formData.get('maxOutputTokens') as string) || 0,
+            longContextModel: (formData.get('longContextModel') as string).trim(),
+            enabled: formData.get('enabled') === 'on',
+        })
+    }
+
+    return (
+        <form onSubmit={handleSubmit} className="space-y-4">
+            <div className="space-y-2">
+                <Label htmlFor="modelPattern">模型模式</Label>
+                <Input
+                    id="modelPattern"
+                    name="modelPattern"
+                    placeholder="例如: gpt-4o* 或 claude-3-haiku"
+                    defaultValue={initial?.modelPattern}
+                    required
+                />
+                <p className="text-xs text-muted-foreground">
+                    按实际发送给上游的模型名匹配，支持末尾通配符 *，精确匹配优先于最长前缀
+                </p>
+            </div>
+            <div className="space-y-2">
+                <Label htmlFor="providerName">提供商（可选）</Label>
+                <Input
+                    id="providerName"
+                    name="providerName"
+                    placeholder="留空表示所有提供商"
+                    defaultValue={initial?.providerName}
+                />
+                <p className="text-xs text-muted-foreground">
+                    指定提供商的配置优先于通用配置，适用于能力受限的私有部署
+                </p>
+            </div>
+            <div className="grid grid-cols-2 gap-2">
+                {capabilityFlags.map(({ key, label }) => (
+                    <div key={key} className="flex items-center space-x-2">
+                        <input type="checkbox" id={key} name={key} defaultChecked={initial?.[key]} className="h-4 w-4" />
+                        <Label htmlFor={key}>{label}</Label>
+                    </div>
+                ))}
+            </div>
+            <div className="grid grid-cols-2 gap-4">
+                <div className="space-y-2">
+                    <Label htmlFor="contextWindow">上下文窗口 (tokens)</Label>
+                    <Input
+                        id="contextWindow"
+                        name="contextWindow"
+                        type="number"
+                        min="0"
+                        defaultValue={initial?.contextWindow ?? 0}
+                    />
+                </div>
+                <div className="space-y-2">
+                    <Label htmlFor="maxOutputTokens">最大输出 (tokens)</Label>
+                    <Input
+                        id="maxOutputTokens"
+                        name="maxOutputTokens"
+                        type="number"
+                        min="0"
+                        defaultValue={initial?.maxOutputTokens ?? 0}
+                    />
+                </div>
+            </div>
+            <p className="text-xs text-muted-foreground">0 表示不限制</p>
+            <div className="space-y-2">
+                <Label htmlFor="longContextModel">长上下文模型（可选）</Label>
+                <Input
+                    id="longContextModel"
+                    name="longContextModel"
+                    placeholder="例如: gpt-4.1"
+                    defaultValue={initial?.longContextModel}
+                />
+                <p className="text-xs text-muted-foreground">
+                    请求超出上下文窗口或最大输出时改用该模型重新路由，留空则直接拒绝
+                </p>
+            </div>
+            <div className="flex items-center space-x-2">
+                <input type="checkbox" id="enabled" name="enabled" defaultChecked={initial?.enabled ?? true} className="h-4 w-4" />
+                <Label htmlFor="enabled">启用</Label>
+            </div>
+            <div className="flex justify-end space-x-2">
+                <Button type="button" variant="outline" onClick={onCancel}>
+                    取消
+                </Button>
+                <Button type="submit" disabled={pending}>
+                    {submitLabel}
+                </Button>
+            </div>
+        </form>
+    )
+}
+
+export function ModelCapabilities() {
+    const [isCreateOpen, setIsCreateOpen] = useState(false)
+    const [editing, setEditing] = useState<ModelCapability | null>(null)
+    const queryClient = useQueryClient()
+
+    const { data: capabilities, isLoading } = useQuery({
+        queryKey: ['modelCapabilities'],
+        queryFn: modelCapabilityApi.list,
+    })
+
+    const createMutation = useMutation({
+        mutationFn: modelCapabilityApi.create,
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['modelCapabilities'] })
+            setIsCreateOpen(false)
+        },
+        onError: (error: any) => {
+            alert('创建失败: ' + (error.response?.data?.msg || '未知错误'))
+        },
+    })
+
+    const updateMutation = useMutation({
+        mutationFn: ({ id, data }: { id: number; data: CreateModelCapabilityRequest }) =>
+            modelCapabilityApi.update(id, data),
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['modelCapabilities'] })
+            setEditing(null)
+        },
+        onError: (error: any) => {
+            alert('更新失败: ' + (error.response?.data?.msg || '未知错误'))
+        },
+    })
+
+    const deleteMutation = useMutation({
+        mutationFn: modelCapabilityApi.delete,
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['modelCapabilities'] })
+        },
+        onError: (error: any) => {
+            alert('删除失败: ' + (error.response?.data?.msg || '未知错误'))
+        },
+    })
+
+    if (isLoading) return <div>Loading...</div>
+
+    return (
+        <div className="space-y-4">
+            <div className="flex items-center justify-between">
+                <div>
+                    <h2 className="text-2xl font-bold tracking-tight">模型目录</h2>
+                    <p className="text-muted-foreground">
+                        路由时跳过不支持请求所需能力（图像、工具、思考、JSON Schema、上下文长度）的候选，全部不满足时直接返回 400；超出容量的请求可改用长上下文模型。未配置的模型不做限制
+                    </p>
+                </div>
+                <Button onClick={() => setIsCreateOpen(true)}>
+                    <Plus className="mr-2 h-4 w-4" />
+                    添加能力
+                </Button>
+            </div>
+
+            <Modal isOpen={isCreateOpen} onClose={() => setIsCreateOpen(false)} title="添加模型目录条目">
+                <CapabilityForm
+                    pending={createMutation.isPending}
+                    submitLabel={createMutation.isPending ? '创建中...' : '创建'}
+                    onSubmit={(data) => createMutation.mutate(data)}
+                    onCancel={() => setIsCreateOpen(false)}
+                />
+            </Modal>
+
+            <div className="rounded-md border">
+                <table className="w-full text-sm">
+                    <thead>
+                        <tr className="border-b bg-muted/50 text-left">
+                            <th className="p-3 font-medium">模型模式</th>
+                            <th className="p-3 font-medium">提供商</th>
+                            <th className="p-3 font-medium">能力</th>
+                            <th className="p-3 font-medium">上下文窗口</th>
+                            <th className="p-3 font-medium">最大输出</th>
+                            <th className="p-3 font-medium">长上下文模型</th>
+                            <th className="p-3 font-medium">状态</th>
+                            <th className="p-3 font-medium w-[100px]">操作</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {capabilities?.map((cap) => (
+                            <tr key={cap.id} className="border-b last:border-0">
+                                <td className="p-3 font-mono">{cap.modelPattern}</td>
+                                <td className="p-3 text-muted-foreground">{cap.providerName || '全部'}</td>
+                                <td className="p-3">
+                                    <div className="flex flex-wrap gap-1">
+                                        {capabilityFlags.filter(({ key }) => cap[key]).map(({ key, label }) => (
+                                            <span key={key} className="rounded bg-muted px-2 py-0.5 text-xs">{label}</span>
+                                        ))}
+                                    </div>
+                                </td>
+                                <td className="p-3 font-mono text-muted-foreground">
+                                    {cap.contextWindow > 0 ? cap.contextWindow.toLocaleString() : '-'}
+                                </td>
+                                <td className="p-3 font-mono text-muted-foreground">
+                                    {cap.maxOutputTokens > 0 ? cap.maxOutputTokens.toLocaleString() : '-'}
+                                </td>
+                                <td className="p-3 font-mono text-muted-foreground">{cap.longContextModel || '-'}</td>
+                                <td className="p-3">
+                                    <div className={`flex items-center gap-2 ${cap.enabled ? 'text-green-600' : 'text-gray-400'}`}>
+                                        <div className={`h-2 w-2 rounded-full ${cap.enabled ? 'bg-green-600' : 'bg-gray-400'}`} />
+                                        {cap.enabled ? '已启用' : '已禁用'}
+                                    </div>
+                                </td>
+                                <td className="p-3">
+                                    <div className="flex items-center gap-2">
+                                        <Button variant="ghost" size="icon" onClick={() => setEditing(cap)}>
+                                            <Pencil className="h-4 w-4" />
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            size="icon"
+                                            className="text-destructive"
+                                            onClick={() => {
+                                                if (confirm('确定要删除这个能力配置吗？')) {
+                                                    deleteMutation.mutate(cap.id)
+                                                }
+                                            }}
+                                        >
+                                            <Trash2 className="h-4 w-4" />
+                                        </Button>
+                                    </div>
+                                </td>
+                            </tr>
+                        ))}
+                        {capabilities?.length === 0 && (
+                            <tr>
+                                <td colSpan={8} className="p-4 text-center text-muted-foreground h-24">
+                                    暂无配置，所有请求按路由规则直接转发
+                                </td>
+                            </tr>
+                        )}
+                    </tbody>
+                </table>
+            </div>
+
+            <Modal isOpen={!!editing} onClose={() => setEditing(null)} title="编辑模型目录条目">
+                {editing && (
+                    <CapabilityForm
+                        initial={editing}
+                        pending={updateMutation.isPending}
+                        submitLabel={updateMutation.isPending ? '更新中...' : '更新'}
+                        onSubmit={(data) => updateMutation.mutate({ id: editing.id, data })}
+                        onCancel={() => setEditing(null)}
+                    />
+                )}
+            </Modal>
+        </div>
+    )
+}
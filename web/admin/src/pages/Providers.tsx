@@ -1,11 +1,11 @@
 import { useState } from 'react'
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
 import { providerApi } from '@/api'
-import type { Provider, CreateProviderRequest } from '@/types'
+import type { Provider, CreateProviderRequest, ProviderKey } from '@/types'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Input } from '@/components/ui/input'
-import { Plus, Trash2, Edit, Check, X } from 'lucide-react'
+import { Plus, Trash2, Edit, Check, X, KeyRound, RotateCcw } from 'lucide-react'
 
 export function Providers() {
     const queryClient = useQueryClient()
@@ -21,6 +21,10 @@ export function Providers() {
         isDefault: false,
         enabled: true,
     })
+    const [deploymentsText, setDeploymentsText] = useState('')
+    // Key 池以 "name=key" 逗号分隔输入
+    const [keysText, setKeysText] = useState('')
+    const [keysProvider, setKeysProvider] = useState<Provider | null>(null)
 
     const { data: providers, isLoading } = useQuery({
         queryKey: ['providers'],
@@ -64,6 +68,8 @@ export function Providers() {
             isDefault: false,
             enabled: true,
         })
+        setDeploymentsText('')
+        setKeysText('')
     }
 
     const startEdit = (provider: Provider) => {
@@ -72,20 +78,52 @@ export function Providers() {
             name: provider.name,
             type: provider.type,
             apiKey: provider.apiKey,
+            keyStrategy: provider.keyStrategy,
             baseURL: provider.baseURL,
             models: provider.models || [],
+            apiVersion: provider.apiVersion,
+            deployments: provider.deployments,
             timeoutMs: provider.timeoutMs,
+            connectTimeoutMs: provider.connectTimeoutMs,
+            responseHeaderTimeoutMs: provider.responseHeaderTimeoutMs,
+            maxIdleConns: provider.maxIdleConns,
+            maxIdleConnsPerHost: provider.maxIdleConnsPerHost,
+            proxyURL: provider.proxyURL,
+            retryMaxAttempts: provider.retryMaxAttempts,
+            retryInitialDelayMs: provider.retryInitialDelayMs,
+            retryMaxDelayMs: provider.retryMaxDelayMs,
+            firstTokenTimeoutMs: provider.firstTokenTimeoutMs,
             isDefault: provider.isDefault,
             enabled: provider.enabled,
         })
+        setDeploymentsText(
+            Object.entries(provider.deployments || {}).map(([m, d]) => `${m}=${d}`).join(', ')
+        )
+        setKeysText((provider.apiKeys || []).map((k) => `${k.name}=${k.key}`).join(', '))
     }
 
     const handleSubmit = (e: React.FormEvent) => {
         e.preventDefault()
+        const data: CreateProviderRequest = {
+            ...formData,
+            deployments: Object.fromEntries(
+                deploymentsText.split(',')
+                    .map(s => s.split('=').map(p => p.trim()))
+                    .filter(([m, d]) => m && d)
+            ),
+            apiKeys: keysText
+                .split(',')
+                .map((s) => s.trim())
+                .filter(Boolean)
+                .map((entry): ProviderKey => {
+                    const eq = entry.indexOf('=')
+                    return { name: entry.slice(0, eq).trim(), key: entry.slice(eq + 1).trim() }
+                }),
+        }
         if (editingId) {
-            updateMutation.mutate({ id: editingId, data: formData })
+            updateMutation.mutate({ id: editingId, data })
         } else {
-            createMutation.mutate(formData)
+            createMutation.mutate(data)
         }
     }
 
@@ -126,6 +164,8 @@ export function Providers() {
                                     >
                                         <option value="openai">OpenAI</option>
                                         <option value="anthropic">Anthropic</option>
+                                        <option value="gemini">Gemini</option>
+                                        <option value="azure-openai">Azure OpenAI</option>
                                     </select>
                                 </div>
                                 <div>
@@ -135,8 +175,40 @@ export function Providers() {
                                         value={formData.apiKey}
                                         onChange={(e) => setFormData({ ...formData, apiKey: e.target.value })}
                                         placeholder="sk-..."
-                                        required
                                     />
+                                    {editingId && (
+                                        <>
+                                            <p className="text-xs text-muted-foreground">Key 已脱敏显示，留空或不修改则保留原 Key</p>
+                                            <label className="flex items-center gap-2">
+                                                <input
+                                                    type="checkbox"
+                                                    checked={!!formData.clearApiKey}
+                                                    onChange={(e) => setFormData({ ...formData, clearApiKey: e.target.checked })}
+                                                />
+                                                <span className="text-xs">清除已保存的 Key</span>
+                                            </label>
+                                        </>
+                                    )}
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">Key 池（可选，与 API Key 一起轮换）</label>
+                                    <Input
+                                        type="password"
+                                        value={keysText}
+                                        onChange={(e) => setKeysText(e.target.value)}
+                                        placeholder="org-b=sk-..., org-c=sk-..."
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">Key 选择策略</label>
+                                    <select
+                                        className="flex h-9 w-full rounded-md border border-input bg-transparent px-3 py-1 text-sm shadow-sm"
+                                        value={formData.keyStrategy || 'round_robin'}
+                                        onChange={(e) => setFormData({ ...formData, keyStrategy: e.target.value })}
+                                    >
+                                        <option value="round_robin">轮询</option>
+                                        <option value="least_used">最少使用</option>
+                                    </select>
                                 </div>
                                 <div>
                                     <Input
@@ -157,6 +229,26 @@ export function Providers() {
                                         placeholder="gpt-4, gpt-3.5-turbo"
                                     />
                                 </div>
+                                {formData.type === 'azure-openai' && (
+                                    <>
+                                        <div>
+                                            <label className="text-sm font-medium">API Version</label>
+                                            <Input
+                                                value={formData.apiVersion || ''}
+                                                onChange={(e) => setFormData({ ...formData, apiVersion: e.target.value })}
+                                                placeholder="2024-10-21"
+                                            />
+                                        </div>
+                                        <div>
+                                            <label className="text-sm font-medium">部署映射 (模型=部署, 逗号分隔)</label>
+                                            <Input
+                                                value={deploymentsText}
+                                                onChange={(e) => setDeploymentsText(e.target.value)}
+                                                placeholder="gpt-4o=my-gpt4o-deployment"
+                                            />
+                                        </div>
+                                    </>
+                                )}
                                 <div>
                                     <label className="text-sm font-medium">超时时间 (ms)</label>
                                     <Input
@@ -165,6 +257,77 @@ export function Providers() {
                                         onChange={(e) => setFormData({ ...formData, timeoutMs: parseInt(e.target.value) })}
                                     />
                                 </div>
+                                <div>
+                                    <label className="text-sm font-medium">连接超时 (ms，0 为默认)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.connectTimeoutMs || 0}
+                                        onChange={(e) => setFormData({ ...formData, connectTimeoutMs: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">响应头超时 (ms，0 为不限制)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.responseHeaderTimeoutMs || 0}
+                                        onChange={(e) => setFormData({ ...formData, responseHeaderTimeoutMs: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">最大空闲连接数 (0 为默认)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.maxIdleConns || 0}
+                                        onChange={(e) => setFormData({ ...formData, maxIdleConns: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">每主机最大空闲连接数 (0 为默认)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.maxIdleConnsPerHost || 0}
+                                        onChange={(e) => setFormData({ ...formData, maxIdleConnsPerHost: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">出站代理 (可选)</label>
+                                    <Input
+                                        value={formData.proxyURL || ''}
+                                        onChange={(e) => setFormData({ ...formData, proxyURL: e.target.value })}
+                                        placeholder="http://proxy.internal:3128"
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">最大尝试次数 (0 为默认，1 不重试)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.retryMaxAttempts || 0}
+                                        onChange={(e) => setFormData({ ...formData, retryMaxAttempts: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">重试间隔 (ms，首次 / 最大，0 为默认)</label>
+                                    <div className="flex gap-2">
+                                        <Input
+                                            type="number"
+                                            value={formData.retryInitialDelayMs || 0}
+                                            onChange={(e) => setFormData({ ...formData, retryInitialDelayMs: parseInt(e.target.value) || 0 })}
+                                        />
+                                        <Input
+                                            type="number"
+                                            value={formData.retryMaxDelayMs || 0}
+                                            onChange={(e) => setFormData({ ...formData, retryMaxDelayMs: parseInt(e.target.value) || 0 })}
+                                        />
+                                    </div>
+                                </div>
+                                <div>
+                                    <label className="text-sm font-medium">流式首 Token 超时 (ms，0 为不限制)</label>
+                                    <Input
+                                        type="number"
+                                        value={formData.firstTokenTimeoutMs || 0}
+                                        onChange={(e) => setFormData({ ...formData, firstTokenTimeoutMs: parseInt(e.target.value) || 0 })}
+                                    />
+                                </div>
                                 <div className="flex items-center gap-4 pt-6">
                                     <label className="flex items-center gap-2">
                                         <input
@@ -265,6 +428,16 @@ export function Providers() {
                                                     >
                                                         <Edit className="h-4 w-4" />
                                                     </Button>
+                                                    {!!provider.apiKeys?.length && (
+                                                        <Button
+                                                            size="sm"
+                                                            variant="ghost"
+                                                            title="Key 池"
+                                                            onClick={() => setKeysProvider(provider)}
+                                                        >
+                                                            <KeyRound className="h-4 w-4" />
+                                                        </Button>
+                                                    )}
                                                     <Button
                                                         size="sm"
                                                         variant="ghost"
@@ -283,6 +456,109 @@ export function Providers() {
                     )}
                 </CardContent>
             </Card>
+
+            {keysProvider && <ProviderKeys provider={keysProvider} onClose={() => setKeysProvider(null)} />}
         </div>
     )
 }
+
+const keyStateStyles: Record<string, string> = {
+    active: 'bg-green-100 text-green-700',
+    cooldown: 'bg-yellow-100 text-yellow-700',
+    disabled: 'bg-red-100 text-red-700',
+}
+
+const keyStateLabels: Record<string, string> = {
+    active: '可用',
+    cooldown: '冷却中',
+    disabled: '已禁用',
+}
+
+// ProviderKeys 展示供应商 Key 池中各 Key 的状态与用量，可重新启用被自动禁用的 Key。
+function ProviderKeys({ provider, onClose }: { provider: Provider; onClose: () => void }) {
+    const queryClient = useQueryClient()
+    const { data: keys, isLoading } = useQuery({
+        queryKey: ['provider-keys', provider.id],
+        queryFn: () => providerApi.keys(provider.id),
+        refetchInterval: 10000,
+    })
+
+    const enableMutation = useMutation({
+        mutationFn: (name: string) => providerApi.enableKey(provider.id, name),
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['provider-keys', provider.id] })
+        },
+    })
+
+    return (
+        <Card>
+            <CardHeader className="flex flex-row items-center justify-between">
+                <CardTitle>Key 池 · {provider.name}</CardTitle>
+                <Button size="sm" variant="ghost" onClick={onClose}>
+                    <X className="h-4 w-4" />
+                </Button>
+            </CardHeader>
+            <CardContent>
+                {isLoading ? (
+                    <div className="text-center py-8 text-muted-foreground">加载中...</div>
+                ) : !keys?.length ? (
+                    <div className="text-center py-8 text-muted-foreground">Key 池尚未加载</div>
+                ) : (
+                    <div className="overflow-x-auto">
+                        <table className="w-full">
+                            <thead>
+                                <tr className="border-b text-left text-sm text-muted-foreground">
+                                    <th className="pb-3 font-medium">名称</th>
+                                    <th className="pb-3 font-medium">Key</th>
+                                    <th className="pb-3 font-medium">状态</th>
+                                    <th className="pb-3 font-medium">请求 / 成功 / 失败 / 限流</th>
+                                    <th className="pb-3 font-medium">Tokens (In/Out)</th>
+                                    <th className="pb-3 font-medium">最近错误</th>
+                                    <th className="pb-3 font-medium">操作</th>
+                                </tr>
+                            </thead>
+                            <tbody>
+                                {keys.map((k) => (
+                                    <tr key={k.name} className="border-b last:border-0">
+                                        <td className="py-3 font-medium">{k.name}</td>
+                                        <td className="py-3 font-mono text-xs">{k.key}</td>
+                                        <td className="py-3">
+                                            <span
+                                                className={`rounded-full px-2 py-1 text-xs ${keyStateStyles[k.state]}`}
+                                                title={k.disabledReason || (k.cooldownUntil && `until ${new Date(k.cooldownUntil).toLocaleTimeString()}`) || undefined}
+                                            >
+                                                {keyStateLabels[k.state]}
+                                            </span>
+                                        </td>
+                                        <td className="py-3 font-mono text-xs">
+                                            {k.requests} / {k.successes} / {k.failures} / {k.rateLimited}
+                                        </td>
+                                        <td className="py-3 font-mono text-xs">
+                                            {k.inputTokens} / {k.outputTokens}
+                                        </td>
+                                        <td className="py-3 text-xs text-muted-foreground truncate max-w-[240px]" title={k.lastError}>
+                                            {k.lastError || '-'}
+                                        </td>
+                                        <td className="py-3">
+                                            {k.state !== 'active' && (
+                                                <Button
+                                                    size="sm"
+                                                    variant="ghost"
+                                                    title="重新启用"
+                                                    onClick={() => enableMutation.mutate(k.name)}
+                                                    disabled={enableMutation.isPending}
+                                                >
+                                                    <RotateCcw className="h-4 w-4" />
+                                                </Button>
+                                            )}
+                                        </td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    </div>
+                )}
+            </CardContent>
+        </Card>
+    )
+}
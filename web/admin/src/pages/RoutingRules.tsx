@@ -1,11 +1,11 @@
 import { useState } from 'react'
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
 import { routingRuleApi } from '@/api'
-import type { RoutingRule, CreateRoutingRuleRequest } from '@/types'
+import type { RoutingRule, CreateRoutingRuleRequest, RouteTarget, RouteVariant, ShadowTarget, RouteExplanation } from '@/types'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Input } from '@/components/ui/input'
-import { Plus, Trash2, Check, X } from 'lucide-react'
+import { Plus, Trash2, Check, X, Search } from 'lucide-react'
 
 export function RoutingRules() {
     const queryClient = useQueryClient()
@@ -18,6 +18,14 @@ export function RoutingRules() {
         priority: 0,
         enabled: true,
     })
+    // 回退链以 "model@provider" 逗号分隔输入，省略 model 时沿用请求模型
+    const [fallbacksText, setFallbacksText] = useState('')
+    // A/B 实验组以 "name:percent:model@provider" 逗号分隔输入，省略 model 时沿用请求模型
+    const [variantsText, setVariantsText] = useState('')
+    // 影子流量以 "percent:model@provider" 输入，省略 model 时沿用请求模型
+    const [shadowText, setShadowText] = useState('')
+    const [explainModel, setExplainModel] = useState('')
+    const [explanation, setExplanation] = useState<RouteExplanation | null>(null)
 
     const { data: rules, isLoading } = useQuery({
         queryKey: ['routing-rules'],
@@ -30,6 +38,9 @@ export function RoutingRules() {
             queryClient.invalidateQueries({ queryKey: ['routing-rules'] })
             setShowForm(false)
             setFormData({ ruleType: 'exact', pattern: '', providerName: '', actualModel: '', priority: 0, enabled: true })
+            setFallbacksText('')
+            setVariantsText('')
+            setShadowText('')
         },
     })
 
@@ -40,9 +51,57 @@ export function RoutingRules() {
         },
     })
 
+    const explainMutation = useMutation({
+        mutationFn: routingRuleApi.explain,
+        onSuccess: setExplanation,
+    })
+
+    const handleExplain = (e: React.FormEvent) => {
+        e.preventDefault()
+        if (explainModel.trim()) {
+            explainMutation.mutate(explainModel.trim())
+        }
+    }
+
     const handleSubmit = (e: React.FormEvent) => {
         e.preventDefault()
-        createMutation.mutate(formData)
+        const fallbacks: RouteTarget[] = fallbacksText
+            .split(',')
+            .map((s) => s.trim())
+            .filter(Boolean)
+            .map((hop) => {
+                const at = hop.lastIndexOf('@')
+                return at < 0
+                    ? { provider: hop }
+                    : { provider: hop.slice(at + 1).trim(), actualModel: hop.slice(0, at).trim() }
+            })
+        const variants: RouteVariant[] = variantsText
+            .split(',')
+            .map((s) => s.trim())
+            .filter(Boolean)
+            .map((arm) => {
+                const [name, percent, ...rest] = arm.split(':')
+                const target = rest.join(':')
+                const at = target.lastIndexOf('@')
+                return {
+                    name: name.trim(),
+                    percent: parseInt(percent) || 0,
+                    provider: (at < 0 ? target : target.slice(at + 1)).trim(),
+                    actualModel: at < 0 ? undefined : target.slice(0, at).trim(),
+                }
+            })
+        let shadow: ShadowTarget | undefined
+        if (shadowText.trim()) {
+            const [percent, ...rest] = shadowText.trim().split(':')
+            const target = rest.join(':')
+            const at = target.lastIndexOf('@')
+            shadow = {
+                percent: parseInt(percent) || 0,
+                provider: (at < 0 ? target : target.slice(at + 1)).trim(),
+                actualModel: at < 0 ? undefined : target.slice(0, at).trim(),
+            }
+        }
+        createMutation.mutate({ ...formData, fallbacks, variants, shadow })
     }
 
     return (
@@ -73,6 +132,7 @@ export function RoutingRules() {
                                         <option value="exact">精确匹配</option>
                                         <option value="prefix">前缀匹配</option>
                                         <option value="wildcard">通配符</option>
+                                        <option value="regex">正则表达式</option>
                                     </select>
                                 </div>
                                 <div>
@@ -80,7 +140,7 @@ export function RoutingRules() {
                                     <Input
                                         value={formData.pattern}
                                         onChange={(e) => setFormData({ ...formData, pattern: e.target.value })}
-                                        placeholder="gpt-4 或 deepseek-*"
+                                        placeholder="gpt-4、claude-*-sonnet-* 或 my-(.*)"
                                         required
                                     />
                                 </div>
@@ -94,11 +154,11 @@ export function RoutingRules() {
                                     />
                                 </div>
                                 <div>
-                                    <label className="text-sm font-medium">实际模型（可选）</label>
+                                    <label className="text-sm font-medium">实际模型（可选，通配符 / 正则可用 $1 引用捕获组）</label>
                                     <Input
                                         value={formData.actualModel}
                                         onChange={(e) => setFormData({ ...formData, actualModel: e.target.value })}
-                                        placeholder="gpt-4-turbo"
+                                        placeholder="gpt-4-turbo 或 gpt-$1"
                                     />
                                 </div>
                                 <div>
@@ -109,6 +169,30 @@ export function RoutingRules() {
                                         onChange={(e) => setFormData({ ...formData, priority: parseInt(e.target.value) })}
                                     />
                                 </div>
+                                <div className="md:col-span-2">
+                                    <label className="text-sm font-medium">回退链（可选，按顺序尝试）</label>
+                                    <Input
+                                        value={fallbacksText}
+                                        onChange={(e) => setFallbacksText(e.target.value)}
+                                        placeholder="gpt-4o@azure, claude-sonnet-4@anthropic"
+                                    />
+                                </div>
+                                <div className="md:col-span-2">
+                                    <label className="text-sm font-medium">A/B 分流（可选，按用户或 API Key 固定分组，余下流量归对照组）</label>
+                                    <Input
+                                        value={variantsText}
+                                        onChange={(e) => setVariantsText(e.target.value)}
+                                        placeholder="sonnet-4.5:10:claude-sonnet-4-5@anthropic"
+                                    />
+                                </div>
+                                <div className="md:col-span-2">
+                                    <label className="text-sm font-medium">影子流量（可选，按比例异步镜像，结果不返回给客户端）</label>
+                                    <Input
+                                        value={shadowText}
+                                        onChange={(e) => setShadowText(e.target.value)}
+                                        placeholder="5:qwen3-32b@self-hosted"
+                                    />
+                                </div>
                                 <div className="flex items-center gap-4 pt-6">
                                     <label className="flex items-center gap-2">
                                         <input
@@ -135,6 +219,55 @@ export function RoutingRules() {
                 </Card>
             )}
 
+            <Card>
+                <CardHeader>
+                    <CardTitle>路由诊断</CardTitle>
+                </CardHeader>
+                <CardContent className="space-y-4">
+                    <form onSubmit={handleExplain} className="flex gap-2">
+                        <Input
+                            value={explainModel}
+                            onChange={(e) => setExplainModel(e.target.value)}
+                            placeholder="输入模型名，例如 gpt-4o"
+                        />
+                        <Button type="submit" disabled={explainMutation.isPending}>
+                            <Search className="mr-2 h-4 w-4" />
+                            解析
+                        </Button>
+                    </form>
+                    {explanation && (
+                        <div className="space-y-2 text-sm">
+                            {explanation.error ? (
+                                <div className="text-destructive">{explanation.error}</div>
+                            ) : (
+                                <div>
+                                    命中 <span className="font-mono">{explanation.matchedBy}</span>
+                                    {explanation.rule && <> (<span className="font-mono">{explanation.rule}</span>)</>}
+                                    {' → '}
+                                    <span className="font-medium">{explanation.provider || '按请求负载均衡'}</span>
+                                    {' / '}
+                                    <span className="font-mono">{explanation.actualModel}</span>
+                                </div>
+                            )}
+                            <ol className="list-decimal pl-5 text-muted-foreground">
+                                {explanation.steps.map((step, i) => (
+                                    <li key={i} className={step.matched ? 'text-foreground' : ''}>
+                                        <span className="font-mono">{step.stage}</span>: {step.detail}
+                                    </li>
+                                ))}
+                            </ol>
+                            {[...(explanation.candidates ?? []), ...(explanation.fallbacks ?? [])].map((c, i) => (
+                                <div key={i} className="font-mono text-xs">
+                                    {c.hop ? `fallback #${c.hop}` : 'candidate'}: {c.provider}
+                                    {c.actualModel && ` (${c.actualModel})`} — {c.available ? 'available' : 'unavailable'}
+                                    {c.breaker && `, breaker ${c.breaker}`}
+                                </div>
+                            ))}
+                        </div>
+                    )}
+                </CardContent>
+            </Card>
+
             <Card>
                 <CardHeader>
                     <CardTitle>规则列表</CardTitle>
@@ -153,6 +286,9 @@ export function RoutingRules() {
                                         <th className="pb-3 font-medium">模式</th>
                                         <th className="pb-3 font-medium">提供商</th>
                                         <th className="pb-3 font-medium">实际模型</th>
+                                        <th className="pb-3 font-medium">回退链</th>
+                                        <th className="pb-3 font-medium">A/B 分流</th>
+                                        <th className="pb-3 font-medium">影子流量</th>
                                         <th className="pb-3 font-medium">优先级</th>
                                         <th className="pb-3 font-medium">状态</th>
                                         <th className="pb-3 font-medium">操作</th>
@@ -171,6 +307,25 @@ export function RoutingRules() {
                                             <td className="py-3 text-sm text-muted-foreground">
                                                 {rule.actualModel || '-'}
                                             </td>
+                                            <td className="py-3 font-mono text-xs text-muted-foreground">
+                                                {rule.fallbacks?.length
+                                                    ? rule.fallbacks
+                                                        .map((f) => (f.actualModel ? `${f.actualModel}@${f.provider}` : f.provider))
+                                                        .join(' → ')
+                                                    : '-'}
+                                            </td>
+                                            <td className="py-3 font-mono text-xs text-muted-foreground">
+                                                {rule.variants?.length
+                                                    ? rule.variants
+                                                        .map((v) => `${v.name} ${v.percent}% → ${v.actualModel ? `${v.actualModel}@${v.provider}` : v.provider}`)
+                                                        .join(', ')
+                                                    : '-'}
+                                            </td>
+                                            <td className="py-3 font-mono text-xs text-muted-foreground">
+                                                {rule.shadow
+                                                    ? `${rule.shadow.percent}% → ${rule.shadow.actualModel ? `${rule.shadow.actualModel}@${rule.shadow.provider}` : rule.shadow.provider}`
+                                                    : '-'}
+                                            </td>
                                             <td className="py-3">{rule.priority}</td>
                                             <td className="py-3">
                                                 <span
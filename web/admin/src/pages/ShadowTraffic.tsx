@@ -0,0 +1,236 @@
+import { Fragment, useState } from 'react'
+import { useQuery } from '@tanstack/react-query'
+import {
+    Table,
+    TableBody,
+    TableCell,
+    TableHead,
+    TableHeader,
+    TableRow,
+} from '@/components/ui/table'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Badge } from '@/components/ui/badge'
+import { Loader2, ChevronLeft, ChevronRight, ChevronDown } from 'lucide-react'
+import { format } from 'date-fns'
+import { shadowApi } from '@/api'
+import type { ShadowResult, ShadowStats } from '@/types'
+
+function Outcome({ error, latencyMs, outputTokens }: { error?: string; latencyMs: number; outputTokens: number }) {
+    if (error) {
+        return (
+            <Badge variant="destructive" title={error}>
+                失败
+            </Badge>
+        )
+    }
+    return (
+        <span className="text-xs text-muted-foreground">
+            {latencyMs}ms · {outputTokens} tokens
+        </span>
+    )
+}
+
+export function ShadowTraffic() {
+    const [page, setPage] = useState(1)
+    const [pageSize] = useState(20)
+    const [days, setDays] = useState(7)
+    const [model, setModel] = useState('')
+    const [expanded, setExpanded] = useState<number | null>(null)
+
+    const { data: stats } = useQuery<ShadowStats[]>({
+        queryKey: ['shadow-stats', days],
+        queryFn: () => shadowApi.stats(days),
+    })
+
+    const { data, isLoading, isError } = useQuery({
+        queryKey: ['shadow-results', page, pageSize, model],
+        queryFn: () => shadowApi.list(page, pageSize, model),
+    })
+
+    return (
+        <div className="space-y-6">
+            <div className="flex items-center justify-between">
+                <h2 className="text-3xl font-bold tracking-tight">影子流量</h2>
+                <select
+                    className="h-9 rounded-md border border-input bg-background px-3 py-1 text-sm shadow-sm"
+                    value={days}
+                    onChange={(e) => setDays(Number(e.target.value))}
+                >
+                    {[1, 7, 30].map((d) => (
+                        <option key={d} value={d}>
+                            最近 {d} 天
+                        </option>
+                    ))}
+                </select>
+            </div>
+
+            {/* Aggregate comparison */}
+            <div className="rounded-md border bg-card">
+                <Table>
+                    <TableHeader>
+                        <TableRow>
+                            <TableHead>模型</TableHead>
+                            <TableHead>影子目标</TableHead>
+                            <TableHead>样本数</TableHead>
+                            <TableHead>平均耗时 (主/影子)</TableHead>
+                            <TableHead>平均输出 Tokens (主/影子)</TableHead>
+                            <TableHead>失败数 (主/影子)</TableHead>
+                        </TableRow>
+                    </TableHeader>
+                    <TableBody>
+                        {!stats || stats.length === 0 ? (
+                            <TableRow>
+                                <TableCell colSpan={6} className="h-16 text-center text-muted-foreground">
+                                    暂无影子流量
+                                </TableCell>
+                            </TableRow>
+                        ) : (
+                            stats.map((s) => (
+                                <TableRow key={`${s.model}/${s.shadowProvider}/${s.shadowModel}`}>
+                                    <TableCell className="font-medium">{s.model}</TableCell>
+                                    <TableCell className="text-sm">
+                                        {s.shadowModel}@{s.shadowProvider}
+                                    </TableCell>
+                                    <TableCell>{s.requests}</TableCell>
+                                    <TableCell className="font-mono text-xs">
+                                        {s.primaryAvgLatencyMs}ms / {s.shadowAvgLatencyMs}ms
+                                    </TableCell>
+                                    <TableCell className="font-mono text-xs">
+                                        {s.primaryAvgOutputTokens} / {s.shadowAvgOutputTokens}
+                                    </TableCell>
+                                    <TableCell className="font-mono text-xs">
+                                        {s.primaryErrors} / {s.shadowErrors}
+                                    </TableCell>
+                                </TableRow>
+                            ))
+                        )}
+                    </TableBody>
+                </Table>
+            </div>
+
+            {/* Filters */}
+            <div className="flex items-center gap-4 bg-card p-4 rounded-lg border">
+                <Input
+                    placeholder="按模型筛选"
+                    value={model}
+                    onChange={(e) => {
+                        setModel(e.target.value)
+                        setPage(1)
+                    }}
+                />
+            </div>
+
+            <div className="rounded-md border bg-card">
+                <Table>
+                    <TableHeader>
+                        <TableRow>
+                            <TableHead className="w-8" />
+                            <TableHead>时间</TableHead>
+                            <TableHead>模型</TableHead>
+                            <TableHead>主路由</TableHead>
+                            <TableHead>影子</TableHead>
+                        </TableRow>
+                    </TableHeader>
+                    <TableBody>
+                        {isLoading ? (
+                            <TableRow>
+                                <TableCell colSpan={5} className="h-24 text-center">
+                                    <div className="flex items-center justify-center gap-2">
+                                        <Loader2 className="h-4 w-4 animate-spin" />
+                                        加载中...
+                                    </div>
+                                </TableCell>
+                            </TableRow>
+                        ) : isError ? (
+                            <TableRow>
+                                <TableCell colSpan={5} className="h-24 text-center text-destructive">
+                                    加载失败
+                                </TableCell>
+                            </TableRow>
+                        ) : data?.data.length === 0 ? (
+                            <TableRow>
+                                <TableCell colSpan={5} className="h-24 text-center text-muted-foreground">
+                                    暂无记录
+                                </TableCell>
+                            </TableRow>
+                        ) : (
+                            data?.data.map((r: ShadowResult) => (
+                                <Fragment key={r.id}>
+                                    <TableRow
+                                        className="cursor-pointer"
+                                        onClick={() => setExpanded(expanded === r.id ? null : r.id)}
+                                    >
+                                        <TableCell>
+                                            {expanded === r.id ? (
+                                                <ChevronDown className="h-4 w-4" />
+                                            ) : (
+                                                <ChevronRight className="h-4 w-4" />
+                                            )}
+                                        </TableCell>
+                                        <TableCell className="text-sm text-muted-foreground">
+                                            {format(new Date(r.createdAt), 'MM-dd HH:mm:ss')}
+                                        </TableCell>
+                                        <TableCell className="font-medium">{r.model}</TableCell>
+                                        <TableCell>
+                                            <div className="flex flex-col">
+                                                <span className="text-sm">{r.primaryModel}@{r.primaryProvider}</span>
+                                                <Outcome error={r.primaryError} latencyMs={r.primaryLatencyMs} outputTokens={r.primaryOutputTokens} />
+                                            </div>
+                                        </TableCell>
+                                        <TableCell>
+                                            <div className="flex flex-col">
+                                                <span className="text-sm">{r.shadowModel}@{r.shadowProvider}</span>
+                                                <Outcome error={r.shadowError} latencyMs={r.shadowLatencyMs} outputTokens={r.shadowOutputTokens} />
+                                            </div>
+                                        </TableCell>
+                                    </TableRow>
+                                    {expanded === r.id && (
+                                        <TableRow>
+                                            <TableCell colSpan={5}>
+                                                <div className="grid grid-cols-2 gap-4">
+                                                    <pre className="whitespace-pre-wrap text-xs bg-muted p-3 rounded max-h-96 overflow-auto">
+                                                        {r.primaryError || r.primaryContent || '-'}
+                                                    </pre>
+                                                    <pre className="whitespace-pre-wrap text-xs bg-muted p-3 rounded max-h-96 overflow-auto">
+                                                        {r.shadowError || r.shadowContent || '-'}
+                                                    </pre>
+                                                </div>
+                                            </TableCell>
+                                        </TableRow>
+                                    )}
+                                </Fragment>
+                            ))
+                        )}
+                    </TableBody>
+                </Table>
+            </div>
+
+            {/* Pagination */}
+            <div className="flex items-center justify-end space-x-2 px-2">
+                <div className="text-sm text-muted-foreground">
+                    Total {data?.total || 0}
+                </div>
+                <Button
+                    variant="outline"
+                    className="h-8 w-8 p-0"
+                    onClick={() => setPage((old) => Math.max(old - 1, 1))}
+                    disabled={page === 1 || isLoading}
+                >
+                    <ChevronLeft className="h-4 w-4" />
+                </Button>
+                <div className="text-sm font-medium">
+                    Page {page}
+                </div>
+                <Button
+                    variant="outline"
+                    className="h-8 w-8 p-0"
+                    onClick={() => setPage((old) => (!data || old * pageSize >= data.total ? old : old + 1))}
+                    disabled={!data || page * pageSize >= data.total || isLoading}
+                >
+                    <ChevronRight className="h-4 w-4" />
+                </Button>
+            </div>
+        </div>
+    )
+}
@@ -2,11 +2,24 @@
 export interface Provider {
     id: number
     name: string
-    type: string // openai, anthropic
+    type: string // openai, anthropic, gemini, azure-openai
     apiKey: string
+    apiKeys?: ProviderKey[] // additional upstream keys, rotated together with apiKey
+    keyStrategy?: string // round_robin (default), least_used
     baseURL: string
     models?: string[] // Optional list of models
+    apiVersion?: string // Azure OpenAI only
+    deployments?: Record<string, string> // Azure OpenAI only: model -> deployment
     timeoutMs: number
+    connectTimeoutMs?: number
+    responseHeaderTimeoutMs?: number
+    maxIdleConns?: number
+    maxIdleConnsPerHost?: number
+    proxyURL?: string
+    retryMaxAttempts?: number // 0 = default, 1 = no retry
+    retryInitialDelayMs?: number
+    retryMaxDelayMs?: number
+    firstTokenTimeoutMs?: number // streaming time-to-first-token limit, 0 = unlimited
     isDefault: boolean
     enabled: boolean
     createdAt: number
@@ -16,14 +29,51 @@ export interface Provider {
 export interface CreateProviderRequest {
     name: string
     type: string
-    apiKey: string
+    apiKey?: string
+    clearApiKey?: boolean // update only: remove the stored apiKey
+    apiKeys?: ProviderKey[]
+    keyStrategy?: string
     baseURL: string
     models?: string[]
+    apiVersion?: string
+    deployments?: Record<string, string>
     timeoutMs: number
+    connectTimeoutMs?: number
+    responseHeaderTimeoutMs?: number
+    maxIdleConns?: number
+    maxIdleConnsPerHost?: number
+    proxyURL?: string
+    retryMaxAttempts?: number // 0 = default, 1 = no retry
+    retryInitialDelayMs?: number
+    retryMaxDelayMs?: number
+    firstTokenTimeoutMs?: number // streaming time-to-first-token limit, 0 = unlimited
     isDefault: boolean
     enabled: boolean
 }
 
+export interface ProviderKey {
+    name: string
+    key: string
+}
+
+// Key 池中单个 Key 的状态与用量（GET /admin/providers/:id/keys）
+export interface ProviderKeyStatus {
+    name: string
+    key: string // masked
+    state: 'active' | 'cooldown' | 'disabled'
+    cooldownUntil?: string
+    disabledReason?: string
+    inflight: number
+    requests: number
+    successes: number
+    failures: number
+    rateLimited: number
+    inputTokens: number
+    outputTokens: number
+    lastUsedAt?: string
+    lastError?: string
+}
+
 // 路由规则类型定义
 export interface RoutingRule {
     id: number
@@ -32,20 +82,71 @@ export interface RoutingRule {
     providerName: string
     actualModel: string
     priority: number
+    fallbacks?: RouteTarget[] // ordered fallback chain
+    variants?: RouteVariant[] // A/B split; the remaining traffic goes to the control group
+    shadow?: ShadowTarget // mirror a sample of requests for comparison
     enabled: boolean
     createdAt: string
     updatedAt: string
 }
 
+export interface RouteTarget {
+    provider: string
+    actualModel?: string
+}
+
+export interface RouteVariant {
+    name: string
+    provider: string
+    actualModel?: string
+    percent: number
+}
+
+export interface ShadowTarget {
+    provider: string
+    actualModel?: string
+    percent: number
+}
+
 export interface CreateRoutingRuleRequest {
     ruleType: string
     pattern: string
     providerName: string
     actualModel?: string
     priority?: number
+    fallbacks?: RouteTarget[]
+    variants?: RouteVariant[]
+    shadow?: ShadowTarget
     enabled?: boolean
 }
 
+// 路由解析说明（GET /admin/routing/explain）
+export interface RouteCandidate {
+    provider: string
+    actualModel?: string
+    hop?: number
+    available: boolean
+    breaker?: string
+}
+
+export interface RouteStep {
+    stage: string // exact, loadBalance, pattern, prefix, typeDefault
+    matched: boolean
+    detail?: string
+}
+
+export interface RouteExplanation {
+    model: string
+    matchedBy?: string
+    rule?: string
+    provider?: string // empty when a load balancer picks per request
+    actualModel?: string
+    candidates?: RouteCandidate[]
+    fallbacks?: RouteCandidate[]
+    steps: RouteStep[]
+    error?: string
+}
+
 // 负载均衡类型定义
 export interface LoadBalanceGroup {
     id: number
@@ -169,6 +270,35 @@ export interface CreateModelRateRequest {
     enabled: boolean
 }
 
+export interface ModelCapability {
+    id: number
+    modelPattern: string
+    providerName: string
+    vision: boolean
+    tools: boolean
+    thinking: boolean
+    jsonSchema: boolean
+    contextWindow: number
+    maxOutputTokens: number
+    longContextModel: string
+    enabled: boolean
+    createdAt: string
+    updatedAt: string
+}
+
+export interface CreateModelCapabilityRequest {
+    modelPattern: string
+    providerName: string
+    vision: boolean
+    tools: boolean
+    thinking: boolean
+    jsonSchema: boolean
+    contextWindow: number
+    maxOutputTokens: number
+    longContextModel: string
+    enabled: boolean
+}
+
 export interface ModelWithPricing {
     modelName: string
     promptPrice: number
@@ -200,6 +330,42 @@ export interface TopUpRequest {
     amount: number
 }
 
+// ========== Shadow Traffic ==========
+
+export interface ShadowResult {
+    id: number
+    requestId?: string
+    model: string
+    primaryProvider: string
+    primaryModel: string
+    primaryLatencyMs: number
+    primaryInputTokens: number
+    primaryOutputTokens: number
+    primaryContent: string
+    primaryError?: string
+    shadowProvider: string
+    shadowModel: string
+    shadowLatencyMs: number
+    shadowInputTokens: number
+    shadowOutputTokens: number
+    shadowContent: string
+    shadowError?: string
+    createdAt: string
+}
+
+export interface ShadowStats {
+    model: string
+    shadowProvider: string
+    shadowModel: string
+    requests: number
+    primaryErrors: number
+    shadowErrors: number
+    primaryAvgLatencyMs: number
+    shadowAvgLatencyMs: number
+    primaryAvgOutputTokens: number
+    shadowAvgOutputTokens: number
+}
+
 // ========== Audit Logs ==========
 
 export interface UsageLog {
@@ -207,9 +373,14 @@ export interface UsageLog {
     userId: number
     apiKeyId?: number
     model: string
-    provider: string
+    provider: string // provider instance name
+    providerId?: number
+    fallbackHop?: number // 0 = primary route
+    variant?: string // A/B split group, empty when the rule has no split
     inputTokens: number
     outputTokens: number
+    cost: number // USD, computed once per request
+    charged: number // USD actually deducted; below cost when billing.noOverdraft capped the charge
     latencyMs: number
     statusCode: number
     clientIp?: string